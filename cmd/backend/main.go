@@ -1,20 +1,29 @@
 package main
 
 import (
-	"context"
+	"flag"
+	_ "net/http/pprof"
 	"os"
 	"os/signal"
+	"syscall"
 
-	"github.com/asianchinaboi/backendserver/internal/api"
+	"github.com/asianchinaboi/backendserver/internal/app"
 	"github.com/asianchinaboi/backendserver/internal/config"
-	"github.com/asianchinaboi/backendserver/internal/db"
 	"github.com/asianchinaboi/backendserver/internal/logger"
-	"github.com/asianchinaboi/backendserver/internal/schedule"
-
-	_ "net/http/pprof"
 )
 
 func main() {
+	src := config.Flags(flag.CommandLine)
+	flag.Parse()
+
+	conf, err := config.Load(*src)
+	if err != nil {
+		logger.Fatal.Fatalln(err)
+	}
+	a, err := app.New(conf, *src)
+	if err != nil {
+		logger.Fatal.Fatalln(err)
+	}
 
 	logger.Info.Println("Handling requests")
 
@@ -25,33 +34,31 @@ func main() {
 		}
 	}()
 
-	if err := os.MkdirAll("uploads/user", os.ModePerm); err != nil {
-		logger.Fatal.Panicln(err)
-	}
-	if err := os.MkdirAll("uploads/msg", os.ModePerm); err != nil {
+	if err := a.Start(); err != nil {
 		logger.Fatal.Panicln(err)
 	}
-	if err := os.MkdirAll("uploads/guild", os.ModePerm); err != nil {
-		logger.Fatal.Panicln(err)
-	}
-	defer db.Db.Close()
-
-	server := api.StartServer()
-	schedule.Start()
 
 	go func() {
-		if err := server.ListenAndServe(); err != nil {
+		if err := a.ListenAndServe(); err != nil {
 			logger.Fatal.Println(err)
 		}
 	}()
 
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			if err := a.Reload(); err != nil {
+				logger.Error.Printf("config not reloaded: %v\n", err)
+				continue
+			}
+			logger.Info.Println("Reloaded config")
+		}
+	}()
+
 	c := make(chan os.Signal, 1) //listen for cancellation
-	signal.Notify(c, os.Interrupt)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	<-c //pause code here until interrupted
 
-	logger.Info.Println("Shutting down server")
-	ctx, cancel := context.WithTimeout(context.Background(), config.Config.Server.Timeout.Server)
-	defer cancel()
-
-	server.Shutdown(ctx)
+	a.Shutdown()
 }
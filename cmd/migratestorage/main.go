@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/asianchinaboi/backendserver/internal/config"
+	"github.com/asianchinaboi/backendserver/internal/logger"
+	"github.com/asianchinaboi/backendserver/internal/storage"
+)
+
+//copies every uploaded file from one storage backend to another
+//keys stay the same so the files rows dont need to change
+//e.g. go run ./cmd/migratestorage -from local -to s3
+
+func main() {
+	sources := config.Flags(flag.CommandLine)
+	from := flag.String("from", "local", "backend to copy from (local or s3)")
+	to := flag.String("to", "", "backend to copy to (local or s3, default storage.backend in the config)")
+	localPath := flag.String("path", "", "folder used by the local backend (default storage.local.path in the config)")
+	remove := flag.Bool("delete", false, "delete each file from the old backend once copied")
+	flag.Parse()
+
+	conf, err := config.Load(*sources)
+	if err != nil {
+		fatal("unable to load the config", "error", err)
+	}
+	if *to == "" {
+		*to = conf.Storage.Backend
+	}
+	if *localPath != "" {
+		conf.Storage.Local.Path = *localPath
+	}
+	if *from == *to {
+		fatal("from and to are the same backend", "backend", *to)
+	}
+
+	src, err := storage.New(conf, *from)
+	if err != nil {
+		fatal("unable to open the backend", "backend", *from, "error", err)
+	}
+	dst, err := storage.New(conf, *to)
+	if err != nil {
+		fatal("unable to open the backend", "backend", *to, "error", err)
+	}
+
+	ctx := context.Background()
+	objects, err := src.List(ctx, "")
+	if err != nil {
+		fatal("unable to list files", "backend", *from, "error", err)
+	}
+	logger.Default.Info("found files", "files", len(objects), "backend", *from)
+
+	copied, skipped, failed := 0, 0, 0
+	for _, object := range objects {
+		if existing, err := dst.Stat(ctx, object.Key); err == nil && existing.Size == object.Size {
+			skipped++ //already copied by an earlier run
+		} else if err := copyObject(ctx, src, dst, object); err != nil {
+			logger.Default.Error("failed to copy", "key", object.Key, "error", err)
+			failed++
+			continue
+		} else {
+			copied++
+		}
+		if *remove {
+			if err := src.Delete(ctx, object.Key); err != nil {
+				logger.Default.Warn("failed to delete", "key", object.Key, "error", err)
+			}
+		}
+	}
+	logger.Default.Info("done", "copied", copied, "skipped", skipped, "failed", failed)
+	if failed > 0 {
+		fatal("some files failed to copy, run again to retry them")
+	}
+}
+
+// fatal logs at the fatal level and exits, the source is the line that called it
+func fatal(msg string, args ...interface{}) {
+	logger.Default.Output(2, logger.LevelFatal, msg, args...)
+	os.Exit(1)
+}
+
+func copyObject(ctx context.Context, src storage.Backend, dst storage.Backend, object storage.Object) error {
+	r, err := src.Get(ctx, object.Key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if err := dst.Put(ctx, object.Key, r, object.Size); err != nil {
+		return err
+	}
+	copiedObject, err := dst.Stat(ctx, object.Key)
+	if err != nil {
+		return err
+	}
+	if copiedObject.Size != object.Size {
+		return fmt.Errorf("size mismatch, expected %d got %d", object.Size, copiedObject.Size)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package deps
+
+import (
+	"database/sql"
+
+	"github.com/asianchinaboi/backendserver/internal/captcha"
+	"github.com/asianchinaboi/backendserver/internal/config"
+	"github.com/asianchinaboi/backendserver/internal/cooldown"
+	"github.com/asianchinaboi/backendserver/internal/interactions"
+	"github.com/asianchinaboi/backendserver/internal/logger"
+	"github.com/asianchinaboi/backendserver/internal/scanner"
+	"github.com/asianchinaboi/backendserver/internal/storage"
+	"github.com/asianchinaboi/backendserver/internal/wsclient"
+)
+
+// Deps is what the app hands every route package, handlers read these instead of package vars
+// fields that can change on SIGHUP are read through Live
+type Deps struct {
+	Db           *sql.DB
+	Store        storage.Backend
+	Config       *config.Settings
+	Live         *config.Live
+	Limiter      cooldown.Limiter
+	Strikes      *cooldown.Strikes
+	Pools        *wsclient.ClientPools
+	Scanner      scanner.Scanner  //nil when scanning is turned off
+	Captcha      captcha.Provider //nil when the configured provider isnt registered
+	Interactions *interactions.Manager
+	Log          *logger.Logger
+	NodeId       int64
+	Scheduler    func() bool //whether scheduled jobs are running, for /readyz
+}
@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"database/sql"
+
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/session"
 	"github.com/gin-gonic/gin"
@@ -8,19 +10,27 @@ import (
 
 const User = "user"
 
-func Auth(c *gin.Context) {
-	token, ok := c.Request.Header["Authorization"]
-	if !ok || len(token) == 0 {
-		errors.SendErrorResponse(c, errors.ErrAbsentToken, errors.StatusAbsentToken)
-		c.Abort()
-		return
-	}
-	user, err := session.CheckToken(token[0])
-	c.Set(User, user)
-	if err != nil {
-		errors.SendErrorResponse(c, err, errors.StatusNotAuthorised)
-		c.Abort()
-		return
+func Auth(conn *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if existing, ok := c.Get(User); ok { //already checked by cooldown
+			if user, _ := existing.(*session.Session); user != nil {
+				c.Next()
+				return
+			}
+		}
+		token, ok := c.Request.Header["Authorization"]
+		if !ok || len(token) == 0 {
+			errors.SendErrorResponse(c, errors.ErrAbsentToken, errors.StatusAbsentToken)
+			c.Abort()
+			return
+		}
+		user, err := session.CheckAuthorization(conn, token[0])
+		c.Set(User, user)
+		if err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusNotAuthorised)
+			c.Abort()
+			return
+		}
+		c.Next()
 	}
-	c.Next()
 }
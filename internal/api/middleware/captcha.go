@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"github.com/asianchinaboi/backendserver/internal/captcha"
+	"github.com/asianchinaboi/backendserver/internal/config"
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	CaptchaIdHeader       = "X-Captcha-Id"
+	CaptchaSolutionHeader = "X-Captcha-Solution"
+)
+
+// Captcha checks the challenge from GET /api/captcha was solved before letting the request through
+// provider is nil when the one in the config isnt registered
+func Captcha(conf *config.Settings, provider captcha.Provider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !conf.Captcha.Enabled {
+			c.Next()
+			return
+		}
+		id := c.GetHeader(CaptchaIdHeader)
+		solution := c.GetHeader(CaptchaSolutionHeader)
+		if id == "" || solution == "" {
+			errors.SendErrorResponse(c, errors.ErrCaptchaRequired, errors.StatusCaptchaRequired)
+			c.Abort()
+			return
+		}
+		if provider == nil {
+			errors.SendErrorResponse(c, errors.ErrCaptchaProviderNotExist, errors.StatusInternalError)
+			c.Abort()
+			return
+		}
+		if err := provider.Verify(c.ClientIP(), id, solution); err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusCaptchaInvalid)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -1,18 +1,114 @@
 package middleware
 
 import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/asianchinaboi/backendserver/internal/config"
 	"github.com/asianchinaboi/backendserver/internal/cooldown"
 	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/asianchinaboi/backendserver/internal/logger"
+	"github.com/asianchinaboi/backendserver/internal/metrics"
+	"github.com/asianchinaboi/backendserver/internal/session"
 	"github.com/gin-gonic/gin"
 )
 
-func Cooldown(c *gin.Context) {
+const (
+	RateLimitHeader          = "X-RateLimit-Limit"
+	RateLimitRemainingHeader = "X-RateLimit-Remaining"
+	RateLimitResetHeader     = "X-RateLimit-Reset" //unix seconds when the bucket is full again
+	RetryAfterHeader         = "Retry-After"       //seconds, only sent when limited
+)
+
+// Cooldown rate limits by ip and then by user if theres a valid token
+// the ip bucket is taken from before the token is looked up so bad tokens cant be used to hammer the db
+// bots get the multiplier on a seperate ip bucket, a bot token that doesnt check out is charged to the normal one too
+// every route with a rule in the config gets its own bucket, the rest share one
+// limiter is where the buckets are kept, memory or postgres depending on the config, conn is where tokens are looked up
+// rejected ips get a strike in strikes, rules are read from live so reloads apply straight away
+func Cooldown(live *config.Live, limiter cooldown.Limiter, strikes *cooldown.Strikes, conn *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		conf := live.Current()
+		ip := c.ClientIP()
+		route := c.Request.Method + " " + c.FullPath()
+		name, rule := cooldown.RuleFor(conf, route)
+		header := c.GetHeader("Authorization")
+
+		if strings.HasPrefix(header, session.BotPrefix) {
+			botRule := rule
+			botRule.Limit *= botMultiplier(conf)
+			botCooldown(c, limiter, strikes, conn, route, name, rule, botRule, header)
+			return
+		}
+		if !take(c, limiter, strikes, route, name+":ip:"+ip, rule) {
+			return
+		}
+		if header == "" {
+			c.Next()
+			return
+		}
+
+		user, err := session.CheckAuthorization(conn, header)
+		if err != nil { //auth rejects it on the routes that need a token
+			c.Next()
+			return
+		}
+		c.Set(User, user) //saves auth from checking the token again
+		if !take(c, limiter, strikes, route, fmt.Sprintf("%s:user:%d", name, user.Id), rule) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// botCooldown is for "Bot <token>" headers, a lot of bots run from the same host so they get more out of their ip
+func botCooldown(c *gin.Context, limiter cooldown.Limiter, strikes *cooldown.Strikes, conn *sql.DB, route string, name string, rule cooldown.Rule, botRule cooldown.Rule, header string) {
 	ip := c.ClientIP()
-	canPass := cooldown.Manager.AddCount(ip)
-	if !canPass {
-		errors.SendErrorResponse(c, errors.ErrCooldownActive, errors.StatusCooldownActive)
-		c.Abort()
+	if !take(c, limiter, strikes, route, name+":botip:"+ip, botRule) {
+		return
+	}
+	user, err := session.CheckAuthorization(conn, header)
+	if err != nil || !user.Bot { //limited like a request without a token so made up bot tokens dont get the multiplier
+		if take(c, limiter, strikes, route, name+":ip:"+ip, rule) {
+			c.Next()
+		}
+		return
+	}
+	c.Set(User, user)
+	if !take(c, limiter, strikes, route, fmt.Sprintf("%s:user:%d", name, user.Id), botRule) {
 		return
 	}
 	c.Next()
 }
+
+func botMultiplier(conf *config.Settings) int {
+	if multiplier := conf.RateLimit.BotMultiplier; multiplier > 1 {
+		return multiplier
+	}
+	return 1
+}
+
+// take sets the headers from the bucket and aborts if its empty, returns whether the request can go on
+func take(c *gin.Context, limiter cooldown.Limiter, strikes *cooldown.Strikes, route string, key string, rule cooldown.Rule) bool {
+	result, err := limiter.Take(c.Request.Context(), key, rule)
+	if err != nil { //let requests through if the store is down rather than taking everything down with it
+		logger.Ctx(c).Error("rate limit store failed", "error", err)
+		return true
+	}
+
+	c.Header(RateLimitHeader, strconv.Itoa(result.Limit))
+	c.Header(RateLimitRemainingHeader, strconv.Itoa(result.Remaining))
+	c.Header(RateLimitResetHeader, strconv.FormatInt(int64(math.Ceil(float64(result.Reset.UnixNano())/1e9)), 10))
+	if !result.Allowed {
+		strikes.AddStrike(c.ClientIP())
+		metrics.RateLimitRejections.Inc(route)
+		c.Header(RetryAfterHeader, strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+		errors.SendErrorResponse(c, errors.ErrCooldownActive, errors.StatusCooldownActive)
+		c.Abort()
+		return false
+	}
+	return true
+}
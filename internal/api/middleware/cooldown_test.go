@@ -0,0 +1,66 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/asianchinaboi/backendserver/internal/api/middleware"
+	"github.com/asianchinaboi/backendserver/internal/config"
+	"github.com/asianchinaboi/backendserver/internal/cooldown"
+	"github.com/asianchinaboi/backendserver/internal/db"
+	"github.com/gin-gonic/gin"
+)
+
+const route = "POST /api/users/auth"
+
+// allowed counts how many logins get past the rate limit with header as the Authorization
+// nothing listens on the db port so every token fails to check out, like a made up one would
+func allowed(t *testing.T, header string) int {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	conf, err := config.Load(config.Sources{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.Server.DatabaseConfig.Host = "127.0.0.1"
+	conf.Server.DatabaseConfig.Port = 1
+	conf.RateLimit.BotMultiplier = 4
+	conn, err := db.Open(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	r := gin.New()
+	r.Use(middleware.Cooldown(config.NewLive(conf), cooldown.NewMemory(), cooldown.NewStrikes(time.Minute), conn))
+	r.POST("/api/users/auth", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	limit := conf.RateLimit.Routes[route].Limit
+	for i := 0; i < limit*conf.RateLimit.BotMultiplier*2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/api/users/auth", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code == http.StatusTooManyRequests {
+			return i
+		}
+	}
+	t.Fatalf("Authorization %q was never limited", header)
+	return 0
+}
+
+func TestCooldownBogusToken(t *testing.T) {
+	want := allowed(t, "")
+	if want == 0 {
+		t.Fatal("no logins allowed without a token")
+	}
+	for _, header := range []string{"x", "Bot x"} {
+		if got := allowed(t, header); got != want {
+			t.Errorf("Authorization %q got %d logins, want %d like no header", header, got, want)
+		}
+	}
+}
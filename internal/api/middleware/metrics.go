@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/asianchinaboi/backendserver/internal/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics records how long every request took by route and status
+func Metrics(c *gin.Context) {
+	start := time.Now()
+	c.Next()
+	route := c.FullPath()
+	if route == "" { //404s would make a new series for every path
+		route = "unmatched"
+	}
+	status := strconv.Itoa(c.Writer.Status())
+	metrics.HTTPRequests.Inc(c.Request.Method, route, status)
+	metrics.HTTPRequestDuration.Observe(time.Since(start).Seconds(), c.Request.Method, route, status)
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/asianchinaboi/backendserver/internal/logger"
+	"github.com/asianchinaboi/backendserver/internal/session"
+	"github.com/gin-gonic/gin"
+)
+
+const RequestIdHeader = "X-Request-Id"
+
+var requestIdRegex = regexp.MustCompile("^[A-Za-z0-9_-]{1,64}$")
+
+// RequestId gives every request an id thats sent back in the header and error responses and added to the logs
+// ids from a proxy in front are kept so requests can be followed through both
+// log is the apps logger, handlers get it with the id added through logger.Ctx
+func RequestId(log *logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIdHeader)
+		if !requestIdRegex.MatchString(id) {
+			id = session.GenerateRandString(16)
+		}
+		c.Set(logger.RequestIdKey, id)
+		logger.SetCtx(c, log.With(logger.RequestIdKey, id))
+		c.Header(RequestIdHeader, id)
+
+		start := time.Now()
+		c.Next()
+		logger.Ctx(c).Info("request", "method", c.Request.Method, "path", c.Request.URL.Path, "status", c.Writer.Status(),
+			"duration", time.Since(start), "ip", c.ClientIP())
+	}
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/asianchinaboi/backendserver/internal/logger"
+	"github.com/asianchinaboi/backendserver/internal/tracing"
+	"github.com/gin-gonic/gin"
+)
+
+const TraceparentHeader = "traceparent"
+
+// Tracing starts a span for every request, a traceparent header from a proxy or the client continues its trace
+func Tracing(c *gin.Context) {
+	if !tracing.Enabled() {
+		c.Next()
+		return
+	}
+	route := c.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+	ctx := tracing.Extract(c.Request.Context(), c.GetHeader(TraceparentHeader))
+	ctx, span := tracing.Start(ctx, c.Request.Method+" "+route, tracing.KindServer,
+		"http.method", c.Request.Method, "http.route", route, "http.target", c.Request.URL.Path, "http.request_id", c.GetString(logger.RequestIdKey))
+	defer span.End()
+	c.Request = c.Request.WithContext(ctx)
+	c.Header(TraceparentHeader, tracing.Traceparent(ctx))
+
+	c.Next()
+	span.SetAttributes("http.status_code", c.Writer.Status())
+	if c.Writer.Status() >= 500 {
+		span.SetError(http.StatusText(c.Writer.Status()))
+	}
+}
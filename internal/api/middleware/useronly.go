@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/asianchinaboi/backendserver/internal/session"
+	"github.com/gin-gonic/gin"
+)
+
+// UserOnly rejects bot tokens, it goes after Auth on groups only people should use e.g. managing bots or oauth2
+func UserOnly(c *gin.Context) {
+	user, _ := c.MustGet(User).(*session.Session)
+	if user == nil {
+		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
+		c.Abort()
+		return
+	}
+	if user.Bot {
+		errors.SendErrorResponse(c, errors.ErrBotNotAllowed, errors.StatusBotNotAllowed)
+		c.Abort()
+		return
+	}
+	c.Next()
+}
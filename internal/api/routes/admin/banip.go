@@ -4,7 +4,6 @@ import (
 	"net/http"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/db"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/session"
 	"github.com/gin-gonic/gin"
@@ -14,7 +13,7 @@ type banIPBody struct {
 	IP string `json:"ip"`
 }
 
-func banIP(c *gin.Context) {
+func (h *Handlers) banIP(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -29,7 +28,7 @@ func banIP(c *gin.Context) {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
-	if _, err := db.Db.Exec("INSERT INTO bannedips (ip) VALUES ($1) ON CONFLICT DO NOTHING", body.IP); err != nil {
+	if _, err := h.Db.Exec("INSERT INTO bannedips (ip) VALUES ($1) ON CONFLICT DO NOTHING", body.IP); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -0,0 +1,85 @@
+package deliveries
+
+import (
+	"database/sql"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/asianchinaboi/backendserver/internal/api/middleware"
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/asianchinaboi/backendserver/internal/events"
+	"github.com/asianchinaboi/backendserver/internal/session"
+	"github.com/gin-gonic/gin"
+)
+
+// dead letter list, deliveries that ran out of attempts
+// two query params page and limit
+func (h *Handlers) Get(c *gin.Context) {
+	user := c.MustGet(middleware.User).(*session.Session)
+	if user == nil {
+		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
+		return
+	}
+	if !user.Perms.Admin {
+		errors.SendErrorResponse(c, errors.ErrNotAuthorised, errors.StatusNotAuthorised)
+		return
+	}
+
+	queryParms := c.Request.URL.Query()
+	page := queryParms.Get("page")
+	limit := queryParms.Get("limit")
+	if match, err := regexp.MatchString(`^[0-9]+$`, page); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if !match {
+		page = "0"
+	}
+	if match, err := regexp.MatchString(`^[0-9]+$`, limit); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if !match {
+		limit = "0"
+	}
+	intPage, err := strconv.ParseInt(page, 10, 64)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	intLimit, err := strconv.ParseInt(limit, 10, 64)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	offset := intPage * intLimit
+	var nullIntLimit sql.NullInt64
+	if intLimit != 0 {
+		nullIntLimit.Valid = true
+		nullIntLimit.Int64 = intLimit
+	}
+
+	rows, err := h.Db.Query(`SELECT d.id, d.subscription_id, d.guild_id, d.event, s.url, d.attempts, d.last_status, d.last_error, d.created 
+	FROM event_deliveries d INNER JOIN event_subscriptions s ON s.id = d.subscription_id 
+	WHERE d.dead = true ORDER BY d.created DESC LIMIT $1 OFFSET $2`, nullIntLimit, offset)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	defer rows.Close()
+
+	deliveries := []events.Delivery{}
+	for rows.Next() {
+		var delivery events.Delivery
+		var lastStatus sql.NullInt64
+		var lastError sql.NullString
+		if err := rows.Scan(&delivery.DeliveryId, &delivery.SubscriptionId, &delivery.GuildId, &delivery.Event, &delivery.Url,
+			&delivery.Attempts, &lastStatus, &lastError, &delivery.Created); err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusInternalError)
+			return
+		}
+		delivery.LastStatus = int(lastStatus.Int64)
+		delivery.LastError = lastError.String
+		deliveries = append(deliveries, delivery)
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
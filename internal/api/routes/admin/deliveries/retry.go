@@ -0,0 +1,47 @@
+package deliveries
+
+import (
+	"net/http"
+	"regexp"
+
+	"github.com/asianchinaboi/backendserver/internal/api/middleware"
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/asianchinaboi/backendserver/internal/session"
+	"github.com/gin-gonic/gin"
+)
+
+// puts a dead delivery back in the queue with its attempts reset
+func (h *Handlers) Retry(c *gin.Context) {
+	user := c.MustGet(middleware.User).(*session.Session)
+	if user == nil {
+		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
+		return
+	}
+	if !user.Perms.Admin {
+		errors.SendErrorResponse(c, errors.ErrNotAuthorised, errors.StatusNotAuthorised)
+		return
+	}
+
+	deliveryId := c.Param("deliveryId")
+	if match, err := regexp.MatchString("^[0-9]+$", deliveryId); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if !match {
+		errors.SendErrorResponse(c, errors.ErrRouteParamInvalid, errors.StatusRouteParamInvalid)
+		return
+	}
+
+	result, err := h.Db.Exec("UPDATE event_deliveries SET dead = false, attempts = 0, next_attempt = now() WHERE id = $1 AND dead = true", deliveryId)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if affected == 0 {
+		errors.SendErrorResponse(c, errors.ErrDeliveryNotExist, errors.StatusDeliveryNotExist)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -1,21 +1,22 @@
 package bans
 
 import (
+	"context"
 	"database/sql"
 	"net/http"
 	"regexp"
 	"strconv"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/db"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
+	"github.com/asianchinaboi/backendserver/internal/outbox"
 	"github.com/asianchinaboi/backendserver/internal/session"
 	"github.com/asianchinaboi/backendserver/internal/wsclient"
 	"github.com/gin-gonic/gin"
 )
 
-func Ban(c *gin.Context) {
+func (h *Handlers) Ban(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -58,7 +59,7 @@ func Ban(c *gin.Context) {
 	var isBanned bool
 	var userExists bool
 
-	if err := db.Db.QueryRow("SELECT EXISTS (SELECT 1 FROM userguilds WHERE guild_id = $1 AND user_id=$2 AND banned = true), EXISTS (SELECT 1 FROM users WHERE id = $2)", guildId, userId).Scan(&isBanned, &userExists); err != nil {
+	if err := h.Db.QueryRow("SELECT EXISTS (SELECT 1 FROM userguilds WHERE guild_id = $1 AND user_id=$2 AND banned = true), EXISTS (SELECT 1 FROM users WHERE id = $2)", guildId, userId).Scan(&isBanned, &userExists); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -71,13 +72,42 @@ func Ban(c *gin.Context) {
 		return
 	}
 
-	if _, err := db.Db.Exec("UPDATE userguilds SET banned=true WHERE guild_id=$1 AND user_id=$2", guildId, userId); err != nil {
+	ctx := context.Background()
+	tx, err := h.Db.BeginTx(ctx, nil)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	defer tx.Rollback() //rollback changes if failed
+
+	if _, err := tx.ExecContext(ctx, "UPDATE userguilds SET banned=true WHERE guild_id=$1 AND user_id=$2", guildId, userId); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	guildRes := wsclient.DataFrame{
+		Op: wsclient.TYPE_DISPATCH,
+		Data: events.Member{
+			GuildId: intGuildId,
+			UserInfo: events.User{
+				UserId: intUserId,
+			},
+		},
+		Event: events.MEMBER_REMOVE,
+	}
+	if err := outbox.Enqueue(ctx, tx, intGuildId, guildRes.Event, guildRes.Data); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
+
+	if err := tx.Commit(); err != nil { //commits the transaction
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
 	var username string
 	var imageId sql.NullInt64
-	if err := db.Db.QueryRow("SELECT username, files.id FROM users LEFT JOIN files ON files.user_id = users.id WHERE users.id=$1", userId).Scan(&username, &imageId); err != nil {
+	if err := h.Db.QueryRow("SELECT username, files.id FROM users LEFT JOIN files ON files.user_id = users.id WHERE users.id=$1", userId).Scan(&username, &imageId); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -89,7 +119,7 @@ func Ban(c *gin.Context) {
 		resImageId = -1
 	}
 
-	rows, err := db.Db.Query("SELECT user_id FROM userguilds WHERE guild_id = $1 AND (owner = true OR admin = true)", guildId)
+	rows, err := h.Db.Query("SELECT user_id FROM userguilds WHERE guild_id = $1 AND (owner = true OR admin = true)", guildId)
 	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
@@ -110,7 +140,7 @@ func Ban(c *gin.Context) {
 			},
 			Event: events.MEMBER_BAN_ADD,
 		}
-		wsclient.Pools.BroadcastClient(adminUserId, res)
+		h.Pools.BroadcastClient(adminUserId, res)
 	}
 
 	banRes := wsclient.DataFrame{
@@ -120,18 +150,8 @@ func Ban(c *gin.Context) {
 		},
 		Event: events.GUILD_DELETE,
 	}
-	guildRes := wsclient.DataFrame{
-		Op: wsclient.TYPE_DISPATCH,
-		Data: events.Member{
-			GuildId: intGuildId,
-			UserInfo: events.User{
-				UserId: intUserId,
-			},
-		},
-		Event: events.MEMBER_REMOVE,
-	}
-	wsclient.Pools.BroadcastClient(intUserId, banRes)
-	wsclient.Pools.RemoveUserFromGuildPool(intGuildId, intUserId)
-	wsclient.Pools.BroadcastGuild(intGuildId, guildRes)
+	h.Pools.BroadcastClient(intUserId, banRes)
+	h.Pools.RemoveUserFromGuildPool(intGuildId, intUserId)
+	h.Pools.BroadcastGuild(intGuildId, guildRes)
 	c.Status(http.StatusNoContent)
 }
@@ -7,14 +7,13 @@ import (
 	"strconv"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/db"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
 	"github.com/asianchinaboi/backendserver/internal/session"
 	"github.com/gin-gonic/gin"
 )
 
-func Get(c *gin.Context) {
+func (h *Handlers) Get(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -34,7 +33,7 @@ func Get(c *gin.Context) {
 		return
 	}
 
-	rows, err := db.Db.Query(
+	rows, err := h.Db.Query(
 		`
 		SELECT u.id, u.username, files.id
 		FROM userguilds g INNER JOIN users u ON u.id = g.user_id
@@ -6,16 +6,14 @@ import (
 	"strconv"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/db"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
-	"github.com/asianchinaboi/backendserver/internal/logger"
 	"github.com/asianchinaboi/backendserver/internal/session"
 	"github.com/asianchinaboi/backendserver/internal/wsclient"
 	"github.com/gin-gonic/gin"
 )
 
-func Unban(c *gin.Context) {
+func (h *Handlers) Unban(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -46,12 +44,8 @@ func Unban(c *gin.Context) {
 
 	var isBanned bool
 
-	if err := db.Db.QueryRow("SELECT EXISTS (SELECT 1 FROM userguilds WHERE guild_id=$1 AND user_id=$2 AND banned = true)", guildId, userId).Scan(&isBanned); err != nil {
-		logger.Error.Println(err)
-		c.JSON(http.StatusInternalServerError, errors.Body{
-			Error:  err.Error(),
-			Status: errors.StatusInternalError,
-		})
+	if err := h.Db.QueryRow("SELECT EXISTS (SELECT 1 FROM userguilds WHERE guild_id=$1 AND user_id=$2 AND banned = true)", guildId, userId).Scan(&isBanned); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
 	if !isBanned {
@@ -59,7 +53,7 @@ func Unban(c *gin.Context) {
 		return
 	}
 
-	if _, err := db.Db.Exec("DELETE FROM userguilds WHERE guild_id=$1 AND user_id=$2", guildId, userId); err != nil {
+	if _, err := h.Db.Exec("DELETE FROM userguilds WHERE guild_id=$1 AND user_id=$2", guildId, userId); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -74,7 +68,7 @@ func Unban(c *gin.Context) {
 		return
 	}
 
-	rows, err := db.Db.Query("SELECT user_id FROM userguilds WHERE guild_id = $1 AND (owner = true OR admin = true)", guildId)
+	rows, err := h.Db.Query("SELECT user_id FROM userguilds WHERE guild_id = $1 AND (owner = true OR admin = true)", guildId)
 	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
@@ -93,7 +87,7 @@ func Unban(c *gin.Context) {
 			},
 			Event: events.MEMBER_BAN_REMOVE,
 		}
-		wsclient.Pools.BroadcastClient(adminUserId, res)
+		h.Pools.BroadcastClient(adminUserId, res)
 	}
 	c.Status(http.StatusNoContent)
 }
@@ -3,14 +3,12 @@ package guilds
 import (
 	"context"
 	"database/sql"
-	"fmt"
 	"net/http"
-	"os"
 	"regexp"
 	"strconv"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/db"
+	"github.com/asianchinaboi/backendserver/internal/blobs"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
 	"github.com/asianchinaboi/backendserver/internal/logger"
@@ -19,7 +17,12 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func Delete(c *gin.Context) {
+type fileEntity struct {
+	Id   int64
+	Hash sql.NullString
+}
+
+func (h *Handlers) Delete(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -41,7 +44,7 @@ func Delete(c *gin.Context) {
 
 	//BEGIN TRANSACTION
 	ctx := context.Background()
-	tx, err := db.Db.BeginTx(ctx, nil)
+	tx, err := h.Db.BeginTx(ctx, nil)
 	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
@@ -49,27 +52,28 @@ func Delete(c *gin.Context) {
 	defer tx.Rollback()
 
 	var guildImageId int64
-	if err := tx.QueryRowContext(ctx, "SELECT id FROM files WHERE guild_id = $1", guildId).Scan(&guildImageId); err != nil && err != sql.ErrNoRows {
+	var guildImageHash sql.NullString
+	if err := tx.QueryRowContext(ctx, "SELECT id, hash FROM files WHERE guild_id = $1", guildId).Scan(&guildImageId, &guildImageHash); err != nil && err != sql.ErrNoRows {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	} else if err == sql.ErrNoRows {
 		guildImageId = -1
 	}
 
-	fileIds, err := tx.QueryContext(ctx, `SELECT f.id FROM files f INNER JOIN msgs ON msgs.id = f.msg_id WHERE msgs.guild_id = $1`, guildId)
+	fileIds, err := tx.QueryContext(ctx, `SELECT f.id, f.hash FROM files f INNER JOIN msgs ON msgs.id = f.msg_id WHERE msgs.guild_id = $1`, guildId)
 	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
-	var fileIdsToDelete []int64
+	var filesToDelete []fileEntity
 
 	for fileIds.Next() {
-		var fileId int64
-		if err := fileIds.Scan(&fileId); err != nil {
+		var file fileEntity
+		if err := fileIds.Scan(&file.Id, &file.Hash); err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
-		fileIdsToDelete = append(fileIdsToDelete, fileId)
+		filesToDelete = append(filesToDelete, file)
 	}
 
 	fileIds.Close()
@@ -91,14 +95,14 @@ func Delete(c *gin.Context) {
 	}
 
 	if guildImageId != -1 {
-		if err := os.Remove(fmt.Sprintf("uploads/guild/%d.lz4", guildImageId)); err != nil {
-			logger.Warn.Printf("unable to remove file: %v\n", err)
+		if err := blobs.Remove(context.Background(), h.Db, h.Store, "guild", guildImageId, guildImageHash); err != nil {
+			logger.Ctx(c).Warn("unable to remove file", "err", err)
 		}
 	}
 
-	for _, fileId := range fileIdsToDelete {
-		if err := os.Remove(fmt.Sprintf("uploads/msg/%d.lz4", fileId)); err != nil {
-			logger.Warn.Printf("unable to remove file: %v\n", err)
+	for _, file := range filesToDelete {
+		if err := blobs.Remove(context.Background(), h.Db, h.Store, "msg", file.Id, file.Hash); err != nil {
+			logger.Ctx(c).Warn("unable to remove file", "err", err)
 		}
 	}
 
@@ -110,6 +114,6 @@ func Delete(c *gin.Context) {
 		Event: events.GUILD_DELETE,
 	}
 
-	wsclient.Pools.BroadcastGuild(intGuildId, res) // kick everyone out of the guild
+	h.Pools.BroadcastGuild(intGuildId, res) // kick everyone out of the guild
 	c.Status(http.StatusNoContent)
 }
@@ -1,14 +1,13 @@
 package guilds
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"encoding/json"
-	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
-	"os"
 	"path/filepath"
 	"regexp"
 	"strconv"
@@ -16,12 +15,13 @@ import (
 	"time"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/config"
-	"github.com/asianchinaboi/backendserver/internal/db"
+	"github.com/asianchinaboi/backendserver/internal/blobs"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
 	"github.com/asianchinaboi/backendserver/internal/files"
 	"github.com/asianchinaboi/backendserver/internal/logger"
+	"github.com/asianchinaboi/backendserver/internal/outbox"
+	"github.com/asianchinaboi/backendserver/internal/scanner"
 	"github.com/asianchinaboi/backendserver/internal/session"
 	"github.com/asianchinaboi/backendserver/internal/uid"
 	"github.com/asianchinaboi/backendserver/internal/wsclient"
@@ -34,7 +34,7 @@ type editGuildBody struct {
 	OwnerId  *int64  `json:"ownerId"`
 }
 
-func Edit(c *gin.Context) {
+func (h *Handlers) Edit(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -86,7 +86,7 @@ func Edit(c *gin.Context) {
 	successful := false
 
 	var exists bool
-	if err := db.Db.QueryRow("SELECT EXISTS(SELECT 1 FROM guilds WHERE id = $1)", guildId).Scan(&exists); err != nil {
+	if err := h.Db.QueryRow("SELECT EXISTS(SELECT 1 FROM guilds WHERE id = $1)", guildId).Scan(&exists); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -104,7 +104,7 @@ func Edit(c *gin.Context) {
 
 	//BEGIN TRANSACTION
 	ctx := context.Background()
-	tx, err := db.Db.BeginTx(ctx, nil)
+	tx, err := h.Db.BeginTx(ctx, nil)
 	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
@@ -126,7 +126,7 @@ func Edit(c *gin.Context) {
 		bodySettingsRes.SaveChat = newSettings.SaveChat
 	} else {
 		var saveChat bool
-		if err := db.Db.QueryRow("SELECT save_chat FROM guilds WHERE id = $1", guildId).Scan(&saveChat); err != nil {
+		if err := h.Db.QueryRow("SELECT save_chat FROM guilds WHERE id = $1", guildId).Scan(&saveChat); err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
@@ -140,7 +140,7 @@ func Edit(c *gin.Context) {
 		bodyRes.Name = *newSettings.Name
 	} else {
 		var name string
-		if err := db.Db.QueryRow("SELECT name FROM guilds WHERE id = $1", guildId).Scan(&name); err != nil {
+		if err := h.Db.QueryRow("SELECT name FROM guilds WHERE id = $1", guildId).Scan(&name); err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
@@ -151,7 +151,8 @@ func Edit(c *gin.Context) {
 		//remove old image
 
 		var oldImageId int64
-		if err := tx.QueryRowContext(ctx, "DELETE FROM files WHERE guild_id = $1 RETURNING id", guildId).Scan(&oldImageId); err != nil && err != sql.ErrNoRows {
+		var oldHash sql.NullString
+		if err := tx.QueryRowContext(ctx, "DELETE FROM files WHERE guild_id = $1 RETURNING id, hash", guildId).Scan(&oldImageId, &oldHash); err != nil && err != sql.ErrNoRows {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
@@ -173,14 +174,15 @@ func Edit(c *gin.Context) {
 			return
 		}
 
-		if valid := files.ValidateImage(fileBytes, fileType); !valid {
+		fileBytes, valid := files.ValidateImage(fileBytes, fileType, h.Config.Server.ImageProfileSize) //crops and scales it down if needed
+		if !valid {
 			errors.SendErrorResponse(c, errors.ErrFileInvalid, errors.StatusFileInvalid)
 			return
 		}
 
 		filesize := len(fileBytes)
 
-		if filesize > config.Config.Server.MaxFileSize {
+		if filesize > h.Config.Server.MaxFileSize {
 			errors.SendErrorResponse(c, errors.ErrFileTooLarge, errors.StatusFileTooLarge)
 			return
 		} else if !(filesize >= 0) {
@@ -188,45 +190,38 @@ func Edit(c *gin.Context) {
 			return
 		}
 
-		compressedBuffer, err := files.Compress(fileBytes, filesize)
-		if err != nil {
+		//images use the default policy, the guild policy is only for attachments
+		verdict, err := scanner.Check(ctx, h.Scanner, bytes.NewReader(fileBytes), h.Config.Scanner.Policy)
+		if err == errors.ErrScannerUnavailable {
+			errors.SendErrorResponse(c, err, errors.StatusScannerUnavailable)
+			return
+		} else if err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
 
-		outFile, err := os.Create(fmt.Sprintf("uploads/guild/%d.lz4", imageId))
+		//stored by content so an image thats already been uploaded isnt stored again
+		hash, err := blobs.Put(ctx, tx, h.Store, bytes.NewReader(fileBytes))
 		if err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
 		defer func() { //defer just in case something went wrong
-			if !successful {
-				if err := os.Remove(fmt.Sprintf("uploads/guild/%d.lz4", imageId)); err != nil {
-					logger.Warn.Printf("failed to remove file: %v\n", err)
-				}
-			} else {
-				if oldImageId != 0 {
-					if err := os.Remove(fmt.Sprintf("uploads/guild/%d.lz4", oldImageId)); err != nil {
-						logger.Warn.Printf("failed to remove file: %v\n", err)
-					}
+			if successful && oldImageId != 0 {
+				if err := blobs.Remove(context.Background(), h.Db, h.Store, "guild", oldImageId, oldHash); err != nil {
+					logger.Ctx(c).Warn("failed to remove file", "err", err)
 				}
 			}
 		}()
-		defer outFile.Close()
 
-		if _, err = outFile.Write(compressedBuffer); err != nil {
-			errors.SendErrorResponse(c, err, errors.StatusInternalError)
-			return
-		}
-
-		if _, err = tx.ExecContext(ctx, "INSERT INTO files (id, guild_id, filename, created, temp, filesize, entity_type) VALUES ($1, $2, $3, $4, $5, $6, 'guild')", imageId, guildId, filename, imageCreated, false, filesize); err != nil {
+		if _, err = tx.ExecContext(ctx, "INSERT INTO files (id, guild_id, filename, created, temp, filesize, entity_type, hash, quarantined, quarantine_reason) VALUES ($1, $2, $3, $4, $5, $6, 'guild', $7, $8, NULLIF($9, ''))", imageId, guildId, filename, imageCreated, false, filesize, hash, verdict.Quarantined, verdict.Reason); err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
 		bodyRes.ImageId = imageId
 	} else {
 		var imageId int64
-		if err := db.Db.QueryRow("SELECT id FROM files WHERE guild_id=$1", guildId).Scan(&imageId); err != nil && err != sql.ErrNoRows {
+		if err := h.Db.QueryRow("SELECT id FROM files WHERE guild_id=$1", guildId).Scan(&imageId); err != nil && err != sql.ErrNoRows {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		} else if err == sql.ErrNoRows {
@@ -247,24 +242,28 @@ func Edit(c *gin.Context) {
 		}
 		bodyRes.OwnerId = *newSettings.OwnerId
 	} else {
-		if err := db.Db.QueryRow("SELECT user_id FROM userguilds WHERE guild_id=$1 AND owner = true", guildId).Scan(&bodyRes.OwnerId); err != nil {
+		if err := h.Db.QueryRow("SELECT user_id FROM userguilds WHERE guild_id=$1 AND owner = true", guildId).Scan(&bodyRes.OwnerId); err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
 	}
 
-	if err := tx.Commit(); err != nil { //commits the transaction
-		errors.SendErrorResponse(c, err, errors.StatusInternalError)
-		return
-	}
-	successful = true
-
 	guildRes := wsclient.DataFrame{
 		Op:    wsclient.TYPE_DISPATCH,
 		Data:  bodyRes,
 		Event: events.GUILD_UPDATE,
 	}
-	wsclient.Pools.BroadcastGuild(intGuildId, guildRes)
+	if err := outbox.Enqueue(ctx, tx, intGuildId, guildRes.Event, guildRes.Data); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil { //commits the transaction
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	successful = true
+	h.Pools.BroadcastGuild(intGuildId, guildRes)
 
 	c.Status(http.StatusNoContent)
 }
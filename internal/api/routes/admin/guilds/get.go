@@ -7,14 +7,13 @@ import (
 	"strconv"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/db"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
 	"github.com/asianchinaboi/backendserver/internal/session"
 	"github.com/gin-gonic/gin"
 )
 
-func Get(c *gin.Context) {
+func (h *Handlers) Get(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -60,7 +59,7 @@ func Get(c *gin.Context) {
 		nullIntLimit.Int64 = intLimit
 		nullIntLimit.Valid = true
 	}
-	rows, err := db.Db.Query("SELECT guilds.*, files.id FROM guilds LEFT JOIN files ON files.guild_id = guilds.id LIMIT $1 OFFSET $2", nullIntLimit, offset)
+	rows, err := h.Db.Query("SELECT guilds.*, files.id FROM guilds LEFT JOIN files ON files.guild_id = guilds.id LIMIT $1 OFFSET $2", nullIntLimit, offset)
 	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
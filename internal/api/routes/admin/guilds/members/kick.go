@@ -1,20 +1,21 @@
 package members
 
 import (
+	"context"
 	"net/http"
 	"regexp"
 	"strconv"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/db"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
+	"github.com/asianchinaboi/backendserver/internal/outbox"
 	"github.com/asianchinaboi/backendserver/internal/session"
 	"github.com/asianchinaboi/backendserver/internal/wsclient"
 	"github.com/gin-gonic/gin"
 )
 
-func Kick(c *gin.Context) {
+func (h *Handlers) Kick(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -59,7 +60,15 @@ func Kick(c *gin.Context) {
 		return
 	}
 
-	if _, err := db.Db.Exec("DELETE FROM userguilds WHERE guild_id=$1 AND user_id=$2", guildId, userId); err != nil {
+	ctx := context.Background()
+	tx, err := h.Db.BeginTx(ctx, nil)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	defer tx.Rollback() //rollback changes if failed
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM userguilds WHERE guild_id=$1 AND user_id=$2", guildId, userId); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -81,8 +90,17 @@ func Kick(c *gin.Context) {
 		},
 		Event: events.MEMBER_REMOVE,
 	}
-	wsclient.Pools.BroadcastClient(intUserId, kickRes)
-	wsclient.Pools.RemoveUserFromGuildPool(intGuildId, intUserId)
-	wsclient.Pools.BroadcastGuild(intGuildId, guildRes)
+	if err := outbox.Enqueue(ctx, tx, intGuildId, guildRes.Event, guildRes.Data); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil { //commits the transaction
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	h.Pools.BroadcastClient(intUserId, kickRes)
+	h.Pools.RemoveUserFromGuildPool(intGuildId, intUserId)
+	h.Pools.BroadcastGuild(intGuildId, guildRes)
 	c.Status(http.StatusNoContent)
 }
@@ -0,0 +1,74 @@
+package guilds
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/asianchinaboi/backendserver/internal/api/middleware"
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/asianchinaboi/backendserver/internal/quota"
+	"github.com/asianchinaboi/backendserver/internal/session"
+	"github.com/gin-gonic/gin"
+)
+
+type quotaBody struct {
+	Quota *int64 `json:"quota"` //bytes, 0 for unlimited and null to go back to the default
+}
+
+// overrides the storage quota from the config for one guild
+func (h *Handlers) Quota(c *gin.Context) {
+	user := c.MustGet(middleware.User).(*session.Session)
+	if user == nil {
+		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
+		return
+	}
+	if !user.Perms.Admin && !user.Perms.Guilds.Edit {
+		errors.SendErrorResponse(c, errors.ErrNotAuthorised, errors.StatusNotAuthorised)
+		return
+	}
+
+	guildId := c.Param("guildId")
+	if match, err := regexp.MatchString("^[0-9]+$", guildId); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if !match {
+		errors.SendErrorResponse(c, errors.ErrRouteParamInvalid, errors.StatusRouteParamInvalid)
+		return
+	}
+	intGuildId, err := strconv.ParseInt(guildId, 10, 64)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	var body quotaBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusBadRequest)
+		return
+	}
+	if body.Quota != nil && *body.Quota < 0 {
+		errors.SendErrorResponse(c, errors.ErrQuotaInvalid, errors.StatusQuotaInvalid)
+		return
+	}
+
+	result, err := h.Db.Exec("UPDATE guilds SET storage_quota = $1 WHERE id = $2", body.Quota, guildId)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if affected == 0 {
+		errors.SendErrorResponse(c, errors.ErrGuildNotExist, errors.StatusGuildNotExist)
+		return
+	}
+
+	usage, err := quota.Guild(c.Request.Context(), h.Db, h.Config, intGuildId)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	c.JSON(http.StatusOK, usage)
+}
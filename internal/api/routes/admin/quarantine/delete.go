@@ -0,0 +1,52 @@
+package quarantine
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"regexp"
+
+	"github.com/asianchinaboi/backendserver/internal/api/middleware"
+	"github.com/asianchinaboi/backendserver/internal/blobs"
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/asianchinaboi/backendserver/internal/logger"
+	"github.com/asianchinaboi/backendserver/internal/session"
+	"github.com/gin-gonic/gin"
+)
+
+// removes a quarantined file for good
+func (h *Handlers) Delete(c *gin.Context) {
+	user := c.MustGet(middleware.User).(*session.Session)
+	if user == nil {
+		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
+		return
+	}
+	if !user.Perms.Admin {
+		errors.SendErrorResponse(c, errors.ErrNotAuthorised, errors.StatusNotAuthorised)
+		return
+	}
+
+	fileId := c.Param("fileId")
+	if match, err := regexp.MatchString("^[0-9]+$", fileId); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if !match {
+		errors.SendErrorResponse(c, errors.ErrRouteParamInvalid, errors.StatusRouteParamInvalid)
+		return
+	}
+
+	var id int64
+	var entityType string
+	var hash sql.NullString
+	if err := h.Db.QueryRow("DELETE FROM files WHERE id = $1 AND quarantined = true RETURNING id, entity_type, hash", fileId).Scan(&id, &entityType, &hash); err != nil && err != sql.ErrNoRows {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if err == sql.ErrNoRows {
+		errors.SendErrorResponse(c, errors.ErrFileNotFound, errors.StatusFileNotFound)
+		return
+	}
+	if err := blobs.Remove(context.Background(), h.Db, h.Store, entityType, id, hash); err != nil {
+		logger.Ctx(c).Warn("failed to remove file", "err", err) //schedule gets the blob later
+	}
+	c.Status(http.StatusNoContent)
+}
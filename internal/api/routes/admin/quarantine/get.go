@@ -0,0 +1,82 @@
+package quarantine
+
+import (
+	"database/sql"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/asianchinaboi/backendserver/internal/api/middleware"
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/asianchinaboi/backendserver/internal/events"
+	"github.com/asianchinaboi/backendserver/internal/session"
+	"github.com/gin-gonic/gin"
+)
+
+// files the scanner flagged or couldnt scan, newest first
+// two query params page and limit
+func (h *Handlers) Get(c *gin.Context) {
+	user := c.MustGet(middleware.User).(*session.Session)
+	if user == nil {
+		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
+		return
+	}
+	if !user.Perms.Admin {
+		errors.SendErrorResponse(c, errors.ErrNotAuthorised, errors.StatusNotAuthorised)
+		return
+	}
+
+	queryParms := c.Request.URL.Query()
+	page := queryParms.Get("page")
+	limit := queryParms.Get("limit")
+	if match, err := regexp.MatchString(`^[0-9]+$`, page); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if !match {
+		page = "0"
+	}
+	if match, err := regexp.MatchString(`^[0-9]+$`, limit); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if !match {
+		limit = "0"
+	}
+	intPage, err := strconv.ParseInt(page, 10, 64)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	intLimit, err := strconv.ParseInt(limit, 10, 64)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	offset := intPage * intLimit
+	var nullIntLimit sql.NullInt64
+	if intLimit != 0 {
+		nullIntLimit.Valid = true
+		nullIntLimit.Int64 = intLimit
+	}
+
+	rows, err := h.Db.Query(`SELECT f.id, f.entity_type, f.filename, COALESCE(f.filetype, ''), f.filesize, 
+	COALESCE(f.uploader_id, f.user_id, 0), COALESCE(f.msg_id, 0), COALESCE(m.guild_id, f.guild_id, 0), COALESCE(f.quarantine_reason, ''), f.created 
+	FROM files f LEFT JOIN msgs m ON m.id = f.msg_id 
+	WHERE f.quarantined = true ORDER BY f.created DESC LIMIT $1 OFFSET $2`, nullIntLimit, offset)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	defer rows.Close()
+
+	quarantined := []events.QuarantinedFile{}
+	for rows.Next() {
+		var file events.QuarantinedFile
+		if err := rows.Scan(&file.FileId, &file.EntityType, &file.Filename, &file.Type, &file.Size,
+			&file.UploaderId, &file.MsgId, &file.GuildId, &file.Reason, &file.Created); err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusInternalError)
+			return
+		}
+		quarantined = append(quarantined, file)
+	}
+	c.JSON(http.StatusOK, quarantined)
+}
@@ -0,0 +1,68 @@
+package quarantine
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"regexp"
+
+	"github.com/asianchinaboi/backendserver/internal/api/middleware"
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/asianchinaboi/backendserver/internal/session"
+	"github.com/asianchinaboi/backendserver/internal/transcode"
+	"github.com/gin-gonic/gin"
+)
+
+// makes a quarantined file downloadable again, for false positives and files that were never scanned
+func (h *Handlers) Release(c *gin.Context) {
+	user := c.MustGet(middleware.User).(*session.Session)
+	if user == nil {
+		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
+		return
+	}
+	if !user.Perms.Admin {
+		errors.SendErrorResponse(c, errors.ErrNotAuthorised, errors.StatusNotAuthorised)
+		return
+	}
+
+	fileId := c.Param("fileId")
+	if match, err := regexp.MatchString("^[0-9]+$", fileId); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if !match {
+		errors.SendErrorResponse(c, errors.ErrRouteParamInvalid, errors.StatusRouteParamInvalid)
+		return
+	}
+
+	//BEGIN TRANSACTION
+	ctx := context.Background()
+	tx, err := h.Db.BeginTx(ctx, nil)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	defer tx.Rollback() //rollback changes if failed
+
+	var hash sql.NullString
+	var filetype sql.NullString
+	if err := tx.QueryRowContext(ctx, "UPDATE files SET quarantined = false, quarantine_reason = NULL WHERE id = $1 AND quarantined = true RETURNING hash, filetype", fileId).Scan(&hash, &filetype); err != nil && err != sql.ErrNoRows {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if err == sql.ErrNoRows {
+		errors.SendErrorResponse(c, errors.ErrFileNotFound, errors.StatusFileNotFound)
+		return
+	}
+	//quarantined files were skipped by the transcoder
+	if hash.Valid {
+		if _, err := transcode.Enqueue(ctx, tx, h.Config, hash.String, filetype.String); err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusInternalError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil { //commits the transaction
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
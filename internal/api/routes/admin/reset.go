@@ -2,16 +2,14 @@ package admin
 
 import (
 	"context"
-	"fmt"
+	"database/sql"
 	"net/http"
-	"os"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/db"
+	"github.com/asianchinaboi/backendserver/internal/blobs"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/logger"
 	"github.com/asianchinaboi/backendserver/internal/session"
-	"github.com/asianchinaboi/backendserver/internal/wsclient"
 	"github.com/gin-gonic/gin"
 )
 
@@ -22,9 +20,10 @@ import (
 type fileEntity struct {
 	Id         int64
 	EntityType string
+	Hash       sql.NullString
 }
 
-func reset(c *gin.Context) {
+func (h *Handlers) reset(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -37,7 +36,7 @@ func reset(c *gin.Context) {
 
 	//BEGIN TRANSACTION
 	ctx := context.Background()
-	tx, err := db.Db.BeginTx(ctx, nil)
+	tx, err := h.Db.BeginTx(ctx, nil)
 	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
@@ -45,14 +44,14 @@ func reset(c *gin.Context) {
 	defer tx.Rollback() //rollback changes if failed
 
 	var files []fileEntity
-	fileRows, err := db.Db.Query("SELECT id, entity_type FROM files")
+	fileRows, err := h.Db.Query("SELECT id, entity_type, hash FROM files")
 	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
 	for fileRows.Next() {
 		file := fileEntity{}
-		if err := fileRows.Scan(&file.Id, &file.EntityType); err != nil {
+		if err := fileRows.Scan(&file.Id, &file.EntityType, &file.Hash); err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
@@ -76,15 +75,15 @@ func reset(c *gin.Context) {
 		return
 	}
 
-	wsclient.Pools.RemoveAll()
+	h.Pools.RemoveAll()
 
 	for _, file := range files {
-		if err := os.Remove(fmt.Sprintf("uploads/%s/%d.lz4", file.EntityType, file.Id)); err != nil {
-			logger.Warn.Printf("unable to remove file: %v\n", err)
+		if err := blobs.Remove(context.Background(), h.Db, h.Store, file.EntityType, file.Id, file.Hash); err != nil {
+			logger.Ctx(c).Warn("unable to remove file", "err", err)
 		}
 	}
 
-	logger.Warn.Println("And thus the database is now gone I hope you're happy")
-	logger.Warn.Println("If you didn't do this u fucked up big time lmao") //cool and extremely helpful message
+	logger.Ctx(c).Warn("And thus the database is now gone I hope you're happy")
+	logger.Ctx(c).Warn("If you didn't do this u fucked up big time lmao") //cool and extremely helpful message
 	c.Status(http.StatusNoContent)
 }
@@ -1,38 +1,71 @@
 package admin
 
 import (
+	"github.com/asianchinaboi/backendserver/internal/api/deps"
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
+	"github.com/asianchinaboi/backendserver/internal/api/routes/admin/deliveries"
 	"github.com/asianchinaboi/backendserver/internal/api/routes/admin/guilds"
 	"github.com/asianchinaboi/backendserver/internal/api/routes/admin/guilds/bans"
 	"github.com/asianchinaboi/backendserver/internal/api/routes/admin/guilds/members"
+	"github.com/asianchinaboi/backendserver/internal/api/routes/admin/quarantine"
 	"github.com/asianchinaboi/backendserver/internal/api/routes/admin/users"
 	"github.com/gin-gonic/gin"
 )
 
 //make this at the end
 
-func Routes(r *gin.RouterGroup) {
+// Handlers are the admin routes, Routes builds them from what the app hands over
+type Handlers struct {
+	*deps.Deps
+}
+
+func New(d *deps.Deps) *Handlers {
+	return &Handlers{d}
+}
+
+func Routes(r *gin.RouterGroup, d *deps.Deps) {
+	h := New(d)
+	quarantined := quarantine.New(d)
+	user := users.New(d)
+	guild := guilds.New(d)
+	member := members.New(d)
+	ban := bans.New(d)
+	delivery := deliveries.New(d)
+
 	admin := r.Group("/admin")
-	admin.Use(middleware.Auth)
+	admin.Use(middleware.Auth(d.Db), middleware.UserOnly)
 	//ADMIN ONLY
-	admin.POST("/reset", reset)     //extremely dangerous
-	admin.POST("/sql", runSqlQuery) //extremely dangeorus too
+	admin.POST("/reset", h.reset)     //extremely dangerous
+	admin.POST("/sql", h.runSqlQuery) //extremely dangeorus too
 	//ADMIN ONLY
 
-	admin.POST("/banip", banIP)
+	admin.POST("/banip", h.banIP)
+
+	admin.GET("/storage", h.storageReport)
+	admin.GET("/storage/top", h.topConsumers) //two query params type and limit
+
+	admin.GET("/quarantine", quarantined.Get) //two query params page and limit
+	admin.POST("/quarantine/:fileId/release", quarantined.Release)
+	admin.DELETE("/quarantine/:fileId", quarantined.Delete)
+
+	admin.GET("/users", user.Get) //two query params page and limit
+	admin.DELETE("/users/:userId", user.Delete)
+	admin.PATCH("/users/:userId", user.Edit)
+	admin.PUT("/users/:userId/quota", user.Quota)
 
-	admin.GET("/users", users.Get) //two query params page and limit
-	admin.DELETE("/users/:userId", users.Delete)
-	admin.PATCH("/users/:userId", users.Edit)
+	admin.GET("/guilds", guild.Get) //two query params page and limit
+	admin.DELETE("/guilds/:guildId", guild.Delete)
+	admin.PATCH("/guilds/:guildId", guild.Edit)
+	admin.PUT("/guilds/:guildId/quota", guild.Quota)
 
-	admin.GET("/guilds", guilds.Get) //two query params page and limit
-	admin.DELETE("/guilds/:guildId", guilds.Delete)
-	admin.PATCH("/guilds/:guildId", guilds.Edit)
+	admin.GET("/guilds/:guildId/members", member.Get)
+	admin.DELETE("/guilds/:guildId/members/:userId", member.Kick)
 
-	admin.GET("/guilds/:guildId/members", members.Get)
-	admin.DELETE("/guilds/:guildId/members/:userId", members.Kick)
+	admin.GET("/guilds/:guildId/bans", ban.Get)
+	admin.PUT("/guilds/:guildId/bans/:userId", ban.Ban)
+	admin.DELETE("/guilds/:guildId/bans/:userId", ban.Unban)
 
-	admin.GET("/guilds/:guildId/bans", bans.Get)
-	admin.PUT("/guilds/:guildId/bans/:userId", bans.Ban)
-	admin.DELETE("/guilds/:guildId/bans/:userId", bans.Unban)
+	admin.GET("/deliveries/dead", delivery.Get) //two query params page and limit
+	admin.POST("/deliveries/:deliveryId/retry", delivery.Retry)
+	admin.DELETE("/deliveries/:deliveryId", delivery.Delete)
 }
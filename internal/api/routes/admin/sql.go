@@ -5,7 +5,6 @@ import (
 	"net/http"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/db"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/session"
 	"github.com/gin-gonic/gin"
@@ -20,7 +19,7 @@ type sqlQueryRes struct {
 	Rows    [][]string `json:"rows"`
 }
 
-func runSqlQuery(c *gin.Context) {
+func (h *Handlers) runSqlQuery(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -36,7 +35,7 @@ func runSqlQuery(c *gin.Context) {
 		return
 	}
 
-	rows, err := db.Db.Query(sqlQuery.Query)
+	rows, err := h.Db.Query(sqlQuery.Query)
 	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
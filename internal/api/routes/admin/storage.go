@@ -0,0 +1,102 @@
+package admin
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/asianchinaboi/backendserver/internal/api/middleware"
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/asianchinaboi/backendserver/internal/events"
+	"github.com/asianchinaboi/backendserver/internal/session"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handlers) storageReport(c *gin.Context) {
+	user := c.MustGet(middleware.User).(*session.Session)
+	if user == nil {
+		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
+		return
+	}
+	if !user.Perms.Admin {
+		errors.SendErrorResponse(c, errors.ErrNotAuthorised, errors.StatusNotAuthorised)
+		return
+	}
+
+	var report events.StorageReport
+	if err := h.Db.QueryRow("SELECT COUNT(*), COALESCE(SUM(filesize), 0) FROM files WHERE hash IS NOT NULL").Scan(&report.Files, &report.LogicalSize); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	if err := h.Db.QueryRow("SELECT COUNT(*), COALESCE(SUM(size), 0), COALESCE(SUM(stored_size), 0) FROM blobs").Scan(&report.Blobs, &report.UniqueSize, &report.StoredSize); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	report.SavedSize = report.LogicalSize - report.UniqueSize
+	if report.SavedSize < 0 { //blobs waiting to be cleaned up
+		report.SavedSize = 0
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// users or guilds storing the most, query params type (users or guilds) and limit
+func (h *Handlers) topConsumers(c *gin.Context) {
+	user := c.MustGet(middleware.User).(*session.Session)
+	if user == nil {
+		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
+		return
+	}
+	if !user.Perms.Admin {
+		errors.SendErrorResponse(c, errors.ErrNotAuthorised, errors.StatusNotAuthorised)
+		return
+	}
+
+	limit := c.Query("limit")
+	if match, err := regexp.MatchString(`^[0-9]+$`, limit); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if !match {
+		limit = "10"
+	}
+	intLimit, err := strconv.ParseInt(limit, 10, 64)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	if intLimit <= 0 || intLimit > 100 {
+		intLimit = 10
+	}
+
+	var query string
+	switch c.Query("type") {
+	case "", "users":
+		query = `SELECT u.id, u.username, SUM(f.filesize), COUNT(f.id) 
+		FROM files f INNER JOIN users u ON u.id = f.uploader_id 
+		GROUP BY u.id, u.username ORDER BY SUM(f.filesize) DESC LIMIT $1`
+	case "guilds":
+		query = `SELECT g.id, g.name, SUM(f.filesize), COUNT(f.id) 
+		FROM files f INNER JOIN msgs m ON m.id = f.msg_id INNER JOIN guilds g ON g.id = m.guild_id 
+		GROUP BY g.id, g.name ORDER BY SUM(f.filesize) DESC LIMIT $1`
+	default:
+		errors.SendErrorResponse(c, errors.ErrRouteParamInvalid, errors.StatusRouteParamInvalid)
+		return
+	}
+
+	rows, err := h.Db.Query(query, intLimit)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	defer rows.Close()
+
+	consumers := []events.StorageConsumer{}
+	for rows.Next() {
+		var consumer events.StorageConsumer
+		if err := rows.Scan(&consumer.Id, &consumer.Name, &consumer.Used, &consumer.Files); err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusInternalError)
+			return
+		}
+		consumers = append(consumers, consumer)
+	}
+	c.JSON(http.StatusOK, consumers)
+}
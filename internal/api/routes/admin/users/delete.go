@@ -2,17 +2,17 @@ package users
 
 import (
 	"context"
-	"fmt"
+	"database/sql"
 	"net/http"
-	"os"
 	"regexp"
 	"strconv"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/db"
+	"github.com/asianchinaboi/backendserver/internal/blobs"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
 	"github.com/asianchinaboi/backendserver/internal/logger"
+	"github.com/asianchinaboi/backendserver/internal/outbox"
 	"github.com/asianchinaboi/backendserver/internal/session"
 	"github.com/asianchinaboi/backendserver/internal/wsclient"
 	"github.com/gin-gonic/gin"
@@ -21,6 +21,7 @@ import (
 type fileEntity struct {
 	Id         int64
 	EntityType string
+	Hash       sql.NullString
 }
 
 type guildEntity struct {
@@ -30,7 +31,7 @@ type guildEntity struct {
 
 //TODO: Replace IN with something else (might be inefficient)
 
-func Delete(c *gin.Context) {
+func (h *Handlers) Delete(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -53,7 +54,7 @@ func Delete(c *gin.Context) {
 
 	var userExists bool
 
-	if err := db.Db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", userId).Scan(&userExists); err != nil {
+	if err := h.Db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", userId).Scan(&userExists); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -64,7 +65,7 @@ func Delete(c *gin.Context) {
 
 	//BEGIN TRANSACTION
 	ctx := context.Background()
-	tx, err := db.Db.BeginTx(ctx, nil)
+	tx, err := h.Db.BeginTx(ctx, nil)
 	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
@@ -72,7 +73,7 @@ func Delete(c *gin.Context) {
 	defer tx.Rollback() //rollback changes if failed
 
 	guildIds := []int64{}
-	guildRows, err := db.Db.Query("SELECT guild_id FROM userguilds WHERE user_id = $1 AND owner = false", userId)
+	guildRows, err := h.Db.Query("SELECT guild_id FROM userguilds WHERE user_id = $1 AND owner = false", userId)
 	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
@@ -88,7 +89,7 @@ func Delete(c *gin.Context) {
 	guildRows.Close()
 
 	files := []fileEntity{}
-	fileRows, err := db.Db.Query(`SELECT files.id, files.entity_type FROM files LEFT JOIN msgs ON msgs.id = files.msg_id LEFT JOIN userguilds ON userguilds.guild_id = files.guild_id AND userguilds.owner = true 
+	fileRows, err := h.Db.Query(`SELECT files.id, files.entity_type, files.hash FROM files LEFT JOIN msgs ON msgs.id = files.msg_id LEFT JOIN userguilds ON userguilds.guild_id = files.guild_id AND userguilds.owner = true 
 		LEFT JOIN users ON users.id = files.user_id WHERE msgs.user_id = $1 OR userguilds.user_id = $1 OR users.id = $1
 		`, userId)
 	if err != nil {
@@ -98,7 +99,7 @@ func Delete(c *gin.Context) {
 
 	for fileRows.Next() {
 		var file fileEntity
-		if err := fileRows.Scan(&file.Id, &file.EntityType); err != nil {
+		if err := fileRows.Scan(&file.Id, &file.EntityType, &file.Hash); err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
@@ -129,26 +130,15 @@ func Delete(c *gin.Context) {
 		return
 	}
 
-	if err := tx.Commit(); err != nil { //commits the transaction
-		errors.SendErrorResponse(c, err, errors.StatusInternalError)
-		return
-	}
-
-	for _, file := range files {
-		if err := os.Remove(fmt.Sprintf("uploads/%s/%d.lz4", file.EntityType, file.Id)); err != nil {
-			logger.Warn.Printf("unable to remove file: %v\n", err)
-		}
-	}
-
 	intUserId, err := strconv.ParseInt(userId, 10, 64)
 	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
 
+	guildFrames := map[int64][]wsclient.DataFrame{} //built before committing so subscriptions get the same events
 	for _, guildId := range guildIds {
-		wsclient.Pools.RemoveUserFromGuildPool(guildId, intUserId)
-		wsclient.Pools.BroadcastGuild(guildId, wsclient.DataFrame{
+		guildFrames[guildId] = []wsclient.DataFrame{{
 			Op: wsclient.TYPE_DISPATCH,
 			Data: events.Msg{
 				GuildId: guildId,
@@ -157,20 +147,43 @@ func Delete(c *gin.Context) {
 				},
 			},
 			Event: events.MESSAGES_USER_CLEAR,
-		})
-		wsclient.Pools.BroadcastGuild(guildId, wsclient.DataFrame{
+		}, {
 			Op: wsclient.TYPE_DISPATCH,
 			Data: events.Msg{
 				GuildId: guildId,
 			},
 			Event: events.MEMBER_REMOVE,
-		})
+		}}
+		for _, frame := range guildFrames[guildId] {
+			if err := outbox.Enqueue(ctx, tx, guildId, frame.Event, frame.Data); err != nil {
+				errors.SendErrorResponse(c, err, errors.StatusInternalError)
+				return
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil { //commits the transaction
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	for _, file := range files {
+		if err := blobs.Remove(context.Background(), h.Db, h.Store, file.EntityType, file.Id, file.Hash); err != nil {
+			logger.Ctx(c).Warn("unable to remove file", "err", err)
+		}
+	}
+
+	for _, guildId := range guildIds {
+		h.Pools.RemoveUserFromGuildPool(guildId, intUserId)
+		for _, frame := range guildFrames[guildId] {
+			h.Pools.BroadcastGuild(guildId, frame)
+		}
 	}
 
 	for _, ownedGuild := range ownedGuilds {
 
 		if ownedGuild.Dm {
-			wsclient.Pools.BroadcastGuild(ownedGuild.Id, wsclient.DataFrame{ //makes the client delete guild
+			h.Pools.BroadcastGuild(ownedGuild.Id, wsclient.DataFrame{ //makes the client delete guild
 				Op: wsclient.TYPE_DISPATCH,
 				Data: events.Dm{
 					DmId: ownedGuild.Id,
@@ -178,7 +191,7 @@ func Delete(c *gin.Context) {
 				Event: events.DM_DELETE,
 			})
 		} else {
-			wsclient.Pools.BroadcastGuild(ownedGuild.Id, wsclient.DataFrame{ //makes the client delete guild
+			h.Pools.BroadcastGuild(ownedGuild.Id, wsclient.DataFrame{ //makes the client delete guild
 				Op: wsclient.TYPE_DISPATCH,
 				Data: events.Guild{
 					GuildId: ownedGuild.Id,
@@ -188,6 +201,6 @@ func Delete(c *gin.Context) {
 		}
 	}
 
-	wsclient.Pools.DisconnectUserFromClientPool(intUserId)
+	h.Pools.DisconnectUserFromClientPool(intUserId)
 	c.Status(http.StatusNoContent)
 }
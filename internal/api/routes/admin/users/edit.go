@@ -1,26 +1,25 @@
 package users
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"encoding/json"
-	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
-	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/config"
-	"github.com/asianchinaboi/backendserver/internal/db"
+	"github.com/asianchinaboi/backendserver/internal/blobs"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
 	"github.com/asianchinaboi/backendserver/internal/files"
 	"github.com/asianchinaboi/backendserver/internal/logger"
+	"github.com/asianchinaboi/backendserver/internal/scanner"
 	"github.com/asianchinaboi/backendserver/internal/session"
 	"github.com/asianchinaboi/backendserver/internal/uid"
 	"github.com/asianchinaboi/backendserver/internal/wsclient"
@@ -35,7 +34,7 @@ type editUserBody struct {
 	Flags    *int    `json:"flags"`
 }
 
-func Edit(c *gin.Context) {
+func (h *Handlers) Edit(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -80,7 +79,7 @@ func Edit(c *gin.Context) {
 
 	var userExists bool
 
-	if err := db.Db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE id=$1)", userId).Scan(&userExists); err != nil {
+	if err := h.Db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE id=$1)", userId).Scan(&userExists); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -97,7 +96,7 @@ func Edit(c *gin.Context) {
 
 	//BEGIN TRANSACTION
 	ctx := context.Background()
-	tx, err := db.Db.BeginTx(ctx, nil)
+	tx, err := h.Db.BeginTx(ctx, nil)
 	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
@@ -113,14 +112,14 @@ func Edit(c *gin.Context) {
 		}
 		strHashedPass := string(hashedPass)
 
-		if _, err = db.Db.Exec("UPDATE users SET password=$1 WHERE id=$2", strHashedPass, userId); err != nil {
+		if _, err = h.Db.Exec("UPDATE users SET password=$1 WHERE id=$2", strHashedPass, userId); err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
 	}
 	if body.Email != nil {
 		var taken bool
-		if err := db.Db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE email=$1)", *body.Email).Scan(&taken); err != nil {
+		if err := h.Db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE email=$1)", *body.Email).Scan(&taken); err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
@@ -128,20 +127,20 @@ func Edit(c *gin.Context) {
 			errors.SendErrorResponse(c, errors.ErrEmailExists, errors.StatusEmailExists)
 			return
 		}
-		if _, err = db.Db.Exec("UPDATE users SET email=$1 WHERE id=$2", *body.Email, userId); err != nil {
+		if _, err = h.Db.Exec("UPDATE users SET email=$1 WHERE id=$2", *body.Email, userId); err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
 		newUserInfo.Email = body.Email
 	} else {
-		if err := db.Db.QueryRow("SELECT email FROM users WHERE id=$1", userId).Scan(&newUserInfo.Email); err != nil {
+		if err := h.Db.QueryRow("SELECT email FROM users WHERE id=$1", userId).Scan(&newUserInfo.Email); err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
 	}
 	if body.Username != nil {
 		var taken bool
-		if err := db.Db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE username=$1)", *body.Username).Scan(&taken); err != nil {
+		if err := h.Db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE username=$1)", *body.Username).Scan(&taken); err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
@@ -155,7 +154,7 @@ func Edit(c *gin.Context) {
 		}
 		newUserInfo.Name = *body.Username
 	} else {
-		if err := db.Db.QueryRow("SELECT username FROM users WHERE id=$1", userId).Scan(&newUserInfo.Name); err != nil {
+		if err := h.Db.QueryRow("SELECT username FROM users WHERE id=$1", userId).Scan(&newUserInfo.Name); err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
@@ -167,7 +166,7 @@ func Edit(c *gin.Context) {
 		}
 		*newUserInfo.Flags = *body.Flags
 	} else {
-		if err := db.Db.QueryRow("SELECT flags FROM users WHERE id=$1", userId).Scan(&newUserInfo.Flags); err != nil {
+		if err := h.Db.QueryRow("SELECT flags FROM users WHERE id=$1", userId).Scan(&newUserInfo.Flags); err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
@@ -175,7 +174,8 @@ func Edit(c *gin.Context) {
 	if imageHeader != nil {
 		//get old image id
 		var oldImageId int64
-		if err := db.Db.QueryRow("SELECT id FROM files WHERE user_id = $1", user.Id).Scan(&oldImageId); err != nil && err != sql.ErrNoRows {
+		var oldHash sql.NullString
+		if err := h.Db.QueryRow("SELECT id, hash FROM files WHERE user_id = $1", user.Id).Scan(&oldImageId, &oldHash); err != nil && err != sql.ErrNoRows {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		} else if err == sql.ErrNoRows {
@@ -185,8 +185,8 @@ func Edit(c *gin.Context) {
 				if successful {
 					deleteImageId := oldImageId
 					if deleteImageId != -1 {
-						if err := os.Remove(fmt.Sprintf("uploads/user/%d.lz4", deleteImageId)); err != nil {
-							logger.Warn.Printf("failed to remove file: %v\n", err)
+						if err := blobs.Remove(context.Background(), h.Db, h.Store, "user", deleteImageId, oldHash); err != nil {
+							logger.Ctx(c).Warn("failed to remove file", "err", err)
 						}
 					}
 				}
@@ -214,14 +214,15 @@ func Edit(c *gin.Context) {
 			return
 		}
 
-		if valid := files.ValidateImage(fileBytes, fileType); !valid {
+		fileBytes, valid := files.ValidateImage(fileBytes, fileType, h.Config.Server.ImageProfileSize) //crops and scales it down if needed
+		if !valid {
 			errors.SendErrorResponse(c, err, errors.StatusFileInvalid)
 			return
 		}
 
 		filesize := len(fileBytes)
 
-		if filesize > config.Config.Server.MaxFileSize {
+		if filesize > h.Config.Server.MaxFileSize {
 			errors.SendErrorResponse(c, err, errors.StatusFileTooLarge)
 			return
 		} else if !(filesize >= 0) {
@@ -229,32 +230,24 @@ func Edit(c *gin.Context) {
 			return
 		}
 
-		compressedBuffer, err := files.Compress(fileBytes, filesize)
-		if err != nil {
-			errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		//images use the default policy, the guild policy is only for attachments
+		verdict, err := scanner.Check(ctx, h.Scanner, bytes.NewReader(fileBytes), h.Config.Scanner.Policy)
+		if err == errors.ErrScannerUnavailable {
+			errors.SendErrorResponse(c, err, errors.StatusScannerUnavailable)
 			return
-		}
-
-		if _, err = tx.ExecContext(ctx, "INSERT INTO files (id, filename, created, temp, filesize, user_id, entity_type) VALUES ($1, $2, $3, $4, $5, $6,'user')", imageId, filename, imageCreated, false, filesize, user.Id); err != nil {
+		} else if err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
 
-		outFile, err := os.Create(fmt.Sprintf("uploads/user/%d.lz4", imageId))
+		//stored by content so an image thats already been uploaded isnt stored again
+		hash, err := blobs.Put(ctx, tx, h.Store, bytes.NewReader(fileBytes))
 		if err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
-		defer func() { //defer just in case something went wrong
-			if !successful {
-				if err := os.Remove(fmt.Sprintf("uploads/user/%d.lz4", imageId)); err != nil {
-					logger.Warn.Printf("failed to remove file: %v\n", err)
-				}
-			}
-		}()
-		defer outFile.Close()
 
-		if _, err = outFile.Write(compressedBuffer); err != nil {
+		if _, err = tx.ExecContext(ctx, "INSERT INTO files (id, filename, created, temp, filesize, user_id, entity_type, hash, quarantined, quarantine_reason) VALUES ($1, $2, $3, $4, $5, $6, 'user', $7, $8, NULLIF($9, ''))", imageId, filename, imageCreated, false, filesize, user.Id, hash, verdict.Quarantined, verdict.Reason); err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
@@ -262,7 +255,7 @@ func Edit(c *gin.Context) {
 		newUserInfo.ImageId = imageId
 	} else {
 		var imageId int64
-		if err := db.Db.QueryRow("SELECT id FROM files WHERE user_id=$1", user.Id).Scan(&imageId); err != nil && err != sql.ErrNoRows {
+		if err := h.Db.QueryRow("SELECT id FROM files WHERE user_id=$1", user.Id).Scan(&imageId); err != nil && err != sql.ErrNoRows {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		} else if err == sql.ErrNoRows {
@@ -286,7 +279,7 @@ func Edit(c *gin.Context) {
 		Event: events.USER_INFO_UPDATE,
 	}
 
-	wsclient.Pools.BroadcastClient(user.Id, res)
+	h.Pools.BroadcastClient(user.Id, res)
 
 	newUserInfoOtherRes := newUserInfo
 
@@ -299,7 +292,7 @@ func Edit(c *gin.Context) {
 		Event: events.USER_INFO_UPDATE,
 	}
 
-	userIdRows, err := db.Db.Query(
+	userIdRows, err := h.Db.Query(
 		`(SELECT DISTINCT userguilds.user_id AS user_id FROM userguilds WHERE EXISTS (SELECT 1 FROM userguilds AS ug2 WHERE ug2.user_id = $1 AND ug2.guild_id = userguilds.guild_id) AND userguilds.user_id != $1)
 		UNION (SELECT DISTINCT friend_id AS user_id FROM friends WHERE user_id = $1)
 		`, user.Id)
@@ -314,7 +307,7 @@ func Edit(c *gin.Context) {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
-		wsclient.Pools.BroadcastClient(userId, otherRes)
+		h.Pools.BroadcastClient(userId, otherRes)
 	}
 	c.Status(http.StatusNoContent)
 }
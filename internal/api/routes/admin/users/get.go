@@ -7,7 +7,6 @@ import (
 	"strconv"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/db"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
 	"github.com/asianchinaboi/backendserver/internal/logger"
@@ -15,7 +14,7 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func Get(c *gin.Context) {
+func (h *Handlers) Get(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -61,9 +60,9 @@ func Get(c *gin.Context) {
 		nullIntLimit.Valid = true
 		nullIntLimit.Int64 = intLimit
 	}
-	logger.Debug.Println(limit, offset)
+	logger.Ctx(c).Debug("listing users", "limit", limit, "offset", offset)
 	//somehow escaping characters probs why
-	rows, err := db.Db.Query("SELECT users.id, username, email, files.id FROM users LEFT JOIN files ON files.user_id = users.id LIMIT $1 OFFSET $2", nullIntLimit, offset)
+	rows, err := h.Db.Query("SELECT users.id, username, email, files.id FROM users LEFT JOIN files ON files.user_id = users.id LIMIT $1 OFFSET $2", nullIntLimit, offset)
 	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
@@ -74,11 +73,7 @@ func Get(c *gin.Context) {
 	for rows.Next() {
 		var user events.User
 		if err := rows.Scan(&user.UserId, &user.Name, &user.Email, &user.ImageId); err != nil {
-			logger.Error.Println(err)
-			c.JSON(http.StatusInternalServerError, errors.Body{
-				Error:  err.Error(),
-				Status: errors.StatusInternalError,
-			})
+			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
 		users = append(users, user)
@@ -0,0 +1,74 @@
+package users
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/asianchinaboi/backendserver/internal/api/middleware"
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/asianchinaboi/backendserver/internal/quota"
+	"github.com/asianchinaboi/backendserver/internal/session"
+	"github.com/gin-gonic/gin"
+)
+
+type quotaBody struct {
+	Quota *int64 `json:"quota"` //bytes, 0 for unlimited and null to go back to the default
+}
+
+// overrides the storage quota from the config for one user
+func (h *Handlers) Quota(c *gin.Context) {
+	user := c.MustGet(middleware.User).(*session.Session)
+	if user == nil {
+		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
+		return
+	}
+	if !user.Perms.Admin && !user.Perms.Users.Edit {
+		errors.SendErrorResponse(c, errors.ErrNotAuthorised, errors.StatusNotAuthorised)
+		return
+	}
+
+	userId := c.Param("userId")
+	if match, err := regexp.MatchString("^[0-9]+$", userId); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if !match {
+		errors.SendErrorResponse(c, errors.ErrRouteParamInvalid, errors.StatusRouteParamInvalid)
+		return
+	}
+	intUserId, err := strconv.ParseInt(userId, 10, 64)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	var body quotaBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusBadRequest)
+		return
+	}
+	if body.Quota != nil && *body.Quota < 0 {
+		errors.SendErrorResponse(c, errors.ErrQuotaInvalid, errors.StatusQuotaInvalid)
+		return
+	}
+
+	result, err := h.Db.Exec("UPDATE users SET storage_quota = $1 WHERE id = $2", body.Quota, userId)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if affected == 0 {
+		errors.SendErrorResponse(c, errors.ErrUserNotFound, errors.StatusUserNotFound)
+		return
+	}
+
+	usage, err := quota.User(c.Request.Context(), h.Db, h.Config, intUserId)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	c.JSON(http.StatusOK, usage)
+}
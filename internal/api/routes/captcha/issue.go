@@ -0,0 +1,34 @@
+package captcha
+
+import (
+	"net/http"
+
+	"github.com/asianchinaboi/backendserver/internal/captcha"
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/gin-gonic/gin"
+)
+
+type issueRes struct {
+	Enabled   bool               `json:"enabled"`
+	Challenge *captcha.Challenge `json:"challenge,omitempty"`
+}
+
+func (h *Handlers) issue(c *gin.Context) {
+	if !h.Config.Captcha.Enabled {
+		c.JSON(http.StatusOK, issueRes{Enabled: false})
+		return
+	}
+	if h.Captcha == nil {
+		errors.SendErrorResponse(c, errors.ErrCaptchaProviderNotExist, errors.StatusInternalError)
+		return
+	}
+	challenge, err := h.Captcha.Issue(c.ClientIP())
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	c.JSON(http.StatusOK, issueRes{
+		Enabled:   true,
+		Challenge: challenge,
+	})
+}
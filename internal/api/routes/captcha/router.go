@@ -0,0 +1,22 @@
+package captcha
+
+import (
+	"github.com/asianchinaboi/backendserver/internal/api/deps"
+	"github.com/gin-gonic/gin"
+)
+
+// Handlers are the captcha routes, Routes builds them from what the app hands over
+type Handlers struct {
+	*deps.Deps
+}
+
+func New(d *deps.Deps) *Handlers {
+	return &Handlers{d}
+}
+
+func Routes(r *gin.RouterGroup, d *deps.Deps) {
+	h := New(d)
+
+	captcha := r.Group("/captcha")
+	captcha.GET("/", h.issue)
+}
@@ -0,0 +1,57 @@
+package files
+
+import (
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/asianchinaboi/backendserver/internal/files"
+	"github.com/asianchinaboi/backendserver/internal/session"
+	"github.com/gin-gonic/gin"
+)
+
+// authorise checks if the request is allowed to download the file and sends the error response if not
+// user and guild images are public, msg attachments need a signed link or a user who can see the msg
+func (h *Handlers) authorise(c *gin.Context, entityType string, fileId int64) bool {
+	if entityType != "msg" {
+		return true
+	}
+
+	if signature := c.Query("signature"); signature != "" {
+		if !files.VerifySignature(h.Config, entityType, fileId, c.Query("expires"), signature) {
+			errors.SendErrorResponse(c, errors.ErrFileSignatureInvalid, errors.StatusFileSignatureInvalid)
+			return false
+		}
+		return true
+	}
+
+	token := c.GetHeader("Authorization")
+	if token == "" {
+		errors.SendErrorResponse(c, errors.ErrAbsentToken, errors.StatusAbsentToken)
+		return false
+	}
+	user, err := session.CheckAuthorization(h.Db, token)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusNotAuthorised)
+		return false
+	}
+	return h.canAccess(c, user, fileId)
+}
+
+// canAccess checks if the user can see the msg the file was sent in
+// not found is sent instead of forbidden so file ids cant be probed
+func (h *Handlers) canAccess(c *gin.Context, user *session.Session, fileId int64) bool {
+	if user.Perms.Admin {
+		return true
+	}
+	var allowed bool
+	if err := h.Db.QueryRow(`SELECT EXISTS (SELECT 1 FROM files f LEFT JOIN msgs m ON m.id = f.msg_id 
+	WHERE f.id = $1 AND f.entity_type = 'msg' AND (f.uploader_id = $2 OR EXISTS (
+		SELECT 1 FROM userguilds ug WHERE ug.guild_id = COALESCE(m.guild_id, f.msg_guild_id) AND ug.user_id = $2 AND ug.banned = false
+	)))`, fileId, user.Id).Scan(&allowed); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return false
+	}
+	if !allowed {
+		errors.SendErrorResponse(c, errors.ErrFileNotFound, errors.StatusFileNotFound)
+		return false
+	}
+	return true
+}
@@ -3,17 +3,20 @@ package files
 import (
 	"database/sql"
 	"fmt"
+	"io"
 	"net/http"
-	"os"
 	"regexp"
+	"strconv"
+	"time"
 
-	"github.com/asianchinaboi/backendserver/internal/db"
+	"github.com/asianchinaboi/backendserver/internal/blobs"
 	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/asianchinaboi/backendserver/internal/files"
 	"github.com/gin-gonic/gin"
-	"github.com/pierrec/lz4/v4"
 )
 
-func get(c *gin.Context) {
+// supports range requests and If-None-Match, files never change once uploaded so the id is the etag
+func (h *Handlers) get(c *gin.Context) {
 
 	fileId := c.Param("fileId")
 	if match, err := regexp.MatchString("^[0-9]+$", fileId); err != nil {
@@ -31,31 +34,47 @@ func get(c *gin.Context) {
 		errors.SendErrorResponse(c, errors.ErrRouteParamInvalid, errors.StatusRouteParamInvalid)
 		return
 	}
+	intFileId, err := strconv.ParseInt(fileId, 10, 64)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	if !h.authorise(c, entityType, intFileId) {
+		return
+	}
+
 	var filename string
 	var filesize int64
-	if err := db.Db.QueryRow("SELECT filename, filesize FROM files WHERE id = $1 AND entity_type = $2", fileId, entityType).Scan(&filename, &filesize); err != nil && err != sql.ErrNoRows {
+	var filetype sql.NullString
+	var created time.Time
+	var hash sql.NullString
+	if err := h.Db.QueryRow("SELECT filename, filesize, filetype, created, hash FROM files WHERE id = $1 AND entity_type = $2 AND quarantined = false", fileId, entityType).Scan(&filename, &filesize, &filetype, &created, &hash); err != nil && err != sql.ErrNoRows {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	} else if err == sql.ErrNoRows {
 		errors.SendErrorResponse(c, errors.ErrFileNotFound, errors.StatusFileNotFound)
 		return
 	}
-	fileBytes, err := os.ReadFile(fmt.Sprintf("uploads/%s/%s.lz4", entityType, fileId))
-	if err != nil {
-		errors.SendErrorResponse(c, err, errors.StatusInternalError)
-		return
-	}
 
-	uncompressedBuffer := make([]byte, filesize)
-
-	if _, err := lz4.UncompressBlock(fileBytes, uncompressedBuffer); err != nil {
-		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+	if size := c.Query("size"); size != "" {
+		h.thumbnail(c, entityType, intFileId, hash, filesize, filetype, created, size)
 		return
 	}
 
-	contentType := http.DetectContentType(uncompressedBuffer)
+	key := blobs.Key(entityType, intFileId, hash)
+	ctx := c.Request.Context()
+	content := files.NewSeeker(filesize, func() (io.ReadCloser, error) {
+		return files.Open(ctx, h.Store, key, filesize)
+	})
+	defer content.Close()
 
+	if filetype.Valid && filetype.String != "" {
+		c.Header("Content-Type", filetype.String)
+	}
+	c.Header("ETag", fmt.Sprintf(`"%s"`, fileId))
+	c.Header("Cache-Control", "private, max-age=86400")
 	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
 
-	c.Data(http.StatusOK, contentType, uncompressedBuffer)
+	//checks the etag, handles ranges and only opens the file if something needs to be sent
+	http.ServeContent(c.Writer, c.Request, "", created, content)
 }
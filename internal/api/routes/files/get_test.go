@@ -0,0 +1,53 @@
+package files_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/asianchinaboi/backendserver/internal/app/apptest"
+	"github.com/asianchinaboi/backendserver/internal/config"
+	"github.com/asianchinaboi/backendserver/internal/events"
+	"github.com/asianchinaboi/backendserver/internal/scanner"
+)
+
+func TestQuarantinedNotServed(t *testing.T) {
+	s := apptest.NewWithDB(t, func(conf *config.Settings) {
+		conf.Scanner.Backend = "fake"
+	})
+	_, token := s.Signup(t, "scanned")
+	guildId := s.CreateGuild(t, token, "quarantine test")
+
+	path := fmt.Sprintf("/guilds/%d/msgs", guildId)
+	res := s.Multipart(t, http.MethodPost, path, token, events.Msg{Content: "two files"}, "file", map[string][]byte{
+		"clean.txt": []byte("nothing to see here"),
+		"eicar.txt": []byte(scanner.Eicar),
+	})
+	apptest.Expect(t, res, http.StatusNoContent, nil)
+
+	var msgs []events.Msg
+	before := fmt.Sprintf("%s?time=%d", path, time.Now().Unix()+1) //msgs sent in the current second arent returned otherwise
+	s.JSON(t, http.MethodGet, before, token, nil, http.StatusOK, &msgs)
+	if len(msgs) != 1 || msgs[0].Attachments == nil || len(*msgs[0].Attachments) != 2 {
+		t.Fatalf("expected one message with two attachments, got %+v", msgs)
+	}
+	for _, attachment := range *msgs[0].Attachments {
+		want, status := false, http.StatusOK
+		if attachment.Filename == "eicar.txt" {
+			want, status = true, http.StatusNotFound
+		}
+		if attachment.Quarantined != want {
+			t.Errorf("%s: quarantined is %v, want %v", attachment.Filename, attachment.Quarantined, want)
+		}
+		res := s.Request(t, http.MethodGet, fmt.Sprintf("/files/msg/%d", attachment.Id), token, "", nil)
+		body, _ := io.ReadAll(res.Body)
+		if res.StatusCode != status {
+			t.Errorf("%s: got %d, want %d", attachment.Filename, res.StatusCode, status)
+		}
+		if status == http.StatusOK && string(body) != "nothing to see here" {
+			t.Errorf("%s: served %q", attachment.Filename, body)
+		}
+	}
+}
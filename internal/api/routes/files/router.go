@@ -1,13 +1,30 @@
 package files
 
 import (
+	"github.com/asianchinaboi/backendserver/internal/api/deps"
+	"github.com/asianchinaboi/backendserver/internal/api/middleware"
 	"github.com/gin-gonic/gin"
 )
 
-//make this at the end
+// Handlers are the files routes, Routes builds them from what the app hands over
+type Handlers struct {
+	*deps.Deps
+}
+
+func New(d *deps.Deps) *Handlers {
+	return &Handlers{d}
+}
+
+func Routes(r *gin.RouterGroup, d *deps.Deps) {
+	h := New(d)
 
-func Routes(r *gin.RouterGroup) {
 	files := r.Group("/files")
-	//files.Use(middleware.Auth)
-	files.GET("/:entityType/:fileId", get)
+	//not behind auth since icons are public, get checks msg attachments itself
+	files.GET("/:entityType/:fileId", h.get) //optional query params size for image thumbnails, expires and signature for signed links
+	files.HEAD("/:entityType/:fileId", h.get)
+	files.POST("/:entityType/:fileId/sign", middleware.Auth(d.Db), h.sign)
+
+	//transcoded video and audio attachments, index.m3u8 is the playlist
+	files.GET("/:entityType/:fileId/hls/:name", h.stream)
+	files.GET("/:entityType/:fileId/poster", h.poster)
 }
@@ -0,0 +1,69 @@
+package files
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/asianchinaboi/backendserver/internal/api/middleware"
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/asianchinaboi/backendserver/internal/events"
+	"github.com/asianchinaboi/backendserver/internal/files"
+	"github.com/asianchinaboi/backendserver/internal/session"
+	"github.com/gin-gonic/gin"
+)
+
+// gives a short lived download link that works without the authorization header
+func (h *Handlers) sign(c *gin.Context) {
+	user := c.MustGet(middleware.User).(*session.Session)
+	if user == nil {
+		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
+		return
+	}
+
+	fileId := c.Param("fileId")
+	if match, err := regexp.MatchString("^[0-9]+$", fileId); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if !match {
+		errors.SendErrorResponse(c, errors.ErrRouteParamInvalid, errors.StatusRouteParamInvalid)
+		return
+	}
+	entityType := c.Param("entityType")
+	if match, err := regexp.MatchString("^(guild|user|msg)$", entityType); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if !match {
+		errors.SendErrorResponse(c, errors.ErrRouteParamInvalid, errors.StatusRouteParamInvalid)
+		return
+	}
+	intFileId, err := strconv.ParseInt(fileId, 10, 64)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	if !files.SignedURLsEnabled(h.Config) {
+		errors.SendErrorResponse(c, errors.ErrNotAuthorised, errors.StatusNotAuthorised)
+		return
+	}
+
+	if entityType == "msg" {
+		if !h.canAccess(c, user, intFileId) {
+			return
+		}
+	} else {
+		var exists bool
+		if err := h.Db.QueryRow("SELECT EXISTS (SELECT 1 FROM files WHERE id = $1 AND entity_type = $2)", fileId, entityType).Scan(&exists); err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusInternalError)
+			return
+		} else if !exists {
+			errors.SendErrorResponse(c, errors.ErrFileNotFound, errors.StatusFileNotFound)
+			return
+		}
+	}
+
+	var signed events.SignedURL
+	signed.Url, signed.Expires = files.SignedURL(h.Config, entityType, intFileId)
+	c.JSON(http.StatusOK, signed)
+}
@@ -0,0 +1,143 @@
+package files
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/asianchinaboi/backendserver/internal/files"
+	"github.com/asianchinaboi/backendserver/internal/transcode"
+	"github.com/gin-gonic/gin"
+)
+
+// serves the hls playlist and segments of a transcoded video or audio attachment
+func (h *Handlers) stream(c *gin.Context) {
+	name := c.Param("name")
+	if !transcode.ValidName(name) || name == transcode.PosterName {
+		errors.SendErrorResponse(c, errors.ErrRouteParamInvalid, errors.StatusRouteParamInvalid)
+		return
+	}
+	h.serveTranscoded(c, name)
+}
+
+// serves the frame picked from a video for previews
+func (h *Handlers) poster(c *gin.Context) {
+	h.serveTranscoded(c, transcode.PosterName)
+}
+
+func (h *Handlers) serveTranscoded(c *gin.Context, name string) {
+	fileId := c.Param("fileId")
+	if match, err := regexp.MatchString("^[0-9]+$", fileId); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if !match {
+		errors.SendErrorResponse(c, errors.ErrRouteParamInvalid, errors.StatusRouteParamInvalid)
+		return
+	}
+	entityType := c.Param("entityType")
+	if entityType != "msg" { //only attachments get transcoded
+		errors.SendErrorResponse(c, errors.ErrRouteParamInvalid, errors.StatusRouteParamInvalid)
+		return
+	}
+	intFileId, err := strconv.ParseInt(fileId, 10, 64)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	if !h.authorise(c, entityType, intFileId) {
+		return
+	}
+
+	var hash string
+	var status string
+	var updated time.Time
+	if err := h.Db.QueryRow(`SELECT t.hash, t.status, t.updated FROM files f INNER JOIN transcodes t ON t.hash = f.hash 
+	WHERE f.id = $1 AND f.entity_type = $2 AND f.quarantined = false`, fileId, entityType).Scan(&hash, &status, &updated); err != nil && err != sql.ErrNoRows {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if err == sql.ErrNoRows {
+		errors.SendErrorResponse(c, errors.ErrFileNotFound, errors.StatusFileNotFound)
+		return
+	}
+	if status != transcode.StatusReady {
+		errors.SendErrorResponse(c, errors.ErrTranscodeNotReady, errors.StatusTranscodeNotReady)
+		return
+	}
+
+	ctx := c.Request.Context()
+	key := transcode.Key(hash, name)
+	object, err := h.Store.Stat(ctx, key)
+	if err == errors.ErrStorageObjectNotExist { //audio has no poster
+		errors.SendErrorResponse(c, errors.ErrFileNotFound, errors.StatusFileNotFound)
+		return
+	} else if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	if name == transcode.PlaylistName {
+		h.playlist(c, key, entityType, intFileId)
+		return
+	}
+
+	switch name {
+	case transcode.PosterName:
+		c.Header("Content-Type", "image/jpeg")
+	default:
+		c.Header("Content-Type", "video/mp2t")
+	}
+	c.Header("ETag", fmt.Sprintf(`"%s-%s"`, fileId, name))
+	c.Header("Cache-Control", "private, max-age=86400")
+	content := files.NewSeeker(object.Size, func() (io.ReadCloser, error) {
+		return h.Store.Get(ctx, key)
+	})
+	defer content.Close()
+	http.ServeContent(c.Writer, c.Request, "", updated, content)
+}
+
+// players fetch segments without the authorization header so every segment gets a signed query added
+// signed links reuse their own signature since it covers the whole file
+func (h *Handlers) playlist(c *gin.Context, key string, entityType string, fileId int64) {
+	reader, err := h.Store.Get(c.Request.Context(), key)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	defer reader.Close()
+
+	query := ""
+	if signature := c.Query("signature"); signature != "" {
+		query = url.Values{"expires": {c.Query("expires")}, "signature": {signature}}.Encode()
+	} else if files.SignedURLsEnabled(h.Config) {
+		signed, _ := files.SignedURL(h.Config, entityType, fileId)
+		query = signed[strings.Index(signed, "?")+1:]
+	}
+
+	var buffer bytes.Buffer
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") && query != "" {
+			line += "?" + query
+		}
+		buffer.WriteString(line)
+		buffer.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	c.Header("Cache-Control", "private, no-cache") //the signatures in it expire
+	c.Data(http.StatusOK, "application/vnd.apple.mpegurl", buffer.Bytes())
+}
@@ -0,0 +1,70 @@
+package files
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/asianchinaboi/backendserver/internal/blobs"
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/asianchinaboi/backendserver/internal/files"
+	"github.com/asianchinaboi/backendserver/internal/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// serves a scaled down copy of an image
+// each size gets made the first time its asked for and is kept until the file is deleted
+func (h *Handlers) thumbnail(c *gin.Context, entityType string, fileId int64, hash sql.NullString, filesize int64, filetype sql.NullString, created time.Time, size string) {
+	intSize, err := strconv.Atoi(size)
+	validSize := false
+	for _, thumbnailSize := range files.ThumbnailSizes {
+		if intSize == thumbnailSize {
+			validSize = true
+		}
+	}
+	if err != nil || !validSize {
+		errors.SendErrorResponse(c, errors.ErrRouteParamInvalid, errors.StatusRouteParamInvalid)
+		return
+	}
+	if _, ok := files.ImageFormat(filetype.String); !ok {
+		errors.SendErrorResponse(c, errors.ErrFileInvalid, errors.StatusFileInvalid)
+		return
+	}
+
+	ctx := c.Request.Context()
+	key := blobs.ThumbKey(entityType, fileId, hash, intSize)
+	c.Header("Content-Type", files.ThumbnailContentType(filetype.String))
+	c.Header("ETag", fmt.Sprintf(`"%d-%d"`, fileId, intSize))
+	c.Header("Cache-Control", "private, max-age=86400")
+
+	if object, err := h.Store.Stat(ctx, key); err == nil {
+		content := files.NewSeeker(object.Size, func() (io.ReadCloser, error) {
+			return h.Store.Get(ctx, key)
+		})
+		defer content.Close()
+		http.ServeContent(c.Writer, c.Request, "", created, content)
+		return
+	} else if err != errors.ErrStorageObjectNotExist {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	originalKey := blobs.Key(entityType, fileId, hash)
+	original := files.NewSeeker(filesize, func() (io.ReadCloser, error) {
+		return files.Open(ctx, h.Store, originalKey, filesize)
+	})
+	defer original.Close()
+	var buffer bytes.Buffer
+	if err := files.Thumbnail(&buffer, original, intSize); err != nil {
+		errors.SendErrorResponse(c, errors.ErrFileInvalid, errors.StatusFileInvalid)
+		return
+	}
+	if err := h.Store.Put(ctx, key, bytes.NewReader(buffer.Bytes()), int64(buffer.Len())); err != nil {
+		logger.Ctx(c).Warn("unable to store thumbnail", "err", err) //still send it, it just gets made again next time
+	}
+	http.ServeContent(c.Writer, c.Request, "", created, bytes.NewReader(buffer.Bytes()))
+}
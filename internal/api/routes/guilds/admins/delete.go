@@ -6,7 +6,6 @@ import (
 	"strconv"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/db"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
 	"github.com/asianchinaboi/backendserver/internal/session"
@@ -14,7 +13,7 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func Delete(c *gin.Context) {
+func (h *Handlers) Delete(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -43,7 +42,7 @@ func Delete(c *gin.Context) {
 	var isDm bool
 	var isUserAdmin bool
 	var isUserInGuild bool
-	if err := db.Db.QueryRow(`
+	if err := h.Db.QueryRow(`
 	SELECT EXISTS (SELECT 1 FROM userguilds WHERE user_id = $1 AND guild_id = $2 AND owner = true), 
 	EXISTS (SELECT 1 FROM guilds WHERE id = $2 AND dm = true),
 	EXISTS (SELECT 1 FROM userguilds WHERE guild_id = $2 AND user_id = $3 AND admin = true),
@@ -73,7 +72,7 @@ func Delete(c *gin.Context) {
 		return
 	}
 
-	if _, err := db.Db.Exec("UPDATE userguilds SET admin = false WHERE user_id = $1 AND guild_id = $2", userId, guildId); err != nil {
+	if _, err := h.Db.Exec("UPDATE userguilds SET admin = false WHERE user_id = $1 AND guild_id = $2", userId, guildId); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -90,7 +89,7 @@ func Delete(c *gin.Context) {
 		return
 	}
 
-	rows, err := db.Db.Query("SELECT user_id FROM userguilds WHERE guild_id = $1 AND admin = true OR owner = true", guildId)
+	rows, err := h.Db.Query("SELECT user_id FROM userguilds WHERE guild_id = $1 AND admin = true OR owner = true", guildId)
 	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
@@ -108,7 +107,7 @@ func Delete(c *gin.Context) {
 				}},
 			Event: events.MEMBER_ADMIN_REMOVE,
 		}
-		wsclient.Pools.BroadcastClient(adminUserId, res)
+		h.Pools.BroadcastClient(adminUserId, res)
 	}
 	c.Status(http.StatusNoContent)
 }
@@ -6,14 +6,13 @@ import (
 	"regexp"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/db"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
 	"github.com/asianchinaboi/backendserver/internal/session"
 	"github.com/gin-gonic/gin"
 )
 
-func Get(c *gin.Context) {
+func (h *Handlers) Get(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -30,7 +29,7 @@ func Get(c *gin.Context) {
 	}
 
 	var isDm bool
-	if err := db.Db.QueryRow("SELECT EXISTS (SELECT 1 FROM guilds WHERE id = $1 AND dm = true)", guildId).Scan(&isDm); err != nil {
+	if err := h.Db.QueryRow("SELECT EXISTS (SELECT 1 FROM guilds WHERE id = $1 AND dm = true)", guildId).Scan(&isDm); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -41,7 +40,7 @@ func Get(c *gin.Context) {
 	}
 
 	admins := []events.User{}
-	rows, err := db.Db.Query("SELECT ug.user_id, u.username, f.id FROM userguilds ug INNER JOIN users u ON u.id = ug.user_id LEFT JOIN files f ON f.user_id = u.id WHERE ug.guild_id=$1 AND admin = true", guildId)
+	rows, err := h.Db.Query("SELECT ug.user_id, u.username, f.id FROM userguilds ug INNER JOIN users u ON u.id = ug.user_id LEFT JOIN files f ON f.user_id = u.id WHERE ug.guild_id=$1 AND admin = true", guildId)
 	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
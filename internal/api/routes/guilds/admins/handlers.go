@@ -0,0 +1,12 @@
+package admins
+
+import "github.com/asianchinaboi/backendserver/internal/api/deps"
+
+// Handlers are the admins routes, the router builds them with New
+type Handlers struct {
+	*deps.Deps
+}
+
+func New(d *deps.Deps) *Handlers {
+	return &Handlers{d}
+}
@@ -1,21 +1,22 @@
 package bans
 
 import (
+	"context"
 	"database/sql"
 	"net/http"
 	"regexp"
 	"strconv"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/db"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
+	"github.com/asianchinaboi/backendserver/internal/outbox"
 	"github.com/asianchinaboi/backendserver/internal/session"
 	"github.com/asianchinaboi/backendserver/internal/wsclient"
 	"github.com/gin-gonic/gin"
 )
 
-func Ban(c *gin.Context) {
+func (h *Handlers) Ban(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -62,7 +63,7 @@ func Ban(c *gin.Context) {
 	var userExists bool
 	var isDm bool
 
-	if err := db.Db.QueryRow(`
+	if err := h.Db.QueryRow(`
 	SELECT EXISTS (SELECT 1 FROM userguilds WHERE guild_id=$1 AND user_id=$2 AND (admin=true OR owner=true)), 
 	EXISTS (SELECT 1 FROM userguilds WHERE guild_id = $1 AND user_id=$3 AND banned = true), 
 	EXISTS (SELECT 1 FROM guilds WHERE id = $1 AND dm = true),
@@ -92,14 +93,41 @@ func Ban(c *gin.Context) {
 		return
 	}
 
-	if _, err := db.Db.Exec("INSERT INTO userguilds (guild_id, user_id, banned) VALUES ($1, $2, true) ON CONFLICT (guild_id, user_id) DO UPDATE SET banned=true", guildId, userId); err != nil {
+	ctx := context.Background()
+	tx, err := h.Db.BeginTx(ctx, nil)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	defer tx.Rollback() //rollback changes if failed
+
+	if _, err := tx.ExecContext(ctx, "INSERT INTO userguilds (guild_id, user_id, banned) VALUES ($1, $2, true) ON CONFLICT (guild_id, user_id) DO UPDATE SET banned=true", guildId, userId); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	guildRes := wsclient.DataFrame{
+		Op: wsclient.TYPE_DISPATCH,
+		Data: events.Member{
+			GuildId: intGuildId,
+			UserInfo: events.User{
+				UserId: intUserId,
+			},
+		},
+		Event: events.MEMBER_REMOVE,
+	}
+	if err := outbox.Enqueue(ctx, tx, intGuildId, guildRes.Event, guildRes.Data); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
 
+	if err := tx.Commit(); err != nil { //commits the transaction
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
 	var username string
 	var imageId sql.NullInt64
-	if err := db.Db.QueryRow("SELECT username, files.id FROM users LEFT JOIN files ON files.user_id = users.id WHERE users.id=$1", userId).Scan(&username, &imageId); err != nil {
+	if err := h.Db.QueryRow("SELECT username, files.id FROM users LEFT JOIN files ON files.user_id = users.id WHERE users.id=$1", userId).Scan(&username, &imageId); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -111,7 +139,7 @@ func Ban(c *gin.Context) {
 		resImageId = -1
 	}
 
-	rows, err := db.Db.Query("SELECT user_id FROM userguilds WHERE guild_id = $1 AND (owner = true OR admin = true)", guildId)
+	rows, err := h.Db.Query("SELECT user_id FROM userguilds WHERE guild_id = $1 AND (owner = true OR admin = true)", guildId)
 	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
@@ -135,7 +163,7 @@ func Ban(c *gin.Context) {
 			},
 			Event: events.MEMBER_BAN_ADD,
 		}
-		wsclient.Pools.BroadcastClient(adminUserId, res)
+		h.Pools.BroadcastClient(adminUserId, res)
 	}
 
 	banRes := wsclient.DataFrame{
@@ -145,18 +173,8 @@ func Ban(c *gin.Context) {
 		},
 		Event: events.GUILD_DELETE,
 	}
-	guildRes := wsclient.DataFrame{
-		Op: wsclient.TYPE_DISPATCH,
-		Data: events.Member{
-			GuildId: intGuildId,
-			UserInfo: events.User{
-				UserId: intUserId,
-			},
-		},
-		Event: events.MEMBER_REMOVE,
-	}
-	wsclient.Pools.BroadcastClient(intUserId, banRes)
-	wsclient.Pools.RemoveUserFromGuildPool(intGuildId, intUserId)
-	wsclient.Pools.BroadcastGuild(intGuildId, guildRes)
+	h.Pools.BroadcastClient(intUserId, banRes)
+	h.Pools.RemoveUserFromGuildPool(intGuildId, intUserId)
+	h.Pools.BroadcastGuild(intGuildId, guildRes)
 	c.Status(http.StatusNoContent)
 }
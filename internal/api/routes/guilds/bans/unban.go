@@ -6,7 +6,6 @@ import (
 	"strconv"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/db"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
 	"github.com/asianchinaboi/backendserver/internal/session"
@@ -14,7 +13,7 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func Unban(c *gin.Context) {
+func (h *Handlers) Unban(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -43,7 +42,7 @@ func Unban(c *gin.Context) {
 	var isBanned bool
 	var isDm bool
 
-	if err := db.Db.QueryRow(`SELECT EXISTS (SELECT 1 FROM userguilds WHERE guild_id=$1 AND user_id=$2 AND owner=true OR admin=true), 
+	if err := h.Db.QueryRow(`SELECT EXISTS (SELECT 1 FROM userguilds WHERE guild_id=$1 AND user_id=$2 AND owner=true OR admin=true), 
 	EXISTS (SELECT 1 FROM userguilds WHERE guild_id=$1 AND user_id=$3 AND banned = true), 
 	EXISTS (SELECT 1 FROM guilds WHERE id = $1 AND dm = true)`, guildId, user.Id, userId).Scan(&hasAuth, &isBanned, &isDm); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
@@ -64,7 +63,7 @@ func Unban(c *gin.Context) {
 		return
 	}
 
-	if _, err := db.Db.Exec("DELETE FROM userguilds WHERE guild_id=$1 AND user_id=$2", guildId, userId); err != nil {
+	if _, err := h.Db.Exec("DELETE FROM userguilds WHERE guild_id=$1 AND user_id=$2", guildId, userId); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -79,7 +78,7 @@ func Unban(c *gin.Context) {
 		return
 	}
 
-	rows, err := db.Db.Query("SELECT user_id FROM userguilds WHERE guild_id = $1 AND (owner = true OR admin = true)", guildId)
+	rows, err := h.Db.Query("SELECT user_id FROM userguilds WHERE guild_id = $1 AND (owner = true OR admin = true)", guildId)
 	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
@@ -101,7 +100,7 @@ func Unban(c *gin.Context) {
 			},
 			Event: events.MEMBER_BAN_REMOVE,
 		}
-		wsclient.Pools.BroadcastClient(adminUserId, res)
+		h.Pools.BroadcastClient(adminUserId, res)
 	}
 	c.Status(http.StatusNoContent)
 }
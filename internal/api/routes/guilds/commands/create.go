@@ -0,0 +1,131 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/asianchinaboi/backendserver/internal/api/middleware"
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/asianchinaboi/backendserver/internal/events"
+	"github.com/asianchinaboi/backendserver/internal/session"
+	"github.com/asianchinaboi/backendserver/internal/uid"
+	"github.com/gin-gonic/gin"
+)
+
+// bots register commands in guilds they are in
+// registering a name the bot already has replaces it
+// expects
+// name : string
+// description : string
+// options : []{name, description, type (string|integer|boolean|user), required}
+func (h *Handlers) Create(c *gin.Context) {
+	user := c.MustGet(middleware.User).(*session.Session)
+	if user == nil {
+		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
+		return
+	}
+	if !user.Bot {
+		errors.SendErrorResponse(c, errors.ErrBotOnly, errors.StatusBotOnly)
+		return
+	}
+
+	guildId := c.Param("guildId")
+	if match, err := regexp.MatchString("^[0-9]+$", guildId); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if !match {
+		errors.SendErrorResponse(c, errors.ErrRouteParamInvalid, errors.StatusRouteParamInvalid)
+		return
+	}
+
+	intGuildId, err := strconv.ParseInt(guildId, 10, 64)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	var command events.Command
+	if err := c.ShouldBindJSON(&command); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusBadRequest)
+		return
+	}
+	if command.Options == nil {
+		command.Options = []events.CommandOption{}
+	}
+	if !events.ValidateCommand(command) {
+		errors.SendErrorResponse(c, errors.ErrCommandInvalid, errors.StatusCommandInvalid)
+		return
+	}
+
+	options, err := json.Marshal(command.Options)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	var inGuild bool
+	if err := h.Db.QueryRow("SELECT EXISTS (SELECT 1 FROM userguilds WHERE guild_id=$1 AND user_id=$2 AND banned=false)", guildId, user.Id).Scan(&inGuild); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	if !inGuild {
+		errors.SendErrorResponse(c, errors.ErrNotInGuild, errors.StatusNotInGuild)
+		return
+	}
+
+	//BEGIN TRANSACTION
+	ctx := context.Background()
+	tx, err := h.Db.BeginTx(ctx, nil)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	defer tx.Rollback()
+
+	var existingId int64
+	var existingBotId int64
+	if err := tx.QueryRowContext(ctx, "SELECT id, bot_id FROM commands WHERE guild_id = $1 AND name = $2 FOR UPDATE", guildId, command.Name).Scan(&existingId, &existingBotId); err != nil && err != sql.ErrNoRows {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if err == sql.ErrNoRows {
+		var count int
+		if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM commands WHERE guild_id = $1", guildId).Scan(&count); err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusInternalError)
+			return
+		}
+		if count >= h.Config.Guild.MaxCommands {
+			errors.SendErrorResponse(c, errors.ErrCommandLimitReached, errors.StatusCommandLimitReached)
+			return
+		}
+		command.CommandId = uid.Snowflake.Generate().Int64()
+		command.Created = time.Now().UTC()
+		if _, err := tx.ExecContext(ctx, "INSERT INTO commands (id, guild_id, bot_id, name, description, options, created) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+			command.CommandId, guildId, user.Id, command.Name, command.Description, string(options), command.Created); err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusInternalError)
+			return
+		}
+	} else if existingBotId != user.Id {
+		errors.SendErrorResponse(c, errors.ErrCommandAlreadyExists, errors.StatusCommandAlreadyExists)
+		return
+	} else {
+		command.CommandId = existingId
+		if err := tx.QueryRowContext(ctx, "UPDATE commands SET description = $2, options = $3 WHERE id = $1 RETURNING created", existingId, command.Description, string(options)).Scan(&command.Created); err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusInternalError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil { //commits the transaction
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	command.GuildId = intGuildId
+	command.BotId = user.Id
+	c.JSON(http.StatusOK, command)
+}
@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"net/http"
+	"regexp"
+
+	"github.com/asianchinaboi/backendserver/internal/api/middleware"
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/asianchinaboi/backendserver/internal/session"
+	"github.com/gin-gonic/gin"
+)
+
+// either the bot that made it or a guild admin can delete a command
+func (h *Handlers) Delete(c *gin.Context) {
+	user := c.MustGet(middleware.User).(*session.Session)
+	if user == nil {
+		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
+		return
+	}
+
+	guildId := c.Param("guildId")
+	if match, err := regexp.MatchString("^[0-9]+$", guildId); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if !match {
+		errors.SendErrorResponse(c, errors.ErrRouteParamInvalid, errors.StatusRouteParamInvalid)
+		return
+	}
+
+	commandId := c.Param("commandId")
+	if match, err := regexp.MatchString("^[0-9]+$", commandId); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if !match {
+		errors.SendErrorResponse(c, errors.ErrRouteParamInvalid, errors.StatusRouteParamInvalid)
+		return
+	}
+
+	var hasAuth bool
+	if err := h.Db.QueryRow("SELECT EXISTS (SELECT 1 FROM userguilds WHERE guild_id = $1 AND user_id = $2 AND (owner = true OR admin = true))", guildId, user.Id).Scan(&hasAuth); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	result, err := h.Db.Exec("DELETE FROM commands WHERE id = $1 AND guild_id = $2 AND ($3 OR bot_id = $4)", commandId, guildId, hasAuth, user.Id)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if affected == 0 {
+		errors.SendErrorResponse(c, errors.ErrCommandNotExist, errors.StatusCommandNotExist)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
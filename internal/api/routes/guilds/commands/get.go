@@ -0,0 +1,66 @@
+package commands
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+
+	"github.com/asianchinaboi/backendserver/internal/api/middleware"
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/asianchinaboi/backendserver/internal/events"
+	"github.com/asianchinaboi/backendserver/internal/session"
+	"github.com/gin-gonic/gin"
+)
+
+// commands from bots that left the guild are not shown
+func (h *Handlers) Get(c *gin.Context) {
+	user := c.MustGet(middleware.User).(*session.Session)
+	if user == nil {
+		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
+		return
+	}
+
+	guildId := c.Param("guildId")
+	if match, err := regexp.MatchString("^[0-9]+$", guildId); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if !match {
+		errors.SendErrorResponse(c, errors.ErrRouteParamInvalid, errors.StatusRouteParamInvalid)
+		return
+	}
+
+	var inGuild bool
+	if err := h.Db.QueryRow("SELECT EXISTS (SELECT 1 FROM userguilds WHERE guild_id=$1 AND user_id=$2 AND banned=false)", guildId, user.Id).Scan(&inGuild); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	if !inGuild {
+		errors.SendErrorResponse(c, errors.ErrNotInGuild, errors.StatusNotInGuild)
+		return
+	}
+
+	rows, err := h.Db.Query(`SELECT c.id, c.guild_id, c.bot_id, c.name, c.description, c.options, c.created FROM commands c 
+	WHERE c.guild_id = $1 AND EXISTS (SELECT 1 FROM userguilds WHERE guild_id = c.guild_id AND user_id = c.bot_id AND banned = false) 
+	ORDER BY c.name`, guildId)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	defer rows.Close()
+
+	commands := []events.Command{}
+	for rows.Next() {
+		var command events.Command
+		var options string
+		if err := rows.Scan(&command.CommandId, &command.GuildId, &command.BotId, &command.Name, &command.Description, &options, &command.Created); err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusInternalError)
+			return
+		}
+		if err := json.Unmarshal([]byte(options), &command.Options); err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusInternalError)
+			return
+		}
+		commands = append(commands, command)
+	}
+	c.JSON(http.StatusOK, commands)
+}
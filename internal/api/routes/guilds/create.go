@@ -1,24 +1,23 @@
 package guilds
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
-	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/config"
-	"github.com/asianchinaboi/backendserver/internal/db"
+	"github.com/asianchinaboi/backendserver/internal/blobs"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
 	"github.com/asianchinaboi/backendserver/internal/files"
 	"github.com/asianchinaboi/backendserver/internal/logger"
+	"github.com/asianchinaboi/backendserver/internal/scanner"
 	"github.com/asianchinaboi/backendserver/internal/session"
 	"github.com/asianchinaboi/backendserver/internal/uid"
 	"github.com/asianchinaboi/backendserver/internal/wsclient"
@@ -27,7 +26,7 @@ import (
 
 //accepts name, icon, savechat
 
-func createGuild(c *gin.Context) {
+func (h *Handlers) createGuild(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -66,15 +65,13 @@ func createGuild(c *gin.Context) {
 
 	//BEGIN TRANSACTION
 	ctx := context.Background()
-	tx, err := db.Db.BeginTx(ctx, nil)
+	tx, err := h.Db.BeginTx(ctx, nil)
 	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
 	defer tx.Rollback() //rollback changes if failed
 
-	successful := false
-
 	guildId := uid.Snowflake.Generate().Int64()
 
 	if _, err := tx.ExecContext(ctx, "INSERT INTO guilds (id, name, save_chat) VALUES ($1, $2, $3)", guildId, guild.Name, guild.SaveChat); err != nil {
@@ -102,14 +99,15 @@ func createGuild(c *gin.Context) {
 
 		fileMIMEType := http.DetectContentType(fileBytes)
 
-		if valid := files.ValidateImage(fileBytes, fileType); !valid {
+		fileBytes, valid := files.ValidateImage(fileBytes, fileType, h.Config.Server.ImageProfileSize) //crops and scales it down if needed
+		if !valid {
 			errors.SendErrorResponse(c, errors.ErrFileInvalid, errors.StatusFileInvalid)
 			return
 		}
 
 		filesize := len(fileBytes)
 
-		if filesize > config.Config.Server.MaxFileSize {
+		if filesize > h.Config.Server.MaxFileSize {
 			errors.SendErrorResponse(c, errors.ErrFileTooLarge, errors.StatusFileTooLarge)
 			return
 		} else if !(filesize >= 0) {
@@ -117,39 +115,30 @@ func createGuild(c *gin.Context) {
 			return
 		}
 
-		compressedBuffer, err := files.Compress(fileBytes, filesize)
-		if err != nil {
+		//images use the default policy, the guild policy is only for attachments
+		verdict, err := scanner.Check(ctx, h.Scanner, bytes.NewReader(fileBytes), h.Config.Scanner.Policy)
+		if err == errors.ErrScannerUnavailable {
+			errors.SendErrorResponse(c, err, errors.StatusScannerUnavailable)
+			return
+		} else if err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
 
-		outFile, err := os.Create(fmt.Sprintf("uploads/guild/%d.lz4", imageId))
-		//TODO: delete files if failed or write them after when its deemed successful
+		//stored by content so an image thats already been uploaded isnt stored again
+		hash, err := blobs.Put(ctx, tx, h.Store, bytes.NewReader(fileBytes))
 		if err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
-		defer func() { //defer just in case something went wrong
-			if !successful {
-				if err := os.Remove(fmt.Sprintf("uploads/guild/%d.lz4", imageId)); err != nil {
-					logger.Warn.Printf("failed to remove file: %v\n", err)
-				}
-			}
-		}()
-		defer outFile.Close()
-
-		if _, err = outFile.Write(compressedBuffer); err != nil {
-			errors.SendErrorResponse(c, err, errors.StatusInternalError)
-			return
-		}
 
-		if _, err = tx.ExecContext(ctx, "INSERT INTO files (id, guild_id, filename, created, temp, filesize, filetype, entity_type) VALUES ($1, $2, $3, $4, $5, $6, $7, 'guild')", imageId, guildId, filename, imageCreated, false, filesize, fileMIMEType); err != nil {
+		if _, err = tx.ExecContext(ctx, "INSERT INTO files (id, guild_id, filename, created, temp, filesize, filetype, entity_type, hash, quarantined, quarantine_reason) VALUES ($1, $2, $3, $4, $5, $6, $7, 'guild', $8, $9, NULLIF($10, ''))", imageId, guildId, filename, imageCreated, false, filesize, fileMIMEType, hash, verdict.Quarantined, verdict.Reason); err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
 		guild.ImageId = imageId
 	} else {
-		logger.Debug.Println("no image provided")
+		logger.Ctx(c).Debug("no image provided")
 		guild.ImageId = -1
 	}
 
@@ -176,8 +165,6 @@ func createGuild(c *gin.Context) {
 		return
 	}
 
-	successful = true
-
 	res := wsclient.DataFrame{
 		Op: wsclient.TYPE_DISPATCH,
 		Data: events.Guild{
@@ -193,10 +180,10 @@ func createGuild(c *gin.Context) {
 		Data:  invite,
 		Event: events.INVITE_CREATE,
 	}
-	wsclient.Pools.BroadcastClient(user.Id, res)
+	h.Pools.BroadcastClient(user.Id, res)
 	//shit i forgot to create a pool
-	wsclient.Pools.AddUserToGuildPool(guildId, user.Id)
-	wsclient.Pools.BroadcastGuild(guildId, invRes)
+	h.Pools.AddUserToGuildPool(guildId, user.Id)
+	h.Pools.BroadcastGuild(guildId, invRes)
 	//possible race condition but shouldnt be possible since sql does it by queue
 	c.Status(http.StatusNoContent) //writing this code at nearly 12 am gotta keep the grind up
 	//dec 9 2022 writing code at nearly 12 am is not good im fixing it rn and holy crap some of the stuff looks shit
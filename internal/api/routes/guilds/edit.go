@@ -1,26 +1,26 @@
 package guilds
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"encoding/json"
-	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
-	"os"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/config"
-	"github.com/asianchinaboi/backendserver/internal/db"
+	"github.com/asianchinaboi/backendserver/internal/blobs"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
 	"github.com/asianchinaboi/backendserver/internal/files"
 	"github.com/asianchinaboi/backendserver/internal/logger"
+	"github.com/asianchinaboi/backendserver/internal/outbox"
+	"github.com/asianchinaboi/backendserver/internal/scanner"
 	"github.com/asianchinaboi/backendserver/internal/session"
 	"github.com/asianchinaboi/backendserver/internal/uid"
 	"github.com/asianchinaboi/backendserver/internal/wsclient"
@@ -28,12 +28,13 @@ import (
 )
 
 type editGuildBody struct {
-	SaveChat *bool   `json:"saveChat"`
-	Name     *string `json:"name"`
-	OwnerId  *int64  `json:"ownerId,string"`
+	SaveChat   *bool   `json:"saveChat"`
+	Name       *string `json:"name"`
+	OwnerId    *int64  `json:"ownerId,string"`
+	ScanPolicy *string `json:"scanPolicy"` //allow, quarantine or reject uploads when the scanner is down
 }
 
-func editGuild(c *gin.Context) {
+func (h *Handlers) editGuild(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -74,7 +75,7 @@ func editGuild(c *gin.Context) {
 		return
 	}
 
-	if newSettings.SaveChat == nil && newSettings.Name == nil && newSettings.OwnerId == nil && imageHeader == nil {
+	if newSettings.SaveChat == nil && newSettings.Name == nil && newSettings.OwnerId == nil && newSettings.ScanPolicy == nil && imageHeader == nil {
 		errors.SendErrorResponse(c, errors.ErrAllFieldsEmpty, errors.StatusAllFieldsEmpty)
 		return
 	}
@@ -84,7 +85,7 @@ func editGuild(c *gin.Context) {
 	var isAdmin bool
 	var isDm bool
 
-	if err := db.Db.QueryRow(`SELECT EXISTS(SELECT 1 FROM guilds WHERE id = $1), 
+	if err := h.Db.QueryRow(`SELECT EXISTS(SELECT 1 FROM guilds WHERE id = $1), 
 	EXISTS(SELECT 1 FROM userguilds WHERE user_id=$2 and guild_id=$1 and owner = true), 
 	EXISTS (SELECT 1 FROM userguilds WHERE user_id=$2 AND guild_id=$1 AND admin = true), 
 	EXISTS (SELECT 1 FROM guilds WHERE id = $1 AND dm = true)`, guildId, user.Id).Scan(&exists, &isOwner, &isAdmin, &isDm); err != nil {
@@ -114,7 +115,7 @@ func editGuild(c *gin.Context) {
 
 	//BEGIN TRANSACTION
 	ctx := context.Background()
-	tx, err := db.Db.BeginTx(ctx, nil)
+	tx, err := h.Db.BeginTx(ctx, nil)
 	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
@@ -132,7 +133,7 @@ func editGuild(c *gin.Context) {
 		bodyRes.SaveChat = newSettings.SaveChat
 	} else {
 		var saveChat bool
-		if err := db.Db.QueryRow("SELECT save_chat FROM guilds WHERE id=$1", guildId).Scan(&saveChat); err != nil {
+		if err := h.Db.QueryRow("SELECT save_chat FROM guilds WHERE id=$1", guildId).Scan(&saveChat); err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
@@ -153,17 +154,37 @@ func editGuild(c *gin.Context) {
 		bodyRes.Name = *newSettings.Name
 	} else {
 		var name string
-		if err := db.Db.QueryRow("SELECT name FROM guilds WHERE id=$1", guildId).Scan(&name); err != nil {
+		if err := h.Db.QueryRow("SELECT name FROM guilds WHERE id=$1", guildId).Scan(&name); err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
 		bodyRes.Name = name
 	}
+	if newSettings.ScanPolicy != nil {
+		if !scanner.ValidPolicy(*newSettings.ScanPolicy) {
+			errors.SendErrorResponse(c, errors.ErrScanPolicyInvalid, errors.StatusScanPolicyInvalid)
+			return
+		}
+
+		if _, err = tx.ExecContext(ctx, "UPDATE guilds SET scan_policy=$1 WHERE id=$2", *newSettings.ScanPolicy, guildId); err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusInternalError)
+			return
+		}
+		bodyRes.ScanPolicy = *newSettings.ScanPolicy
+	} else {
+		var scanPolicy string
+		if err := h.Db.QueryRow("SELECT scan_policy FROM guilds WHERE id=$1", guildId).Scan(&scanPolicy); err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusInternalError)
+			return
+		}
+		bodyRes.ScanPolicy = scanPolicy
+	}
 	if imageHeader != nil {
 		//remove old image
 
 		var oldImageId int64
-		if err := tx.QueryRowContext(ctx, "DELETE FROM files WHERE guild_id = $1 RETURNING id", guildId).Scan(&oldImageId); err != nil && err != sql.ErrNoRows {
+		var oldHash sql.NullString
+		if err := tx.QueryRowContext(ctx, "DELETE FROM files WHERE guild_id = $1 RETURNING id, hash", guildId).Scan(&oldImageId, &oldHash); err != nil && err != sql.ErrNoRows {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
@@ -184,7 +205,8 @@ func editGuild(c *gin.Context) {
 			return
 		}
 
-		if valid := files.ValidateImage(fileBytes, fileType); !valid {
+		fileBytes, valid := files.ValidateImage(fileBytes, fileType, h.Config.Server.ImageProfileSize) //crops and scales it down if needed
+		if !valid {
 			errors.SendErrorResponse(c, errors.ErrFileInvalid, errors.StatusFileInvalid)
 			return
 		}
@@ -193,7 +215,7 @@ func editGuild(c *gin.Context) {
 
 		filesize := len(fileBytes)
 
-		if filesize > config.Config.Server.MaxFileSize {
+		if filesize > h.Config.Server.MaxFileSize {
 			errors.SendErrorResponse(c, errors.ErrFileTooLarge, errors.StatusFileTooLarge)
 			return
 		} else if !(filesize >= 0) {
@@ -201,49 +223,38 @@ func editGuild(c *gin.Context) {
 			return
 		}
 
-		compressedBuffer, err := files.Compress(fileBytes, filesize)
-		if err != nil {
-			logger.Error.Println(err)
-			c.JSON(http.StatusInternalServerError, errors.Body{
-				Error:  err.Error(),
-				Status: errors.StatusInternalError,
-			})
+		//images use the default policy, the guild policy is only for attachments
+		verdict, err := scanner.Check(ctx, h.Scanner, bytes.NewReader(fileBytes), h.Config.Scanner.Policy)
+		if err == errors.ErrScannerUnavailable {
+			errors.SendErrorResponse(c, err, errors.StatusScannerUnavailable)
+			return
+		} else if err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
 
-		outFile, err := os.Create(fmt.Sprintf("uploads/guild/%d.lz4", imageId))
+		//stored by content so an image thats already been uploaded isnt stored again
+		hash, err := blobs.Put(ctx, tx, h.Store, bytes.NewReader(fileBytes))
 		if err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
 		defer func() { //defer just in case something went wrong
-			if !successful {
-				if err := os.Remove(fmt.Sprintf("uploads/guild/%d.lz4", imageId)); err != nil {
-					logger.Warn.Printf("failed to remove file: %v\n", err)
-				}
-			} else {
-				if oldImageId != 0 {
-					if err := os.Remove(fmt.Sprintf("uploads/guild/%d.lz4", oldImageId)); err != nil {
-						logger.Warn.Printf("failed to remove file: %v\n", err)
-					}
+			if successful && oldImageId != 0 {
+				if err := blobs.Remove(context.Background(), h.Db, h.Store, "guild", oldImageId, oldHash); err != nil {
+					logger.Ctx(c).Warn("failed to remove file", "err", err)
 				}
 			}
 		}()
-		defer outFile.Close()
-
-		if _, err = outFile.Write(compressedBuffer); err != nil {
-			errors.SendErrorResponse(c, err, errors.StatusInternalError)
-			return
-		}
 
-		if _, err = tx.ExecContext(ctx, "INSERT INTO files (id, guild_id, filename, created, temp, filesize, filetype, entity_type) VALUES ($1, $2, $3, now(), $4, $5, $6, 'guild')", imageId, guildId, filename, false, filesize, fileMIMEType); err != nil {
+		if _, err = tx.ExecContext(ctx, "INSERT INTO files (id, guild_id, filename, created, temp, filesize, filetype, entity_type, hash, quarantined, quarantine_reason) VALUES ($1, $2, $3, now(), $4, $5, $6, 'guild', $7, $8, NULLIF($9, ''))", imageId, guildId, filename, false, filesize, fileMIMEType, hash, verdict.Quarantined, verdict.Reason); err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
 		bodyRes.ImageId = imageId
 	} else {
 		var imageId int64
-		if err := db.Db.QueryRow("SELECT id FROM files WHERE guild_id=$1", guildId).Scan(&imageId); err != nil && err != sql.ErrNoRows {
+		if err := h.Db.QueryRow("SELECT id FROM files WHERE guild_id=$1", guildId).Scan(&imageId); err != nil && err != sql.ErrNoRows {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		} else if err == sql.ErrNoRows {
@@ -255,7 +266,7 @@ func editGuild(c *gin.Context) {
 
 	if newSettings.OwnerId != nil {
 		var inGuild bool
-		if err := db.Db.QueryRow(`
+		if err := h.Db.QueryRow(`
 		SELECT EXISTS (SELECT 1 FROM userguilds WHERE guild_id=$1 AND user_id=$2),
 		EXISTS (SELECT 1 FROM userguilds WHERE guild_id=$1 AND user_id=$3 AND owner=true)
 		`, guildId, newSettings.OwnerId, user.Id).Scan(&inGuild, &isOwner); err != nil {
@@ -288,18 +299,22 @@ func editGuild(c *gin.Context) {
 		bodyRes.OwnerId = user.Id
 	}
 
-	if err := tx.Commit(); err != nil { //commits the transaction
-		errors.SendErrorResponse(c, err, errors.StatusInternalError)
-		return
-	}
-	successful = true
-
 	guildRes := wsclient.DataFrame{
 		Op:    wsclient.TYPE_DISPATCH,
 		Data:  bodyRes,
 		Event: events.GUILD_UPDATE,
 	}
-	wsclient.Pools.BroadcastGuild(intGuildId, guildRes)
+	if err := outbox.Enqueue(ctx, tx, intGuildId, guildRes.Event, guildRes.Data); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil { //commits the transaction
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	successful = true
+	h.Pools.BroadcastGuild(intGuildId, guildRes)
 
 	c.Status(http.StatusNoContent)
 }
@@ -6,14 +6,13 @@ import (
 	"regexp"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/db"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
 	"github.com/asianchinaboi/backendserver/internal/session"
 	"github.com/gin-gonic/gin"
 )
 
-func getGuild(c *gin.Context) {
+func (h *Handlers) getGuild(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -32,7 +31,7 @@ func getGuild(c *gin.Context) {
 	var isInGuild bool
 	var isDm bool
 
-	if err := db.Db.QueryRow("SELECT EXISTS(SELECT 1 FROM userguilds WHERE user_id = $1 AND guild_id = $2 AND banned = false), EXISTS(SELECT 1 FROM guilds WHERE id = $2 AND dm = true)", user.Id, guildId).Scan(&isInGuild); err != nil {
+	if err := h.Db.QueryRow("SELECT EXISTS(SELECT 1 FROM userguilds WHERE user_id = $1 AND guild_id = $2 AND banned = false), EXISTS(SELECT 1 FROM guilds WHERE id = $2 AND dm = true)", user.Id, guildId).Scan(&isInGuild); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -48,7 +47,7 @@ func getGuild(c *gin.Context) {
 	}
 
 	query := `
-		SELECT g.id, g.name, f.id, g.save_chat, 
+		SELECT g.id, g.name, f.id, g.save_chat, g.scan_policy, 
 		(SELECT user_id FROM userguilds WHERE guild_id = $1 AND owner = true) AS owner_id, 
 		un.msg_id AS last_read_msg_id, COUNT(m.id) filter (WHERE m.created > un.time) AS unread_msgs,
 		un.time, COUNT(mm.msg_id) filter (WHERE mm.user_id = $2 AND m.created > un.time) +
@@ -62,9 +61,9 @@ func getGuild(c *gin.Context) {
 	`
 	var guild events.Guild
 	var imageId sql.NullInt64
-	if err := db.Db.QueryRow(query, guildId, user.Id).Scan(&guild.GuildId,
+	if err := h.Db.QueryRow(query, guildId, user.Id).Scan(&guild.GuildId,
 		&guild.Name, &imageId,
-		&guild.SaveChat, &guild.OwnerId,
+		&guild.SaveChat, &guild.ScanPolicy, &guild.OwnerId,
 		&guild.Unread.MsgId, &guild.Unread.Count,
 		&guild.Unread.Time); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
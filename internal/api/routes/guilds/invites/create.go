@@ -1,21 +1,21 @@
 package invites
 
 import (
+	"context"
 	"net/http"
 	"regexp"
 	"strconv"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/config"
-	"github.com/asianchinaboi/backendserver/internal/db"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
+	"github.com/asianchinaboi/backendserver/internal/outbox"
 	"github.com/asianchinaboi/backendserver/internal/session"
 	"github.com/asianchinaboi/backendserver/internal/wsclient"
 	"github.com/gin-gonic/gin"
 )
 
-func Create(c *gin.Context) {
+func (h *Handlers) Create(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -34,7 +34,7 @@ func Create(c *gin.Context) {
 	var count int
 	var inGuild bool
 	var isDm bool
-	if err := db.Db.QueryRow("SELECT EXISTS(SELECT 1 FROM userguilds WHERE user_id=$1 AND guild_id = $2) ,EXISTS (SELECT 1 FROM guilds WHERE id = $2 AND dm = true) ", user.Id, guildId).Scan(&inGuild, &isDm); err != nil {
+	if err := h.Db.QueryRow("SELECT EXISTS(SELECT 1 FROM userguilds WHERE user_id=$1 AND guild_id = $2) ,EXISTS (SELECT 1 FROM guilds WHERE id = $2 AND dm = true) ", user.Id, guildId).Scan(&inGuild, &isDm); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -48,18 +48,26 @@ func Create(c *gin.Context) {
 		errors.SendErrorResponse(c, errors.ErrNotInGuild, errors.StatusNotInGuild)
 		return
 	}
-	if err := db.Db.QueryRow("SELECT COUNT(*) FROM invites WHERE guild_id=$1", guildId).Scan(&count); err != nil {
+	if err := h.Db.QueryRow("SELECT COUNT(*) FROM invites WHERE guild_id=$1", guildId).Scan(&count); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
-	if count > config.Config.Guild.MaxInvites {
+	if count > h.Live.Current().Guild.MaxInvites {
 		errors.SendErrorResponse(c, errors.ErrInviteLimitReached, errors.StatusInviteLimitReached)
 		return
 	}
 
 	invite := session.GenerateRandString(10)
 
-	if _, err := db.Db.Exec("INSERT INTO invites (invite, guild_id) VALUES ($1, $2)", invite, guildId); err != nil {
+	ctx := context.Background()
+	tx, err := h.Db.BeginTx(ctx, nil)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	defer tx.Rollback() //rollback changes if failed
+
+	if _, err := tx.ExecContext(ctx, "INSERT INTO invites (invite, guild_id) VALUES ($1, $2)", invite, guildId); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -79,7 +87,16 @@ func Create(c *gin.Context) {
 		Data:  inviteBody,
 		Event: events.INVITE_CREATE,
 	}
+	if err := outbox.Enqueue(ctx, tx, intGuildId, res.Event, res.Data); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil { //commits the transaction
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
 
-	wsclient.Pools.BroadcastGuild(intGuildId, res)
+	h.Pools.BroadcastGuild(intGuildId, res)
 	c.JSON(http.StatusOK, inviteBody)
 }
@@ -1,20 +1,21 @@
 package invites
 
 import (
+	"context"
 	"net/http"
 	"regexp"
 	"strconv"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/db"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
+	"github.com/asianchinaboi/backendserver/internal/outbox"
 	"github.com/asianchinaboi/backendserver/internal/session"
 	"github.com/asianchinaboi/backendserver/internal/wsclient"
 	"github.com/gin-gonic/gin"
 )
 
-func Delete(c *gin.Context) {
+func (h *Handlers) Delete(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -42,7 +43,7 @@ func Delete(c *gin.Context) {
 	var hasAuth bool
 	var inviteValid bool
 	var isDm bool
-	if err := db.Db.QueryRow("SELECT EXISTS (SELECT 1 FROM invites WHERE invite = $1 AND guild_id=$2), EXISTS (SELECT 1 FROM guilds WHERE id = $2 AND dm = true)", invite, guildId).Scan(&inviteValid, &isDm); err != nil {
+	if err := h.Db.QueryRow("SELECT EXISTS (SELECT 1 FROM invites WHERE invite = $1 AND guild_id=$2), EXISTS (SELECT 1 FROM guilds WHERE id = $2 AND dm = true)", invite, guildId).Scan(&inviteValid, &isDm); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -57,7 +58,7 @@ func Delete(c *gin.Context) {
 		return
 	}
 
-	if err := db.Db.QueryRow("SELECT EXISTS (SELECT 1 FROM userguilds WHERE guild_id=$1 AND user_id=$2 AND (owner=true OR admin=true))", guildId, user.Id).Scan(&hasAuth); err != nil {
+	if err := h.Db.QueryRow("SELECT EXISTS (SELECT 1 FROM userguilds WHERE guild_id=$1 AND user_id=$2 AND (owner=true OR admin=true))", guildId, user.Id).Scan(&hasAuth); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -66,7 +67,15 @@ func Delete(c *gin.Context) {
 		return
 	}
 
-	if _, err := db.Db.Exec("DELETE FROM invites WHERE invite=$1", invite); err != nil {
+	ctx := context.Background()
+	tx, err := h.Db.BeginTx(ctx, nil)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	defer tx.Rollback() //rollback changes if failed
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM invites WHERE invite=$1", invite); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -85,6 +94,15 @@ func Delete(c *gin.Context) {
 		},
 		Event: events.INVITE_DELETE,
 	}
-	wsclient.Pools.BroadcastGuild(intGuildId, res)
+	if err := outbox.Enqueue(ctx, tx, intGuildId, res.Event, res.Data); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil { //commits the transaction
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	h.Pools.BroadcastGuild(intGuildId, res)
 	c.Status(http.StatusNoContent)
 }
@@ -6,10 +6,10 @@ import (
 	"net/http"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/db"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
 	"github.com/asianchinaboi/backendserver/internal/logger"
+	"github.com/asianchinaboi/backendserver/internal/outbox"
 	"github.com/asianchinaboi/backendserver/internal/session"
 	"github.com/asianchinaboi/backendserver/internal/wsclient"
 	"github.com/gin-gonic/gin"
@@ -19,7 +19,7 @@ type joinGuildBody struct {
 	Invite string `json:"invite" binding:"required"`
 }
 
-func joinGuild(c *gin.Context) {
+func (h *Handlers) joinGuild(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -39,7 +39,7 @@ func joinGuild(c *gin.Context) {
 	var guild events.Guild
 	var imageId sql.NullInt64
 
-	err := db.Db.QueryRow(
+	err := h.Db.QueryRow(
 		`
 		SELECT i.guild_id, g.name, f.id, ug.user_id
 		FROM invites i INNER JOIN guilds g ON g.id = i.guild_id 
@@ -63,7 +63,7 @@ func joinGuild(c *gin.Context) {
 
 	var isInGuild bool
 
-	if err := db.Db.QueryRow("SELECT EXISTS (SELECT * FROM userguilds WHERE user_id=$1 AND guild_id=$2)", user.Id, guild.GuildId).Scan(&isInGuild); err != nil {
+	if err := h.Db.QueryRow("SELECT EXISTS (SELECT * FROM userguilds WHERE user_id=$1 AND guild_id=$2)", user.Id, guild.GuildId).Scan(&isInGuild); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -72,11 +72,11 @@ func joinGuild(c *gin.Context) {
 		return
 	}
 
-	logger.Debug.Println("user", user.Id, "joined guild", guild.GuildId)
+	logger.Ctx(c).Debug("user joined guild", "userId", user.Id, "guildId", guild.GuildId)
 
 	//BEGIN TRANSACTION
 	ctx := context.Background()
-	tx, err := db.Db.BeginTx(ctx, nil)
+	tx, err := h.Db.BeginTx(ctx, nil)
 	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
@@ -93,23 +93,11 @@ func joinGuild(c *gin.Context) {
 		return
 	}
 
-	if err := tx.Commit(); err != nil { //commits the transaction
-		errors.SendErrorResponse(c, err, errors.StatusInternalError)
-		return
-	}
-
-	res := wsclient.DataFrame{
-		Op:    wsclient.TYPE_DISPATCH,
-		Data:  guild,
-		Event: events.GUILD_CREATE,
-	}
-	wsclient.Pools.BroadcastClient(user.Id, res)
-
 	userData := events.Member{} //change name later
 
 	//reusing same imageid from before
 
-	if err := db.Db.QueryRow("SELECT username, files.id FROM users LEFT JOIN files ON files.user_id = users.id WHERE users.id=$1", user.Id).Scan(&userData.UserInfo.Name, &imageId); err != nil {
+	if err := h.Db.QueryRow("SELECT username, files.id FROM users LEFT JOIN files ON files.user_id = users.id WHERE users.id=$1", user.Id).Scan(&userData.UserInfo.Name, &imageId); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -128,7 +116,23 @@ func joinGuild(c *gin.Context) {
 		Data:  userData,
 		Event: events.MEMBER_ADD,
 	}
-	wsclient.Pools.BroadcastGuild(guild.GuildId, guildRes)
-	wsclient.Pools.AddUserToGuildPool(guild.GuildId, user.Id)
+	if err := outbox.Enqueue(ctx, tx, guild.GuildId, guildRes.Event, guildRes.Data); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil { //commits the transaction
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	res := wsclient.DataFrame{
+		Op:    wsclient.TYPE_DISPATCH,
+		Data:  guild,
+		Event: events.GUILD_CREATE,
+	}
+	h.Pools.BroadcastClient(user.Id, res)
+	h.Pools.BroadcastGuild(guild.GuildId, guildRes)
+	h.Pools.AddUserToGuildPool(guild.GuildId, user.Id)
 	c.Status(http.StatusNoContent)
 }
@@ -1,20 +1,21 @@
 package members
 
 import (
+	"context"
 	"net/http"
 	"regexp"
 	"strconv"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/db"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
+	"github.com/asianchinaboi/backendserver/internal/outbox"
 	"github.com/asianchinaboi/backendserver/internal/session"
 	"github.com/asianchinaboi/backendserver/internal/wsclient"
 	"github.com/gin-gonic/gin"
 )
 
-func Kick(c *gin.Context) {
+func (h *Handlers) Kick(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -62,7 +63,7 @@ func Kick(c *gin.Context) {
 	var isUserInGuild bool
 	var isDm bool
 
-	if err := db.Db.QueryRow(`
+	if err := h.Db.QueryRow(`
 	SELECT EXISTS (SELECT 1 FROM userguilds WHERE guild_id=$1 AND user_id=$3 AND owner=true), 
 	EXISTS (SELECT 1 FROM guilds WHERE id = $1 AND dm = true),
 	EXISTS (SELECT 1 FROM userguilds WHERE guild_id=$1 AND user_id=$2 AND admin=true),
@@ -94,7 +95,15 @@ func Kick(c *gin.Context) {
 		return
 	}
 
-	if _, err := db.Db.Exec("DELETE FROM userguilds WHERE guild_id=$1 AND user_id=$2", guildId, userId); err != nil {
+	ctx := context.Background()
+	tx, err := h.Db.BeginTx(ctx, nil)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	defer tx.Rollback() //rollback changes if failed
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM userguilds WHERE guild_id=$1 AND user_id=$2", guildId, userId); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -116,8 +125,17 @@ func Kick(c *gin.Context) {
 		},
 		Event: events.MEMBER_REMOVE,
 	}
-	wsclient.Pools.BroadcastClient(intUserId, kickRes)
-	wsclient.Pools.RemoveUserFromGuildPool(intGuildId, intUserId)
-	wsclient.Pools.BroadcastGuild(intGuildId, guildRes)
+	if err := outbox.Enqueue(ctx, tx, intGuildId, guildRes.Event, guildRes.Data); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil { //commits the transaction
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	h.Pools.BroadcastClient(intUserId, kickRes)
+	h.Pools.RemoveUserFromGuildPool(intGuildId, intUserId)
+	h.Pools.BroadcastGuild(intGuildId, guildRes)
 	c.Status(http.StatusNoContent)
 }
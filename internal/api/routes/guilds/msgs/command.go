@@ -0,0 +1,86 @@
+package msgs
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/asianchinaboi/backendserver/internal/events"
+	"github.com/asianchinaboi/backendserver/internal/interactions"
+	"github.com/asianchinaboi/backendserver/internal/uid"
+	"github.com/asianchinaboi/backendserver/internal/wsclient"
+	"github.com/gin-gonic/gin"
+)
+
+// sends the command to the bot that owns it instead of creating a message
+// returns false if there is no command with that name so it gets sent as a normal message
+func (h *Handlers) invokeCommand(c *gin.Context, userId int64, guildId int64, name string, args string) bool {
+	var command events.Command
+	var options string
+	if err := h.Db.QueryRow(`SELECT c.id, c.bot_id, c.options FROM commands c WHERE c.guild_id = $1 AND c.name = $2 
+	AND EXISTS (SELECT 1 FROM userguilds WHERE guild_id = c.guild_id AND user_id = c.bot_id AND banned = false)`, guildId, name).Scan(&command.CommandId, &command.BotId, &options); err != nil && err != sql.ErrNoRows {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return true
+	} else if err == sql.ErrNoRows {
+		return false
+	}
+	if err := json.Unmarshal([]byte(options), &command.Options); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return true
+	}
+
+	parsed, ok := events.ParseCommandArgs(command.Options, args)
+	if !ok {
+		errors.SendErrorResponse(c, errors.ErrCommandBadArguments, errors.StatusCommandBadArguments)
+		return true
+	}
+
+	invoker := events.User{UserId: userId}
+	var imageId sql.NullInt64
+	if err := h.Db.QueryRow("SELECT username, files.id FROM users LEFT JOIN files ON files.user_id = users.id WHERE users.id=$1", userId).Scan(&invoker.Name, &imageId); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return true
+	}
+	if imageId.Valid {
+		invoker.ImageId = imageId.Int64
+	} else {
+		invoker.ImageId = -1
+	}
+
+	pending := h.Interactions.Add(events.Interaction{
+		InteractionId: uid.Snowflake.Generate().Int64(),
+		CommandId:     command.CommandId,
+		GuildId:       guildId,
+		Name:          name,
+		Options:       parsed,
+		User:          invoker,
+	}, command.BotId)
+
+	h.Pools.BroadcastClient(command.BotId, wsclient.DataFrame{
+		Op:    wsclient.TYPE_DISPATCH,
+		Data:  pending.Interaction,
+		Event: events.INTERACTION_CREATE,
+	})
+	c.JSON(http.StatusOK, pending.Interaction)
+	return true
+}
+
+// SendInteractionResponse sends the bots reply to a command
+// ephemeral replies only go to whoever used the command
+func (h *Handlers) SendInteractionResponse(c *gin.Context, pending *interactions.Pending) {
+	body, attachmentFiles, statusCode, err := h.parseSendBody(c)
+	if err != nil {
+		errors.SendErrorResponse(c, err, statusCode)
+		return
+	}
+	author := sender{userId: pending.BotId, interactionId: pending.InteractionId}
+	if body.Ephemeral {
+		if len(attachmentFiles) != 0 || len(body.Uploads) != 0 {
+			errors.SendErrorResponse(c, errors.ErrInteractionEphemeralFiles, errors.StatusInteractionEphemeralFiles)
+			return
+		}
+		author.ephemeralTo = pending.InvokerId
+	}
+	h.send(c, author, pending.GuildId, body.Msg, attachmentFiles, body.Uploads)
+}
@@ -3,23 +3,22 @@ package msgs
 import (
 	"context"
 	"database/sql"
-	"fmt"
 	"net/http"
-	"os"
 	"regexp"
 	"strconv"
 	"strings"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/db"
+	"github.com/asianchinaboi/backendserver/internal/blobs"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
+	"github.com/asianchinaboi/backendserver/internal/outbox"
 	"github.com/asianchinaboi/backendserver/internal/session"
 	"github.com/asianchinaboi/backendserver/internal/wsclient"
 	"github.com/gin-gonic/gin"
 )
 
-func Delete(c *gin.Context) { //deletes message
+func (h *Handlers) Delete(c *gin.Context) { //deletes message
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -59,14 +58,14 @@ func Delete(c *gin.Context) { //deletes message
 
 	var hasAuth bool
 	var isDm bool
-	if err := db.Db.QueryRow("SELECT EXISTS (SELECT 1 FROM userguilds WHERE guild_id=$1 AND user_id=$2 AND owner=true OR admin=true), EXISTS (SELECT 1 FROM guilds WHERE id = $1 AND dm = true)", guildId, user.Id).Scan(&hasAuth, &isDm); err != nil {
+	if err := h.Db.QueryRow("SELECT EXISTS (SELECT 1 FROM userguilds WHERE guild_id=$1 AND user_id=$2 AND owner=true OR admin=true), EXISTS (SELECT 1 FROM guilds WHERE id = $1 AND dm = true)", guildId, user.Id).Scan(&hasAuth, &isDm); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
 
 	//BEGIN TRANSACTION
 	ctx := context.Background()
-	tx, err := db.Db.BeginTx(ctx, nil)
+	tx, err := h.Db.BeginTx(ctx, nil)
 	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
@@ -90,7 +89,7 @@ func Delete(c *gin.Context) { //deletes message
 		}
 	} else {
 		var isAuthor bool
-		if err := db.Db.QueryRow("SELECT EXISTS (SELECT 1 FROM msgs WHERE id = $1 AND user_id = $2)", msgId, user.Id).Scan(&isAuthor); err != nil {
+		if err := h.Db.QueryRow("SELECT EXISTS (SELECT 1 FROM msgs WHERE id = $1 AND user_id = $2)", msgId, user.Id).Scan(&isAuthor); err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
@@ -99,7 +98,7 @@ func Delete(c *gin.Context) { //deletes message
 			return
 		}
 		var msgExists bool
-		if err := db.Db.QueryRow("SELECT EXISTS(SELECT 1 FROM msgs WHERE id = $1 AND guild_id=$2)", msgId, guildId).Scan(&msgExists); err != nil {
+		if err := h.Db.QueryRow("SELECT EXISTS(SELECT 1 FROM msgs WHERE id = $1 AND guild_id=$2)", msgId, guildId).Scan(&msgExists); err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
@@ -110,7 +109,7 @@ func Delete(c *gin.Context) { //deletes message
 		}
 
 		//delete files associated with msg
-		fileRows, err = db.Db.Query("SELECT id FROM files WHERE msg_id = $1", msgId)
+		fileRows, err = h.Db.Query("SELECT id, hash FROM files WHERE msg_id = $1", msgId)
 		if err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
@@ -142,6 +141,20 @@ func Delete(c *gin.Context) { //deletes message
 		}
 	}
 
+	res := wsclient.DataFrame{
+		Op: wsclient.TYPE_DISPATCH,
+		Data: events.Msg{
+			MsgId:     intMsgId,
+			GuildId:   intGuildId,
+			RequestId: requestId,
+		},
+		Event: events.MESSAGE_DELETE,
+	}
+	if err := outbox.Enqueue(ctx, tx, intGuildId, res.Event, res.Data); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
 	if err := tx.Commit(); err != nil { //commits the transaction
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
@@ -150,31 +163,22 @@ func Delete(c *gin.Context) { //deletes message
 	if fileRows != nil { //if there are files to delete
 		for fileRows.Next() {
 			var fileId int64
-			if err := fileRows.Scan(&fileId); err != nil {
+			var hash sql.NullString
+			if err := fileRows.Scan(&fileId, &hash); err != nil {
 				errors.SendErrorResponse(c, err, errors.StatusInternalError)
 				return
 			}
-			if err := os.Remove(fmt.Sprintf("uploads/msg/%d.lz4", fileId)); err != nil {
+			if err := blobs.Remove(context.Background(), h.Db, h.Store, "msg", fileId, hash); err != nil {
 				errors.SendErrorResponse(c, err, errors.StatusInternalError)
 				return
 			}
 		}
 	}
-
-	res := wsclient.DataFrame{
-		Op: wsclient.TYPE_DISPATCH,
-		Data: events.Msg{
-			MsgId:     intMsgId,
-			GuildId:   intGuildId,
-			RequestId: requestId,
-		},
-		Event: events.MESSAGE_DELETE,
-	}
-	wsclient.Pools.BroadcastGuild(intGuildId, res)
+	h.Pools.BroadcastGuild(intGuildId, res)
 	c.Status(http.StatusNoContent)
 }
 
-func Clear(c *gin.Context) {
+func (h *Handlers) Clear(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -198,7 +202,7 @@ func Clear(c *gin.Context) {
 
 	var hasAuth bool
 	var isDm bool
-	if err := db.Db.QueryRow("SELECT EXISTS (SELECT 1 FROM userguilds WHERE guild_id=$1 AND user_id=$2 AND (owner=true OR admin=true)), EXISTS (SELECT 1 FROM userguilds WHERE guild_id=$1 AND dm=true)", guildId, user.Id).Scan(&hasAuth, &isDm); err != nil {
+	if err := h.Db.QueryRow("SELECT EXISTS (SELECT 1 FROM userguilds WHERE guild_id=$1 AND user_id=$2 AND (owner=true OR admin=true)), EXISTS (SELECT 1 FROM userguilds WHERE guild_id=$1 AND dm=true)", guildId, user.Id).Scan(&hasAuth, &isDm); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -207,14 +211,39 @@ func Clear(c *gin.Context) {
 		return
 	}
 
-	fileRows, err := db.Db.Query("SELECT files.id FROM files INNER JOIN msgs ON msg.id=files.msg_id WHERE msgs.guild_id = $1", guildId)
+	fileRows, err := h.Db.Query("SELECT files.id, files.hash FROM files INNER JOIN msgs ON msg.id=files.msg_id WHERE msgs.guild_id = $1", guildId)
 	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
 	defer fileRows.Close()
 
-	if _, err := db.Db.Exec("DELETE FROM msgs WHERE guild_id = $1", guildId); err != nil {
+	ctx := context.Background()
+	tx, err := h.Db.BeginTx(ctx, nil)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	defer tx.Rollback() //rollback changes if failed
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM msgs WHERE guild_id = $1", guildId); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	res := wsclient.DataFrame{
+		Op: wsclient.TYPE_DISPATCH,
+		Data: events.Msg{
+			GuildId: intGuildId,
+		},
+		Event: events.MESSAGES_GUILD_CLEAR,
+	}
+	if err := outbox.Enqueue(ctx, tx, intGuildId, res.Event, res.Data); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil { //commits the transaction
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -222,23 +251,17 @@ func Clear(c *gin.Context) {
 	if fileRows != nil { //if there are files to delete
 		for fileRows.Next() {
 			var fileId int64
-			if err := fileRows.Scan(&fileId); err != nil {
+			var hash sql.NullString
+			if err := fileRows.Scan(&fileId, &hash); err != nil {
 				errors.SendErrorResponse(c, err, errors.StatusInternalError)
 				return
 			}
-			if err := os.Remove(fmt.Sprintf("uploads/msg/%d.lz4", fileId)); err != nil {
+			if err := blobs.Remove(context.Background(), h.Db, h.Store, "msg", fileId, hash); err != nil {
 				errors.SendErrorResponse(c, err, errors.StatusInternalError)
 				return
 			}
 		}
 	}
-	res := wsclient.DataFrame{
-		Op: wsclient.TYPE_DISPATCH,
-		Data: events.Msg{
-			GuildId: intGuildId,
-		},
-		Event: events.MESSAGES_GUILD_CLEAR,
-	}
-	wsclient.Pools.BroadcastGuild(intGuildId, res)
+	h.Pools.BroadcastGuild(intGuildId, res)
 	c.Status(http.StatusNoContent)
 }
@@ -10,17 +10,17 @@ import (
 	"time"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/db"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
 	"github.com/asianchinaboi/backendserver/internal/logger"
+	"github.com/asianchinaboi/backendserver/internal/outbox"
 	"github.com/asianchinaboi/backendserver/internal/session"
 	"github.com/asianchinaboi/backendserver/internal/wsclient"
 	"github.com/gin-gonic/gin"
 )
 
 // expects msg:content
-func Edit(c *gin.Context) {
+func (h *Handlers) Edit(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -73,7 +73,7 @@ func Edit(c *gin.Context) {
 	var isDm bool
 	var inGuild bool
 
-	if err := db.Db.QueryRow("SELECT EXISTS (SELECT 1 FROM guilds WHERE id = $1 AND dm = true), EXISTS (SELECT * FROM userguilds WHERE guild_id=$1 AND user_id=$2 AND banned=false)", guildId, user.Id).Scan(&isDm, &inGuild); err != nil {
+	if err := h.Db.QueryRow("SELECT EXISTS (SELECT 1 FROM guilds WHERE id = $1 AND dm = true), EXISTS (SELECT * FROM userguilds WHERE guild_id=$1 AND user_id=$2 AND banned=false)", guildId, user.Id).Scan(&isDm, &inGuild); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -87,7 +87,7 @@ func Edit(c *gin.Context) {
 
 	//BEGIN TRANSACTION
 	ctx := context.Background()
-	tx, err := db.Db.BeginTx(ctx, nil)
+	tx, err := h.Db.BeginTx(ctx, nil)
 	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
@@ -97,7 +97,7 @@ func Edit(c *gin.Context) {
 	if !isRequestId { //vulnerability: isrequestid can be updated by any user
 
 		var msgExists bool
-		if err := db.Db.QueryRow("SELECT EXISTS(SELECT 1 FROM msgs WHERE id = $1 AND user_id = $2 AND guild_id=$3)", msgId, user.Id, guildId).Scan(&msgExists); err != nil {
+		if err := h.Db.QueryRow("SELECT EXISTS(SELECT 1 FROM msgs WHERE id = $1 AND user_id = $2 AND guild_id=$3)", msgId, user.Id, guildId).Scan(&msgExists); err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
@@ -135,14 +135,13 @@ func Edit(c *gin.Context) {
 	timestamp = time.Now()
 
 	mentions := events.MentionExp.FindAllStringSubmatch(msg.Content, -1)
-	logger.Debug.Println("msgcontent:", msg.Content)
-	logger.Debug.Println("mentions:", mentions)
+	logger.Ctx(c).Debug("finding mentions", "content", msg.Content, "mentions", mentions)
 	msg.MentionsEveryone = new(bool)
 	*msg.MentionsEveryone = events.MentionEveryoneExp.MatchString(msg.Content)
 	msg.Mentions = &[]events.User{}
 
 	if len(mentions) > 0 {
-		logger.Debug.Println("mentions found")
+		logger.Ctx(c).Debug("mentions found")
 		seen := make(map[int64]bool)
 		for _, mention := range mentions {
 			mentionUserId, err := strconv.ParseInt(mention[1], 10, 64)
@@ -157,7 +156,7 @@ func Edit(c *gin.Context) {
 
 			var mentionUser events.User
 			mentionUser.UserId = mentionUserId
-			if err := db.Db.QueryRow("SELECT username FROM users WHERE id = $1", mentionUserId).Scan(&mentionUser.Name); err != nil && err != sql.ErrNoRows {
+			if err := h.Db.QueryRow("SELECT username FROM users WHERE id = $1", mentionUserId).Scan(&mentionUser.Name); err != nil && err != sql.ErrNoRows {
 				errors.SendErrorResponse(c, err, errors.StatusInternalError)
 				return
 			} else if err == sql.ErrNoRows {
@@ -165,7 +164,7 @@ func Edit(c *gin.Context) {
 				return
 			}
 			if !isRequestId {
-				logger.Debug.Println("inserting mention", mentionUserId, msgId)
+				logger.Ctx(c).Debug("inserting mention", "userId", mentionUserId, "msgId", msgId)
 				if _, err := tx.ExecContext(ctx, "INSERT INTO msgmentions (msg_id, user_id) VALUES ($1, $2)", msgId, mentionUserId); err != nil {
 					errors.SendErrorResponse(c, err, errors.StatusInternalError)
 					return
@@ -176,11 +175,6 @@ func Edit(c *gin.Context) {
 		}
 	}
 
-	if err := tx.Commit(); err != nil { //commits the transaction
-		errors.SendErrorResponse(c, err, errors.StatusInternalError)
-		return
-	}
-
 	var requestId string
 	var intMsgId int64
 	if isRequestId {
@@ -219,6 +213,15 @@ func Edit(c *gin.Context) {
 		},
 		Event: events.MESSAGE_UPDATE,
 	}
-	wsclient.Pools.BroadcastGuild(intGuildId, res)
+	if err := outbox.Enqueue(ctx, tx, intGuildId, res.Event, res.Data); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil { //commits the transaction
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	h.Pools.BroadcastGuild(intGuildId, res)
 	c.Status(http.StatusNoContent)
 }
@@ -8,14 +8,13 @@ import (
 	"time"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/db"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
 	"github.com/asianchinaboi/backendserver/internal/session"
 	"github.com/gin-gonic/gin"
 )
 
-func Get(c *gin.Context) { //sends message history
+func (h *Handlers) Get(c *gin.Context) { //sends message history
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -45,7 +44,7 @@ func Get(c *gin.Context) { //sends message history
 
 	var inGuild bool
 
-	if err := db.Db.QueryRow("SELECT EXISTS (SELECT * FROM userguilds WHERE guild_id=$1 AND user_id=$2 AND banned=false)", guildId, user.Id).Scan(&inGuild); err != nil {
+	if err := h.Db.QueryRow("SELECT EXISTS (SELECT * FROM userguilds WHERE guild_id=$1 AND user_id=$2 AND banned=false)", guildId, user.Id).Scan(&inGuild); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -54,8 +53,8 @@ func Get(c *gin.Context) { //sends message history
 		return
 	}
 
-	rows, err := db.Db.Query(
-		`SELECT m.id, m.content, m.user_id, m.guild_id, m.created, m.modified, m.mentions_everyone, u.username, f.id
+	rows, err := h.Db.Query(
+		`SELECT m.id, m.content, m.user_id, m.guild_id, m.created, m.modified, m.mentions_everyone, COALESCE(m.webhook_name, u.username), u.flags, f.id
 		FROM msgs m INNER JOIN users u 
 		ON u.id = m.user_id LEFT JOIN files f
 		ON f.user_id = u.id 
@@ -72,11 +71,15 @@ func Get(c *gin.Context) { //sends message history
 		message := events.Msg{}
 		var imageId sql.NullInt64
 		var modified sql.NullTime
+		var flags int
 		if err := rows.Scan(&message.MsgId, &message.Content, &message.Author.UserId,
-			&message.GuildId, &message.Created, &modified, &message.MentionsEveryone, &message.Author.Name, &imageId); err != nil {
+			&message.GuildId, &message.Created, &modified, &message.MentionsEveryone, &message.Author.Name, &flags, &imageId); err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
+		if flags&events.FLwebhook != 0 {
+			message.WebhookId = message.Author.UserId
+		}
 		if modified.Valid { //to make it show in json
 			message.Modified = modified.Time
 		} else {
@@ -88,7 +91,7 @@ func Get(c *gin.Context) { //sends message history
 			message.Author.ImageId = -1
 		}
 
-		mentions, err := db.Db.Query(`SELECT mm.user_id, u.username FROM msgmentions mm INNER JOIN users u ON u.id = mm.user_id WHERE msg_id = $1`, message.MsgId)
+		mentions, err := h.Db.Query(`SELECT mm.user_id, u.username FROM msgmentions mm INNER JOIN users u ON u.id = mm.user_id WHERE msg_id = $1`, message.MsgId)
 		if err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
@@ -106,7 +109,8 @@ func Get(c *gin.Context) { //sends message history
 		}
 		mentions.Close()
 
-		attachments, err := db.Db.Query(`SELECT id, filename, filetype FROM files WHERE msg_id = $1`, message.MsgId)
+		attachments, err := h.Db.Query(`SELECT f.id, f.filename, f.filetype, f.filesize, COALESCE(f.width, 0), COALESCE(f.height, 0), COALESCE(f.blurhash, ''), COALESCE(f.duration, 0), f.quarantined, COALESCE(t.status, '') 
+		FROM files f LEFT JOIN transcodes t ON t.hash = f.hash WHERE f.msg_id = $1`, message.MsgId)
 		if err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
@@ -116,10 +120,11 @@ func Get(c *gin.Context) { //sends message history
 
 		for attachments.Next() {
 			var attachment events.Attachment
-			if err := attachments.Scan(&attachment.Id, &attachment.Filename, &attachment.Type); err != nil {
+			if err := attachments.Scan(&attachment.Id, &attachment.Filename, &attachment.Type, &attachment.Size, &attachment.Width, &attachment.Height, &attachment.Blurhash, &attachment.Duration, &attachment.Quarantined, &attachment.Transcode); err != nil {
 				errors.SendErrorResponse(c, err, errors.StatusInternalError)
 				return
 			}
+			attachment.ContentType = attachment.Type
 
 			*message.Attachments = append(*message.Attachments, attachment)
 		}
@@ -8,28 +8,48 @@ import (
 	"io"
 	"mime/multipart"
 	"net/http"
-	"os"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/config"
-	"github.com/asianchinaboi/backendserver/internal/db"
+	"github.com/asianchinaboi/backendserver/internal/blobs"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
 	"github.com/asianchinaboi/backendserver/internal/files"
 	"github.com/asianchinaboi/backendserver/internal/logger"
+	"github.com/asianchinaboi/backendserver/internal/outbox"
+	"github.com/asianchinaboi/backendserver/internal/quota"
+	"github.com/asianchinaboi/backendserver/internal/scanner"
 	"github.com/asianchinaboi/backendserver/internal/session"
+	"github.com/asianchinaboi/backendserver/internal/tracing"
+	"github.com/asianchinaboi/backendserver/internal/transcode"
 	"github.com/asianchinaboi/backendserver/internal/uid"
 	"github.com/asianchinaboi/backendserver/internal/wsclient"
 	"github.com/gin-gonic/gin"
 )
 
+type sendBody struct {
+	events.Msg
+	Username  string   `json:"username"`  //display name override, only used by webhooks
+	Ephemeral bool     `json:"ephemeral"` //only used by bots replying to commands
+	Uploads   []string `json:"uploads"`   //ids of finalized chunked uploads to attach
+}
+
+// who the message is being sent as
+type sender struct {
+	userId    int64
+	webhookId int64  //0 if not sent by a webhook
+	name      string //webhook display name
+
+	interactionId int64 //set if a bot is replying to a command
+	ephemeralTo   int64 //only send to this user and dont save
+}
+
 // expects
 // content : string
-func Send(c *gin.Context) {
+func (h *Handlers) Send(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -51,68 +71,91 @@ func Send(c *gin.Context) {
 		return
 	}
 
-	var msg events.Msg
+	body, attachmentFiles, statusCode, err := h.parseSendBody(c)
+	if err != nil {
+		errors.SendErrorResponse(c, err, statusCode)
+		return
+	}
+
+	//send msg to database
+	//broadcast msg to all connections to websocket
+	var inGuild bool
+	if err := h.Db.QueryRow("SELECT EXISTS (SELECT * FROM userguilds WHERE guild_id=$1 AND user_id=$2 AND banned=false)", guildId, user.Id).Scan(&inGuild); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	if !inGuild {
+		errors.SendErrorResponse(c, errors.ErrNotInGuild, errors.StatusNotInGuild)
+		return
+	}
+
+	if name, args, ok := events.ParseCommandInvocation(body.Content); ok && !user.Bot && len(attachmentFiles) == 0 && len(body.Uploads) == 0 {
+		if h.invokeCommand(c, user.Id, intGuildId, name, args) {
+			return
+		}
+	}
+
+	h.send(c, sender{userId: user.Id}, intGuildId, body.Msg, attachmentFiles, body.Uploads)
+}
+
+// SendAsWebhook creates a message through the same path as Send but authored by the webhook
+func (h *Handlers) SendAsWebhook(c *gin.Context, webhookId int64, guildId int64, name string) {
+	body, attachmentFiles, statusCode, err := h.parseSendBody(c)
+	if err != nil {
+		errors.SendErrorResponse(c, err, statusCode)
+		return
+	}
+	if body.Username != "" {
+		name = body.Username
+	}
+	if valid, err := events.ValidateWebhookName(name); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if !valid {
+		errors.SendErrorResponse(c, errors.ErrInvalidUsername, errors.StatusInvalidUsername)
+		return
+	}
+	h.send(c, sender{userId: webhookId, webhookId: webhookId, name: name}, guildId, body.Msg, attachmentFiles, body.Uploads)
+}
+
+// takes either json or a multipart form with the json in body and the attachments in file
+func (h *Handlers) parseSendBody(c *gin.Context) (sendBody, []*multipart.FileHeader, errors.ErrCode, error) {
+	var body sendBody
 	var attachmentFiles []*multipart.FileHeader
-	fileIds := []int64{}
-	fileSucessful := false
 
 	contentType := c.GetHeader("Content-Type")
 
-	msg.Attachments = &[]events.Attachment{}
-
 	if strings.HasPrefix(contentType, "multipart/form-data") {
 		form, err := c.MultipartForm()
 		if err != nil {
-			errors.SendErrorResponse(c, err, errors.StatusBadRequest)
-			return
+			return body, nil, errors.StatusBadRequest, err
 		}
 
 		attachmentFiles = form.File["file"]
-		defer func() {
-			if !fileSucessful {
-				for _, id := range fileIds {
-					if err := os.Remove(fmt.Sprintf("uploads/msg/%d.lz4", id)); err != nil {
-						logger.Warn.Printf("unable to remove file: %v\n", err)
-					}
-				}
-			}
-		}()
 		jsonData := c.PostForm("body")
-		if err := json.Unmarshal([]byte(jsonData), &msg); err != nil {
-			errors.SendErrorResponse(c, err, errors.StatusBadRequest)
-			return
+		if err := json.Unmarshal([]byte(jsonData), &body); err != nil {
+			return body, nil, errors.StatusBadRequest, err
 		}
 	} else if strings.HasPrefix(contentType, "application/json") {
-		if err := c.ShouldBindJSON(&msg); err != nil {
-			errors.SendErrorResponse(c, err, errors.StatusBadRequest)
-			return
+		if err := c.ShouldBindJSON(&body); err != nil {
+			return body, nil, errors.StatusBadRequest, err
 		}
 	} else {
-		errors.SendErrorResponse(c, errors.ErrNotSupportedContentType, errors.StatusBadRequest)
-		return
+		return body, nil, errors.StatusBadRequest, errors.ErrNotSupportedContentType
 	}
+	return body, attachmentFiles, 0, nil
+}
 
-	//send msg to database
-	//broadcast msg to all connections to websocket
-	var inGuild bool
-	if err := db.Db.QueryRow("SELECT EXISTS (SELECT * FROM userguilds WHERE guild_id=$1 AND user_id=$2 AND banned=false)", guildId, user.Id).Scan(&inGuild); err != nil {
-		errors.SendErrorResponse(c, err, errors.StatusInternalError)
-		return
-	}
-	if !inGuild {
-		errors.SendErrorResponse(c, errors.ErrNotInGuild, errors.StatusNotInGuild)
-		return
-	}
+func (h *Handlers) send(c *gin.Context, author sender, intGuildId int64, msg events.Msg, attachmentFiles []*multipart.FileHeader, uploadIds []string) {
+	guildId := strconv.FormatInt(intGuildId, 10)
+
+	msg.Attachments = &[]events.Attachment{}
 
 	//BEGIN TRANSACTION
-	ctx := context.Background()
-	tx, err := db.Db.BeginTx(ctx, nil)
+	ctx := tracing.Detach(c.Request.Context()) //keeps the trace but not the cancellation so the transaction isnt cut off halfway
+	tx, err := h.Db.BeginTx(ctx, nil)
 	if err != nil {
-		logger.Error.Println(err)
-		c.JSON(http.StatusInternalServerError, errors.Body{
-			Error:  err.Error(),
-			Status: errors.StatusInternalError,
-		})
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
 	defer tx.Rollback() //rollback changes if failed
@@ -126,30 +169,65 @@ func Send(c *gin.Context) {
 
 	//check if guild has chat messages save turned on
 	var isChatSaveOn bool
-	if err := db.Db.QueryRow("SELECT save_chat FROM guilds WHERE id=$1", guildId).Scan(&isChatSaveOn); err != nil {
+	var scanPolicy string
+	if err := h.Db.QueryRow("SELECT save_chat, scan_policy FROM guilds WHERE id=$1", guildId).Scan(&isChatSaveOn, &scanPolicy); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
+	if author.ephemeralTo != 0 {
+		isChatSaveOn = false
+	}
 
-	if len(msg.Content) == 0 && len(attachmentFiles) == 0 {
+	if len(msg.Content) == 0 && len(attachmentFiles) == 0 && len(uploadIds) == 0 {
 		errors.SendErrorResponse(c, errors.ErrNoMsgContent, errors.StatusNoMsgContent)
 		return
 	}
 
-	if len(msg.Content) > config.Config.Guild.MaxMsgLength {
+	if len(msg.Content) > h.Live.Current().Guild.MaxMsgLength {
 		errors.SendErrorResponse(c, errors.ErrMsgTooLong, errors.StatusMsgTooLong)
 		return
 	}
 
+	if len(attachmentFiles) > 0 {
+		//rough check so nothing gets stored if its obviously over, the real check is done after the files are written
+		var incoming int64
+		for _, file := range attachmentFiles {
+			incoming += file.Size
+		}
+		userUsage, err := quota.User(ctx, h.Db, h.Config, author.userId)
+		if err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusInternalError)
+			return
+		}
+		if quota.Exceeds(userUsage, incoming) {
+			errors.SendErrorResponse(c, errors.ErrQuotaUserExceeded, errors.StatusQuotaExceeded)
+			return
+		}
+		if isChatSaveOn {
+			guildUsage, err := quota.Guild(ctx, h.Db, h.Config, intGuildId)
+			if err != nil {
+				errors.SendErrorResponse(c, err, errors.StatusInternalError)
+				return
+			}
+			if quota.Exceeds(guildUsage, incoming) {
+				errors.SendErrorResponse(c, errors.ErrQuotaGuildExceeded, errors.StatusQuotaExceeded)
+				return
+			}
+		}
+	}
+
 	//finding mentions
 	mentions := events.MentionExp.FindAllStringSubmatch(msg.Content, -1)
-	logger.Debug.Println("msgcontent:", msg.Content)
-	logger.Debug.Println("mentions:", mentions)
+	logger.Ctx(c).Debug("finding mentions", "content", msg.Content, "mentions", mentions)
 	msg.MentionsEveryone = new(bool)
 	*msg.MentionsEveryone = events.MentionEveryoneExp.MatchString(msg.Content)
 
 	if isChatSaveOn {
-		if err := tx.QueryRowContext(ctx, "INSERT INTO msgs (id, content, user_id, guild_id, mentions_everyone) VALUES ($1, $2, $3, $4, $5) RETURNING created", msg.MsgId, msg.Content, user.Id, guildId, msg.MentionsEveryone).Scan(&msg.Created); err != nil {
+		var webhookName sql.NullString
+		if author.webhookId != 0 {
+			webhookName = sql.NullString{String: author.name, Valid: true}
+		}
+		if err := tx.QueryRowContext(ctx, "INSERT INTO msgs (id, content, user_id, guild_id, mentions_everyone, webhook_name) VALUES ($1, $2, $3, $4, $5, $6) RETURNING created", msg.MsgId, msg.Content, author.userId, guildId, msg.MentionsEveryone, webhookName).Scan(&msg.Created); err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
@@ -160,37 +238,13 @@ func Send(c *gin.Context) {
 	msg.Mentions = &[]events.User{}
 
 	if len(mentions) > 0 {
-		logger.Debug.Println("mentions found")
-		seen := map[int64]bool{}
-		for _, mention := range mentions {
-			mentionUserId, err := strconv.ParseInt(mention[1], 10, 64)
-			if err != nil {
-				errors.SendErrorResponse(c, err, errors.StatusInternalError)
-				return
-			}
-			if seen[mentionUserId] {
-				continue
-			}
-			seen[mentionUserId] = true
-
-			var mentionUser events.User
-			mentionUser.UserId = mentionUserId
-			if err := db.Db.QueryRow("SELECT username FROM users WHERE id = $1", mentionUserId).Scan(&mentionUser.Name); err != nil && err != sql.ErrNoRows {
-				errors.SendErrorResponse(c, err, errors.StatusInternalError)
-				return
-			} else if err == sql.ErrNoRows {
-				errors.SendErrorResponse(c, errors.ErrUserNotFound, errors.StatusBadRequest)
-				return
-			}
-
-			if isChatSaveOn {
-				if _, err := tx.ExecContext(ctx, "INSERT INTO msgmentions (msg_id, user_id) VALUES ($1, $2)", msg.MsgId, mentionUserId); err != nil {
-					errors.SendErrorResponse(c, err, errors.StatusInternalError)
-					return
-				}
-			}
-
-			*msg.Mentions = append(*msg.Mentions, mentionUser)
+		logger.Ctx(c).Debug("mentions found")
+		if err := h.addMentions(ctx, tx, &msg, mentions, isChatSaveOn); err == errors.ErrUserNotFound {
+			errors.SendErrorResponse(c, err, errors.StatusBadRequest)
+			return
+		} else if err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusInternalError)
+			return
 		}
 	}
 
@@ -199,7 +253,13 @@ func Send(c *gin.Context) {
 		attachment.Filename = file.Filename
 		attachment.Id = uid.Snowflake.Generate().Int64()
 
-		//compress the file using LZ4 now
+		if file.Size > int64(h.Config.Server.MaxFileSize) {
+			errors.SendErrorResponse(c, errors.ErrFileTooLarge, errors.StatusFileTooLarge)
+			return
+		} else if !(file.Size >= 0) {
+			errors.SendErrorResponse(c, errors.ErrFileNoBytes, errors.StatusFileNoBytes)
+			return
+		}
 
 		fileContents, err := file.Open()
 		if err != nil {
@@ -207,76 +267,136 @@ func Send(c *gin.Context) {
 			return
 		}
 		defer fileContents.Close()
-		fileBytes, err := io.ReadAll(fileContents)
 
-		if err != nil {
+		//only the start of the file is needed to work out the type
+		head := make([]byte, 512)
+		n, err := io.ReadFull(fileContents, head)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
 
-		attachment.Type = http.DetectContentType(fileBytes)
-		logger.Debug.Println("uploaded type", attachment.Type)
-		*msg.Attachments = append(*msg.Attachments, attachment)
-
-		filesize := len(fileBytes)
+		attachment.Type = http.DetectContentType(head[:n])
+		logger.Ctx(c).Debug("uploaded type", "type", attachment.Type)
+		attachment.ContentType = attachment.Type
 
-		if filesize > config.Config.Server.MaxFileSize {
-			errors.SendErrorResponse(c, errors.ErrFileTooLarge, errors.StatusFileTooLarge)
+		//strips exif from images and works out the dimensions and duration
+		content, meta, err := files.Inspect(fileContents, file.Size, attachment.Type)
+		if err == errors.ErrFileInvalid {
+			errors.SendErrorResponse(c, err, errors.StatusFileInvalid)
 			return
-		} else if !(filesize >= 0) {
-			errors.SendErrorResponse(c, errors.ErrFileNoBytes, errors.StatusFileNoBytes)
+		} else if err == errors.ErrFileTooLarge {
+			errors.SendErrorResponse(c, err, errors.StatusFileTooLarge)
 			return
-		}
-
-		compressedBuffer, err := files.Compress(fileBytes, filesize)
-		if err != nil {
+		} else if err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
-
-		outFile, err := os.Create(fmt.Sprintf("uploads/msg/%d.lz4", attachment.Id))
-		//TODO: delete files if failed or write them after when its deemed successful
-		if err != nil {
+		attachment.Size = meta.Size
+		attachment.Width = meta.Width
+		attachment.Height = meta.Height
+		attachment.Blurhash = meta.Blurhash
+		attachment.Duration = meta.Duration
+
+		//scanned before the row is written so members never get served something unchecked
+		verdict, err := scanner.Check(ctx, h.Scanner, content, scanPolicy)
+		if err == errors.ErrScannerUnavailable {
+			errors.SendErrorResponse(c, err, errors.StatusScannerUnavailable)
+			return
+		} else if err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
-		defer outFile.Close()
+		attachment.Quarantined = verdict.Quarantined
 
-		_, err = outFile.Write(compressedBuffer)
+		//stored by content so the same file sent again doesnt take up more space
+		hash, err := blobs.Put(ctx, tx, h.Store, content)
 		if err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
+		filesize := attachment.Size
 
 		//make files temporary if chat messages save turned off
 
 		if isChatSaveOn {
-			if _, err := tx.ExecContext(ctx, "INSERT INTO files (id, msg_id, filename, created, temp, filesize, filetype, entity_type) VALUES ($1, $2, $3, $4, $5, $6, $7, 'msg')", attachment.Id, msg.MsgId, attachment.Filename, msg.Created, !isChatSaveOn, filesize, attachment.Type); err != nil {
+			if _, err := tx.ExecContext(ctx, "INSERT INTO files (id, msg_id, filename, created, temp, filesize, filetype, entity_type, uploader_id, hash, width, height, blurhash, duration, quarantined, quarantine_reason, msg_guild_id) VALUES ($1, $2, $3, $4, $5, $6, $7, 'msg', $8, $9, NULLIF($10, 0), NULLIF($11, 0), NULLIF($12, ''), NULLIF($13, 0), $14, NULLIF($15, ''), $16)",
+				attachment.Id, msg.MsgId, attachment.Filename, msg.Created, !isChatSaveOn, filesize, attachment.Type, author.userId, hash, attachment.Width, attachment.Height, attachment.Blurhash, attachment.Duration, verdict.Quarantined, verdict.Reason, intGuildId); err != nil {
 				errors.SendErrorResponse(c, err, errors.StatusInternalError)
 				return
 			}
 		} else {
-			if _, err := tx.ExecContext(ctx, "INSERT INTO files (id, filename, created, temp, filesize, filetype ,entity_type) VALUES ($1, $2, $3, $4, $5, $6, 'msg')", attachment.Id, attachment.Filename, msg.Created, !isChatSaveOn, filesize, attachment.Type); err != nil {
+			if _, err := tx.ExecContext(ctx, "INSERT INTO files (id, filename, created, temp, filesize, filetype ,entity_type, uploader_id, hash, width, height, blurhash, duration, quarantined, quarantine_reason, msg_guild_id) VALUES ($1, $2, $3, $4, $5, $6, 'msg', $7, $8, NULLIF($9, 0), NULLIF($10, 0), NULLIF($11, ''), NULLIF($12, 0), $13, NULLIF($14, ''), $15)",
+				attachment.Id, attachment.Filename, msg.Created, !isChatSaveOn, filesize, attachment.Type, author.userId, hash, attachment.Width, attachment.Height, attachment.Blurhash, attachment.Duration, verdict.Quarantined, verdict.Reason, intGuildId); err != nil {
 				errors.SendErrorResponse(c, err, errors.StatusInternalError)
 				return
 			}
 		}
 
-		fileIds = append(fileIds, attachment.Id)
+		//videos and audio get turned into hls in the background, flagged files arent worth the risk
+		if !verdict.Quarantined {
+			if attachment.Transcode, err = transcode.Enqueue(ctx, tx, h.Config, hash, attachment.Type); err != nil {
+				errors.SendErrorResponse(c, err, errors.StatusInternalError)
+				return
+			}
+		}
+		*msg.Attachments = append(*msg.Attachments, attachment)
+	}
+
+	//uploads were already stored when finalized so they only need to be claimed by this message
+	for _, uploadId := range uploadIds {
+		if match, err := regexp.MatchString("^[0-9]+$", uploadId); err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusInternalError)
+			return
+		} else if !match {
+			errors.SendErrorResponse(c, errors.ErrUploadNotExist, errors.StatusUploadNotExist)
+			return
+		}
+		var msgId interface{}
+		if isChatSaveOn {
+			msgId = msg.MsgId
+		}
+		var attachment events.Attachment
+		if err := tx.QueryRowContext(ctx, `UPDATE files SET msg_id = $1, temp = $2, created = $3, msg_guild_id = $6
+			WHERE id = $4 AND uploader_id = $5 AND entity_type = 'msg' AND msg_id IS NULL AND temp = true
+			RETURNING id, filename, filetype, filesize, COALESCE(width, 0), COALESCE(height, 0), COALESCE(blurhash, ''), COALESCE(duration, 0), quarantined,
+			COALESCE((SELECT status FROM transcodes t WHERE t.hash = files.hash), '')`, msgId, !isChatSaveOn, msg.Created, uploadId, author.userId, intGuildId).Scan(
+			&attachment.Id, &attachment.Filename, &attachment.Type, &attachment.Size, &attachment.Width, &attachment.Height, &attachment.Blurhash, &attachment.Duration, &attachment.Quarantined, &attachment.Transcode); err != nil && err != sql.ErrNoRows {
+			errors.SendErrorResponse(c, err, errors.StatusInternalError)
+			return
+		} else if err == sql.ErrNoRows {
+			errors.SendErrorResponse(c, errors.ErrUploadNotExist, errors.StatusUploadNotExist)
+			return
+		}
+		attachment.ContentType = attachment.Type
+		*msg.Attachments = append(*msg.Attachments, attachment)
+	}
+
+	if len(attachmentFiles) > 0 || len(uploadIds) > 0 {
+		//the files are in the transaction now so they count towards the quotas
+		if err := quota.Check(ctx, tx, h.Config, author.userId, intGuildId); err == errors.ErrQuotaUserExceeded || err == errors.ErrQuotaGuildExceeded {
+			errors.SendErrorResponse(c, err, errors.StatusQuotaExceeded)
+			return
+		} else if err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusInternalError)
+			return
+		}
 	}
 
 	var isDm bool
 
-	if err := db.Db.QueryRow("SELECT receiver_id IS NOT NULL FROM userguilds WHERE guild_id = $1 AND user_id = $2", guildId, user.Id).Scan(&isDm); err != nil {
-		errors.SendErrorResponse(c, err, errors.StatusInternalError)
-		return
+	if author.webhookId == 0 { //webhooks arent in userguilds and cant be in dms anyway
+		if err := h.Db.QueryRow("SELECT receiver_id IS NOT NULL FROM userguilds WHERE guild_id = $1 AND user_id = $2", guildId, author.userId).Scan(&isDm); err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusInternalError)
+			return
+		}
 	}
 
 	if isDm {
 		var isBlocked bool
-		if err := db.Db.QueryRow(`
+		if err := h.Db.QueryRow(`
 		SELECT EXISTS (SELECT 1 FROM blocked INNER JOIN userguilds ON (blocked.blocked_id = userguilds.receiver_id OR blocked.user_id = userguilds.receiver_id) AND userguilds.guild_id = $2
-			WHERE blocked.user_id = $1 OR blocked.blocked_id = $1)`, user.Id, guildId).Scan(&isBlocked); err != nil {
+			WHERE blocked.user_id = $1 OR blocked.blocked_id = $1)`, author.userId, guildId).Scan(&isBlocked); err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
@@ -319,10 +439,9 @@ func Send(c *gin.Context) {
 				},
 				Event: events.DM_CREATE,
 			}
-			logger.Debug.Printf("trying to user to dm in guild pool dmId: %d userId: %d\n", dmId, userId)
-			wsclient.Pools.AddUserToGuildPool(dmId, userId)
-			logger.Debug.Println("after adding :0")
-			wsclient.Pools.BroadcastClient(userId, res)
+			logger.Ctx(c).Debug("adding user to dm guild pool", "dmId", dmId, "userId", userId)
+			h.Pools.AddUserToGuildPool(dmId, userId)
+			h.Pools.BroadcastClient(userId, res)
 		}
 	}
 
@@ -330,7 +449,8 @@ func Send(c *gin.Context) {
 
 	var authorBody events.User
 	var imageId sql.NullInt64
-	if err := db.Db.QueryRow("SELECT username, files.id FROM users LEFT JOIN files ON files.user_id = users.id WHERE users.id=$1", user.Id).Scan(&authorBody.Name, &imageId); err != nil {
+	authorBody.Flags = new(int)
+	if err := h.Db.QueryRowContext(ctx, "SELECT username, flags, files.id FROM users LEFT JOIN files ON files.user_id = users.id WHERE users.id=$1", author.userId).Scan(&authorBody.Name, authorBody.Flags, &imageId); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -339,25 +459,82 @@ func Send(c *gin.Context) {
 	} else {
 		authorBody.ImageId = -1
 	}
-	authorBody.UserId = user.Id
+	authorBody.UserId = author.userId
+	if author.webhookId != 0 {
+		authorBody.Name = author.name
+		msg.WebhookId = author.webhookId
+	}
+	msg.InteractionId = author.interactionId
+	msg.Ephemeral = author.ephemeralTo != 0
 	msg.Author = authorBody
 	msg.GuildId = intGuildId
+	if !isChatSaveOn {
+		msg.RequestId = fmt.Sprintf("%d-%d", author.userId, msg.MsgId)
+	}
+
+	if author.ephemeralTo == 0 { //ephemeral replies are only for one user so subscriptions dont get them
+		if err := outbox.Enqueue(ctx, tx, intGuildId, events.MESSAGE_CREATE, msg); err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusInternalError)
+			return
+		}
+	}
 
 	if err := tx.Commit(); err != nil { //commits the transaction
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
 
-	fileSucessful = true
-
-	if !isChatSaveOn {
-		msg.RequestId = fmt.Sprintf("%d-%d", user.Id, msg.MsgId)
+	if author.ephemeralTo != 0 {
+		h.Pools.BroadcastClient(author.ephemeralTo, wsclient.DataFrame{
+			Op:    wsclient.TYPE_DISPATCH,
+			Data:  msg,
+			Event: events.MESSAGE_CREATE,
+		})
+		c.Status(http.StatusNoContent)
+		return
 	}
 
-	wsclient.Pools.BroadcastGuild(intGuildId, wsclient.DataFrame{
+	h.Pools.BroadcastGuildContext(ctx, intGuildId, wsclient.DataFrame{
 		Op:    wsclient.TYPE_DISPATCH,
 		Data:  msg,
 		Event: events.MESSAGE_CREATE,
 	})
 	c.Status(http.StatusNoContent)
 }
+
+// addMentions looks up every mentioned user once and adds them to the msg, theyre saved too if the chat is
+func (h *Handlers) addMentions(ctx context.Context, tx *sql.Tx, msg *events.Msg, mentions [][]string, save bool) error {
+	ctx, span := tracing.Start(ctx, "msgs.mentions", tracing.KindInternal, "mentions", len(mentions))
+	defer span.End()
+	seen := map[int64]bool{}
+	for _, mention := range mentions {
+		mentionUserId, err := strconv.ParseInt(mention[1], 10, 64)
+		if err != nil {
+			span.RecordError(err)
+			return err
+		}
+		if seen[mentionUserId] {
+			continue
+		}
+		seen[mentionUserId] = true
+
+		var mentionUser events.User
+		mentionUser.UserId = mentionUserId
+		if err := h.Db.QueryRowContext(ctx, "SELECT username FROM users WHERE id = $1", mentionUserId).Scan(&mentionUser.Name); err == sql.ErrNoRows {
+			return errors.ErrUserNotFound
+		} else if err != nil {
+			span.RecordError(err)
+			return err
+		}
+
+		if save {
+			if _, err := tx.ExecContext(ctx, "INSERT INTO msgmentions (msg_id, user_id) VALUES ($1, $2)", msg.MsgId, mentionUserId); err != nil {
+				span.RecordError(err)
+				return err
+			}
+		}
+
+		*msg.Mentions = append(*msg.Mentions, mentionUser)
+	}
+	return nil
+}
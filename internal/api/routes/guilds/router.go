@@ -1,46 +1,84 @@
 package guilds
 
 import (
+	"github.com/asianchinaboi/backendserver/internal/api/deps"
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
 	"github.com/asianchinaboi/backendserver/internal/api/routes/guilds/admins"
 	"github.com/asianchinaboi/backendserver/internal/api/routes/guilds/bans"
+	"github.com/asianchinaboi/backendserver/internal/api/routes/guilds/commands"
 	"github.com/asianchinaboi/backendserver/internal/api/routes/guilds/invites"
 	"github.com/asianchinaboi/backendserver/internal/api/routes/guilds/members"
 	"github.com/asianchinaboi/backendserver/internal/api/routes/guilds/msgs"
+	"github.com/asianchinaboi/backendserver/internal/api/routes/guilds/subscriptions"
+	"github.com/asianchinaboi/backendserver/internal/api/routes/guilds/webhooks"
 	"github.com/gin-gonic/gin"
 )
 
-func Routes(r *gin.RouterGroup) {
+// Handlers are the guilds routes, Routes builds them from what the app hands over
+type Handlers struct {
+	*deps.Deps
+}
+
+func New(d *deps.Deps) *Handlers {
+	return &Handlers{d}
+}
+
+func Routes(r *gin.RouterGroup, d *deps.Deps) {
+	h := New(d)
+	member := members.New(d)
+	admin := admins.New(d)
+	msg := msgs.New(d)
+	ban := bans.New(d)
+	invite := invites.New(d)
+	webhook := webhooks.New(d)
+	subscription := subscriptions.New(d)
+	command := commands.New(d)
+
 	guilds := r.Group("/guilds")
-	guilds.Use(middleware.Auth)
+	guilds.Use(middleware.Auth(d.Db))
+
+	owner := guilds.Group("", middleware.UserOnly) //bots only get into guilds through oauth2 and cant run them
+
+	owner.POST("/", h.createGuild)
+	owner.POST("/join", middleware.Captcha(d.Config, d.Captcha), h.joinGuild)
+
+	owner.DELETE("/:guildId", h.deleteGuild)
+	guilds.PATCH("/:guildId", h.editGuild)
+	guilds.GET("/:guildId", h.getGuild)
+	guilds.GET("/:guildId/storage", h.getGuildStorage)
+
+	guilds.GET("/:guildId/members", member.Get)
+	guilds.DELETE("/:guildId/members/:userId", member.Kick)
 
-	guilds.POST("/", createGuild)
-	guilds.POST("/join", joinGuild)
+	owner.PUT("/:guildId/admins/:userId", admin.Create)
+	owner.DELETE("/:guildId/admins/:userId", admin.Delete)
+	guilds.GET("/:guildId/admins", admin.Get)
 
-	guilds.DELETE("/:guildId", deleteGuild)
-	guilds.PATCH("/:guildId", editGuild)
-	guilds.GET("/:guildId", getGuild)
+	guilds.GET("/:guildId/msgs", msg.Get)
+	guilds.POST("/:guildId/msgs", msg.Send)
+	guilds.DELETE("/:guildId/msgs/:msgId", msg.Delete)
+	guilds.PATCH("/:guildId/msgs/:msgId", msg.Edit)
+	guilds.DELETE("/:guildId/msgs/clear", msg.Clear) //change to post later on
+	guilds.POST("/:guildId/msgs/typing", msg.Typing) //need to persist to typing in guild pool later on
+	guilds.POST("/:guildId/msgs/read", msg.Read)
 
-	guilds.GET("/:guildId/members", members.Get)
-	guilds.DELETE("/:guildId/members/:userId", members.Kick)
+	guilds.GET("/:guildId/bans", ban.Get)
+	guilds.PUT("/:guildId/bans/:userId", ban.Ban)
+	guilds.DELETE("/:guildId/bans/:userId", ban.Unban)
 
-	guilds.PUT("/:guildId/admins/:userId", admins.Create)
-	guilds.DELETE("/:guildId/admins/:userId", admins.Delete)
-	guilds.GET("/:guildId/admins", admins.Get)
+	guilds.GET("/:guildId/invites", invite.Get)
+	guilds.POST("/:guildId/invites", invite.Create)
+	guilds.DELETE(`/:guildId/invites/:invite`, invite.Delete)
 
-	guilds.GET("/:guildId/msgs", msgs.Get)
-	guilds.POST("/:guildId/msgs", msgs.Send)
-	guilds.DELETE("/:guildId/msgs/:msgId", msgs.Delete)
-	guilds.PATCH("/:guildId/msgs/:msgId", msgs.Edit)
-	guilds.DELETE("/:guildId/msgs/clear", msgs.Clear) //change to post later on
-	guilds.POST("/:guildId/msgs/typing", msgs.Typing) //need to persist to typing in guild pool later on
-	guilds.POST("/:guildId/msgs/read", msgs.Read)
+	owner.GET("/:guildId/webhooks", webhook.Get)
+	owner.POST("/:guildId/webhooks", webhook.Create)
+	owner.DELETE("/:guildId/webhooks/:webhookId", webhook.Delete)
 
-	guilds.GET("/:guildId/bans", bans.Get)
-	guilds.PUT("/:guildId/bans/:userId", bans.Ban)
-	guilds.DELETE("/:guildId/bans/:userId", bans.Unban)
+	owner.GET("/:guildId/subscriptions", subscription.Get)
+	owner.POST("/:guildId/subscriptions", subscription.Create)
+	owner.DELETE("/:guildId/subscriptions/:subscriptionId", subscription.Delete)
 
-	guilds.GET("/:guildId/invites", invites.Get)
-	guilds.POST("/:guildId/invites", invites.Create)
-	guilds.DELETE(`/:guildId/invites/:invite`, invites.Delete)
+	guilds.GET("/:guildId/commands", command.Get)
+	guilds.POST("/:guildId/commands", command.Create)
+	guilds.DELETE("/:guildId/commands/:commandId", command.Delete)
 }
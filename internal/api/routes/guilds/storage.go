@@ -0,0 +1,75 @@
+package guilds
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/asianchinaboi/backendserver/internal/api/middleware"
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/asianchinaboi/backendserver/internal/events"
+	"github.com/asianchinaboi/backendserver/internal/quota"
+	"github.com/asianchinaboi/backendserver/internal/session"
+	"github.com/gin-gonic/gin"
+)
+
+const topUploadersLimit = 10
+
+func (h *Handlers) getGuildStorage(c *gin.Context) {
+	user := c.MustGet(middleware.User).(*session.Session)
+	if user == nil {
+		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
+		return
+	}
+
+	guildId := c.Param("guildId")
+	if match, err := regexp.MatchString("^[0-9]+$", guildId); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if !match {
+		errors.SendErrorResponse(c, errors.ErrRouteParamInvalid, errors.StatusRouteParamInvalid)
+		return
+	}
+	intGuildId, err := strconv.ParseInt(guildId, 10, 64)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	var isInGuild bool
+	if err := h.Db.QueryRow("SELECT EXISTS(SELECT 1 FROM userguilds WHERE user_id = $1 AND guild_id = $2 AND banned = false)", user.Id, guildId).Scan(&isInGuild); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	if !isInGuild {
+		errors.SendErrorResponse(c, errors.ErrNotInGuild, errors.StatusNotInGuild)
+		return
+	}
+
+	var summary events.GuildStorage
+	summary.StorageUsage, err = quota.Guild(c.Request.Context(), h.Db, h.Config, intGuildId)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	rows, err := h.Db.Query(`SELECT u.id, u.username, SUM(f.filesize), COUNT(f.id) 
+	FROM files f INNER JOIN msgs m ON m.id = f.msg_id INNER JOIN users u ON u.id = f.uploader_id 
+	WHERE m.guild_id = $1 GROUP BY u.id, u.username ORDER BY SUM(f.filesize) DESC LIMIT $2`, guildId, topUploadersLimit)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	defer rows.Close()
+
+	summary.TopUploaders = []events.StorageConsumer{}
+	for rows.Next() {
+		var uploader events.StorageConsumer
+		if err := rows.Scan(&uploader.Id, &uploader.Name, &uploader.Used, &uploader.Files); err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusInternalError)
+			return
+		}
+		summary.TopUploaders = append(summary.TopUploaders, uploader)
+	}
+	c.JSON(http.StatusOK, summary)
+}
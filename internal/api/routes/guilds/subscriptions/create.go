@@ -0,0 +1,115 @@
+package subscriptions
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/asianchinaboi/backendserver/internal/api/middleware"
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/asianchinaboi/backendserver/internal/events"
+	"github.com/asianchinaboi/backendserver/internal/session"
+	"github.com/asianchinaboi/backendserver/internal/uid"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+type createBody struct {
+	Url    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+// expects
+// url : string (http or https)
+// events : []string
+// the secret is only returned here, it signs every delivery
+func (h *Handlers) Create(c *gin.Context) {
+	user := c.MustGet(middleware.User).(*session.Session)
+	if user == nil {
+		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
+		return
+	}
+
+	guildId := c.Param("guildId")
+	if match, err := regexp.MatchString("^[0-9]+$", guildId); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if !match {
+		errors.SendErrorResponse(c, errors.ErrRouteParamInvalid, errors.StatusRouteParamInvalid)
+		return
+	}
+
+	intGuildId, err := strconv.ParseInt(guildId, 10, 64)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	var body createBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusBadRequest)
+		return
+	}
+
+	if !events.ValidateSubscriptionUrl(body.Url) {
+		errors.SendErrorResponse(c, errors.ErrSubscriptionInvalidUrl, errors.StatusSubscriptionInvalidUrl)
+		return
+	}
+	if len(body.Events) == 0 {
+		errors.SendErrorResponse(c, errors.ErrSubscriptionInvalidEvent, errors.StatusSubscriptionInvalidEvent)
+		return
+	}
+	for _, event := range body.Events {
+		if !events.SubscribableEvents[event] {
+			errors.SendErrorResponse(c, errors.ErrSubscriptionInvalidEvent, errors.StatusSubscriptionInvalidEvent)
+			return
+		}
+	}
+
+	var hasAuth bool
+	var isDm bool
+	var count int
+	if err := h.Db.QueryRow(`SELECT EXISTS (SELECT 1 FROM userguilds WHERE guild_id = $1 AND user_id = $2 AND (owner = true OR admin = true)), 
+	EXISTS (SELECT 1 FROM guilds WHERE id = $1 AND dm = true), 
+	(SELECT COUNT(*) FROM event_subscriptions WHERE guild_id = $1)`, guildId, user.Id).Scan(&hasAuth, &isDm, &count); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	if isDm {
+		errors.SendErrorResponse(c, errors.ErrGuildIsDm, errors.StatusGuildIsDm)
+		return
+	}
+	if !hasAuth {
+		errors.SendErrorResponse(c, errors.ErrNotGuildAuthorised, errors.StatusNotGuildAuthorised)
+		return
+	}
+	if count >= h.Config.EventHooks.MaxPerGuild {
+		errors.SendErrorResponse(c, errors.ErrSubscriptionLimitReached, errors.StatusSubscriptionLimitReached)
+		return
+	}
+
+	secret, err := session.GenSubscriptionSecret()
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	subscription := events.Subscription{
+		SubscriptionId: uid.Snowflake.Generate().Int64(),
+		GuildId:        intGuildId,
+		CreatorId:      user.Id,
+		Url:            body.Url,
+		Secret:         secret,
+		Events:         body.Events,
+		Created:        time.Now().UTC(),
+	}
+
+	if _, err := h.Db.Exec("INSERT INTO event_subscriptions (id, guild_id, creator_id, url, secret, events, created) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		subscription.SubscriptionId, guildId, user.Id, subscription.Url, secret, pq.Array(subscription.Events), subscription.Created); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	c.JSON(http.StatusOK, subscription)
+}
@@ -0,0 +1,61 @@
+package subscriptions
+
+import (
+	"database/sql"
+	"net/http"
+	"regexp"
+
+	"github.com/asianchinaboi/backendserver/internal/api/middleware"
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/asianchinaboi/backendserver/internal/events"
+	"github.com/asianchinaboi/backendserver/internal/session"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+func (h *Handlers) Get(c *gin.Context) {
+	user := c.MustGet(middleware.User).(*session.Session)
+	if user == nil {
+		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
+		return
+	}
+
+	guildId := c.Param("guildId")
+	if match, err := regexp.MatchString("^[0-9]+$", guildId); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if !match {
+		errors.SendErrorResponse(c, errors.ErrRouteParamInvalid, errors.StatusRouteParamInvalid)
+		return
+	}
+
+	var hasAuth bool
+	if err := h.Db.QueryRow("SELECT EXISTS (SELECT 1 FROM userguilds WHERE guild_id = $1 AND user_id = $2 AND (owner = true OR admin = true))", guildId, user.Id).Scan(&hasAuth); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	if !hasAuth {
+		errors.SendErrorResponse(c, errors.ErrNotGuildAuthorised, errors.StatusNotGuildAuthorised)
+		return
+	}
+
+	rows, err := h.Db.Query("SELECT id, guild_id, creator_id, url, events, created FROM event_subscriptions WHERE guild_id = $1", guildId)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	defer rows.Close()
+
+	subscriptions := []events.Subscription{}
+	for rows.Next() {
+		var subscription events.Subscription
+		var creatorId sql.NullInt64
+		if err := rows.Scan(&subscription.SubscriptionId, &subscription.GuildId, &creatorId, &subscription.Url, pq.Array(&subscription.Events), &subscription.Created); err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusInternalError)
+			return
+		}
+		subscription.CreatorId = creatorId.Int64
+		subscriptions = append(subscriptions, subscription)
+	}
+	c.JSON(http.StatusOK, subscriptions)
+}
@@ -0,0 +1,214 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asianchinaboi/backendserver/internal/api/middleware"
+	"github.com/asianchinaboi/backendserver/internal/blobs"
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/asianchinaboi/backendserver/internal/events"
+	"github.com/asianchinaboi/backendserver/internal/files"
+	"github.com/asianchinaboi/backendserver/internal/scanner"
+	"github.com/asianchinaboi/backendserver/internal/session"
+	"github.com/asianchinaboi/backendserver/internal/uid"
+	"github.com/gin-gonic/gin"
+)
+
+type createBody struct {
+	Name string `json:"name"`
+}
+
+// accepts name and image (avatar)
+func (h *Handlers) Create(c *gin.Context) {
+	user := c.MustGet(middleware.User).(*session.Session)
+	if user == nil {
+		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
+		return
+	}
+
+	guildId := c.Param("guildId")
+	if match, err := regexp.MatchString("^[0-9]+$", guildId); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if !match {
+		errors.SendErrorResponse(c, errors.ErrRouteParamInvalid, errors.StatusRouteParamInvalid)
+		return
+	}
+
+	intGuildId, err := strconv.ParseInt(guildId, 10, 64)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	var body createBody
+	var imageHeader *multipart.FileHeader
+
+	contentType := c.GetHeader("Content-Type")
+	if strings.HasPrefix(contentType, "multipart/form-data") {
+		if imageHeader, err = c.FormFile("image"); err != nil && err != http.ErrMissingFile {
+			errors.SendErrorResponse(c, err, errors.StatusBadRequest)
+			return
+		}
+		jsonData := c.PostForm("body")
+		if err := json.Unmarshal([]byte(jsonData), &body); err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusBadRequest)
+			return
+		}
+	} else if strings.HasPrefix(contentType, "application/json") {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusBadRequest)
+			return
+		}
+	} else {
+		errors.SendErrorResponse(c, errors.ErrNotSupportedContentType, errors.StatusBadRequest)
+		return
+	}
+
+	if valid, err := events.ValidateWebhookName(body.Name); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if !valid {
+		errors.SendErrorResponse(c, errors.ErrInvalidUsername, errors.StatusInvalidUsername)
+		return
+	}
+
+	var hasAuth bool
+	var isDm bool
+	var count int
+	if err := h.Db.QueryRow(`SELECT EXISTS (SELECT 1 FROM userguilds WHERE guild_id = $1 AND user_id = $2 AND (owner = true OR admin = true)), 
+	EXISTS (SELECT 1 FROM guilds WHERE id = $1 AND dm = true),
+	(SELECT COUNT(*) FROM webhooks WHERE guild_id = $1)`, guildId, user.Id).Scan(&hasAuth, &isDm, &count); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	if isDm {
+		errors.SendErrorResponse(c, errors.ErrGuildIsDm, errors.StatusGuildIsDm)
+		return
+	}
+	if !hasAuth {
+		errors.SendErrorResponse(c, errors.ErrNotGuildAuthorised, errors.StatusNotGuildAuthorised)
+		return
+	}
+	if count >= h.Config.Guild.MaxWebhooks {
+		errors.SendErrorResponse(c, errors.ErrWebhookLimitReached, errors.StatusWebhookLimitReached)
+		return
+	}
+
+	token, err := session.GenWebhookToken()
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	webhook := events.Webhook{
+		WebhookId: uid.Snowflake.Generate().Int64(),
+		GuildId:   intGuildId,
+		Name:      body.Name,
+		ImageId:   -1,
+		CreatorId: user.Id,
+		Token:     token,
+		Created:   time.Now().UTC(),
+	}
+	webhook.Url = fmt.Sprintf("/api/webhooks/%d/%s", webhook.WebhookId, token)
+
+	//BEGIN TRANSACTION
+	ctx := context.Background()
+	tx, err := h.Db.BeginTx(ctx, nil)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	defer tx.Rollback() //rollback changes if failed
+
+	//messages need an author so every webhook gets a user that cant log in
+	//the username is never shown, the webhook name is used instead
+	if _, err := tx.ExecContext(ctx, "INSERT INTO users (id, email, password, username, flags) VALUES ($1, '', '', $2, $3)", webhook.WebhookId, fmt.Sprintf("webhook_%d", webhook.WebhookId), events.FLwebhook); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	if _, err := tx.ExecContext(ctx, "INSERT INTO webhooks (user_id, guild_id, creator_id, name, token, created) VALUES ($1, $2, $3, $4, $5, $6)", webhook.WebhookId, guildId, user.Id, webhook.Name, token, webhook.Created); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	if imageHeader != nil {
+		imageId := uid.Snowflake.Generate().Int64()
+
+		filename := imageHeader.Filename
+		fileType := filepath.Ext(filename)
+
+		image, err := imageHeader.Open()
+		if err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusBadRequest)
+			return
+		}
+		defer image.Close()
+
+		fileBytes, err := io.ReadAll(image)
+		if err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusInternalError)
+			return
+		}
+
+		fileMIMEType := http.DetectContentType(fileBytes)
+
+		fileBytes, valid := files.ValidateImage(fileBytes, fileType, h.Config.Server.ImageProfileSize) //crops and scales it down if needed
+		if !valid {
+			errors.SendErrorResponse(c, errors.ErrFileInvalid, errors.StatusFileInvalid)
+			return
+		}
+
+		filesize := len(fileBytes)
+
+		if filesize > h.Config.Server.MaxFileSize {
+			errors.SendErrorResponse(c, errors.ErrFileTooLarge, errors.StatusFileTooLarge)
+			return
+		} else if filesize < 0 {
+			errors.SendErrorResponse(c, errors.ErrFileNoBytes, errors.StatusFileNoBytes)
+			return
+		}
+
+		//images use the default policy, the guild policy is only for attachments
+		verdict, err := scanner.Check(ctx, h.Scanner, bytes.NewReader(fileBytes), h.Config.Scanner.Policy)
+		if err == errors.ErrScannerUnavailable {
+			errors.SendErrorResponse(c, err, errors.StatusScannerUnavailable)
+			return
+		} else if err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusInternalError)
+			return
+		}
+
+		//stored by content so an image thats already been uploaded isnt stored again
+		hash, err := blobs.Put(ctx, tx, h.Store, bytes.NewReader(fileBytes))
+		if err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusInternalError)
+			return
+		}
+
+		if _, err = tx.ExecContext(ctx, "INSERT INTO files (id, user_id, filename, created, temp, filesize, filetype, entity_type, hash, quarantined, quarantine_reason) VALUES ($1, $2, $3, NOW() , $4, $5, $6, 'user', $7, $8, NULLIF($9, ''))", imageId, webhook.WebhookId, filename, false, filesize, fileMIMEType, hash, verdict.Quarantined, verdict.Reason); err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusInternalError)
+			return
+		}
+		webhook.ImageId = imageId
+	}
+
+	if err := tx.Commit(); err != nil { //commits the transaction
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
@@ -0,0 +1,69 @@
+package webhooks
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/asianchinaboi/backendserver/internal/api/middleware"
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/asianchinaboi/backendserver/internal/events"
+	"github.com/asianchinaboi/backendserver/internal/session"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handlers) Get(c *gin.Context) {
+	user := c.MustGet(middleware.User).(*session.Session)
+	if user == nil {
+		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
+		return
+	}
+
+	guildId := c.Param("guildId")
+	if match, err := regexp.MatchString("^[0-9]+$", guildId); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if !match {
+		errors.SendErrorResponse(c, errors.ErrRouteParamInvalid, errors.StatusRouteParamInvalid)
+		return
+	}
+
+	var hasAuth bool
+	if err := h.Db.QueryRow("SELECT EXISTS (SELECT 1 FROM userguilds WHERE guild_id = $1 AND user_id = $2 AND (owner = true OR admin = true))", guildId, user.Id).Scan(&hasAuth); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	if !hasAuth {
+		errors.SendErrorResponse(c, errors.ErrNotGuildAuthorised, errors.StatusNotGuildAuthorised)
+		return
+	}
+
+	rows, err := h.Db.Query(`SELECT w.user_id, w.guild_id, w.name, f.id, w.creator_id, w.token, w.created FROM webhooks w 
+	LEFT JOIN files f ON f.user_id = w.user_id WHERE w.guild_id = $1`, guildId)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	defer rows.Close()
+
+	webhooks := []events.Webhook{}
+	for rows.Next() {
+		var webhook events.Webhook
+		var imageId sql.NullInt64
+		var creatorId sql.NullInt64
+		if err := rows.Scan(&webhook.WebhookId, &webhook.GuildId, &webhook.Name, &imageId, &creatorId, &webhook.Token, &webhook.Created); err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusInternalError)
+			return
+		}
+		if imageId.Valid {
+			webhook.ImageId = imageId.Int64
+		} else {
+			webhook.ImageId = -1
+		}
+		webhook.CreatorId = creatorId.Int64
+		webhook.Url = fmt.Sprintf("/api/webhooks/%d/%s", webhook.WebhookId, webhook.Token)
+		webhooks = append(webhooks, webhook)
+	}
+	c.JSON(http.StatusOK, webhooks)
+}
@@ -1,26 +1,41 @@
 package routes
 
 import (
+	"github.com/asianchinaboi/backendserver/internal/api/deps"
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
 	"github.com/asianchinaboi/backendserver/internal/api/routes/admin"
+	"github.com/asianchinaboi/backendserver/internal/api/routes/captcha"
 	"github.com/asianchinaboi/backendserver/internal/api/routes/files"
 	"github.com/asianchinaboi/backendserver/internal/api/routes/guilds"
+	"github.com/asianchinaboi/backendserver/internal/api/routes/interactions"
+	"github.com/asianchinaboi/backendserver/internal/api/routes/oauth2"
 	"github.com/asianchinaboi/backendserver/internal/api/routes/static"
 	"github.com/asianchinaboi/backendserver/internal/api/routes/status"
+	"github.com/asianchinaboi/backendserver/internal/api/routes/uploads"
 	"github.com/asianchinaboi/backendserver/internal/api/routes/users"
+	"github.com/asianchinaboi/backendserver/internal/api/routes/webhooks"
 	"github.com/asianchinaboi/backendserver/internal/api/routes/ws"
 	"github.com/gin-gonic/gin"
 )
 
-func PrepareRoutes(r *gin.Engine) {
-	r.Use(middleware.CheckIP)
+func PrepareRoutes(r *gin.Engine, d *deps.Deps) {
+	r.Use(middleware.RequestId(d.Log))
+	r.Use(middleware.Metrics)
+	r.Use(middleware.Tracing)
+	status.RootRoutes(r, d) //before the ip check so probes still answer when the db is down
+	r.Use(middleware.CheckIP(d.Db))
 	static.Routes(r)
 	apiRoute := r.Group("/api")
-	apiRoute.Use(middleware.Cooldown)
-	admin.Routes(apiRoute)
-	status.Routes(apiRoute)
-	guilds.Routes(apiRoute)
-	users.Routes(apiRoute)
-	ws.Routes(apiRoute)
-	files.Routes(apiRoute)
+	apiRoute.Use(middleware.Cooldown(d.Live, d.Limiter, d.Strikes, d.Db))
+	admin.Routes(apiRoute, d)
+	status.Routes(apiRoute, d)
+	captcha.Routes(apiRoute, d)
+	guilds.Routes(apiRoute, d)
+	users.Routes(apiRoute, d)
+	ws.Routes(apiRoute, d)
+	files.Routes(apiRoute, d)
+	oauth2.Routes(apiRoute, d)
+	webhooks.Routes(apiRoute, d)
+	interactions.Routes(apiRoute, d)
+	uploads.Routes(apiRoute, d)
 }
@@ -0,0 +1,49 @@
+package interactions
+
+import (
+	"regexp"
+	"strconv"
+
+	"github.com/asianchinaboi/backendserver/internal/api/middleware"
+	"github.com/asianchinaboi/backendserver/internal/api/routes/guilds/msgs"
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/asianchinaboi/backendserver/internal/session"
+	"github.com/gin-gonic/gin"
+)
+
+// expects the same body as sending a message
+// ephemeral : bool (optional) only the user who used the command sees it
+func (h *Handlers) callback(c *gin.Context) {
+	user := c.MustGet(middleware.User).(*session.Session)
+	if user == nil {
+		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
+		return
+	}
+	if !user.Bot {
+		errors.SendErrorResponse(c, errors.ErrBotOnly, errors.StatusBotOnly)
+		return
+	}
+
+	interactionId := c.Param("interactionId")
+	if match, err := regexp.MatchString("^[0-9]+$", interactionId); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if !match {
+		errors.SendErrorResponse(c, errors.ErrRouteParamInvalid, errors.StatusRouteParamInvalid)
+		return
+	}
+
+	intInteractionId, err := strconv.ParseInt(interactionId, 10, 64)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	pending, ok := h.Interactions.Get(intInteractionId, user.Id)
+	if !ok {
+		errors.SendErrorResponse(c, errors.ErrInteractionNotExist, errors.StatusInteractionNotExist)
+		return
+	}
+
+	msgs.New(h.Deps).SendInteractionResponse(c, pending)
+}
@@ -0,0 +1,24 @@
+package interactions
+
+import (
+	"github.com/asianchinaboi/backendserver/internal/api/deps"
+	"github.com/asianchinaboi/backendserver/internal/api/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// Handlers are the interactions routes, Routes builds them from what the app hands over
+type Handlers struct {
+	*deps.Deps
+}
+
+func New(d *deps.Deps) *Handlers {
+	return &Handlers{d}
+}
+
+func Routes(r *gin.RouterGroup, d *deps.Deps) {
+	h := New(d)
+
+	interactions := r.Group("/interactions")
+	interactions.Use(middleware.Auth(d.Db))
+	interactions.POST("/:interactionId/callback", h.callback)
+}
@@ -0,0 +1,174 @@
+package oauth2
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"regexp"
+
+	"github.com/asianchinaboi/backendserver/internal/api/middleware"
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/asianchinaboi/backendserver/internal/events"
+	"github.com/asianchinaboi/backendserver/internal/outbox"
+	"github.com/asianchinaboi/backendserver/internal/session"
+	"github.com/asianchinaboi/backendserver/internal/wsclient"
+	"github.com/gin-gonic/gin"
+)
+
+type authorizeBody struct {
+	ClientId int64 `json:"clientId,string"` //bot user id
+	GuildId  int64 `json:"guildId,string"`
+}
+
+// shows the bot that is asking to be added so the client can make a consent screen
+func (h *Handlers) getAuthorize(c *gin.Context) {
+	user := c.MustGet(middleware.User).(*session.Session)
+	if user == nil {
+		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
+		return
+	}
+
+	clientId := c.Query("clientId")
+	if match, err := regexp.MatchString("^[0-9]+$", clientId); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if !match {
+		errors.SendErrorResponse(c, errors.ErrRouteParamInvalid, errors.StatusRouteParamInvalid)
+		return
+	}
+
+	var bot events.Bot
+	var imageId sql.NullInt64
+	if err := h.Db.QueryRow(`SELECT b.user_id, u.username, f.id, b.owner_id, b.created FROM bots b INNER JOIN users u ON u.id = b.user_id 
+	LEFT JOIN files f ON f.user_id = b.user_id WHERE b.user_id = $1`, clientId).Scan(&bot.UserInfo.UserId, &bot.UserInfo.Name, &imageId, &bot.OwnerId, &bot.Created); err != nil && err != sql.ErrNoRows {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if err == sql.ErrNoRows {
+		errors.SendErrorResponse(c, errors.ErrBotNotExist, errors.StatusBotNotExist)
+		return
+	}
+	if imageId.Valid {
+		bot.UserInfo.ImageId = imageId.Int64
+	} else {
+		bot.UserInfo.ImageId = -1
+	}
+	c.JSON(http.StatusOK, bot)
+}
+
+// adds a bot to a guild, only the owner or admins of the guild can do this
+func (h *Handlers) authorize(c *gin.Context) {
+	user := c.MustGet(middleware.User).(*session.Session)
+	if user == nil {
+		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
+		return
+	}
+
+	var body authorizeBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusBadRequest)
+		return
+	}
+
+	var guildExists bool
+	var isDm bool
+	var hasAuth bool
+	var botExists bool
+	var botInGuild bool
+	if err := h.Db.QueryRow(`SELECT EXISTS (SELECT 1 FROM guilds WHERE id = $1),
+	EXISTS (SELECT 1 FROM guilds WHERE id = $1 AND dm = true),
+	EXISTS (SELECT 1 FROM userguilds WHERE guild_id = $1 AND user_id = $2 AND (owner = true OR admin = true)),
+	EXISTS (SELECT 1 FROM bots WHERE user_id = $3),
+	EXISTS (SELECT 1 FROM userguilds WHERE guild_id = $1 AND user_id = $3)`, body.GuildId, user.Id, body.ClientId).Scan(&guildExists, &isDm, &hasAuth, &botExists, &botInGuild); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	if !guildExists {
+		errors.SendErrorResponse(c, errors.ErrGuildNotExist, errors.StatusGuildNotExist)
+		return
+	}
+	if isDm {
+		errors.SendErrorResponse(c, errors.ErrGuildIsDm, errors.StatusGuildIsDm)
+		return
+	}
+	if !hasAuth {
+		errors.SendErrorResponse(c, errors.ErrNotGuildAuthorised, errors.StatusNotGuildAuthorised)
+		return
+	}
+	if !botExists {
+		errors.SendErrorResponse(c, errors.ErrBotNotExist, errors.StatusBotNotExist)
+		return
+	}
+	if botInGuild {
+		errors.SendErrorResponse(c, errors.ErrAlreadyInGuild, errors.StatusAlreadyInGuild)
+		return
+	}
+
+	var guild events.Guild
+	var imageId sql.NullInt64
+	if err := h.Db.QueryRow(`SELECT g.id, g.name, f.id, ug.user_id FROM guilds g 
+	INNER JOIN userguilds ug ON ug.guild_id = g.id AND owner = true 
+	LEFT JOIN files f ON f.guild_id = g.id WHERE g.id = $1`, body.GuildId).Scan(&guild.GuildId, &guild.Name, &imageId, &guild.OwnerId); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	if imageId.Valid {
+		guild.ImageId = imageId.Int64
+	} else {
+		guild.ImageId = -1
+	}
+
+	//BEGIN TRANSACTION
+	ctx := context.Background()
+	tx, err := h.Db.BeginTx(ctx, nil)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	defer tx.Rollback() //rollback changes if failed
+
+	if _, err := tx.ExecContext(ctx, "INSERT INTO userguilds (guild_id, user_id) VALUES ($1, $2)", body.GuildId, body.ClientId); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	if _, err := tx.ExecContext(ctx, "INSERT INTO unreadmsgs (guild_id, user_id) VALUES ($1, $2)", body.GuildId, body.ClientId); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	botData := events.Member{
+		GuildId: body.GuildId,
+	}
+	if err := h.Db.QueryRow("SELECT username, flags, files.id FROM users LEFT JOIN files ON files.user_id = users.id WHERE users.id=$1", body.ClientId).Scan(&botData.UserInfo.Name, &botData.UserInfo.Flags, &imageId); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	if imageId.Valid {
+		botData.UserInfo.ImageId = imageId.Int64
+	} else {
+		botData.UserInfo.ImageId = -1
+	}
+	botData.UserInfo.UserId = body.ClientId
+	if err := outbox.Enqueue(ctx, tx, body.GuildId, events.MEMBER_ADD, botData); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil { //commits the transaction
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	h.Pools.BroadcastClient(body.ClientId, wsclient.DataFrame{
+		Op:    wsclient.TYPE_DISPATCH,
+		Data:  guild,
+		Event: events.GUILD_CREATE,
+	})
+	h.Pools.BroadcastGuild(body.GuildId, wsclient.DataFrame{
+		Op:    wsclient.TYPE_DISPATCH,
+		Data:  botData,
+		Event: events.MEMBER_ADD,
+	})
+	h.Pools.AddUserToGuildPool(body.GuildId, body.ClientId)
+	c.Status(http.StatusNoContent)
+}
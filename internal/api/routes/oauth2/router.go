@@ -0,0 +1,25 @@
+package oauth2
+
+import (
+	"github.com/asianchinaboi/backendserver/internal/api/deps"
+	"github.com/asianchinaboi/backendserver/internal/api/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// Handlers are the oauth2 routes, Routes builds them from what the app hands over
+type Handlers struct {
+	*deps.Deps
+}
+
+func New(d *deps.Deps) *Handlers {
+	return &Handlers{d}
+}
+
+func Routes(r *gin.RouterGroup, d *deps.Deps) {
+	h := New(d)
+
+	oauth2 := r.Group("/oauth2")
+	oauth2.Use(middleware.Auth(d.Db), middleware.UserOnly)
+	oauth2.GET("/authorize", h.getAuthorize) //query param clientId
+	oauth2.POST("/authorize", h.authorize)
+}
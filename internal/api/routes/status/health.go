@@ -0,0 +1,68 @@
+package status
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/asianchinaboi/backendserver/internal/logger"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	checkOk          = "ok"
+	checkUnavailable = "unavailable" //errors are only logged since these routes arent behind auth
+)
+
+type readiness struct {
+	Database  string `json:"database"`
+	Storage   string `json:"storage"`
+	Scheduler string `json:"scheduler"`
+	Draining  bool   `json:"draining"`
+}
+
+// Healthz is the liveness check, if this doesnt answer the process should be restarted
+func (h *Handlers) Healthz(c *gin.Context) {
+	c.String(http.StatusOK, checkOk)
+}
+
+// Readyz is the readiness check, it fails while draining so load balancers stop sending traffic here
+func (h *Handlers) Readyz(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+
+	ready := readiness{Database: checkOk, Storage: checkOk, Scheduler: checkOk, Draining: h.Pools.Draining()}
+	ok := !ready.Draining
+	if err := h.Db.PingContext(ctx); err != nil {
+		logger.Ctx(c).Warn("readiness: database", "err", err)
+		ready.Database = checkUnavailable
+		ok = false
+	}
+	if err := h.checkStorage(ctx); err != nil {
+		logger.Ctx(c).Warn("readiness: storage", "err", err)
+		ready.Storage = checkUnavailable
+		ok = false
+	}
+	if !h.Scheduler() {
+		ready.Scheduler = checkUnavailable
+		ok = false
+	}
+
+	if !ok {
+		c.JSON(http.StatusServiceUnavailable, ready)
+		return
+	}
+	c.JSON(http.StatusOK, ready)
+}
+
+// checkStorage writes and removes a small object, every instance has its own key so they dont fight over it
+func (h *Handlers) checkStorage(ctx context.Context) error {
+	key := fmt.Sprintf("health/%d", h.NodeId)
+	data := []byte(time.Now().UTC().Format(time.RFC3339))
+	if err := h.Store.Put(ctx, key, bytes.NewReader(data), int64(len(data))); err != nil {
+		return err
+	}
+	return h.Store.Delete(ctx, key)
+}
@@ -0,0 +1,49 @@
+package status
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/asianchinaboi/backendserver/internal/logger"
+	"github.com/asianchinaboi/backendserver/internal/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// ShowMetrics is the prometheus endpoint, scrapers send the metrics token as a bearer token
+// nothing here touches the tables so scraping often is cheap
+func (h *Handlers) ShowMetrics(c *gin.Context) {
+	if !h.checkMetricsToken(c) {
+		return
+	}
+
+	metrics.WebsocketUsers.Set(float64(h.Pools.GetLengthClients()))
+	metrics.GuildPools.Set(float64(h.Pools.GetLengthGuilds()))
+	stats := h.Db.Stats()
+	metrics.DBOpenConnections.Set(float64(stats.OpenConnections))
+	metrics.DBInUse.Set(float64(stats.InUse))
+	metrics.DBIdle.Set(float64(stats.Idle))
+	metrics.DBWaitCount.Set(float64(stats.WaitCount))
+	metrics.DBWaitDuration.Set(stats.WaitDuration.Seconds())
+	metrics.DBMaxIdleClosed.Set(float64(stats.MaxIdleClosed))
+	metrics.DBMaxLifetimeClose.Set(float64(stats.MaxLifetimeClosed))
+
+	c.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.Status(http.StatusOK)
+	if err := metrics.Write(c.Writer); err != nil {
+		logger.Ctx(c).Warn("unable to write metrics", "err", err)
+	}
+}
+
+// checkMetricsToken sends 404 when theres no metrics token and 401 when its wrong, returns whether to go on
+func (h *Handlers) checkMetricsToken(c *gin.Context) bool {
+	token := h.Config.Server.MetricsToken
+	if token == "" {
+		c.Status(http.StatusNotFound)
+		return false
+	}
+	if subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), []byte("Bearer "+token)) != 1 {
+		c.Status(http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
@@ -1,8 +1,31 @@
 package status
 
-import "github.com/gin-gonic/gin"
+import (
+	"github.com/asianchinaboi/backendserver/internal/api/deps"
+	"github.com/gin-gonic/gin"
+)
+
+// Handlers are the status routes, Routes builds them from what the app hands over
+type Handlers struct {
+	*deps.Deps
+}
+
+func New(d *deps.Deps) *Handlers {
+	return &Handlers{d}
+}
+
+func Routes(r *gin.RouterGroup, d *deps.Deps) {
+	h := New(d)
 
-func Routes(r *gin.RouterGroup) {
 	status := r.Group("/status")
-	status.GET("/", ShowStatus)
+	status.GET("/", h.ShowStatus)
+}
+
+// RootRoutes are outside of /api so scrapes and probes dont count towards the rate limits
+func RootRoutes(r *gin.Engine, d *deps.Deps) {
+	h := New(d)
+
+	r.GET("/metrics", h.ShowMetrics)
+	r.GET("/healthz", h.Healthz)
+	r.GET("/readyz", h.Readyz)
 }
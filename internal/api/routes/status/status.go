@@ -3,9 +3,7 @@ package status
 import (
 	"net/http"
 
-	"github.com/asianchinaboi/backendserver/internal/db"
 	"github.com/asianchinaboi/backendserver/internal/errors"
-	"github.com/asianchinaboi/backendserver/internal/wsclient"
 	"github.com/gin-gonic/gin"
 )
 
@@ -17,31 +15,36 @@ type statusInfo struct {
 	GuildPoolNumber int `json:"guildPoolNumber"`
 }
 
-func ShowStatus(c *gin.Context) { //debugging
+// ShowStatus counts whole tables so it needs the metrics token like /metrics does
+func (h *Handlers) ShowStatus(c *gin.Context) { //debugging
+	if !h.checkMetricsToken(c) {
+		return
+	}
 	var msgNumber int
-	if err := db.Db.QueryRow("SELECT COUNT(*) FROM msgs").Scan(&msgNumber); err != nil {
+	if err := h.Db.QueryRow("SELECT COUNT(*) FROM msgs").Scan(&msgNumber); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
 
 	var guildNumber int
-	if err := db.Db.QueryRow("SELECT COUNT(*) FROM guilds").Scan(&guildNumber); err != nil {
+	if err := h.Db.QueryRow("SELECT COUNT(*) FROM guilds").Scan(&guildNumber); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
 
 	var fileNumber int
-	if err := db.Db.QueryRow("SELECT COUNT(*) FROM files").Scan(&fileNumber); err != nil {
+	if err := h.Db.QueryRow("SELECT COUNT(*) FROM files").Scan(&fileNumber); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
 
-	guildPoolNumber := wsclient.Pools.GetLengthGuilds()
+	guildPoolNumber := h.Pools.GetLengthGuilds()
 
 	status := statusInfo{
-		ClientNumber:    wsclient.Pools.GetLengthClients(),
+		ClientNumber:    h.Pools.GetLengthClients(),
 		GuildNumber:     guildNumber,
 		MsgNumber:       msgNumber,
+		FileNumber:      fileNumber,
 		GuildPoolNumber: guildPoolNumber,
 	}
 	c.JSON(http.StatusOK, status)
@@ -0,0 +1,45 @@
+package status_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/asianchinaboi/backendserver/internal/api/deps"
+	"github.com/asianchinaboi/backendserver/internal/api/routes/status"
+	"github.com/asianchinaboi/backendserver/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// theres no db so the counts would panic if the token check let the request through
+func TestStatusNeedsMetricsToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	conf, err := config.Load(config.Sources{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	status.Routes(r.Group("/api"), &deps.Deps{Config: conf})
+
+	for _, test := range []struct {
+		token  string
+		header string
+		want   int
+	}{
+		{"", "", http.StatusNotFound},
+		{"", "Bearer ", http.StatusNotFound},
+		{"secret", "", http.StatusUnauthorized},
+		{"secret", "Bearer wrong", http.StatusUnauthorized},
+	} {
+		conf.Server.MetricsToken = test.token
+		req := httptest.NewRequest(http.MethodGet, "/api/status/", nil)
+		if test.header != "" {
+			req.Header.Set("Authorization", test.header)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != test.want {
+			t.Errorf("token %q header %q: got %d, want %d", test.token, test.header, w.Code, test.want)
+		}
+	}
+}
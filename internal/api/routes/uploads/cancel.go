@@ -0,0 +1,55 @@
+package uploads
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/asianchinaboi/backendserver/internal/api/middleware"
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/asianchinaboi/backendserver/internal/logger"
+	"github.com/asianchinaboi/backendserver/internal/session"
+	"github.com/asianchinaboi/backendserver/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handlers) cancel(c *gin.Context) {
+	user := c.MustGet(middleware.User).(*session.Session)
+	if user == nil {
+		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
+		return
+	}
+
+	uploadId := c.Param("uploadId")
+	if match, err := regexp.MatchString("^[0-9]+$", uploadId); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if !match {
+		errors.SendErrorResponse(c, errors.ErrRouteParamInvalid, errors.StatusRouteParamInvalid)
+		return
+	}
+	intUploadId, err := strconv.ParseInt(uploadId, 10, 64)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	result, err := h.Db.Exec("DELETE FROM uploads WHERE id = $1 AND user_id = $2", uploadId, user.Id)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if affected == 0 {
+		errors.SendErrorResponse(c, errors.ErrUploadNotExist, errors.StatusUploadNotExist)
+		return
+	}
+
+	if err := storage.DeletePrefix(context.Background(), h.Store, storage.ChunkPrefix(intUploadId)); err != nil {
+		logger.Ctx(c).Warn("unable to remove chunks", "err", err)
+	}
+	c.Status(http.StatusNoContent)
+}
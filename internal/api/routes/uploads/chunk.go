@@ -0,0 +1,133 @@
+package uploads
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/asianchinaboi/backendserver/internal/api/middleware"
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/asianchinaboi/backendserver/internal/events"
+	"github.com/asianchinaboi/backendserver/internal/logger"
+	"github.com/asianchinaboi/backendserver/internal/session"
+	"github.com/asianchinaboi/backendserver/internal/storage"
+	"github.com/asianchinaboi/backendserver/internal/uid"
+	"github.com/gin-gonic/gin"
+)
+
+// body is the raw bytes of the chunk
+// offset query param has to be the number of bytes received so far
+func (h *Handlers) putChunk(c *gin.Context) {
+	user := c.MustGet(middleware.User).(*session.Session)
+	if user == nil {
+		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
+		return
+	}
+
+	uploadId := c.Param("uploadId")
+	if match, err := regexp.MatchString("^[0-9]+$", uploadId); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if !match {
+		errors.SendErrorResponse(c, errors.ErrRouteParamInvalid, errors.StatusRouteParamInvalid)
+		return
+	}
+	intUploadId, err := strconv.ParseInt(uploadId, 10, 64)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.Query("offset"), 10, 64)
+	if err != nil || offset < 0 {
+		errors.SendErrorResponse(c, errors.ErrRouteParamInvalid, errors.StatusRouteParamInvalid)
+		return
+	}
+
+	var upload events.Upload
+	if err := h.Db.QueryRow("SELECT id, filename, filesize, received FROM uploads WHERE id = $1 AND user_id = $2", uploadId, user.Id).Scan(
+		&upload.UploadId, &upload.Filename, &upload.Size, &upload.Received); err != nil && err != sql.ErrNoRows {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if err == sql.ErrNoRows {
+		errors.SendErrorResponse(c, errors.ErrUploadNotExist, errors.StatusUploadNotExist)
+		return
+	}
+	if offset != upload.Received {
+		errors.SendErrorResponse(c, errors.ErrUploadOffsetMismatch, errors.StatusUploadOffsetMismatch)
+		return
+	}
+
+	//chunks are small enough to keep in memory, reading one more byte shows if it was too big
+	maxChunkSize := int64(h.Config.Server.MaxChunkSize)
+	chunk, err := io.ReadAll(io.LimitReader(c.Request.Body, maxChunkSize+1))
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusBadRequest)
+		return
+	}
+	chunkSize := int64(len(chunk))
+	if chunkSize > maxChunkSize {
+		errors.SendErrorResponse(c, errors.ErrUploadChunkTooLarge, errors.StatusUploadChunkTooLarge)
+		return
+	} else if chunkSize == 0 {
+		errors.SendErrorResponse(c, errors.ErrFileNoBytes, errors.StatusFileNoBytes)
+		return
+	} else if offset+chunkSize > upload.Size {
+		errors.SendErrorResponse(c, errors.ErrFileTooLarge, errors.StatusFileTooLarge)
+		return
+	}
+
+	chunkId := uid.Snowflake.Generate().Int64()
+	key := storage.ChunkKey(intUploadId, offset, chunkId)
+	if err := h.Store.Put(c.Request.Context(), key, bytes.NewReader(chunk), chunkSize); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	successful := false
+	defer func() {
+		if !successful {
+			if err := h.Store.Delete(context.Background(), key); err != nil {
+				logger.Ctx(c).Warn("unable to remove chunk", "err", err)
+			}
+		}
+	}()
+
+	//BEGIN TRANSACTION
+	ctx := context.Background()
+	tx, err := h.Db.BeginTx(ctx, nil)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	defer tx.Rollback()
+
+	//only counts if nothing else got in first
+	var updated time.Time
+	if err := tx.QueryRowContext(ctx, "UPDATE uploads SET received = received + $1, updated = now() WHERE id = $2 AND received = $3 RETURNING received, updated",
+		chunkSize, uploadId, offset).Scan(&upload.Received, &updated); err != nil && err != sql.ErrNoRows {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if err == sql.ErrNoRows {
+		errors.SendErrorResponse(c, errors.ErrUploadOffsetMismatch, errors.StatusUploadOffsetMismatch)
+		return
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO upload_chunks (upload_id, chunk_offset, chunk_id) VALUES ($1, $2, $3)", uploadId, offset, chunkId); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil { //commits the transaction
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	successful = true
+	upload.MaxChunkSize = h.Config.Server.MaxChunkSize
+	upload.Expires = updated.Add(h.Config.Server.UploadSessionAlive)
+
+	c.JSON(http.StatusOK, upload)
+}
@@ -0,0 +1,73 @@
+package uploads
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/asianchinaboi/backendserver/internal/api/middleware"
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/asianchinaboi/backendserver/internal/events"
+	"github.com/asianchinaboi/backendserver/internal/quota"
+	"github.com/asianchinaboi/backendserver/internal/session"
+	"github.com/asianchinaboi/backendserver/internal/uid"
+	"github.com/gin-gonic/gin"
+)
+
+type createBody struct {
+	Filename string `json:"filename"`
+	Size     int64  `json:"size"`
+}
+
+// expects
+// filename : string
+// size : int (total bytes)
+func (h *Handlers) create(c *gin.Context) {
+	user := c.MustGet(middleware.User).(*session.Session)
+	if user == nil {
+		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
+		return
+	}
+
+	var body createBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusBadRequest)
+		return
+	}
+	if body.Filename == "" || len(body.Filename) > 255 {
+		errors.SendErrorResponse(c, errors.ErrFileInvalid, errors.StatusFileInvalid)
+		return
+	}
+	if body.Size > int64(h.Config.Server.MaxFileSize) {
+		errors.SendErrorResponse(c, errors.ErrFileTooLarge, errors.StatusFileTooLarge)
+		return
+	} else if body.Size <= 0 {
+		errors.SendErrorResponse(c, errors.ErrFileNoBytes, errors.StatusFileNoBytes)
+		return
+	}
+
+	usage, err := quota.User(c.Request.Context(), h.Db, h.Config, user.Id)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	if quota.Exceeds(usage, body.Size) {
+		errors.SendErrorResponse(c, errors.ErrQuotaUserExceeded, errors.StatusQuotaExceeded)
+		return
+	}
+
+	upload := events.Upload{
+		UploadId:     uid.Snowflake.Generate().Int64(),
+		Filename:     body.Filename,
+		Size:         body.Size,
+		MaxChunkSize: h.Config.Server.MaxChunkSize,
+	}
+	var updated time.Time
+	if err := h.Db.QueryRow("INSERT INTO uploads (id, user_id, filename, filesize) VALUES ($1, $2, $3, $4) RETURNING updated",
+		upload.UploadId, user.Id, upload.Filename, upload.Size).Scan(&updated); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	upload.Expires = updated.Add(h.Config.Server.UploadSessionAlive)
+
+	c.JSON(http.StatusOK, upload)
+}
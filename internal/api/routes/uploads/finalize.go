@@ -0,0 +1,223 @@
+package uploads
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/asianchinaboi/backendserver/internal/api/middleware"
+	"github.com/asianchinaboi/backendserver/internal/blobs"
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/asianchinaboi/backendserver/internal/events"
+	"github.com/asianchinaboi/backendserver/internal/files"
+	"github.com/asianchinaboi/backendserver/internal/logger"
+	"github.com/asianchinaboi/backendserver/internal/quota"
+	"github.com/asianchinaboi/backendserver/internal/scanner"
+	"github.com/asianchinaboi/backendserver/internal/session"
+	"github.com/asianchinaboi/backendserver/internal/storage"
+	"github.com/asianchinaboi/backendserver/internal/transcode"
+	"github.com/asianchinaboi/backendserver/internal/uid"
+	"github.com/gin-gonic/gin"
+)
+
+// reads the chunks one after another without opening them all at once
+type chunkReader struct {
+	ctx     context.Context
+	store   storage.Backend
+	keys    []string
+	current io.ReadCloser
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.keys) == 0 {
+				return 0, io.EOF
+			}
+			object, err := r.store.Get(r.ctx, r.keys[0])
+			if err != nil {
+				return 0, err
+			}
+			r.current = object
+			r.keys = r.keys[1:]
+		}
+		n, err := r.current.Read(p)
+		if err == io.EOF {
+			r.current.Close()
+			r.current = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (r *chunkReader) Close() error {
+	if r.current == nil {
+		return nil
+	}
+	return r.current.Close()
+}
+
+// joins the chunks into a temporary msg file
+// the file stays temporary until a message uses it so unused ones get cleaned up like any other temp file
+func (h *Handlers) finalize(c *gin.Context) {
+	user := c.MustGet(middleware.User).(*session.Session)
+	if user == nil {
+		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
+		return
+	}
+
+	uploadId := c.Param("uploadId")
+	if match, err := regexp.MatchString("^[0-9]+$", uploadId); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if !match {
+		errors.SendErrorResponse(c, errors.ErrRouteParamInvalid, errors.StatusRouteParamInvalid)
+		return
+	}
+	intUploadId, err := strconv.ParseInt(uploadId, 10, 64)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	//BEGIN TRANSACTION
+	ctx := context.Background()
+	tx, err := h.Db.BeginTx(ctx, nil)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	defer tx.Rollback()
+
+	var filename string
+	var filesize int64
+	var received int64
+	if err := tx.QueryRowContext(ctx, "SELECT filename, filesize, received FROM uploads WHERE id = $1 AND user_id = $2 FOR UPDATE", uploadId, user.Id).Scan(&filename, &filesize, &received); err != nil && err != sql.ErrNoRows {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if err == sql.ErrNoRows {
+		errors.SendErrorResponse(c, errors.ErrUploadNotExist, errors.StatusUploadNotExist)
+		return
+	}
+	if received != filesize {
+		errors.SendErrorResponse(c, errors.ErrUploadIncomplete, errors.StatusUploadIncomplete)
+		return
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT chunk_offset, chunk_id FROM upload_chunks WHERE upload_id = $1 ORDER BY chunk_offset", uploadId)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	keys := []string{}
+	for rows.Next() {
+		var offset int64
+		var chunkId int64
+		if err := rows.Scan(&offset, &chunkId); err != nil {
+			rows.Close()
+			errors.SendErrorResponse(c, err, errors.StatusInternalError)
+			return
+		}
+		keys = append(keys, storage.ChunkKey(intUploadId, offset, chunkId))
+	}
+	rows.Close()
+
+	//the chunks get read twice, once for the hash and once to store them
+	chunks := files.NewSeeker(filesize, func() (io.ReadCloser, error) {
+		return &chunkReader{ctx: c.Request.Context(), store: h.Store, keys: keys}, nil
+	})
+	defer chunks.Close()
+	head := make([]byte, 512)
+	n, err := io.ReadFull(chunks, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	attachment := events.Attachment{
+		Id:       uid.Snowflake.Generate().Int64(),
+		Filename: filename,
+		Type:     http.DetectContentType(head[:n]),
+	}
+
+	attachment.ContentType = attachment.Type
+
+	//strips exif from images and works out the dimensions and duration
+	content, meta, err := files.Inspect(chunks, filesize, attachment.Type)
+	if err == errors.ErrFileInvalid {
+		errors.SendErrorResponse(c, err, errors.StatusFileInvalid)
+		return
+	} else if err == errors.ErrFileTooLarge {
+		errors.SendErrorResponse(c, err, errors.StatusFileTooLarge)
+		return
+	} else if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	attachment.Size = meta.Size
+	attachment.Width = meta.Width
+	attachment.Height = meta.Height
+	attachment.Blurhash = meta.Blurhash
+	attachment.Duration = meta.Duration
+
+	//uploads arent tied to a guild until theyre sent so the default policy is used
+	verdict, err := scanner.Check(ctx, h.Scanner, content, h.Config.Scanner.Policy)
+	if err == errors.ErrScannerUnavailable {
+		errors.SendErrorResponse(c, err, errors.StatusScannerUnavailable)
+		return
+	} else if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	attachment.Quarantined = verdict.Quarantined
+
+	hash, err := blobs.Put(ctx, tx, h.Store, content)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO files (id, filename, created, temp, filesize, filetype, entity_type, uploader_id, hash, width, height, blurhash, duration, quarantined, quarantine_reason)
+		VALUES ($1, $2, now(), true, $3, $4, 'msg', $5, $6, NULLIF($7, 0), NULLIF($8, 0), NULLIF($9, ''), NULLIF($10, 0), $11, NULLIF($12, ''))`,
+		attachment.Id, filename, attachment.Size, attachment.Type, user.Id, hash, attachment.Width, attachment.Height, attachment.Blurhash, attachment.Duration, verdict.Quarantined, verdict.Reason); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	if !verdict.Quarantined {
+		if attachment.Transcode, err = transcode.Enqueue(ctx, tx, h.Config, hash, attachment.Type); err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusInternalError)
+			return
+		}
+	}
+
+	//the upload counts against the user from now on even before its sent
+	if err := quota.Check(ctx, tx, h.Config, user.Id, 0); err == errors.ErrQuotaUserExceeded {
+		errors.SendErrorResponse(c, err, errors.StatusQuotaExceeded)
+		return
+	} else if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM uploads WHERE id = $1", uploadId); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil { //commits the transaction
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	if err := storage.DeletePrefix(context.Background(), h.Store, storage.ChunkPrefix(intUploadId)); err != nil {
+		logger.Ctx(c).Warn("unable to remove chunks", "err", err) //schedule gets them later
+	}
+
+	c.JSON(http.StatusOK, attachment)
+}
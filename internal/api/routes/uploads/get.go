@@ -0,0 +1,47 @@
+package uploads
+
+import (
+	"database/sql"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/asianchinaboi/backendserver/internal/api/middleware"
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/asianchinaboi/backendserver/internal/events"
+	"github.com/asianchinaboi/backendserver/internal/session"
+	"github.com/gin-gonic/gin"
+)
+
+// shows how much was received so a failed upload can carry on from there
+func (h *Handlers) get(c *gin.Context) {
+	user := c.MustGet(middleware.User).(*session.Session)
+	if user == nil {
+		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
+		return
+	}
+
+	uploadId := c.Param("uploadId")
+	if match, err := regexp.MatchString("^[0-9]+$", uploadId); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if !match {
+		errors.SendErrorResponse(c, errors.ErrRouteParamInvalid, errors.StatusRouteParamInvalid)
+		return
+	}
+
+	var upload events.Upload
+	var updated time.Time
+	if err := h.Db.QueryRow("SELECT id, filename, filesize, received, updated FROM uploads WHERE id = $1 AND user_id = $2", uploadId, user.Id).Scan(
+		&upload.UploadId, &upload.Filename, &upload.Size, &upload.Received, &updated); err != nil && err != sql.ErrNoRows {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if err == sql.ErrNoRows {
+		errors.SendErrorResponse(c, errors.ErrUploadNotExist, errors.StatusUploadNotExist)
+		return
+	}
+	upload.MaxChunkSize = h.Config.Server.MaxChunkSize
+	upload.Expires = updated.Add(h.Config.Server.UploadSessionAlive)
+
+	c.JSON(http.StatusOK, upload)
+}
@@ -0,0 +1,32 @@
+package uploads
+
+import (
+	"github.com/asianchinaboi/backendserver/internal/api/deps"
+	"github.com/asianchinaboi/backendserver/internal/api/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+//chunked uploads for files too big to send in one request
+//create the upload, PUT chunks in order with ?offset=, then finalize it
+//the file id from finalize goes in "uploads" when sending a message
+
+// Handlers are the uploads routes, Routes builds them from what the app hands over
+type Handlers struct {
+	*deps.Deps
+}
+
+func New(d *deps.Deps) *Handlers {
+	return &Handlers{d}
+}
+
+func Routes(r *gin.RouterGroup, d *deps.Deps) {
+	h := New(d)
+
+	uploads := r.Group("/uploads")
+	uploads.Use(middleware.Auth(d.Db))
+	uploads.POST("/", h.create)
+	uploads.GET("/:uploadId", h.get)
+	uploads.PUT("/:uploadId", h.putChunk)
+	uploads.POST("/:uploadId/finalize", h.finalize)
+	uploads.DELETE("/:uploadId", h.cancel)
+}
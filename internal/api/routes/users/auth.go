@@ -4,7 +4,6 @@ import (
 	"database/sql"
 	"net/http"
 
-	"github.com/asianchinaboi/backendserver/internal/db"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
 	"github.com/asianchinaboi/backendserver/internal/logger"
@@ -12,8 +11,8 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func userAuth(c *gin.Context) {
-	logger.Debug.Println("Getting user")
+func (h *Handlers) userAuth(c *gin.Context) {
+	logger.Ctx(c).Debug("getting user")
 
 	var user events.User
 
@@ -22,7 +21,7 @@ func userAuth(c *gin.Context) {
 		return
 	}
 	var userHashedPass string
-	if err := db.Db.QueryRow("SELECT password, id FROM users WHERE username = $1", user.Name).Scan(&userHashedPass, &user.UserId); err != nil && err != sql.ErrNoRows {
+	if err := h.Db.QueryRow("SELECT password, id FROM users WHERE username = $1", user.Name).Scan(&userHashedPass, &user.UserId); err != nil && err != sql.ErrNoRows {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	} else if err != nil {
@@ -34,7 +33,7 @@ func userAuth(c *gin.Context) {
 		return
 	}
 
-	authData, err := session.GenToken(user.UserId)
+	authData, err := session.GenToken(h.Db, h.Config.User.TokenExpireTime, user.UserId)
 	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
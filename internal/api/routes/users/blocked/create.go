@@ -8,7 +8,6 @@ import (
 	"strconv"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/db"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
 	"github.com/asianchinaboi/backendserver/internal/session"
@@ -16,7 +15,7 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func Create(c *gin.Context) {
+func (h *Handlers) Create(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -39,7 +38,7 @@ func Create(c *gin.Context) {
 	}
 
 	var isBlocked bool
-	if err := db.Db.QueryRow(`
+	if err := h.Db.QueryRow(`
 		SELECT EXISTS (SELECT 1 FROM blocked WHERE user_id = $1 AND blocked_id = $2)
 		`, user.Id, userId).Scan(&isBlocked); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
@@ -51,14 +50,14 @@ func Create(c *gin.Context) {
 	}
 
 	var isFriends bool
-	if err := db.Db.QueryRow(`
+	if err := h.Db.QueryRow(`
 		SELECT EXISTS (SELECT 1 FROM friends WHERE user_id = $1 AND friend_id = $2)
 		`, user.Id, userId).Scan(&isFriends); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
 	ctx := context.Background()
-	tx, err := db.Db.BeginTx(ctx, nil)
+	tx, err := h.Db.BeginTx(ctx, nil)
 	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
@@ -83,7 +82,7 @@ func Create(c *gin.Context) {
 	var hasBeenRequested bool
 	var isTheRequestor bool
 
-	if err := db.Db.QueryRow(`
+	if err := h.Db.QueryRow(`
 	SELECT EXISTS(SELECT 1 FROM friends WHERE (user_id = $1 AND friend_id = $2) AND friended = false), EXISTS(SELECT 1 FROM friends WHERE (user_id = $2 AND friend_id = $1) AND friended = false)
 	`, userId, user.Id).Scan(&hasBeenRequested, &isTheRequestor); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
@@ -91,7 +90,7 @@ func Create(c *gin.Context) {
 	}
 
 	if isTheRequestor || hasBeenRequested {
-		if _, err := db.Db.Exec(`
+		if _, err := h.Db.Exec(`
 		DELETE FROM friends WHERE (user_id = $1 AND friend_id = $2) OR (user_id = $2 AND friend_id = $1)
 		`, userId, user.Id); err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
@@ -101,7 +100,7 @@ func Create(c *gin.Context) {
 
 	var sqlBlockedImageId sql.NullInt64
 	var blockedUsername string
-	if err := db.Db.QueryRow("SELECT username, f.id FROM users LEFT JOIN files f ON f.user_id = users.id WHERE users.id = $1", userId).Scan(&blockedUsername, &sqlBlockedImageId); err != nil {
+	if err := h.Db.QueryRow("SELECT username, f.id FROM users LEFT JOIN files f ON f.user_id = users.id WHERE users.id = $1", userId).Scan(&blockedUsername, &sqlBlockedImageId); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -126,7 +125,7 @@ func Create(c *gin.Context) {
 		},
 		Event: events.USER_BLOCKED_ADD,
 	}
-	wsclient.Pools.BroadcastClient(user.Id, res)
+	h.Pools.BroadcastClient(user.Id, res)
 	if isFriends {
 		resAfter := wsclient.DataFrame{
 			Op: wsclient.TYPE_DISPATCH,
@@ -142,8 +141,8 @@ func Create(c *gin.Context) {
 			},
 			Event: events.USER_FRIEND_REMOVE,
 		}
-		wsclient.Pools.BroadcastClient(user.Id, resFriendAfter)
-		wsclient.Pools.BroadcastClient(intUserId, resAfter)
+		h.Pools.BroadcastClient(user.Id, resFriendAfter)
+		h.Pools.BroadcastClient(intUserId, resAfter)
 	}
 
 	if hasBeenRequested || isTheRequestor {
@@ -172,8 +171,8 @@ func Create(c *gin.Context) {
 			Event: eventTypeResFriend,
 		}
 
-		wsclient.Pools.BroadcastClient(user.Id, res)
-		wsclient.Pools.BroadcastClient(intUserId, resFriend)
+		h.Pools.BroadcastClient(user.Id, res)
+		h.Pools.BroadcastClient(intUserId, resFriend)
 
 	}
 	c.Status(http.StatusNoContent)
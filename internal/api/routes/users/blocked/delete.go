@@ -6,7 +6,6 @@ import (
 	"strconv"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/db"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
 	"github.com/asianchinaboi/backendserver/internal/session"
@@ -14,7 +13,7 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func Delete(c *gin.Context) {
+func (h *Handlers) Delete(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -39,7 +38,7 @@ func Delete(c *gin.Context) {
 	var isBlocked bool
 
 	//blocked in the clients perspective
-	if err := db.Db.QueryRow("SELECT EXISTS (SELECT 1 FROM blocked WHERE user_id = $1 AND blocked_id = $2)", user.Id, intUserId).Scan(&isBlocked); err != nil {
+	if err := h.Db.QueryRow("SELECT EXISTS (SELECT 1 FROM blocked WHERE user_id = $1 AND blocked_id = $2)", user.Id, intUserId).Scan(&isBlocked); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -49,7 +48,7 @@ func Delete(c *gin.Context) {
 		return
 	}
 
-	if _, err := db.Db.Exec("DELETE FROM blocked WHERE user_id = $1 AND blocked_id = $2", user.Id, intUserId); err != nil {
+	if _, err := h.Db.Exec("DELETE FROM blocked WHERE user_id = $1 AND blocked_id = $2", user.Id, intUserId); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -60,7 +59,7 @@ func Delete(c *gin.Context) {
 		},
 		Event: events.USER_BLOCKED_REMOVE,
 	}
-	wsclient.Pools.BroadcastClient(user.Id, res)
+	h.Pools.BroadcastClient(user.Id, res)
 	c.Status(http.StatusNoContent)
 
 }
@@ -5,21 +5,20 @@ import (
 	"net/http"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/db"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
 	"github.com/asianchinaboi/backendserver/internal/session"
 	"github.com/gin-gonic/gin"
 )
 
-func Get(c *gin.Context) {
+func (h *Handlers) Get(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
 		return
 	}
 
-	rows, err := db.Db.Query("SELECT b.blocked_id, u.username, f.id FROM blocked b INNER JOIN users u ON b.blocked_id = u.id LEFT JOIN files f ON f.user_id = u.id WHERE b.user_id = $1", user.Id)
+	rows, err := h.Db.Query("SELECT b.blocked_id, u.username, f.id FROM blocked b INNER JOIN users u ON b.blocked_id = u.id LEFT JOIN files f ON f.user_id = u.id WHERE b.user_id = $1", user.Id)
 	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
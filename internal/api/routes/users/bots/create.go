@@ -0,0 +1,94 @@
+package bots
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/asianchinaboi/backendserver/internal/api/middleware"
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/asianchinaboi/backendserver/internal/events"
+	"github.com/asianchinaboi/backendserver/internal/session"
+	"github.com/asianchinaboi/backendserver/internal/uid"
+	"github.com/gin-gonic/gin"
+)
+
+// expects
+// name : string
+func (h *Handlers) Create(c *gin.Context) {
+	user := c.MustGet(middleware.User).(*session.Session)
+	if user == nil {
+		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
+		return
+	}
+
+	var body events.User
+	if err := c.ShouldBindJSON(&body); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusBadRequest)
+		return
+	}
+
+	if statusCode, err := events.ValidateBotInput(body); err != nil {
+		errors.SendErrorResponse(c, err, statusCode)
+		return
+	}
+
+	var botCount int
+	var isUsernameTaken bool
+	if err := h.Db.QueryRow("SELECT (SELECT COUNT(*) FROM bots WHERE owner_id = $1), EXISTS (SELECT 1 FROM users WHERE username = $2)", user.Id, body.Name).Scan(&botCount, &isUsernameTaken); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	if botCount >= h.Config.User.MaxBotsPerUser {
+		errors.SendErrorResponse(c, errors.ErrBotLimitReached, errors.StatusBotLimitReached)
+		return
+	}
+	if isUsernameTaken {
+		errors.SendErrorResponse(c, errors.ErrUsernameExists, errors.StatusUsernameExists)
+		return
+	}
+
+	token, err := session.GenBotToken()
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	bot := events.Bot{
+		UserInfo: events.User{
+			UserId:  uid.Snowflake.Generate().Int64(),
+			Name:    body.Name,
+			ImageId: -1,
+		},
+		OwnerId: user.Id,
+		Token:   token,
+		Created: time.Now().UTC(),
+	}
+
+	//BEGIN TRANSACTION
+	ctx := context.Background()
+	tx, err := h.Db.BeginTx(ctx, nil)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	defer tx.Rollback() //rollback changes if failed
+
+	//bots cant log in with a password so its left empty
+	if _, err := tx.ExecContext(ctx, "INSERT INTO users (id, email, password, username, flags) VALUES ($1, '', '', $2, $3)", bot.UserInfo.UserId, bot.UserInfo.Name, events.FLbot); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	if _, err := tx.ExecContext(ctx, "INSERT INTO bots (user_id, owner_id, token, created) VALUES ($1, $2, $3, $4)", bot.UserInfo.UserId, user.Id, token, bot.Created); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil { //commits the transaction
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	c.JSON(http.StatusOK, bot)
+}
@@ -0,0 +1,144 @@
+package bots
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/asianchinaboi/backendserver/internal/api/middleware"
+	"github.com/asianchinaboi/backendserver/internal/blobs"
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/asianchinaboi/backendserver/internal/events"
+	"github.com/asianchinaboi/backendserver/internal/logger"
+	"github.com/asianchinaboi/backendserver/internal/outbox"
+	"github.com/asianchinaboi/backendserver/internal/session"
+	"github.com/asianchinaboi/backendserver/internal/wsclient"
+	"github.com/gin-gonic/gin"
+)
+
+type fileEntity struct {
+	Id         int64
+	EntityType string
+	Hash       sql.NullString
+}
+
+func (h *Handlers) Delete(c *gin.Context) {
+	user := c.MustGet(middleware.User).(*session.Session)
+	if user == nil {
+		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
+		return
+	}
+
+	botId := c.Param("botId")
+	if match, err := regexp.MatchString("^[0-9]+$", botId); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if !match {
+		errors.SendErrorResponse(c, errors.ErrRouteParamInvalid, errors.StatusRouteParamInvalid)
+		return
+	}
+
+	intBotId, err := strconv.ParseInt(botId, 10, 64)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	var isOwner bool
+	if err := h.Db.QueryRow("SELECT EXISTS (SELECT 1 FROM bots WHERE user_id = $1 AND owner_id = $2)", botId, user.Id).Scan(&isOwner); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	if !isOwner {
+		errors.SendErrorResponse(c, errors.ErrBotNotExist, errors.StatusBotNotExist)
+		return
+	}
+
+	//BEGIN TRANSACTION
+	ctx := context.Background()
+	tx, err := h.Db.BeginTx(ctx, nil)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	defer tx.Rollback() //rollback changes if failed
+
+	guildIds := []int64{}
+	guildRows, err := tx.QueryContext(ctx, "SELECT guild_id FROM userguilds WHERE user_id = $1", botId)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	for guildRows.Next() {
+		var guildId int64
+		if err := guildRows.Scan(&guildId); err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusInternalError)
+			return
+		}
+		guildIds = append(guildIds, guildId)
+	}
+	guildRows.Close()
+
+	files := []fileEntity{}
+	fileRows, err := tx.QueryContext(ctx, `SELECT files.id, files.entity_type, files.hash FROM files LEFT JOIN msgs ON msgs.id = files.msg_id WHERE msgs.user_id = $1 OR files.user_id = $1`, botId)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	for fileRows.Next() {
+		var file fileEntity
+		if err := fileRows.Scan(&file.Id, &file.EntityType, &file.Hash); err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusInternalError)
+			return
+		}
+		files = append(files, file)
+	}
+	fileRows.Close()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM users WHERE id = $1", botId); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	for _, guildId := range guildIds {
+		if err := outbox.Enqueue(ctx, tx, guildId, events.MEMBER_REMOVE, events.Member{
+			GuildId: guildId,
+			UserInfo: events.User{
+				UserId: intBotId,
+			},
+		}); err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusInternalError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil { //commits the transaction
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	for _, file := range files {
+		if err := blobs.Remove(context.Background(), h.Db, h.Store, file.EntityType, file.Id, file.Hash); err != nil {
+			logger.Ctx(c).Warn("unable to remove file", "err", err)
+		}
+	}
+
+	for _, guildId := range guildIds {
+		h.Pools.RemoveUserFromGuildPool(guildId, intBotId)
+		h.Pools.BroadcastGuild(guildId, wsclient.DataFrame{
+			Op: wsclient.TYPE_DISPATCH,
+			Data: events.Member{
+				GuildId: guildId,
+				UserInfo: events.User{
+					UserId: intBotId,
+				},
+			},
+			Event: events.MEMBER_REMOVE,
+		})
+	}
+
+	h.Pools.DisconnectUserFromClientPool(intBotId)
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,46 @@
+package bots
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/asianchinaboi/backendserver/internal/api/middleware"
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/asianchinaboi/backendserver/internal/events"
+	"github.com/asianchinaboi/backendserver/internal/session"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handlers) Get(c *gin.Context) {
+	user := c.MustGet(middleware.User).(*session.Session)
+	if user == nil {
+		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
+		return
+	}
+
+	rows, err := h.Db.Query(`
+		SELECT b.user_id, u.username, f.id, b.created FROM bots b INNER JOIN users u ON u.id = b.user_id LEFT JOIN files f ON f.user_id = b.user_id WHERE b.owner_id = $1
+	`, user.Id)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	defer rows.Close()
+	bots := []events.Bot{}
+	for rows.Next() {
+		var bot events.Bot
+		var imageId sql.NullInt64
+		if err := rows.Scan(&bot.UserInfo.UserId, &bot.UserInfo.Name, &imageId, &bot.Created); err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusInternalError)
+			return
+		}
+		if imageId.Valid {
+			bot.UserInfo.ImageId = imageId.Int64
+		} else {
+			bot.UserInfo.ImageId = -1
+		}
+		bot.OwnerId = user.Id
+		bots = append(bots, bot)
+	}
+	c.JSON(http.StatusOK, bots)
+}
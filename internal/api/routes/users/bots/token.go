@@ -0,0 +1,62 @@
+package bots
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/asianchinaboi/backendserver/internal/api/middleware"
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/asianchinaboi/backendserver/internal/events"
+	"github.com/asianchinaboi/backendserver/internal/session"
+	"github.com/gin-gonic/gin"
+)
+
+// ResetToken gives the bot a new token and kicks off anything using the old one
+func (h *Handlers) ResetToken(c *gin.Context) {
+	user := c.MustGet(middleware.User).(*session.Session)
+	if user == nil {
+		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
+		return
+	}
+
+	botId := c.Param("botId")
+	if match, err := regexp.MatchString("^[0-9]+$", botId); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if !match {
+		errors.SendErrorResponse(c, errors.ErrRouteParamInvalid, errors.StatusRouteParamInvalid)
+		return
+	}
+
+	intBotId, err := strconv.ParseInt(botId, 10, 64)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	token, err := session.GenBotToken()
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	bot := events.Bot{Token: token, OwnerId: user.Id}
+	bot.UserInfo.UserId = intBotId
+
+	result, err := h.Db.Exec("UPDATE bots SET token = $1 WHERE user_id = $2 AND owner_id = $3", token, botId, user.Id)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if affected == 0 {
+		errors.SendErrorResponse(c, errors.ErrBotNotExist, errors.StatusBotNotExist)
+		return
+	}
+
+	h.Pools.DisconnectUserFromClientPool(intBotId)
+	c.JSON(http.StatusOK, bot)
+}
@@ -5,22 +5,22 @@ import (
 	"net/http"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/db"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
+	"github.com/asianchinaboi/backendserver/internal/outbox"
 	"github.com/asianchinaboi/backendserver/internal/session"
 	"github.com/asianchinaboi/backendserver/internal/wsclient"
 	"github.com/gin-gonic/gin"
 )
 
-func clearUserMsg(c *gin.Context) {
+func (h *Handlers) clearUserMsg(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
 		return
 	}
 
-	guildRows, err := db.Db.Query("SELECT DISTINCT msgs.guild_id, guilds.dm FROM msgs INNER JOIN guilds ON msgs.guild_id = guilds.id WHERE user_id = $1", user.Id)
+	guildRows, err := h.Db.Query("SELECT DISTINCT msgs.guild_id, guilds.dm FROM msgs INNER JOIN guilds ON msgs.guild_id = guilds.id WHERE user_id = $1", user.Id)
 
 	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
@@ -29,9 +29,22 @@ func clearUserMsg(c *gin.Context) {
 
 	defer guildRows.Close()
 
+	guildIds := []int64{}
+	for guildRows.Next() {
+		var guildId int64
+		var isDm bool
+		err = guildRows.Scan(&guildId, &isDm)
+		if err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusInternalError)
+			return
+		}
+		guildIds = append(guildIds, guildId)
+	}
+	guildRows.Close()
+
 	//BEGIN TRANSACTION
 	ctx := context.Background()
-	tx, err := db.Db.BeginTx(ctx, nil)
+	tx, err := h.Db.BeginTx(ctx, nil)
 	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
@@ -43,31 +56,33 @@ func clearUserMsg(c *gin.Context) {
 		return
 	}
 
-	if err = tx.Commit(); err != nil {
-		errors.SendErrorResponse(c, err, errors.StatusInternalError)
-		return
-	}
-
-	for guildRows.Next() {
-		var guildId int64
-		var isDm bool
-		err = guildRows.Scan(&guildId, &isDm)
-		if err != nil {
-			errors.SendErrorResponse(c, err, errors.StatusInternalError)
-			return
-		}
+	frames := make([]wsclient.DataFrame, len(guildIds))
+	for i, guildId := range guildIds {
 		clearMsg := events.Msg{
 			Author: events.User{
 				UserId: user.Id,
 			},
 			GuildId: guildId,
 		}
-		res := wsclient.DataFrame{
+		frames[i] = wsclient.DataFrame{
 			Op:    wsclient.TYPE_DISPATCH,
 			Data:  clearMsg,
 			Event: events.MESSAGES_USER_CLEAR,
 		}
-		if err := wsclient.Pools.BroadcastGuild(guildId, res); err != nil && err != errors.ErrGuildPoolNotExist {
+		if err := outbox.Enqueue(ctx, tx, guildId, frames[i].Event, clearMsg); err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusInternalError)
+			return
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	for i, guildId := range guildIds {
+		res := frames[i]
+		if err := h.Pools.BroadcastGuild(guildId, res); err != nil && err != errors.ErrGuildPoolNotExist {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
@@ -1,29 +1,28 @@
 package users
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
-	"os"
 	"path/filepath"
 	"strings"
 
-	"github.com/asianchinaboi/backendserver/internal/config"
-	"github.com/asianchinaboi/backendserver/internal/db"
+	"github.com/asianchinaboi/backendserver/internal/blobs"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
 	"github.com/asianchinaboi/backendserver/internal/files"
 	"github.com/asianchinaboi/backendserver/internal/logger"
+	"github.com/asianchinaboi/backendserver/internal/scanner"
 	"github.com/asianchinaboi/backendserver/internal/session"
 	"github.com/asianchinaboi/backendserver/internal/uid"
 	"github.com/gin-gonic/gin"
 )
 
-func userCreate(c *gin.Context) {
-	logger.Debug.Println("Creating User")
+func (h *Handlers) userCreate(c *gin.Context) {
+	logger.Ctx(c).Debug("creating user")
 	var user events.User
 	var imageHeader *multipart.FileHeader
 
@@ -49,8 +48,6 @@ func userCreate(c *gin.Context) {
 		return
 	}
 
-	successful := false
-
 	if user.Email == nil {
 		user.Email = new(string)
 	}
@@ -64,7 +61,7 @@ func userCreate(c *gin.Context) {
 
 	//BEGIN TRANSACTION
 	ctx := context.Background()
-	tx, err := db.Db.BeginTx(ctx, nil)
+	tx, err := h.Db.BeginTx(ctx, nil)
 	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
@@ -73,7 +70,7 @@ func userCreate(c *gin.Context) {
 
 	var isUsernameTaken bool
 
-	if err := db.Db.QueryRow("SELECT EXISTS (SELECT 1 FROM users WHERE username=$1)", user.Name).Scan(&isUsernameTaken); err != nil {
+	if err := h.Db.QueryRow("SELECT EXISTS (SELECT 1 FROM users WHERE username=$1)", user.Name).Scan(&isUsernameTaken); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -113,47 +110,40 @@ func userCreate(c *gin.Context) {
 
 		fileMIMEType := http.DetectContentType(fileBytes)
 
-		if valid := files.ValidateImage(fileBytes, fileType); !valid {
+		fileBytes, valid := files.ValidateImage(fileBytes, fileType, h.Config.Server.ImageProfileSize) //crops and scales it down if needed
+		if !valid {
 			errors.SendErrorResponse(c, errors.ErrFileInvalid, errors.StatusFileInvalid)
 			return
 		}
 
 		filesize := len(fileBytes)
 
-		if filesize > config.Config.Server.MaxFileSize {
+		if filesize > h.Config.Server.MaxFileSize {
 			errors.SendErrorResponse(c, errors.ErrFileTooLarge, errors.StatusFileTooLarge)
 			return
 		} else if !(filesize >= 0) {
 			errors.SendErrorResponse(c, errors.ErrFileNoBytes, errors.StatusFileNoBytes)
 			return
 		}
-		compressedBuffer, err := files.Compress(fileBytes, filesize)
-		if err != nil {
+
+		//images use the default policy, the guild policy is only for attachments
+		verdict, err := scanner.Check(ctx, h.Scanner, bytes.NewReader(fileBytes), h.Config.Scanner.Policy)
+		if err == errors.ErrScannerUnavailable {
+			errors.SendErrorResponse(c, err, errors.StatusScannerUnavailable)
+			return
+		} else if err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
 
-		outFile, err := os.Create(fmt.Sprintf("uploads/user/%d.lz4", imageId))
-		//TODO: delete files if failed or write them after when its deemed successful
+		//stored by content so an image thats already been uploaded isnt stored again
+		hash, err := blobs.Put(ctx, tx, h.Store, bytes.NewReader(fileBytes))
 		if err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
-		defer func() { //defer just in case something went wrong
-			if !successful {
-				if err := os.Remove(fmt.Sprintf("uploads/user/%d.lz4", imageId)); err != nil {
-					logger.Warn.Printf("failed to remove file: %v\n", err)
-				}
-			}
-		}()
-		defer outFile.Close()
-
-		if _, err = outFile.Write(compressedBuffer); err != nil {
-			errors.SendErrorResponse(c, err, errors.StatusInternalError)
-			return
-		}
 
-		if _, err = tx.ExecContext(ctx, "INSERT INTO files (id, user_id, filename, created, temp, filesize, filetype, entity_type) VALUES ($1, $2, $3, NOW() , $4, $5, $6, 'user')", imageId, user.UserId, filename, false, filesize, fileMIMEType); err != nil {
+		if _, err = tx.ExecContext(ctx, "INSERT INTO files (id, user_id, filename, created, temp, filesize, filetype, entity_type, hash, quarantined, quarantine_reason) VALUES ($1, $2, $3, NOW() , $4, $5, $6, 'user', $7, $8, NULLIF($9, ''))", imageId, user.UserId, filename, false, filesize, fileMIMEType, hash, verdict.Quarantined, verdict.Reason); err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
@@ -171,10 +161,8 @@ func userCreate(c *gin.Context) {
 		return
 	}
 
-	successful = true
-
 	//create session for new user
-	authData, err := session.GenToken(user.UserId)
+	authData, err := session.GenToken(h.Db, h.Config.User.TokenExpireTime, user.UserId)
 	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
@@ -2,15 +2,15 @@ package users
 
 import (
 	"context"
-	"fmt"
+	"database/sql"
 	"net/http"
-	"os"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/db"
+	"github.com/asianchinaboi/backendserver/internal/blobs"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
 	"github.com/asianchinaboi/backendserver/internal/logger"
+	"github.com/asianchinaboi/backendserver/internal/outbox"
 	"github.com/asianchinaboi/backendserver/internal/session"
 	"github.com/asianchinaboi/backendserver/internal/wsclient"
 	"github.com/gin-gonic/gin"
@@ -19,6 +19,7 @@ import (
 type fileEntity struct {
 	Id         int64
 	EntityType string
+	Hash       sql.NullString
 }
 
 type guildEntity struct {
@@ -28,7 +29,7 @@ type guildEntity struct {
 
 //TODO: Replace IN with something else (might be inefficient)
 
-func userDelete(c *gin.Context) {
+func (h *Handlers) userDelete(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -42,7 +43,7 @@ func userDelete(c *gin.Context) {
 	}
 
 	var userHashedPass string
-	if err := db.Db.QueryRow("SELECT password FROM users WHERE id = $1", user.Id).Scan(&userHashedPass); err != nil {
+	if err := h.Db.QueryRow("SELECT password FROM users WHERE id = $1", user.Id).Scan(&userHashedPass); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -54,7 +55,7 @@ func userDelete(c *gin.Context) {
 
 	//BEGIN TRANSACTION
 	ctx := context.Background()
-	tx, err := db.Db.BeginTx(ctx, nil)
+	tx, err := h.Db.BeginTx(ctx, nil)
 	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
@@ -62,7 +63,7 @@ func userDelete(c *gin.Context) {
 	defer tx.Rollback() //rollback changes if failed
 
 	guildIds := []int64{}
-	guildRows, err := db.Db.Query("SELECT guild_id FROM userguilds WHERE user_id = $1 AND owner = false", user.Id)
+	guildRows, err := h.Db.Query("SELECT guild_id FROM userguilds WHERE user_id = $1 AND owner = false", user.Id)
 	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
@@ -78,7 +79,7 @@ func userDelete(c *gin.Context) {
 	guildRows.Close()
 
 	files := []fileEntity{}
-	fileRows, err := db.Db.Query(`SELECT files.id, files.entity_type FROM files LEFT JOIN msgs ON msgs.id = files.msg_id LEFT JOIN userguilds ON userguilds.guild_id = files.guild_id AND userguilds.owner = true 
+	fileRows, err := h.Db.Query(`SELECT files.id, files.entity_type, files.hash FROM files LEFT JOIN msgs ON msgs.id = files.msg_id LEFT JOIN userguilds ON userguilds.guild_id = files.guild_id AND userguilds.owner = true 
 	LEFT JOIN users ON users.id = files.user_id WHERE msgs.user_id = $1 OR userguilds.user_id = $1 OR users.id = $1
 	`, user.Id)
 	if err != nil {
@@ -88,7 +89,7 @@ func userDelete(c *gin.Context) {
 
 	for fileRows.Next() {
 		var file fileEntity
-		if err := fileRows.Scan(&file.Id, &file.EntityType); err != nil {
+		if err := fileRows.Scan(&file.Id, &file.EntityType, &file.Hash); err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
@@ -114,25 +115,31 @@ func userDelete(c *gin.Context) {
 	}
 	ownedGuildRows.Close()
 
-	if _, err := tx.ExecContext(ctx, "DELETE FROM users WHERE id = $1", user.Id); err != nil {
+	//bots go with their owner
+	ownedBots := []int64{}
+	ownedBotRows, err := tx.QueryContext(ctx, "DELETE FROM users WHERE id IN (SELECT user_id FROM bots WHERE owner_id = $1) RETURNING id", user.Id)
+	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
+	for ownedBotRows.Next() {
+		var botId int64
+		if err := ownedBotRows.Scan(&botId); err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusInternalError)
+			return
+		}
+		ownedBots = append(ownedBots, botId)
+	}
+	ownedBotRows.Close()
 
-	if err := tx.Commit(); err != nil { //commits the transaction
+	if _, err := tx.ExecContext(ctx, "DELETE FROM users WHERE id = $1", user.Id); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
 
-	for _, file := range files {
-		if err := os.Remove(fmt.Sprintf("uploads/%s/%d.lz4", file.EntityType, file.Id)); err != nil {
-			logger.Warn.Printf("unable to remove file: %v\n", err)
-		}
-	}
-
+	guildFrames := map[int64][]wsclient.DataFrame{} //built before committing so subscriptions get the same events
 	for _, guildId := range guildIds {
-		wsclient.Pools.RemoveUserFromGuildPool(guildId, user.Id)
-		wsclient.Pools.BroadcastGuild(guildId, wsclient.DataFrame{
+		guildFrames[guildId] = []wsclient.DataFrame{{
 			Op: wsclient.TYPE_DISPATCH,
 			Data: events.Msg{
 				GuildId: guildId,
@@ -141,8 +148,7 @@ func userDelete(c *gin.Context) {
 				},
 			},
 			Event: events.MESSAGES_USER_CLEAR,
-		})
-		wsclient.Pools.BroadcastGuild(guildId, wsclient.DataFrame{
+		}, {
 			Op: wsclient.TYPE_DISPATCH,
 			Data: events.Member{
 				GuildId: guildId,
@@ -151,13 +157,37 @@ func userDelete(c *gin.Context) {
 				},
 			},
 			Event: events.MEMBER_REMOVE,
-		})
+		}}
+		for _, frame := range guildFrames[guildId] {
+			if err := outbox.Enqueue(ctx, tx, guildId, frame.Event, frame.Data); err != nil {
+				errors.SendErrorResponse(c, err, errors.StatusInternalError)
+				return
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil { //commits the transaction
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	for _, file := range files {
+		if err := blobs.Remove(context.Background(), h.Db, h.Store, file.EntityType, file.Id, file.Hash); err != nil {
+			logger.Ctx(c).Warn("unable to remove file", "err", err)
+		}
+	}
+
+	for _, guildId := range guildIds {
+		h.Pools.RemoveUserFromGuildPool(guildId, user.Id)
+		for _, frame := range guildFrames[guildId] {
+			h.Pools.BroadcastGuild(guildId, frame)
+		}
 	}
 
 	for _, ownedGuild := range ownedGuilds {
 
 		if ownedGuild.Dm {
-			wsclient.Pools.BroadcastGuild(ownedGuild.Id, wsclient.DataFrame{ //makes the client delete guild
+			h.Pools.BroadcastGuild(ownedGuild.Id, wsclient.DataFrame{ //makes the client delete guild
 				Op: wsclient.TYPE_DISPATCH,
 				Data: events.Dm{
 					DmId: ownedGuild.Id,
@@ -165,7 +195,7 @@ func userDelete(c *gin.Context) {
 				Event: events.DM_DELETE,
 			})
 		} else {
-			wsclient.Pools.BroadcastGuild(ownedGuild.Id, wsclient.DataFrame{ //makes the client delete guild
+			h.Pools.BroadcastGuild(ownedGuild.Id, wsclient.DataFrame{ //makes the client delete guild
 				Op: wsclient.TYPE_DISPATCH,
 				Data: events.Guild{
 					GuildId: ownedGuild.Id,
@@ -175,6 +205,10 @@ func userDelete(c *gin.Context) {
 		}
 	}
 
-	wsclient.Pools.DisconnectUserFromClientPool(user.Id)
+	for _, botId := range ownedBots {
+		h.Pools.DisconnectUserFromClientPool(botId)
+	}
+
+	h.Pools.DisconnectUserFromClientPool(user.Id)
 	c.Status(http.StatusNoContent)
 }
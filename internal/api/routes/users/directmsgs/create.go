@@ -6,7 +6,6 @@ import (
 	"net/http"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/db"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
 	"github.com/asianchinaboi/backendserver/internal/session"
@@ -19,7 +18,7 @@ type CreateDmBody struct {
 	ReceiverId int64 `json:"receiverId,string" binding:"required"`
 }
 
-func Create(c *gin.Context) {
+func (h *Handlers) Create(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -40,7 +39,7 @@ func Create(c *gin.Context) {
 
 	var userExists bool
 
-	if err := db.Db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", body.ReceiverId).Scan(&userExists); err != nil {
+	if err := h.Db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", body.ReceiverId).Scan(&userExists); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -52,7 +51,7 @@ func Create(c *gin.Context) {
 
 	var dmExists bool
 
-	if err := db.Db.QueryRow("SELECT EXISTS(SELECT 1 FROM userguilds WHERE user_id = $1 AND receiver_id = $2)", user.Id, body.ReceiverId).Scan(&dmExists); err != nil {
+	if err := h.Db.QueryRow("SELECT EXISTS(SELECT 1 FROM userguilds WHERE user_id = $1 AND receiver_id = $2)", user.Id, body.ReceiverId).Scan(&dmExists); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -60,14 +59,14 @@ func Create(c *gin.Context) {
 	if dmExists {
 		var dmId int64
 		//will return two rows but query row should be fine
-		if err := db.Db.QueryRow("UPDATE userguilds SET left_dm = false WHERE user_id = $1 AND receiver_id = $2 RETURNING guild_id", user.Id, body.ReceiverId).Scan(&dmId); err != nil {
+		if err := h.Db.QueryRow("UPDATE userguilds SET left_dm = false WHERE user_id = $1 AND receiver_id = $2 RETURNING guild_id", user.Id, body.ReceiverId).Scan(&dmId); err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
 		var username string
 		var sqlImageId sql.NullInt64
 
-		if err := db.Db.QueryRow("SELECT username, f.id FROM users LEFT JOIN files f ON f.user_id = users.id WHERE users.id = $1", user.Id).Scan(&username, &sqlImageId); err != nil {
+		if err := h.Db.QueryRow("SELECT username, f.id FROM users LEFT JOIN files f ON f.user_id = users.id WHERE users.id = $1", user.Id).Scan(&username, &sqlImageId); err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
@@ -92,15 +91,15 @@ func Create(c *gin.Context) {
 			},
 			Event: events.DM_CREATE,
 		}
-		wsclient.Pools.AddUserToGuildPool(dmId, user.Id)
-		wsclient.Pools.BroadcastClient(user.Id, res)
+		h.Pools.AddUserToGuildPool(dmId, user.Id)
+		h.Pools.BroadcastClient(user.Id, res)
 
 		c.Status(http.StatusCreated)
 		return
 	}
 	//BEGIN TRANSACTION
 	ctx := context.Background()
-	tx, err := db.Db.BeginTx(ctx, nil)
+	tx, err := h.Db.BeginTx(ctx, nil)
 	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
@@ -127,7 +126,7 @@ func Create(c *gin.Context) {
 	var username string
 	var sqlImageId sql.NullInt64
 
-	if err := db.Db.QueryRow("SELECT username, f.id FROM users LEFT JOIN files f ON f.user_id = users.id WHERE users.id = $1", user.Id).Scan(&username, &sqlImageId); err != nil {
+	if err := h.Db.QueryRow("SELECT username, f.id FROM users LEFT JOIN files f ON f.user_id = users.id WHERE users.id = $1", user.Id).Scan(&username, &sqlImageId); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -157,8 +156,8 @@ func Create(c *gin.Context) {
 		},
 		Event: events.DM_CREATE,
 	}
-	wsclient.Pools.AddUserToGuildPool(dmId, user.Id)
-	wsclient.Pools.BroadcastClient(user.Id, res)
+	h.Pools.AddUserToGuildPool(dmId, user.Id)
+	h.Pools.BroadcastClient(user.Id, res)
 
 	c.Status(http.StatusCreated)
 }
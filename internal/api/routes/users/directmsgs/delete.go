@@ -7,7 +7,6 @@ import (
 	"strconv"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/db"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
 	"github.com/asianchinaboi/backendserver/internal/session"
@@ -15,7 +14,7 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func Delete(c *gin.Context) {
+func (h *Handlers) Delete(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -38,7 +37,7 @@ func Delete(c *gin.Context) {
 	}
 
 	var dmExists bool
-	if err := db.Db.QueryRow("SELECT EXISTS(SELECT 1 FROM userguilds WHERE user_id = $1 AND guild_id = $2 AND receiver_id IS NOT NULL)", user.Id, intDmId).Scan(&dmExists); err != nil {
+	if err := h.Db.QueryRow("SELECT EXISTS(SELECT 1 FROM userguilds WHERE user_id = $1 AND guild_id = $2 AND receiver_id IS NOT NULL)", user.Id, intDmId).Scan(&dmExists); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -48,7 +47,7 @@ func Delete(c *gin.Context) {
 		return
 	}
 
-	if _, err := db.Db.Exec("UPDATE userguilds SET left_dm = true WHERE user_id = $1 AND guild_id = $2", user.Id, dmId); err != nil {
+	if _, err := h.Db.Exec("UPDATE userguilds SET left_dm = true WHERE user_id = $1 AND guild_id = $2", user.Id, dmId); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -57,7 +56,7 @@ func Delete(c *gin.Context) {
 	var username string
 	var sqlImageId sql.NullInt64
 
-	if err := db.Db.QueryRow("SELECT ug.receiver_id, username, f.id FROM users INNER JOIN userguilds ug ON ug.user_id = $1 AND ug.guild_id = $2 LEFT JOIN files f ON f.user_id = ug.receiver_id  WHERE users.id = $1", user.Id, dmId).Scan(&userId, &username, &sqlImageId); err != nil {
+	if err := h.Db.QueryRow("SELECT ug.receiver_id, username, f.id FROM users INNER JOIN userguilds ug ON ug.user_id = $1 AND ug.guild_id = $2 LEFT JOIN files f ON f.user_id = ug.receiver_id  WHERE users.id = $1", user.Id, dmId).Scan(&userId, &username, &sqlImageId); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -82,7 +81,7 @@ func Delete(c *gin.Context) {
 		},
 		Event: events.DM_DELETE,
 	}
-	wsclient.Pools.RemoveUserFromGuildPool(intDmId, user.Id)
-	wsclient.Pools.BroadcastClient(user.Id, res)
+	h.Pools.RemoveUserFromGuildPool(intDmId, user.Id)
+	h.Pools.BroadcastClient(user.Id, res)
 	c.Status(http.StatusNoContent)
 }
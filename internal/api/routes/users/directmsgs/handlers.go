@@ -0,0 +1,12 @@
+package directmsgs
+
+import "github.com/asianchinaboi/backendserver/internal/api/deps"
+
+// Handlers are the directmsgs routes, the router builds them with New
+type Handlers struct {
+	*deps.Deps
+}
+
+func New(d *deps.Deps) *Handlers {
+	return &Handlers{d}
+}
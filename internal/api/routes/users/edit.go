@@ -1,24 +1,23 @@
 package users
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"encoding/json"
-	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
-	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/config"
-	"github.com/asianchinaboi/backendserver/internal/db"
+	"github.com/asianchinaboi/backendserver/internal/blobs"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
 	"github.com/asianchinaboi/backendserver/internal/files"
 	"github.com/asianchinaboi/backendserver/internal/logger"
+	"github.com/asianchinaboi/backendserver/internal/scanner"
 	"github.com/asianchinaboi/backendserver/internal/session"
 	"github.com/asianchinaboi/backendserver/internal/uid"
 	"github.com/asianchinaboi/backendserver/internal/wsclient"
@@ -34,7 +33,7 @@ type editSelfBody struct {
 	Options     *int    `json:"options"`
 }
 
-func editSelf(c *gin.Context) {
+func (h *Handlers) editSelf(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -74,7 +73,7 @@ func editSelf(c *gin.Context) {
 
 	if body.Password != nil {
 		var oldhashedpass string
-		if err := db.Db.QueryRow("SELECT password FROM users WHERE id=$1", user.Id).Scan(&oldhashedpass); err != nil {
+		if err := h.Db.QueryRow("SELECT password FROM users WHERE id=$1", user.Id).Scan(&oldhashedpass); err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusBadRequest)
 			return
 		}
@@ -89,7 +88,7 @@ func editSelf(c *gin.Context) {
 
 	//BEGIN TRANSACTION
 	ctx := context.Background()
-	tx, err := db.Db.BeginTx(ctx, nil)
+	tx, err := h.Db.BeginTx(ctx, nil)
 	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
@@ -113,7 +112,7 @@ func editSelf(c *gin.Context) {
 	}
 	if body.Email != nil {
 		var taken bool
-		if err := db.Db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE email=$1)", *body.Email).Scan(&taken); err != nil {
+		if err := h.Db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE email=$1)", *body.Email).Scan(&taken); err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
@@ -127,14 +126,14 @@ func editSelf(c *gin.Context) {
 		}
 		newUserInfo.Email = body.Email
 	} else {
-		if err := db.Db.QueryRow("SELECT email FROM users WHERE id=$1", user.Id).Scan(&newUserInfo.Email); err != nil {
+		if err := h.Db.QueryRow("SELECT email FROM users WHERE id=$1", user.Id).Scan(&newUserInfo.Email); err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
 	}
 	if body.Username != nil {
 		var taken bool
-		if err := db.Db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE username=$1)", *body.Username).Scan(&taken); err != nil {
+		if err := h.Db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE username=$1)", *body.Username).Scan(&taken); err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
@@ -148,7 +147,7 @@ func editSelf(c *gin.Context) {
 		}
 		newUserInfo.Name = *body.Username
 	} else {
-		if err := db.Db.QueryRow("SELECT username FROM users WHERE id=$1", user.Id).Scan(&newUserInfo.Name); err != nil {
+		if err := h.Db.QueryRow("SELECT username FROM users WHERE id=$1", user.Id).Scan(&newUserInfo.Name); err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
@@ -161,7 +160,7 @@ func editSelf(c *gin.Context) {
 		newUserInfo.Options = body.Options
 	} else {
 		var options int
-		if err := db.Db.QueryRow("SELECT options FROM users WHERE id=$1", user.Id).Scan(&options); err != nil {
+		if err := h.Db.QueryRow("SELECT options FROM users WHERE id=$1", user.Id).Scan(&options); err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
@@ -171,7 +170,8 @@ func editSelf(c *gin.Context) {
 	if imageHeader != nil {
 		//get old image id
 		var oldImageId int64
-		if err := db.Db.QueryRow("SELECT id FROM files WHERE user_id = $1", user.Id).Scan(&oldImageId); err != nil && err != sql.ErrNoRows {
+		var oldHash sql.NullString
+		if err := h.Db.QueryRow("SELECT id, hash FROM files WHERE user_id = $1", user.Id).Scan(&oldImageId, &oldHash); err != nil && err != sql.ErrNoRows {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		} else if err == sql.ErrNoRows {
@@ -181,8 +181,8 @@ func editSelf(c *gin.Context) {
 				if successful {
 					deleteImageId := oldImageId
 					if deleteImageId != -1 {
-						if err := os.Remove(fmt.Sprintf("uploads/user/%d.lz4", deleteImageId)); err != nil {
-							logger.Warn.Printf("failed to remove file: %v\n", err)
+						if err := blobs.Remove(context.Background(), h.Db, h.Store, "user", deleteImageId, oldHash); err != nil {
+							logger.Ctx(c).Warn("failed to remove file", "err", err)
 						}
 					}
 				}
@@ -211,14 +211,15 @@ func editSelf(c *gin.Context) {
 		}
 		fileMIMEType := http.DetectContentType(fileBytes)
 
-		if valid := files.ValidateImage(fileBytes, fileType); !valid {
+		fileBytes, valid := files.ValidateImage(fileBytes, fileType, h.Config.Server.ImageProfileSize) //crops and scales it down if needed
+		if !valid {
 			errors.SendErrorResponse(c, errors.ErrFileInvalid, errors.StatusFileInvalid)
 			return
 		}
 
 		filesize := len(fileBytes)
 
-		if filesize > config.Config.Server.MaxFileSize {
+		if filesize > h.Config.Server.MaxFileSize {
 			errors.SendErrorResponse(c, errors.ErrFileTooLarge, errors.StatusFileTooLarge)
 			return
 		} else if !(filesize >= 0) {
@@ -226,32 +227,24 @@ func editSelf(c *gin.Context) {
 			return
 		}
 
-		compressedBuffer, err := files.Compress(fileBytes, filesize)
-		if err != nil {
-			errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		//images use the default policy, the guild policy is only for attachments
+		verdict, err := scanner.Check(ctx, h.Scanner, bytes.NewReader(fileBytes), h.Config.Scanner.Policy)
+		if err == errors.ErrScannerUnavailable {
+			errors.SendErrorResponse(c, err, errors.StatusScannerUnavailable)
 			return
-		}
-
-		if _, err = tx.ExecContext(ctx, "INSERT INTO files (id, filename, created, temp, filesize, user_id, filetype, entity_type) VALUES ($1, $2, now() , $3, $4, $5, $6,'user')", imageId, filename, false, filesize, user.Id, fileMIMEType); err != nil {
+		} else if err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
 
-		outFile, err := os.Create(fmt.Sprintf("uploads/user/%d.lz4", imageId))
+		//stored by content so an image thats already been uploaded isnt stored again
+		hash, err := blobs.Put(ctx, tx, h.Store, bytes.NewReader(fileBytes))
 		if err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
-		defer func() { //defer just in case something went wrong
-			if !successful {
-				if err := os.Remove(fmt.Sprintf("uploads/user/%d.lz4", imageId)); err != nil {
-					logger.Warn.Printf("failed to remove file: %v\n", err)
-				}
-			}
-		}()
-		defer outFile.Close()
 
-		if _, err = outFile.Write(compressedBuffer); err != nil {
+		if _, err = tx.ExecContext(ctx, "INSERT INTO files (id, filename, created, temp, filesize, user_id, filetype, entity_type, hash, quarantined, quarantine_reason) VALUES ($1, $2, now() , $3, $4, $5, $6, 'user', $7, $8, NULLIF($9, ''))", imageId, filename, false, filesize, user.Id, fileMIMEType, hash, verdict.Quarantined, verdict.Reason); err != nil {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		}
@@ -259,7 +252,7 @@ func editSelf(c *gin.Context) {
 		newUserInfo.ImageId = imageId
 	} else {
 		var imageId int64
-		if err := db.Db.QueryRow("SELECT id FROM files WHERE user_id=$1", user.Id).Scan(&imageId); err != nil && err != sql.ErrNoRows {
+		if err := h.Db.QueryRow("SELECT id FROM files WHERE user_id=$1", user.Id).Scan(&imageId); err != nil && err != sql.ErrNoRows {
 			errors.SendErrorResponse(c, err, errors.StatusInternalError)
 			return
 		} else if err == sql.ErrNoRows {
@@ -269,7 +262,7 @@ func editSelf(c *gin.Context) {
 		}
 	}
 
-	if err := db.Db.QueryRow("SELECT flags FROM users WHERE id=$1", user.Id).Scan(&newUserInfo.Flags); err != nil {
+	if err := h.Db.QueryRow("SELECT flags FROM users WHERE id=$1", user.Id).Scan(&newUserInfo.Flags); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -299,7 +292,7 @@ func editSelf(c *gin.Context) {
 		Event: events.USER_INFO_UPDATE,
 	}
 
-	userIdRows, err := db.Db.Query(
+	userIdRows, err := h.Db.Query(
 		`(SELECT DISTINCT userguilds.user_id AS user_id FROM userguilds WHERE EXISTS (SELECT 1 FROM userguilds AS ug2 WHERE ug2.user_id = $1 AND ug2.guild_id = userguilds.guild_id) AND userguilds.user_id != $1)
 		UNION (SELECT DISTINCT friend_id AS user_id FROM friends WHERE user_id = $1) UNION (SELECT $1 AS user_id)
 		`, user.Id)
@@ -315,9 +308,9 @@ func editSelf(c *gin.Context) {
 			return
 		}
 		if userId == user.Id {
-			wsclient.Pools.BroadcastClient(userId, res)
+			h.Pools.BroadcastClient(userId, res)
 		} else {
-			wsclient.Pools.BroadcastClient(userId, otherRes)
+			h.Pools.BroadcastClient(userId, otherRes)
 		}
 	}
 	c.Status(http.StatusNoContent)
@@ -7,7 +7,6 @@ import (
 	"strconv"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/db"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
 	"github.com/asianchinaboi/backendserver/internal/logger"
@@ -16,7 +15,7 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func Create(c *gin.Context) {
+func (h *Handlers) Create(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -47,7 +46,7 @@ func Create(c *gin.Context) {
 	var isAlreadyFriends bool
 	var isRequested bool
 
-	if err := db.Db.QueryRow(`
+	if err := h.Db.QueryRow(`
 		SELECT EXISTS (SELECT 1 FROM blocked WHERE user_id = $1 AND blocked_id = $2)
 		 OR 
 		EXISTS (SELECT 1 FROM blocked WHERE user_id = $2 AND blocked_id = $1)
@@ -60,7 +59,7 @@ func Create(c *gin.Context) {
 		return
 	}
 
-	if err := db.Db.QueryRow(`
+	if err := h.Db.QueryRow(`
 	SELECT EXISTS(SELECT 1 FROM friends WHERE user_id = $1 AND friend_id = $2 AND friended = false) OR EXISTS(SELECT 1 FROM friends WHERE user_id = $2 AND friend_id = $1 AND friended = false)
 	`, userId, user.Id).Scan(&isRequested); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
@@ -72,7 +71,7 @@ func Create(c *gin.Context) {
 		return
 	}
 
-	if err := db.Db.QueryRow(`
+	if err := h.Db.QueryRow(`
 		SELECT EXISTS(SELECT 1 FROM friends WHERE user_id = $1 AND friend_id = $2) OR EXISTS(SELECT 1 FROM friends WHERE user_id = $2 AND friend_id = $1)
 		`, user.Id, userId).Scan(&isAlreadyFriends); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
@@ -83,7 +82,7 @@ func Create(c *gin.Context) {
 		return
 	}
 
-	if _, err := db.Db.Exec(`
+	if _, err := h.Db.Exec(`
 		INSERT INTO friends(user_id, friend_id, friended) VALUES($1, $2, false)
 		`, user.Id, userId); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
@@ -92,7 +91,7 @@ func Create(c *gin.Context) {
 
 	var sqlUserImageId sql.NullInt64
 	var username string
-	if err := db.Db.QueryRow("SELECT files.id, username FROM users LEFT JOIN files ON user_id = users.id WHERE users.id = $1", user.Id).Scan(&sqlUserImageId, &username); err != nil {
+	if err := h.Db.QueryRow("SELECT files.id, username FROM users LEFT JOIN files ON user_id = users.id WHERE users.id = $1", user.Id).Scan(&sqlUserImageId, &username); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -104,7 +103,7 @@ func Create(c *gin.Context) {
 	}
 	var sqlFriendImageId sql.NullInt64
 	var friendUsername string
-	if err := db.Db.QueryRow("SELECT files.id, username FROM users LEFT JOIN files ON user_id = users.id WHERE users.id = $1", userId).Scan(&sqlFriendImageId, &friendUsername); err != nil {
+	if err := h.Db.QueryRow("SELECT files.id, username FROM users LEFT JOIN files ON user_id = users.id WHERE users.id = $1", userId).Scan(&sqlFriendImageId, &friendUsername); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -133,8 +132,8 @@ func Create(c *gin.Context) {
 		},
 		Event: events.USER_FRIEND_INCOMING_REQUEST_ADD,
 	}
-	wsclient.Pools.BroadcastClient(user.Id, res)
-	wsclient.Pools.BroadcastClient(intUserId, resFriend)
+	h.Pools.BroadcastClient(user.Id, res)
+	h.Pools.BroadcastClient(intUserId, resFriend)
 
 	c.Status(http.StatusCreated)
 
@@ -144,10 +143,9 @@ type CreateByNameBody struct {
 	Username string `json:"username" binding:"required"`
 }
 
-func CreateByName(c *gin.Context) {
+func (h *Handlers) CreateByName(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
-		logger.Error.Println("user token not sent in data")
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
 		return
 	}
@@ -159,8 +157,8 @@ func CreateByName(c *gin.Context) {
 	}
 
 	var userId int64
-	logger.Debug.Println(body.Username)
-	if err := db.Db.QueryRow(`
+	logger.Ctx(c).Debug("adding friend by name", "name", body.Username)
+	if err := h.Db.QueryRow(`
 	SELECT id FROM users WHERE username = $1
 	`, body.Username).Scan(&userId); err != nil && err != sql.ErrNoRows {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
@@ -178,7 +176,7 @@ func CreateByName(c *gin.Context) {
 	var isAlreadyFriends bool
 	var isRequested bool
 
-	if err := db.Db.QueryRow(`
+	if err := h.Db.QueryRow(`
 		SELECT EXISTS (SELECT 1 FROM blocked WHERE user_id = $1 AND blocked_id = $2)
 		 OR 
 		EXISTS (SELECT 1 FROM blocked WHERE user_id = $2 AND blocked_id = $1)
@@ -191,7 +189,7 @@ func CreateByName(c *gin.Context) {
 		return
 	}
 
-	if err := db.Db.QueryRow(`
+	if err := h.Db.QueryRow(`
 	SELECT EXISTS(SELECT 1 FROM friends WHERE user_id = $1 AND friend_id = $2 AND friended = false) OR EXISTS(SELECT 1 FROM friends WHERE user_id = $2 AND friend_id = $1 AND friended = false)
 	`, userId, user.Id).Scan(&isRequested); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
@@ -203,7 +201,7 @@ func CreateByName(c *gin.Context) {
 		return
 	}
 
-	if err := db.Db.QueryRow(`
+	if err := h.Db.QueryRow(`
 		SELECT EXISTS(SELECT 1 FROM friends WHERE user_id = $1 AND friend_id = $2) OR EXISTS(SELECT 1 FROM friends WHERE user_id = $2 AND friend_id = $1)
 		`, user.Id, userId).Scan(&isAlreadyFriends); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
@@ -213,8 +211,8 @@ func CreateByName(c *gin.Context) {
 		errors.SendErrorResponse(c, errors.ErrFriendAlreadyFriends, errors.StatusFriendAlreadyFriends)
 		return
 	}
-	logger.Debug.Println("user id", user.Id, "friend id", userId)
-	if _, err := db.Db.Exec(`
+	logger.Ctx(c).Debug("adding friend", "userId", user.Id, "friendId", userId)
+	if _, err := h.Db.Exec(`
 	INSERT INTO friends(user_id, friend_id, friended) VALUES($1, $2, false)
 	`, user.Id, userId); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
@@ -223,7 +221,7 @@ func CreateByName(c *gin.Context) {
 
 	var sqlUserImageId sql.NullInt64
 	var username string
-	if err := db.Db.QueryRow("SELECT files.id, username FROM users LEFT JOIN files ON user_id = users.id WHERE users.id = $1", user.Id).Scan(&sqlUserImageId, &username); err != nil {
+	if err := h.Db.QueryRow("SELECT files.id, username FROM users LEFT JOIN files ON user_id = users.id WHERE users.id = $1", user.Id).Scan(&sqlUserImageId, &username); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -236,7 +234,7 @@ func CreateByName(c *gin.Context) {
 	}
 
 	var friendImageId int64
-	if err := db.Db.QueryRow("SELECT files FROM files WHERE user_id = $1", userId).Scan(&friendImageId); err != nil && err != sql.ErrNoRows {
+	if err := h.Db.QueryRow("SELECT files FROM files WHERE user_id = $1", userId).Scan(&friendImageId); err != nil && err != sql.ErrNoRows {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	} else if err == sql.ErrNoRows {
@@ -261,8 +259,8 @@ func CreateByName(c *gin.Context) {
 		},
 		Event: events.USER_FRIEND_INCOMING_REQUEST_ADD,
 	}
-	wsclient.Pools.BroadcastClient(user.Id, res)
-	wsclient.Pools.BroadcastClient(userId, resFriend)
+	h.Pools.BroadcastClient(user.Id, res)
+	h.Pools.BroadcastClient(userId, resFriend)
 
 	c.Status(http.StatusCreated)
 }
@@ -6,7 +6,6 @@ import (
 	"strconv"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/db"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
 	"github.com/asianchinaboi/backendserver/internal/session"
@@ -14,7 +13,7 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func Delete(c *gin.Context) {
+func (h *Handlers) Delete(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -36,7 +35,7 @@ func Delete(c *gin.Context) {
 	}
 
 	var friendExists bool
-	if err := db.Db.QueryRow(`
+	if err := h.Db.QueryRow(`
 		SELECT EXISTS(SELECT 1 FROM friends WHERE user_id = $1 AND friend_id = $2 AND friended = true)
 		`, user.Id, userId).Scan(&friendExists); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
@@ -47,7 +46,7 @@ func Delete(c *gin.Context) {
 		return
 	}
 
-	if _, err := db.Db.Exec(`
+	if _, err := h.Db.Exec(`
 		DELETE FROM friends WHERE (user_id = $1 AND friend_id = $2) OR (user_id = $2 AND friend_id = $1)
 		`, user.Id, userId); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
@@ -68,7 +67,7 @@ func Delete(c *gin.Context) {
 		},
 		Event: events.USER_FRIEND_REMOVE,
 	}
-	wsclient.Pools.BroadcastClient(user.Id, res)
-	wsclient.Pools.BroadcastClient(intUserId, resFriend)
+	h.Pools.BroadcastClient(user.Id, res)
+	h.Pools.BroadcastClient(intUserId, resFriend)
 	c.Status(http.StatusNoContent)
 }
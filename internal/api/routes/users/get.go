@@ -6,14 +6,13 @@ import (
 	"regexp"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/db"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
 	"github.com/asianchinaboi/backendserver/internal/session"
 	"github.com/gin-gonic/gin"
 )
 
-func getUserInfo(c *gin.Context) {
+func (h *Handlers) getUserInfo(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -31,7 +30,7 @@ func getUserInfo(c *gin.Context) {
 	var userBody events.User
 	var imageId sql.NullInt64
 
-	if err := db.Db.QueryRow("SELECT users.id, username, flags, files.id, options FROM users LEFT JOIN files ON files.user_id = users.id WHERE users.id = $1", userId).Scan(&userBody.UserId, &userBody.Name, &userBody.Flags, &imageId, &userBody.Options); err != nil && err == sql.ErrNoRows {
+	if err := h.Db.QueryRow("SELECT users.id, username, flags, files.id, options FROM users LEFT JOIN files ON files.user_id = users.id WHERE users.id = $1", userId).Scan(&userBody.UserId, &userBody.Name, &userBody.Flags, &imageId, &userBody.Options); err != nil && err == sql.ErrNoRows {
 		errors.SendErrorResponse(c, errors.ErrUserNotFound, errors.StatusUserNotFound)
 		return
 	} else if err != nil {
@@ -48,7 +47,7 @@ func getUserInfo(c *gin.Context) {
 	c.JSON(http.StatusOK, userBody)
 }
 
-func getUserByUsername(c *gin.Context) {
+func (h *Handlers) getUserByUsername(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -67,7 +66,7 @@ func getUserByUsername(c *gin.Context) {
 	var userBody events.User
 	var imageId sql.NullInt64
 
-	if err := db.Db.QueryRow("SELECT users.id, username, flags, files.id, options FROM users LEFT JOIN files ON files.user_id = users.id WHERE username = $1", username).Scan(&userBody.UserId, &userBody.Name, &userBody.Flags, &imageId, &userBody.Options); err != nil && err == sql.ErrNoRows {
+	if err := h.Db.QueryRow("SELECT users.id, username, flags, files.id, options FROM users LEFT JOIN files ON files.user_id = users.id WHERE username = $1", username).Scan(&userBody.UserId, &userBody.Name, &userBody.Flags, &imageId, &userBody.Options); err != nil && err == sql.ErrNoRows {
 		errors.SendErrorResponse(c, errors.ErrUserNotFound, errors.StatusUserNotFound)
 		return
 	} else if err != nil {
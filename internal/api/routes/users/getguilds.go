@@ -5,7 +5,6 @@ import (
 	"net/http"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/db"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
 	"github.com/asianchinaboi/backendserver/internal/logger"
@@ -18,14 +17,14 @@ type guildList struct {
 	Dms    []events.Dm    `json:"dms"`
 }
 
-func getSelfGuilds(c *gin.Context) {
+func (h *Handlers) getSelfGuilds(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
 		return
 	}
-	logger.Info.Println("Getting guilds")
-	guildRows, err := db.Db.Query(
+	logger.Ctx(c).Info("getting guilds")
+	guildRows, err := h.Db.Query(
 		/* long goofy aaaaa code*/
 		`
 		SELECT g.id, g.name, f.id, g.save_chat, (SELECT user_id FROM userguilds WHERE guild_id = u.guild_id AND owner = true) AS owner_id, 
@@ -51,7 +50,7 @@ func getSelfGuilds(c *gin.Context) {
 		return
 	}
 	defer guildRows.Close()
-	dmRows, err := db.Db.Query(
+	dmRows, err := h.Db.Query(
 		`
 		SELECT userg.guild_id, userg.receiver_id, users.username, files.id, 
 		unread.msg_id AS last_read_msg_id, COUNT(msgs.id) filter (WHERE msgs.created > unread.time) AS unread_msgs,
@@ -107,7 +106,7 @@ func getSelfGuilds(c *gin.Context) {
 		} else {
 			dm.UserInfo.ImageId = -1
 		}
-		logger.Debug.Println(dm)
+		logger.Ctx(c).Debug("dm", "dm", dm)
 		dms = append(dms, dm)
 	}
 	c.JSON(http.StatusOK, guildList{
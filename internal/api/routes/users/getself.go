@@ -5,18 +5,17 @@ import (
 	"net/http"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/db"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
 	"github.com/asianchinaboi/backendserver/internal/session"
 	"github.com/gin-gonic/gin"
 )
 
-func getSelfInfo(c *gin.Context) {
+func (h *Handlers) getSelfInfo(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	var body events.User
 	var imageId sql.NullInt64
-	if err := db.Db.QueryRow("SELECT users.id, email, username, files.id, options, flags FROM users LEFT JOIN files ON files.user_id = users.id WHERE users.id=$1", user.Id).Scan(&body.UserId, &body.Email, &body.Name, &imageId, &body.Options, &body.Flags); err != nil {
+	if err := h.Db.QueryRow("SELECT users.id, email, username, files.id, options, flags FROM users LEFT JOIN files ON files.user_id = users.id WHERE users.id=$1", user.Id).Scan(&body.UserId, &body.Email, &body.Name, &imageId, &body.Options, &body.Flags); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
 	}
@@ -27,7 +26,7 @@ func getSelfInfo(c *gin.Context) {
 		body.ImageId = -1
 	}
 
-	rows, err := db.Db.Query(`SELECT DISTINCT r.permission_id FROM userroles u INNER JOIN rolepermissions r ON u.role_id = r.role_id WHERE user_id = $1 `, user.Id)
+	rows, err := h.Db.Query(`SELECT DISTINCT r.permission_id FROM userroles u INNER JOIN rolepermissions r ON u.role_id = r.role_id WHERE user_id = $1 `, user.Id)
 	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
@@ -9,7 +9,7 @@ func hashPass(pwd string) string {
 	byteString := []byte(pwd)
 	hash, err := bcrypt.GenerateFromPassword(byteString, bcrypt.DefaultCost)
 	if err != nil {
-		logger.Error.Println(err)
+		logger.Default.Error("unable to hash password", "err", err)
 	}
 	return string(hash)
 }
@@ -19,7 +19,7 @@ func comparePasswords(pwd string, userHashedPwd string) bool {
 	byteUserHash := []byte(userHashedPwd)
 	err := bcrypt.CompareHashAndPassword(byteUserHash, byteHash)
 	if err != nil {
-		logger.Error.Println(err)
+		logger.Default.Debug("password doesnt match", "err", err) //wrong passwords arent errors
 		return false
 	}
 	return true
@@ -7,15 +7,15 @@ import (
 	"strconv"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/db"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
+	"github.com/asianchinaboi/backendserver/internal/outbox"
 	"github.com/asianchinaboi/backendserver/internal/session"
 	"github.com/asianchinaboi/backendserver/internal/wsclient"
 	"github.com/gin-gonic/gin"
 )
 
-func leaveGuild(c *gin.Context) {
+func (h *Handlers) leaveGuild(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -34,7 +34,7 @@ func leaveGuild(c *gin.Context) {
 	var isOwner bool
 	var isInGuild bool
 
-	if err := db.Db.QueryRow(`
+	if err := h.Db.QueryRow(`
 	SELECT EXISTS (SELECT 1 FROM userguilds WHERE guild_id=$1 AND user_id=$2 AND owner = true),
 	EXISTS(SELECT 1 FROM userguilds WHERE guild_id=$1 AND user_id=$2 AND banned = false)
 	`, guildId, user.Id).Scan(&isOwner, &isInGuild); err != nil {
@@ -52,7 +52,7 @@ func leaveGuild(c *gin.Context) {
 	}
 	//BEGIN TRANSACTION
 	ctx := context.Background()
-	tx, err := db.Db.BeginTx(ctx, nil)
+	tx, err := h.Db.BeginTx(ctx, nil)
 	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
@@ -68,11 +68,6 @@ func leaveGuild(c *gin.Context) {
 		return
 	}
 
-	if err := tx.Commit(); err != nil { //commits the transaction
-		errors.SendErrorResponse(c, err, errors.StatusInternalError)
-		return
-	}
-
 	intGuildId, err := strconv.ParseInt(guildId, 10, 64)
 	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
@@ -96,8 +91,17 @@ func leaveGuild(c *gin.Context) {
 		},
 		Event: events.MEMBER_REMOVE,
 	}
-	wsclient.Pools.BroadcastClient(user.Id, res)
-	wsclient.Pools.RemoveUserFromGuildPool(intGuildId, user.Id)
-	wsclient.Pools.BroadcastGuild(intGuildId, guildRes)
+	if err := outbox.Enqueue(ctx, tx, intGuildId, guildRes.Event, guildRes.Data); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil { //commits the transaction
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+	h.Pools.BroadcastClient(user.Id, res)
+	h.Pools.RemoveUserFromGuildPool(intGuildId, user.Id)
+	h.Pools.BroadcastGuild(intGuildId, guildRes)
 	c.Status(http.StatusNoContent)
 }
@@ -8,7 +8,6 @@ import (
 	"strconv"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/db"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
 	"github.com/asianchinaboi/backendserver/internal/session"
@@ -16,7 +15,7 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func Accept(c *gin.Context) {
+func (h *Handlers) Accept(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -40,7 +39,7 @@ func Accept(c *gin.Context) {
 
 	var isRequested bool
 
-	if err := db.Db.QueryRow(`
+	if err := h.Db.QueryRow(`
 	SELECT EXISTS(SELECT 1 FROM friends WHERE user_id = $1 AND friend_id = $2 AND friended = false)
 	`, userId, user.Id).Scan(&isRequested); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
@@ -53,7 +52,7 @@ func Accept(c *gin.Context) {
 	}
 
 	ctx := context.Background()
-	tx, err := db.Db.BeginTx(ctx, nil)
+	tx, err := h.Db.BeginTx(ctx, nil)
 	if err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
 		return
@@ -81,7 +80,7 @@ func Accept(c *gin.Context) {
 
 	var clientUsername string
 	var clientImage sql.NullInt64
-	if err := db.Db.QueryRow(`
+	if err := h.Db.QueryRow(`
 	SELECT username, f.id FROM users LEFT JOIN files f ON f.user_id = users.id WHERE users.id = $1
 	`, user.Id).Scan(&clientUsername, &clientImage); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
@@ -97,7 +96,7 @@ func Accept(c *gin.Context) {
 
 	var friendUsername string
 	var friendImage sql.NullInt64
-	if err := db.Db.QueryRow(`
+	if err := h.Db.QueryRow(`
 	SELECT username, f.id FROM users LEFT JOIN files f ON f.user_id = users.id WHERE users.id = $1
 	`, userId).Scan(&friendUsername, &friendImage); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
@@ -126,8 +125,8 @@ func Accept(c *gin.Context) {
 		Event: events.USER_FRIEND_REQUEST_REMOVE,
 	}
 
-	wsclient.Pools.BroadcastClient(user.Id, res)
-	wsclient.Pools.BroadcastClient(intUserId, resFriend)
+	h.Pools.BroadcastClient(user.Id, res)
+	h.Pools.BroadcastClient(intUserId, resFriend)
 
 	resAfter := wsclient.DataFrame{
 		Op: wsclient.TYPE_DISPATCH,
@@ -148,8 +147,8 @@ func Accept(c *gin.Context) {
 		Event: events.USER_FRIEND_ADD,
 	}
 
-	wsclient.Pools.BroadcastClient(user.Id, resAfter)
-	wsclient.Pools.BroadcastClient(intUserId, resFriendAfter)
+	h.Pools.BroadcastClient(user.Id, resAfter)
+	h.Pools.BroadcastClient(intUserId, resFriendAfter)
 
 	c.Status(http.StatusNoContent)
 
@@ -6,7 +6,6 @@ import (
 	"strconv"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/db"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
 	"github.com/asianchinaboi/backendserver/internal/session"
@@ -14,7 +13,7 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func Decline(c *gin.Context) { //original sender of request can also decline
+func (h *Handlers) Decline(c *gin.Context) { //original sender of request can also decline
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
@@ -39,7 +38,7 @@ func Decline(c *gin.Context) { //original sender of request can also decline
 	var hasBeenRequested bool
 	var isTheRequestor bool
 
-	if err := db.Db.QueryRow(`
+	if err := h.Db.QueryRow(`
 	SELECT EXISTS(SELECT 1 FROM friends WHERE (user_id = $1 AND friend_id = $2) AND friended = false), EXISTS(SELECT 1 FROM friends WHERE (user_id = $2 AND friend_id = $1) AND friended = false)
 	`, userId, user.Id).Scan(&hasBeenRequested, &isTheRequestor); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
@@ -51,7 +50,7 @@ func Decline(c *gin.Context) { //original sender of request can also decline
 		return
 	}
 
-	if _, err := db.Db.Exec(`
+	if _, err := h.Db.Exec(`
 	DELETE FROM friends WHERE (user_id = $1 AND friend_id = $2) OR (user_id = $2 AND friend_id = $1)
 	`, userId, user.Id); err != nil {
 		errors.SendErrorResponse(c, err, errors.StatusInternalError)
@@ -83,8 +82,8 @@ func Decline(c *gin.Context) { //original sender of request can also decline
 		Event: eventTypeResFriend,
 	}
 
-	wsclient.Pools.BroadcastClient(user.Id, res)
-	wsclient.Pools.BroadcastClient(intUserId, resFriend)
+	h.Pools.BroadcastClient(user.Id, res)
+	h.Pools.BroadcastClient(intUserId, resFriend)
 
 	c.Status(http.StatusNoContent)
 
@@ -5,7 +5,6 @@ import (
 	"net/http"
 
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
-	"github.com/asianchinaboi/backendserver/internal/db"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
 	"github.com/asianchinaboi/backendserver/internal/session"
@@ -17,14 +16,14 @@ type FriendRequest struct {
 	Pending   []events.User `json:"pending"`   //people who have requested to be friends with you
 }
 
-func Get(c *gin.Context) {
+func (h *Handlers) Get(c *gin.Context) {
 	user := c.MustGet(middleware.User).(*session.Session)
 	if user == nil {
 		errors.SendErrorResponse(c, errors.ErrSessionDidntPass, errors.StatusInternalError)
 		return
 	}
 
-	pendingReqs, err := db.Db.Query(`
+	pendingReqs, err := h.Db.Query(`
 	SELECT f.user_id AS friend_id, u.username AS username, files.id AS image_id FROM friends f INNER JOIN users u ON f.user_id = u.id LEFT JOIN files ON files.user_id = f.user_id WHERE f.friend_id = $1 AND f.friended = false
 	`, user.Id)
 	if err != nil {
@@ -53,7 +52,7 @@ func Get(c *gin.Context) {
 		requests.Pending = append(requests.Pending, friendUser)
 	}
 
-	requestedReqs, err := db.Db.Query(`
+	requestedReqs, err := h.Db.Query(`
 	SELECT f.friend_id AS friend_id, u.username AS username, files.id AS image_id FROM friends f INNER JOIN users u ON f.friend_id = u.id LEFT JOIN files ON files.user_id = f.friend_id WHERE f.user_id = $1 AND f.friended = false
 	`, user.Id)
 	if err != nil {
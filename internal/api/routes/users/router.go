@@ -1,46 +1,73 @@
 package users
 
 import (
+	"github.com/asianchinaboi/backendserver/internal/api/deps"
 	"github.com/asianchinaboi/backendserver/internal/api/middleware"
 	"github.com/asianchinaboi/backendserver/internal/api/routes/users/blocked"
+	"github.com/asianchinaboi/backendserver/internal/api/routes/users/bots"
 	"github.com/asianchinaboi/backendserver/internal/api/routes/users/directmsgs"
 	"github.com/asianchinaboi/backendserver/internal/api/routes/users/friends"
 	"github.com/asianchinaboi/backendserver/internal/api/routes/users/requests"
 	"github.com/gin-gonic/gin"
 )
 
-func Routes(r *gin.RouterGroup) {
+// Handlers are the users routes, Routes builds them from what the app hands over
+type Handlers struct {
+	*deps.Deps
+}
+
+func New(d *deps.Deps) *Handlers {
+	return &Handlers{d}
+}
+
+func Routes(r *gin.RouterGroup, d *deps.Deps) {
+	h := New(d)
+	dm := directmsgs.New(d)
+	friend := friends.New(d)
+	request := requests.New(d)
+	block := blocked.New(d)
+	bot := bots.New(d)
+
 	users := r.Group("/users")
-	users.POST("/", userCreate)
-	users.GET("/:userId", middleware.Auth, getUserInfo)
-	users.GET("/username/:username", middleware.Auth, getUserByUsername)
+	users.POST("/", middleware.Captcha(d.Config, d.Captcha), h.userCreate)
+	users.GET("/:userId", middleware.Auth(d.Db), h.getUserInfo)
+	users.GET("/username/:username", middleware.Auth(d.Db), h.getUserByUsername)
+
+	users.POST("/auth", middleware.Captcha(d.Config, d.Captcha), h.userAuth)
+
+	self := users.Group("/@me", middleware.Auth(d.Db))
+
+	self.GET("/", h.getSelfInfo)
+	self.GET("/guilds", h.getSelfGuilds)
+	self.DELETE("/guilds/:guildId", h.leaveGuild)
 
-	users.POST("/auth", userAuth)
+	person := self.Group("", middleware.UserOnly) //bots cant manage the account that owns them
 
-	self := users.Group("/@me").Use(middleware.Auth)
+	person.PATCH("/", h.editSelf)
+	person.DELETE("/", h.userDelete)
 
-	self.PATCH("/", editSelf)
-	self.DELETE("/", userDelete)
-	self.GET("/", getSelfInfo)
+	person.POST("/dms", dm.Create)
+	person.DELETE("/dms/:dmId", dm.Delete)
 
-	self.POST("/dms", directmsgs.Create)
-	self.DELETE("/dms/:dmId", directmsgs.Delete)
+	person.PUT("/friends", friend.CreateByName)
+	person.PUT("/friends/:userId", friend.Create)
+	person.GET("/friends", friend.Get)
+	person.DELETE("/friends/:userId", friend.Delete)
 
-	self.PUT("/friends", friends.CreateByName)
-	self.PUT("/friends/:userId", friends.Create)
-	self.GET("/friends", friends.Get)
-	self.DELETE("/friends/:userId", friends.Delete)
+	person.POST("/requests/:userId/accept", request.Accept)
+	person.POST("/requests/:userId/decline", request.Decline)
+	person.GET("/requests", request.Get)
 
-	self.POST("/requests/:userId/accept", requests.Accept)
-	self.POST("/requests/:userId/decline", requests.Decline)
-	self.GET("/requests", requests.Get)
+	person.PUT("/blocked/:userId", block.Create)
+	person.GET("/blocked", block.Get)
+	person.DELETE("/blocked/:userId", block.Delete)
 
-	self.PUT("/blocked/:userId", blocked.Create)
-	self.GET("/blocked", blocked.Get)
-	self.DELETE("/blocked/:userId", blocked.Delete)
+	person.POST("/bots", bot.Create)
+	person.GET("/bots", bot.Get)
+	person.DELETE("/bots/:botId", bot.Delete)
+	person.POST("/bots/:botId/token", bot.ResetToken)
 
-	self.GET("/guilds", getSelfGuilds)
-	self.DELETE("/guilds/:guildId", leaveGuild)
+	person.GET("/storage", h.getSelfStorage)
 
-	self.DELETE("/msgs", clearUserMsg)
+	person.DELETE("/msgs", h.clearUserMsg)
 }
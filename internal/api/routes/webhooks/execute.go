@@ -0,0 +1,49 @@
+package webhooks
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"regexp"
+	"strconv"
+
+	"github.com/asianchinaboi/backendserver/internal/api/routes/guilds/msgs"
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/gin-gonic/gin"
+)
+
+// expects the same body as sending a message
+// username : string (optional) overrides the webhook name for this message
+func (h *Handlers) execute(c *gin.Context) {
+	webhookId := c.Param("webhookId")
+	if match, err := regexp.MatchString("^[0-9]+$", webhookId); err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if !match {
+		errors.SendErrorResponse(c, errors.ErrRouteParamInvalid, errors.StatusRouteParamInvalid)
+		return
+	}
+
+	intWebhookId, err := strconv.ParseInt(webhookId, 10, 64)
+	if err != nil {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	}
+
+	var guildId int64
+	var name string
+	var token string
+	if err := h.Db.QueryRow("SELECT guild_id, name, token FROM webhooks WHERE user_id = $1", webhookId).Scan(&guildId, &name, &token); err != nil && err != sql.ErrNoRows {
+		errors.SendErrorResponse(c, err, errors.StatusInternalError)
+		return
+	} else if err == sql.ErrNoRows {
+		errors.SendErrorResponse(c, errors.ErrWebhookNotExist, errors.StatusWebhookNotExist)
+		return
+	}
+
+	if subtle.ConstantTimeCompare([]byte(token), []byte(c.Param("token"))) != 1 {
+		errors.SendErrorResponse(c, errors.ErrWebhookNotExist, errors.StatusWebhookNotExist)
+		return
+	}
+
+	msgs.New(h.Deps).SendAsWebhook(c, intWebhookId, guildId, name)
+}
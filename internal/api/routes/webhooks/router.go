@@ -0,0 +1,23 @@
+package webhooks
+
+import (
+	"github.com/asianchinaboi/backendserver/internal/api/deps"
+	"github.com/gin-gonic/gin"
+)
+
+// no auth middleware since the token in the url is the auth
+// Handlers are the webhooks routes, Routes builds them from what the app hands over
+type Handlers struct {
+	*deps.Deps
+}
+
+func New(d *deps.Deps) *Handlers {
+	return &Handlers{d}
+}
+
+func Routes(r *gin.RouterGroup, d *deps.Deps) {
+	h := New(d)
+
+	webhooks := r.Group("/webhooks")
+	webhooks.POST("/:webhookId/:token", h.execute)
+}
@@ -10,25 +10,45 @@ import (
 	"github.com/gorilla/websocket"
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  config.Config.Server.BufferSize.Read,
-	WriteBufferSize: config.Config.Server.BufferSize.Write,
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
+// newUpgrader is built when the routes are so the buffer sizes come from the loaded config
+func newUpgrader(conf *config.Settings) *websocket.Upgrader {
+	return &websocket.Upgrader{
+		ReadBufferSize:  conf.Server.BufferSize.Read,
+		WriteBufferSize: conf.Server.BufferSize.Write,
+		CheckOrigin: func(r *http.Request) bool {
+			return true
+		},
+		EnableCompression: conf.Gateway.Compression, //permessage-deflate if the client asks for it
+	}
 }
 
-func webSocket(c *gin.Context) {
-	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
-	if err != nil {
-		errors.SendErrorResponse(c, err, errors.StatusInternalError)
-		return
-	}
-	client, err := wsclient.NewWsClient(conn)
-	if err != nil {
-		errors.SendErrorResponse(c, err, errors.StatusInternalError)
-		return
-	}
-	go client.Run()
+// query params encoding (json or msgpack) and compress (zlib-stream)
+func (h *Handlers) webSocket(upgrader *websocket.Upgrader) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if h.Pools.Draining() {
+			errors.SendErrorResponse(c, errors.ErrServerDraining, errors.StatusServerDraining)
+			return
+		}
+		encoding := c.DefaultQuery("encoding", wsclient.ENCODING_JSON)
+		compress := c.Query("compress")
+		if !wsclient.ValidEncoding(encoding) || !wsclient.ValidCompress(compress) {
+			errors.SendErrorResponse(c, errors.ErrGatewayOptionInvalid, errors.StatusBadRequest)
+			return
+		}
 
+		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusInternalError)
+			return
+		}
+		if compress == wsclient.COMPRESS_ZLIB_STREAM {
+			conn.EnableWriteCompression(false) //already compressed, deflating again is a waste
+		}
+		client, err := wsclient.NewWsClient(conn, h.Db, h.Pools, encoding, compress)
+		if err != nil {
+			errors.SendErrorResponse(c, err, errors.StatusInternalError)
+			return
+		}
+		go client.Run()
+	}
 }
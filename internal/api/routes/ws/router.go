@@ -1,10 +1,22 @@
 package ws
 
 import (
+	"github.com/asianchinaboi/backendserver/internal/api/deps"
 	"github.com/gin-gonic/gin"
 )
 
-func Routes(r *gin.RouterGroup) {
+// Handlers are the ws routes, Routes builds them from what the app hands over
+type Handlers struct {
+	*deps.Deps
+}
+
+func New(d *deps.Deps) *Handlers {
+	return &Handlers{d}
+}
+
+func Routes(r *gin.RouterGroup, d *deps.Deps) {
+	h := New(d)
+
 	ws := r.Group("/ws")
-	ws.GET("/", webSocket)
+	ws.GET("/", h.webSocket(newUpgrader(d.Config)))
 }
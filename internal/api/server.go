@@ -3,28 +3,38 @@ package api
 import (
 	"net/http"
 
+	"github.com/asianchinaboi/backendserver/internal/api/deps"
+	"github.com/asianchinaboi/backendserver/internal/api/middleware"
 	"github.com/asianchinaboi/backendserver/internal/api/routes"
 	"github.com/asianchinaboi/backendserver/internal/config"
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/handlers"
 )
 
-func StartServer() *http.Server {
+// NewHandler builds the routes with cors in front, httptest servers can use it directly
+func NewHandler(d *deps.Deps) http.Handler {
 	r := gin.New()
-	routes.PrepareRoutes(r)
+	r.MaxMultipartMemory = 1 << 20 //uploads bigger than this get spooled to disk instead of kept in memory
+	routes.PrepareRoutes(r, d)
+	return handlers.CORS(
+		handlers.AllowedHeaders([]string{"content-type", "Authorization", middleware.CaptchaIdHeader, middleware.CaptchaSolutionHeader, middleware.TraceparentHeader, "Range", "If-None-Match", ""}), //took some time to figure out middleware problem
+		handlers.ExposedHeaders([]string{"ETag", "Content-Range", "Accept-Ranges", "Content-Disposition", middleware.RequestIdHeader, middleware.TraceparentHeader,
+			middleware.RateLimitHeader, middleware.RateLimitRemainingHeader, middleware.RateLimitResetHeader, middleware.RetryAfterHeader}),
+		handlers.AllowedOrigins([]string{"*"}),
+		handlers.AllowedMethods([]string{"GET", "HEAD", "POST", "PUT", "OPTIONS", "DELETE", "PATCH"}),
+		handlers.AllowCredentials(),
+	)(r)
+}
+
+func NewServer(conf *config.Settings, handler http.Handler) *http.Server {
 	server := &http.Server{ //server settings
-		Addr:           config.Config.Server.Host + ":" + config.Config.Server.Port,
-		MaxHeaderBytes: config.Config.Server.MaxFileSize + config.Config.Server.MaxBodyRequestSize,
+		Addr:           conf.Server.Host + ":" + conf.Server.Port,
+		MaxHeaderBytes: conf.Server.MaxFileSize + conf.Server.MaxBodyRequestSize,
 		//prevents ddos attacks
-		WriteTimeout: config.Config.Server.Timeout.Write,
-		ReadTimeout:  config.Config.Server.Timeout.Read,
-		IdleTimeout:  config.Config.Server.Timeout.Idle,
-		Handler: handlers.CORS(
-			handlers.AllowedHeaders([]string{"content-type", "Authorization", ""}), //took some time to figure out middleware problem
-			handlers.AllowedOrigins([]string{"*"}),
-			handlers.AllowedMethods([]string{"GET", "HEAD", "POST", "PUT", "OPTIONS", "DELETE", "PATCH"}),
-			handlers.AllowCredentials(),
-		)(r),
+		WriteTimeout: conf.Server.Timeout.Write,
+		ReadTimeout:  conf.Server.Timeout.Read,
+		IdleTimeout:  conf.Server.Timeout.Idle,
+		Handler:      handler,
 	}
 	return server
 }
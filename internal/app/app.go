@@ -0,0 +1,156 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"github.com/asianchinaboi/backendserver/internal/api"
+	"github.com/asianchinaboi/backendserver/internal/api/deps"
+	"github.com/asianchinaboi/backendserver/internal/captcha"
+	"github.com/asianchinaboi/backendserver/internal/config"
+	"github.com/asianchinaboi/backendserver/internal/cooldown"
+	"github.com/asianchinaboi/backendserver/internal/db"
+	"github.com/asianchinaboi/backendserver/internal/interactions"
+	"github.com/asianchinaboi/backendserver/internal/logger"
+	"github.com/asianchinaboi/backendserver/internal/outbox"
+	"github.com/asianchinaboi/backendserver/internal/scanner"
+	"github.com/asianchinaboi/backendserver/internal/schedule"
+	"github.com/asianchinaboi/backendserver/internal/storage"
+	"github.com/asianchinaboi/backendserver/internal/tracing"
+	"github.com/asianchinaboi/backendserver/internal/uid"
+	"github.com/asianchinaboi/backendserver/internal/wsclient"
+)
+
+// App holds everything the server runs on, main builds one from the config and so can tests
+// the routes and jobs get what they need from here so apps in the same process dont share state
+// only the log output, the tracer, metrics and the snowflake node are per process
+type App struct {
+	Config       *config.Settings
+	Live         *config.Live   //Config with reloads applied
+	Sources      config.Sources //reloads read the config from the same places
+	Db           *sql.DB
+	Store        storage.Backend
+	Scanner      scanner.Scanner //nil when scanning is turned off
+	Limiter      cooldown.Limiter
+	Strikes      *cooldown.Strikes
+	Captcha      captcha.Provider //nil when the configured provider isnt registered
+	Interactions *interactions.Manager
+	Pools        *wsclient.ClientPools
+	Log          *logger.Logger
+	Handler      http.Handler
+	Server       *http.Server
+
+	scheduler  *schedule.Scheduler       //nil until Start
+	stopOutbox func(ctx context.Context) //nil until Start
+}
+
+// New sets everything up from conf in order, nothing connects to the db or listens until Start and ListenAndServe
+func New(conf *config.Settings, src config.Sources) (*App, error) {
+	if err := logger.Setup(logger.Options{
+		Level:   conf.Logging.Level,
+		Format:  conf.Logging.Format,
+		Dir:     conf.Logging.Dir,
+		MaxSize: conf.Logging.MaxSize,
+		MaxAge:  conf.Logging.MaxAge,
+	}); err != nil {
+		return nil, err
+	}
+	tracing.Setup(conf)
+	if err := uid.Setup(conf.Server.SnowflakeNodeID); err != nil {
+		return nil, err
+	}
+
+	a := &App{
+		Config:       conf,
+		Live:         config.NewLive(conf),
+		Sources:      src,
+		Strikes:      cooldown.NewStrikes(conf.Captcha.StrikeDecay),
+		Interactions: interactions.New(conf.Guild.InteractionTimeout),
+		Log:          logger.Default,
+	}
+	a.Pools = wsclient.NewPools(conf, a.Log)
+	var err error
+	if a.Db, err = db.Open(conf); err != nil {
+		return nil, err
+	}
+	if a.Store, err = storage.Open(conf, conf.Storage.Backend); err != nil {
+		return nil, err
+	}
+	if a.Scanner, err = scanner.New(conf); err != nil {
+		return nil, err
+	}
+	if a.Limiter, err = cooldown.New(conf.RateLimit.Backend, a.Db); err != nil {
+		return nil, err
+	}
+	captcha.RegisterBuiltin()
+	if a.Captcha, err = captcha.New(conf, a.Strikes); err != nil && conf.Captcha.Enabled {
+		a.Log.Warn("captcha provider is not registered", "provider", conf.Captcha.Provider)
+	}
+
+	a.Handler = api.NewHandler(&deps.Deps{
+		Db:           a.Db,
+		Store:        a.Store,
+		Config:       conf,
+		Live:         a.Live,
+		Limiter:      a.Limiter,
+		Strikes:      a.Strikes,
+		Pools:        a.Pools,
+		Scanner:      a.Scanner,
+		Captcha:      a.Captcha,
+		Interactions: a.Interactions,
+		Log:          a.Log,
+		NodeId:       conf.Server.SnowflakeNodeID,
+		Scheduler:    func() bool { return a.scheduler.Running() },
+	})
+	a.Server = api.NewServer(conf, a.Handler)
+	return a, nil
+}
+
+// Start migrates the db and starts the background jobs
+func (a *App) Start() error {
+	if err := db.Migrate(a.Db); err != nil {
+		return err
+	}
+	a.scheduler = schedule.Start(a.Db, a.Store, a.Config, a.Pools)
+	a.stopOutbox = outbox.Start(a.Db, a.Config)
+	return nil
+}
+
+func (a *App) ListenAndServe() error {
+	return a.Server.ListenAndServe()
+}
+
+// Reload applies the fields that are safe to change while running, see config.Reload
+func (a *App) Reload() error {
+	return a.Live.Reload(a.Sources)
+}
+
+// Shutdown drains websockets so readyz fails and clients move elsewhere, then waits for in flight requests
+// each step gets its timeout from the config, the drain timeout has to cover the readiness interval too
+func (a *App) Shutdown() {
+	a.Log.Info("draining websockets")
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), a.Config.Server.Timeout.Drain)
+	defer drainCancel()
+	a.Pools.Drain(drainCtx, a.Config.Gateway.ReadinessInterval, a.Config.Gateway.DrainJitter)
+
+	a.Log.Info("shutting down server")
+	ctx, cancel := context.WithTimeout(context.Background(), a.Config.Server.Timeout.Server)
+	defer cancel()
+	if err := a.Server.Shutdown(ctx); err != nil { //waits for in flight requests
+		a.Log.Warn("requests still running when shutting down", "error", err)
+	}
+	a.Close(ctx)
+}
+
+// Close stops the jobs and closes the db without draining anything first
+func (a *App) Close(ctx context.Context) {
+	a.scheduler.Stop(ctx)
+	if a.stopOutbox != nil {
+		a.stopOutbox(ctx)
+	}
+	tracing.Shutdown(ctx) //sends whatever spans are left
+	if err := a.Db.Close(); err != nil {
+		a.Log.Warn("unable to close the db", "error", err)
+	}
+}
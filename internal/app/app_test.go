@@ -0,0 +1,91 @@
+package app_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/asianchinaboi/backendserver/internal/app/apptest"
+	"github.com/asianchinaboi/backendserver/internal/events"
+	"github.com/asianchinaboi/backendserver/internal/session"
+)
+
+// runs without postgres so the wiring in app.New is checked every time
+func TestHealthz(t *testing.T) {
+	s := apptest.New(t, nil)
+	res, err := http.Get(s.URL + "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	if res.StatusCode != http.StatusOK || string(body) != "ok" {
+		t.Errorf("got %d %q", res.StatusCode, body)
+	}
+}
+
+// draining one app shouldnt make the other one fail readyz
+func TestAppsDontShareState(t *testing.T) {
+	a, b := apptest.New(t, nil), apptest.New(t, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel() //only sets the flag, theres nothing to wait for
+	a.App.Pools.Drain(ctx, time.Hour, 0)
+
+	for _, test := range []struct {
+		s    *apptest.Server
+		want bool
+	}{{a, true}, {b, false}} {
+		res, err := http.Get(test.s.URL + "/readyz")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ready struct {
+			Draining bool `json:"draining"`
+		}
+		err = json.NewDecoder(res.Body).Decode(&ready)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ready.Draining != test.want {
+			t.Errorf("%s draining %v, want %v", test.s.URL, ready.Draining, test.want)
+		}
+	}
+}
+
+// TestSignupLoginSend goes through what a new client does first, everything through the http api
+func TestSignupLoginSend(t *testing.T) {
+	s := apptest.NewWithDB(t, nil)
+	name, _ := s.Signup(t, "e2e")
+
+	s.JSON(t, http.MethodPost, "/users/auth", "", events.User{Name: name, Password: "wrong password"}, http.StatusBadRequest, nil)
+	var auth session.Session
+	s.JSON(t, http.MethodPost, "/users/auth", "", events.User{Name: name, Password: apptest.Password}, http.StatusOK, &auth)
+	if auth.Token == "" {
+		t.Fatal("login returned no token")
+	}
+	var self events.User
+	s.JSON(t, http.MethodGet, "/users/@me/", auth.Token, nil, http.StatusOK, &self)
+	if self.Name != name {
+		t.Fatalf("logged in as %q, want %q", self.Name, name)
+	}
+
+	guildId := s.CreateGuild(t, auth.Token, "e2e guild")
+	path := fmt.Sprintf("/guilds/%d/msgs", guildId)
+	s.JSON(t, http.MethodPost, path, "", events.Msg{Content: "no token"}, http.StatusForbidden, nil)
+	s.JSON(t, http.MethodPost, path, auth.Token, events.Msg{Content: "hello from the test"}, http.StatusNoContent, nil)
+
+	var msgs []events.Msg
+	before := fmt.Sprintf("%s?time=%d", path, time.Now().Unix()+1) //only msgs created before the second in time are returned
+	s.JSON(t, http.MethodGet, before, auth.Token, nil, http.StatusOK, &msgs)
+	if len(msgs) != 1 {
+		t.Fatalf("got %d messages, want 1", len(msgs))
+	}
+	if msgs[0].Content != "hello from the test" || msgs[0].Author.UserId != self.UserId || msgs[0].GuildId != guildId {
+		t.Errorf("got %+v", msgs[0])
+	}
+}
@@ -0,0 +1,188 @@
+// Package apptest runs the whole server inside an httptest server
+// the db isnt connected to until something queries it, so routes like /healthz work without postgres
+// point BACKEND_SERVER_DATABASECONFIG_* at a test database and use NewWithDB for everything else
+package apptest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/asianchinaboi/backendserver/internal/app"
+	"github.com/asianchinaboi/backendserver/internal/config"
+	"github.com/asianchinaboi/backendserver/internal/events"
+	"github.com/asianchinaboi/backendserver/internal/session"
+	"github.com/gin-gonic/gin"
+)
+
+type Server struct {
+	*httptest.Server
+	App *app.App
+}
+
+// New builds an app from the defaults and env vars with storage in a temp dir and logs only on the console
+// configure can change anything else before the app is built, it can be nil
+func New(tb testing.TB, configure func(conf *config.Settings)) *Server {
+	tb.Helper()
+	gin.SetMode(gin.TestMode)
+	conf, err := config.Load(config.Sources{})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	conf.Storage.Backend = "local"
+	conf.Storage.Local.Path = tb.TempDir()
+	conf.Logging.Dir = ""
+	conf.Logging.Level = "warn"
+	conf.RateLimit.Backend = "memory"
+	if configure != nil {
+		configure(conf)
+	}
+
+	a, err := app.New(conf, config.Sources{})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	s := &Server{Server: httptest.NewServer(a.Handler), App: a}
+	tb.Cleanup(func() {
+		s.Close()
+		a.Close(context.Background())
+	})
+	return s
+}
+
+// NewWithDB is New with the db migrated and the background jobs running
+// the test is skipped unless BACKEND_SERVER_DATABASECONFIG_HOST is set, the database should be one that can be thrown away
+func NewWithDB(tb testing.TB, configure func(conf *config.Settings)) *Server {
+	tb.Helper()
+	if os.Getenv(config.EnvPrefix+"SERVER_DATABASECONFIG_HOST") == "" {
+		tb.Skip("no test database, set " + config.EnvPrefix + "SERVER_DATABASECONFIG_HOST")
+	}
+	s := New(tb, configure)
+	if err := s.App.Start(); err != nil {
+		tb.Fatal(err)
+	}
+	return s
+}
+
+// Request sends body to the api, token is sent as the Authorization header if its set
+func (s *Server) Request(tb testing.TB, method string, path string, token string, contentType string, body io.Reader) *http.Response {
+	tb.Helper()
+	req, err := http.NewRequest(method, s.URL+"/api"+path, body)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	res, err := s.Client().Do(req)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { res.Body.Close() })
+	return res
+}
+
+// JSON sends v as json and fails the test unless the response has the wanted status
+// the response is decoded into out if its not nil
+func (s *Server) JSON(tb testing.TB, method string, path string, token string, v interface{}, status int, out interface{}) {
+	tb.Helper()
+	var body io.Reader
+	if v != nil {
+		data, err := json.Marshal(v)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		body = bytes.NewReader(data)
+	}
+	res := s.Request(tb, method, path, token, "application/json", body)
+	Expect(tb, res, status, out)
+}
+
+// Multipart sends v as json in the body field and every file under field, like the clients do for uploads
+func (s *Server) Multipart(tb testing.TB, method string, path string, token string, v interface{}, field string, files map[string][]byte) *http.Response {
+	tb.Helper()
+	var buffer bytes.Buffer
+	w := multipart.NewWriter(&buffer)
+	data, err := json.Marshal(v)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if err := w.WriteField("body", string(data)); err != nil {
+		tb.Fatal(err)
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names) //same order every run
+	for _, name := range names {
+		part, err := w.CreateFormFile(field, name)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		part.Write(files[name])
+	}
+	if err := w.Close(); err != nil {
+		tb.Fatal(err)
+	}
+	return s.Request(tb, method, path, token, w.FormDataContentType(), &buffer)
+}
+
+// Expect fails the test unless res has the wanted status, then decodes the body into out if its not nil
+func Expect(tb testing.TB, res *http.Response, status int, out interface{}) {
+	tb.Helper()
+	if res.StatusCode != status {
+		message, _ := io.ReadAll(res.Body)
+		tb.Fatalf("%s %s: got %d, want %d: %s", res.Request.Method, res.Request.URL.Path, res.StatusCode, status, message)
+	}
+	if out != nil {
+		if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+			tb.Fatal(err)
+		}
+	}
+}
+
+// Signup creates a user with a name that wont clash with earlier runs and returns its token
+func (s *Server) Signup(tb testing.TB, name string) (string, string) {
+	tb.Helper()
+	name = fmt.Sprintf("%s_%d", name, time.Now().UnixNano()%1e9)
+	var auth session.Session
+	s.JSON(tb, http.MethodPost, "/users/", "", events.User{Name: name, Password: Password}, http.StatusOK, &auth)
+	if auth.Token == "" {
+		tb.Fatal("signup returned no token")
+	}
+	return name, auth.Token
+}
+
+// Password is what Signup gives every user
+const Password = "correct horse battery"
+
+// CreateGuild makes a guild with chat saved and returns its id
+func (s *Server) CreateGuild(tb testing.TB, token string, name string) int64 {
+	tb.Helper()
+	saveChat := true
+	s.JSON(tb, http.MethodPost, "/guilds/", token, events.Guild{Name: name, SaveChat: &saveChat}, http.StatusNoContent, nil)
+	var list struct {
+		Guilds []events.Guild `json:"guilds"`
+	}
+	s.JSON(tb, http.MethodGet, "/users/@me/guilds", token, nil, http.StatusOK, &list)
+	for _, guild := range list.Guilds {
+		if guild.Name == name {
+			return guild.GuildId
+		}
+	}
+	tb.Fatalf("guild %q wasnt created", name)
+	return 0
+}
@@ -0,0 +1,194 @@
+package blobs
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/asianchinaboi/backendserver/internal/files"
+	"github.com/asianchinaboi/backendserver/internal/metrics"
+	"github.com/asianchinaboi/backendserver/internal/storage"
+	"github.com/asianchinaboi/backendserver/internal/transcode"
+)
+
+//file contents are stored once under the sha256 of the uncompressed bytes
+//every stored blob has a row in blobs and the files rows with the same hash are its references
+//a blob is only removed once no files row points at it anymore
+//files from before this have no hash and are still stored under their own id
+//content is stored before the row commits and removed after the row is gone, the sweep in schedule cleans up
+//whatever is left when a transaction rolls back or a delete fails
+
+var hashExp = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// ValidHash is whether hash could have come from Hash, anything else isnt a blob
+func ValidHash(hash string) bool {
+	return hashExp.MatchString(hash)
+}
+
+// Hash returns the hex sha256 and size of everything in src
+func Hash(src io.Reader) (string, int64, error) {
+	h := sha256.New()
+	size, err := io.Copy(h, src)
+	if err != nil {
+		return "", size, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// Put stores src unless the same content is already stored and returns its hash
+// it has to be called in the transaction that inserts the files row, the lock it takes stops Release removing the blob before that commits
+func Put(ctx context.Context, tx *sql.Tx, store storage.Backend, src io.ReadSeeker) (string, error) {
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	hash, size, err := Hash(src)
+	if err != nil {
+		return "", err
+	}
+	if err := lock(ctx, tx, hash); err != nil {
+		return "", err
+	}
+
+	for attempt := 0; attempt < 3; attempt++ {
+		var inserted bool
+		if err := tx.QueryRowContext(ctx, "INSERT INTO blobs (hash, size) VALUES ($1, $2) ON CONFLICT (hash) DO NOTHING RETURNING true", hash, size).Scan(&inserted); err != nil && err != sql.ErrNoRows {
+			return "", err
+		} else if err == nil { //first time this content was seen
+			metrics.UploadBytes.Add(float64(size), "new")
+			if _, err := src.Seek(0, io.SeekStart); err != nil {
+				return "", err
+			}
+			key := storage.BlobKey(hash)
+			if _, err := files.Save(ctx, store, key, src); err != nil {
+				return "", err
+			}
+			object, err := store.Stat(ctx, key)
+			if err != nil {
+				return "", err
+			}
+			if _, err := tx.ExecContext(ctx, "UPDATE blobs SET stored_size = $1 WHERE hash = $2", object.Size, hash); err != nil {
+				return "", err
+			}
+			return hash, nil
+		}
+
+		var exists bool
+		if err := tx.QueryRowContext(ctx, "SELECT true FROM blobs WHERE hash = $1 FOR SHARE", hash).Scan(&exists); err == nil {
+			metrics.UploadBytes.Add(float64(size), "duplicate")
+			return hash, nil
+		} else if err != sql.ErrNoRows {
+			return "", err
+		}
+		//released between the insert and the lock so try again
+	}
+	return "", errors.ErrFileBlobRemoved
+}
+
+// Release removes the blob if nothing references it anymore
+func Release(ctx context.Context, conn *sql.DB, store storage.Backend, hash string) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	//waits for anything still putting the blob to commit
+	if err := lock(ctx, tx, hash); err != nil {
+		return err
+	}
+	var exists bool
+	if err := tx.QueryRowContext(ctx, "SELECT true FROM blobs WHERE hash = $1 FOR UPDATE", hash).Scan(&exists); err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	var referenced bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM files WHERE hash = $1)", hash).Scan(&referenced); err != nil {
+		return err
+	}
+	if referenced {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM blobs WHERE hash = $1", hash); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if err := RemoveOrphan(ctx, conn, store, hash); err != nil {
+		return err
+	}
+	if err := storage.DeletePrefix(ctx, store, transcode.Prefix(hash)); err != nil {
+		return err
+	}
+	return storage.DeletePrefix(ctx, store, ThumbPrefix("", 0, sql.NullString{String: hash, Valid: true}))
+}
+
+// RemoveOrphan deletes the stored content of a blob that has no row
+// it takes the same lock as Put so content thats being stored again under the same hash is left alone
+func RemoveOrphan(ctx context.Context, conn *sql.DB, store storage.Backend, hash string) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lock(ctx, tx, hash); err != nil {
+		return err
+	}
+	var exists bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM blobs WHERE hash = $1)", hash).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+	if err := store.Delete(ctx, storage.BlobKey(hash)); err != nil && err != errors.ErrStorageObjectNotExist {
+		return err
+	}
+	return tx.Commit()
+}
+
+// lock is held until tx ends, it covers the blob row and its content in storage
+func lock(ctx context.Context, tx *sql.Tx, hash string) error {
+	_, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", "blob:"+hash)
+	return err
+}
+
+// Remove cleans up after a deleted files row
+func Remove(ctx context.Context, conn *sql.DB, store storage.Backend, entityType string, id int64, hash sql.NullString) error {
+	if hash.Valid {
+		return Release(ctx, conn, store, hash.String)
+	}
+	if err := store.Delete(ctx, storage.Key(entityType, id)); err != nil {
+		return err
+	}
+	return storage.DeletePrefix(ctx, store, ThumbPrefix(entityType, id, hash))
+}
+
+// Key returns where the contents of a files row are stored
+func Key(entityType string, id int64, hash sql.NullString) string {
+	if hash.Valid {
+		return storage.BlobKey(hash.String)
+	}
+	return storage.Key(entityType, id)
+}
+
+// ThumbPrefix returns where the thumbnails of a files row are kept
+// thumbnails are stored as they are since images dont compress any further
+func ThumbPrefix(entityType string, id int64, hash sql.NullString) string {
+	if hash.Valid {
+		return fmt.Sprintf("thumbs/%s/", hash.String)
+	}
+	return fmt.Sprintf("thumbs/%s-%d/", entityType, id)
+}
+
+// ThumbKey returns the key of one thumbnail size
+func ThumbKey(entityType string, id int64, hash sql.NullString, size int) string {
+	return fmt.Sprintf("%s%d", ThumbPrefix(entityType, id, hash), size)
+}
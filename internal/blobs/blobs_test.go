@@ -0,0 +1,21 @@
+package blobs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidHash(t *testing.T) {
+	hash, _, err := Hash(strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ValidHash(hash) {
+		t.Errorf("%s from Hash isnt valid", hash)
+	}
+	for _, name := range []string{"", "a", "ab", ".DS_Store", strings.ToUpper(hash), hash[:63], hash + "0", "../" + hash[3:]} {
+		if ValidHash(name) {
+			t.Errorf("%q is valid", name)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package captcha
+
+import (
+	"sync"
+
+	"github.com/asianchinaboi/backendserver/internal/config"
+	"github.com/asianchinaboi/backendserver/internal/cooldown"
+	"github.com/asianchinaboi/backendserver/internal/errors"
+)
+
+// Challenge is sent to the client, fields not used by a provider are left empty
+type Challenge struct {
+	Id         string `json:"id"`
+	Provider   string `json:"provider"`
+	Nonce      string `json:"nonce,omitempty"`
+	Difficulty int    `json:"difficulty,omitempty"`
+	Expires    int64  `json:"expires"`
+}
+
+// Provider issues challenges and checks the answers for them
+// a solved challenge cant be used again, Verify has to reject it the second time
+type Provider interface {
+	Name() string
+	Issue(ip string) (*Challenge, error)
+	Verify(ip string, id string, solution string) error
+}
+
+// Factory creates a provider for one app so apps dont share challenges
+// strikes is how many times ips have hit the cooldown, providers can use it to make challenges harder
+type Factory func(conf *config.Settings, strikes *cooldown.Strikes) (Provider, error)
+
+var (
+	providersMutex sync.RWMutex
+	providers      = make(map[string]Factory)
+)
+
+// Register adds a provider that can be selected with captcha.provider in the config
+func Register(name string, factory Factory) {
+	providersMutex.Lock()
+	defer providersMutex.Unlock()
+	providers[name] = factory
+}
+
+// New creates the provider selected in conf
+func New(conf *config.Settings, strikes *cooldown.Strikes) (Provider, error) {
+	name := conf.Captcha.Provider
+	if name == "" {
+		name = powName
+	}
+	providersMutex.RLock()
+	defer providersMutex.RUnlock()
+	factory, ok := providers[name]
+	if !ok {
+		return nil, errors.ErrCaptchaProviderNotExist
+	}
+	return factory(conf, strikes)
+}
+
+// RegisterBuiltin adds the providers that come with the server, the app calls it before creating the configured one
+func RegisterBuiltin() {
+	Register(powName, newPow)
+}
@@ -0,0 +1,160 @@
+package captcha
+
+import (
+	"crypto/hmac"
+	crypto "crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asianchinaboi/backendserver/internal/config"
+	"github.com/asianchinaboi/backendserver/internal/cooldown"
+	"github.com/asianchinaboi/backendserver/internal/errors"
+)
+
+//proof of work challenge
+//client has to find a solution where sha256(nonce + solution) starts with difficulty zero bits
+//costs nothing for the server to check and doesnt need any outside service
+//challenges arent stored, the id has the nonce, difficulty and expiry signed with a secret only this app knows
+//so issuing one is free, only solved ones are kept until they expire so they cant be used twice
+
+const (
+	powName       = "pow"
+	nonceLength   = 16
+	secretLength  = 32
+	sweepInterval = time.Minute
+)
+
+type pow struct {
+	sync.Mutex
+	secret    []byte
+	used      map[string]time.Time //solved ids to when they expire
+	lastSweep time.Time
+	conf      *config.Settings
+	strikes   *cooldown.Strikes
+}
+
+func newPow(conf *config.Settings, strikes *cooldown.Strikes) (Provider, error) {
+	secret := make([]byte, secretLength)
+	if _, err := crypto.Read(secret); err != nil {
+		return nil, err
+	}
+	return &pow{
+		secret:  secret,
+		used:    make(map[string]time.Time),
+		conf:    conf,
+		strikes: strikes,
+	}, nil
+}
+
+func (p *pow) Name() string {
+	return powName
+}
+
+func (p *pow) Issue(ip string) (*Challenge, error) {
+	nonce, err := randomHex(nonceLength)
+	if err != nil {
+		return nil, err
+	}
+	difficulty := p.difficultyFor(ip)
+	expires := time.Now().Add(p.conf.Captcha.Expire).Unix()
+	payload := fmt.Sprintf("%s.%d.%d", nonce, difficulty, expires)
+	return &Challenge{
+		Id:         payload + "." + p.sign(ip, payload),
+		Provider:   powName,
+		Nonce:      nonce,
+		Difficulty: difficulty,
+		Expires:    expires,
+	}, nil
+}
+
+// Verify checks the id was issued to ip and hasnt expired or been solved before, then checks the solution
+// wrong solutions dont use the challenge up since finding the right one is the work anyway
+func (p *pow) Verify(ip string, id string, solution string) error {
+	parts := strings.Split(id, ".")
+	if len(parts) != 4 {
+		return errors.ErrCaptchaExpired
+	}
+	payload := strings.Join(parts[:3], ".")
+	if !hmac.Equal([]byte(parts[3]), []byte(p.sign(ip, payload))) { //made up, changed or issued to another ip
+		return errors.ErrCaptchaExpired
+	}
+	difficulty, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return errors.ErrCaptchaExpired
+	}
+	expires, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return errors.ErrCaptchaExpired
+	}
+	hash := sha256.Sum256([]byte(parts[0] + solution))
+	if leadingZeroBits(hash[:]) < difficulty {
+		return errors.ErrCaptchaInvalid
+	}
+
+	p.Lock()
+	defer p.Unlock()
+	now := time.Now()
+	if now.Sub(p.lastSweep) > sweepInterval {
+		p.sweep(now)
+	}
+	if _, ok := p.used[id]; ok {
+		return errors.ErrCaptchaExpired
+	}
+	p.used[id] = time.Unix(expires, 0)
+	return nil
+}
+
+// sign ties the payload to the ip so a solved challenge cant be handed to someone else
+func (p *pow) sign(ip string, payload string) string {
+	mac := hmac.New(sha256.New, p.secret)
+	fmt.Fprintf(mac, "%s:%s", ip, payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// lock must be held by caller
+func (p *pow) sweep(now time.Time) {
+	p.lastSweep = now
+	for id, expires := range p.used { //expired ones fail the expiry check anyway
+		if now.After(expires) {
+			delete(p.used, id)
+		}
+	}
+}
+
+// difficulty goes up by one bit for every few times the ip got stopped by the cooldown
+func (p *pow) difficultyFor(ip string) int {
+	conf := p.conf.Captcha
+	difficulty := conf.Difficulty
+	if perLevel := conf.StrikesPerLevel; perLevel > 0 {
+		difficulty += p.strikes.Count(ip) / perLevel
+	}
+	if maxDifficulty := conf.MaxDifficulty; maxDifficulty > 0 && difficulty > maxDifficulty {
+		difficulty = maxDifficulty
+	}
+	return difficulty
+}
+
+func leadingZeroBits(hash []byte) int {
+	count := 0
+	for _, b := range hash {
+		if b != 0 {
+			return count + bits.LeadingZeros8(b)
+		}
+		count += 8
+	}
+	return count
+}
+
+func randomHex(l int) (string, error) {
+	b := make([]byte, l)
+	if _, err := crypto.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
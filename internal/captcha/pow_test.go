@@ -0,0 +1,104 @@
+package captcha_test
+
+import (
+	"crypto/sha256"
+	"math/bits"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asianchinaboi/backendserver/internal/captcha"
+	"github.com/asianchinaboi/backendserver/internal/config"
+	"github.com/asianchinaboi/backendserver/internal/cooldown"
+	"github.com/asianchinaboi/backendserver/internal/errors"
+)
+
+const ip = "127.0.0.1"
+
+func newPow(t *testing.T, expire time.Duration) captcha.Provider {
+	t.Helper()
+	conf := &config.Settings{}
+	conf.Captcha.Difficulty = 8
+	conf.Captcha.Expire = expire
+	captcha.RegisterBuiltin()
+	provider, err := captcha.New(conf, cooldown.NewStrikes(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+func issue(t *testing.T, p captcha.Provider) *captcha.Challenge {
+	t.Helper()
+	challenge, err := p.Issue(ip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return challenge
+}
+
+func zeros(nonce string, solution string) int {
+	hash := sha256.Sum256([]byte(nonce + solution))
+	count := 0
+	for _, b := range hash {
+		count += bits.LeadingZeros8(b)
+		if b != 0 {
+			break
+		}
+	}
+	return count
+}
+
+// find returns the first solution that passes or fails the challenge
+func find(challenge *captcha.Challenge, passes bool) string {
+	for i := 0; ; i++ {
+		solution := strconv.Itoa(i)
+		if (zeros(challenge.Nonce, solution) >= challenge.Difficulty) == passes {
+			return solution
+		}
+	}
+}
+
+// solve does the work a client would
+func solve(challenge *captcha.Challenge) string {
+	return find(challenge, true)
+}
+
+func TestPowVerify(t *testing.T) {
+	p := newPow(t, time.Minute)
+	challenge := issue(t, p)
+	solution := solve(challenge)
+
+	if err := p.Verify(ip, challenge.Id, find(challenge, false)); err != errors.ErrCaptchaInvalid {
+		t.Fatalf("wrong solution got %v", err)
+	}
+	if err := p.Verify("10.0.0.1", challenge.Id, solution); err != errors.ErrCaptchaExpired {
+		t.Fatalf("other ip got %v", err)
+	}
+	easier := strings.Replace(challenge.Id, "."+strconv.Itoa(challenge.Difficulty)+".", ".0.", 1)
+	if err := p.Verify(ip, easier, solution); err != errors.ErrCaptchaExpired {
+		t.Fatalf("changed difficulty got %v", err)
+	}
+	if err := p.Verify(ip, challenge.Id, solution); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Verify(ip, challenge.Id, solution); err != errors.ErrCaptchaExpired {
+		t.Fatalf("reused challenge got %v", err)
+	}
+}
+
+func TestPowExpired(t *testing.T) {
+	p := newPow(t, -time.Second)
+	challenge := issue(t, p)
+	if err := p.Verify(ip, challenge.Id, solve(challenge)); err != errors.ErrCaptchaExpired {
+		t.Fatalf("expired challenge got %v", err)
+	}
+}
+
+func TestPowOtherApp(t *testing.T) {
+	challenge := issue(t, newPow(t, time.Minute))
+	if err := newPow(t, time.Minute).Verify(ip, challenge.Id, solve(challenge)); err != errors.ErrCaptchaExpired {
+		t.Fatalf("challenge from another app got %v", err)
+	}
+}
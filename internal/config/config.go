@@ -1,33 +1,143 @@
 package config
 
 import (
-	"os"
-	"path/filepath"
+	"sync/atomic"
 	"time"
-
-	"github.com/asianchinaboi/backendserver/internal/logger"
-	"gopkg.in/yaml.v3"
 )
 
-type config struct {
-	Guild  guild  `yaml:"guild"`
-	User   user   `yaml:"user"`
-	Server server `yaml:"server"`
+type Settings struct {
+	Guild      guild      `yaml:"guild"`
+	User       user       `yaml:"user"`
+	Server     server     `yaml:"server"`
+	Captcha    captcha    `yaml:"captcha"`
+	EventHooks eventHooks `yaml:"eventHooks"`
+	Storage    storage    `yaml:"storage"`
+	Scanner    scanner    `yaml:"scanner"`
+	Transcode  transcode  `yaml:"transcode"`
+	RateLimit  rateLimit  `yaml:"rateLimit"`
+	Gateway    gateway    `yaml:"gateway"`
+	Logging    logging    `yaml:"logging"`
+	Tracing    tracing    `yaml:"tracing"`
 }
 
 type guild struct {
 	MaxInvites   int           `yaml:"maxInvites"`
 	MaxMsgLength int           `yaml:"maxMsgLength"`
+	MaxWebhooks  int           `yaml:"maxWebhooks"`
+	MaxCommands  int           `yaml:"maxCommands"`
 	Timeout      time.Duration `yaml:"timeout"`
+	StorageQuota int64         `yaml:"storageQuota"` //bytes of attachments kept in a guild, 0 for unlimited
+
+	//how long a bot has to reply to a command
+	//pending interactions are only kept in the memory of the instance that got the command
+	//so with more than one instance the bot has to reply to that same one or it gets interaction not found
+	InteractionTimeout time.Duration `yaml:"interactionTimeout"`
 }
 
 type user struct {
 	MaxGuildsPerUser  int           `yaml:"maxGuildsPerUser"`  //not used yet
 	MaxFriendsPerUser int           `yaml:"maxFriendsPerUser"` //not used yet
-	CoolDownLength    time.Duration `yaml:"coolDownLength"`
-	CoolDownTokens    int           `yaml:"coolDownTokens"`
+	MaxBotsPerUser    int           `yaml:"maxBotsPerUser"`
 	TokenExpireTime   time.Duration `yaml:"tokenExpireTime"`
 	WSPerUser         int           `yaml:"wsPerUser"`
+	StorageQuota      int64         `yaml:"storageQuota"` //bytes a user can upload in total, 0 for unlimited
+}
+
+type captcha struct {
+	Enabled         bool          `yaml:"enabled"`         //off by default, login, signup and join need a solved challenge when on
+	Provider        string        `yaml:"provider"`        //pow is the only built in one for now
+	Difficulty      int           `yaml:"difficulty"`      //leading zero bits required for pow
+	MaxDifficulty   int           `yaml:"maxDifficulty"`   //cap so legit users dont get stuck forever
+	StrikesPerLevel int           `yaml:"strikesPerLevel"` //cooldown strikes needed to add one bit
+	StrikeDecay     time.Duration `yaml:"strikeDecay"`
+	Expire          time.Duration `yaml:"expire"`
+}
+
+type eventHooks struct {
+	MaxPerGuild  int           `yaml:"maxPerGuild"`
+	MaxAttempts  int           `yaml:"maxAttempts"` //goes to the dead letter list after this
+	BaseBackoff  time.Duration `yaml:"baseBackoff"` //doubles every failed attempt
+	MaxBackoff   time.Duration `yaml:"maxBackoff"`
+	Timeout      time.Duration `yaml:"timeout"`
+	PollInterval time.Duration `yaml:"pollInterval"`
+	BatchSize    int           `yaml:"batchSize"`
+}
+
+type storage struct {
+	Backend string       `yaml:"backend"` //local or s3
+	Local   localStorage `yaml:"local"`
+	S3      s3Storage    `yaml:"s3"`
+}
+
+type localStorage struct {
+	Path string `yaml:"path"`
+}
+
+type s3Storage struct {
+	Endpoint  string `yaml:"endpoint"` //including the scheme e.g. http://localhost:9000
+	Region    string `yaml:"region"`
+	Bucket    string `yaml:"bucket"`
+	AccessKey string `yaml:"accessKey"`
+	SecretKey string `yaml:"secretKey"`
+	PathStyle bool   `yaml:"pathStyle"`
+}
+
+type scanner struct {
+	Backend string        `yaml:"backend"` //none, clamd or fake
+	Network string        `yaml:"network"` //unix or tcp
+	Address string        `yaml:"address"` //socket path or host:port
+	Timeout time.Duration `yaml:"timeout"`
+	Policy  string        `yaml:"policy"` //when the scanner is down for files outside guilds, allow quarantine or reject
+}
+
+type transcode struct {
+	Enabled       bool          `yaml:"enabled"` //needs ffmpeg with libx264
+	FFmpegPath    string        `yaml:"ffmpegPath"`
+	PollInterval  time.Duration `yaml:"pollInterval"`
+	BatchSize     int           `yaml:"batchSize"`
+	Timeout       time.Duration `yaml:"timeout"` //per job, a job stuck for longer is picked up again
+	MaxAttempts   int           `yaml:"maxAttempts"`
+	SegmentLength time.Duration `yaml:"segmentLength"`
+}
+
+type rateLimit struct {
+	Backend       string                   `yaml:"backend"`       //memory or postgres, postgres shares limits between instances
+	Default       rateLimitRule            `yaml:"default"`       //shared by every route without its own rule
+	Routes        map[string]rateLimitRule `yaml:"routes"`        //keyed by method and route e.g. "POST /api/guilds/:guildId/msgs"
+	BotMultiplier int                      `yaml:"botMultiplier"` //bots get this many times the limit since a lot of them run from the same host
+}
+
+type gateway struct {
+	MaxFrameSize int64                    `yaml:"maxFrameSize"` //bytes, bigger frames close the connection
+	Compression  bool                     `yaml:"compression"`  //permessage-deflate for clients that support it
+	OpRateLimits map[string]rateLimitRule `yaml:"opRateLimits"` //per connection, keyed by op name e.g. "heartbeat"
+	DrainJitter  time.Duration            `yaml:"drainJitter"`  //reconnects are spread over this long so other instances arent hit all at once
+
+	ReadinessInterval time.Duration `yaml:"readinessInterval"` //how often the load balancer checks /readyz, draining waits this long before sending reconnects
+}
+
+type tracing struct {
+	Enabled     bool              `yaml:"enabled"`
+	Endpoint    string            `yaml:"endpoint"`    //otlp http collector, spans are posted to <endpoint>/v1/traces
+	Headers     map[string]string `yaml:"headers"`     //sent with every export e.g. an api key
+	ServiceName string            `yaml:"serviceName"` //so instances can be told apart, give them different names
+	SampleRatio float64           `yaml:"sampleRatio"` //share of new traces kept, 1 keeps all of them
+	BatchSize   int               `yaml:"batchSize"`
+	QueueSize   int               `yaml:"queueSize"` //spans waiting to be sent before new ones are dropped
+	Interval    time.Duration     `yaml:"interval"`
+}
+
+type logging struct {
+	Level   string `yaml:"level"`   //debug, info, warn or error
+	Format  string `yaml:"format"`  //json or text
+	Dir     string `yaml:"dir"`     //leave empty to only log to the console
+	MaxSize int64  `yaml:"maxSize"` //bytes before a log file is rotated, 0 only rotates daily
+	MaxAge  int    `yaml:"maxAge"`  //days to keep old log files, 0 keeps them forever
+}
+
+type rateLimitRule struct {
+	Limit  int           `yaml:"limit"`  //requests that can be made at once
+	Window time.Duration `yaml:"window"` //how long it takes to get them all back
 }
 
 type database struct {
@@ -51,6 +161,11 @@ type server struct {
 	ImageProfileSize   int           `yaml:"imageProfileSize"`
 	MaxFileSize        int           `yaml:"maxFileSize"`
 	MaxBodyRequestSize int           `yaml:"maxBodyRequestSize"`
+	MaxChunkSize       int           `yaml:"maxChunkSize"`       //for chunked uploads
+	UploadSessionAlive time.Duration `yaml:"uploadSessionAlive"` //unfinished uploads are removed after this long without a chunk
+	FileURLSecret      string        `yaml:"fileUrlSecret"`      //signs download links, leave empty to turn them off
+	FileURLExpire      time.Duration `yaml:"fileUrlExpire"`
+	MetricsToken       string        `yaml:"metricsToken"` //bearer token for /metrics and /api/status, leave empty to turn both off
 	DatabaseConfig     database      `yaml:"databaseConfig"`
 }
 
@@ -59,6 +174,7 @@ type timeout struct {
 	Write  time.Duration `yaml:"write"`
 	Read   time.Duration `yaml:"read"`
 	Idle   time.Duration `yaml:"idle"`
+	Drain  time.Duration `yaml:"drain"` //how long websockets get to leave when shutting down
 }
 
 type bufferSize struct {
@@ -66,99 +182,19 @@ type bufferSize struct {
 	Write int `yaml:"write"`
 }
 
-var (
-	Config *config
-)
+// Live is the config an app is running with, fields that can change on SIGHUP are read through Current
+// everything else is read from the *Settings the app was created with
+type Live struct {
+	current atomic.Value //*Settings
+}
+
+func NewLive(conf *Settings) *Live {
+	l := &Live{}
+	l.current.Store(conf)
+	return l
+}
 
-func loadConfig() (*config, error) {
-	conf := &config{}
-	path, err := os.Getwd()
-	if err != nil {
-		return nil, err
-	}
-	path = filepath.Join(path, "config.yml")
-	file, err := os.OpenFile(path, os.O_RDONLY, 0644)
-	if err != nil {
-		return nil, err //make config if doesnt exist later - done
-	}
-	defer file.Close()
-	d := yaml.NewDecoder(file)
-	if err := d.Decode(&conf); err != nil {
-		return nil, err
-	}
-	return conf, nil
-}
-
-func createConfig() (*config, error) {
-	conf := &config{
-		Guild: guild{
-			MaxInvites:   10,
-			MaxMsgLength: 2048,
-			Timeout:      20 * time.Second,
-		},
-		User: user{
-			MaxGuildsPerUser:  100,
-			MaxFriendsPerUser: 200,
-			CoolDownLength:    10 * time.Second,
-			CoolDownTokens:    25,
-			TokenExpireTime:   60 * time.Hour * 24,
-			WSPerUser:         5,
-		},
-		Server: server{
-			Host: "0.0.0.0",
-			Port: "8080",
-			Timeout: timeout{
-				Server: 15 * time.Second,
-				Write:  15 * time.Second,
-				Read:   15 * time.Second,
-				Idle:   15 * time.Second,
-			},
-			BufferSize: bufferSize{
-				Read:  4096,
-				Write: 4096,
-			},
-			SnowflakeNodeID:    1,
-			TempFileAlive:      24 * time.Hour,
-			ImageProfileSize:   4096,
-			MaxFileSize:        1024 * 1024 * 15, // 15mb
-			MaxBodyRequestSize: 1024 * 1024 * 5,  // 5mb
-			DatabaseConfig: database{ //replace cred values later on
-				Host:         "localhost",
-				Port:         5432,
-				User:         "postgres",
-				Password:     "1",
-				DBName:       "chatapp",
-				SSLMode:      "disable",
-				MaxOpenConns: 50,
-				MaxIdleConns: 25,
-			},
-		},
-	}
-	path, err := os.Getwd()
-	if err != nil {
-		return nil, err
-	}
-	path = filepath.Join(path, "config.yml")
-	file, err := os.Create(path)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-	e := yaml.NewEncoder(file)
-	if err := e.Encode(conf); err != nil {
-		return nil, err
-	}
-	return conf, nil
-}
-
-func init() {
-	var err error
-	Config, err = loadConfig()
-	if err != nil { //have a look at this later
-		Config, err = createConfig()
-		if err != nil {
-			logger.Fatal.Fatalln(err)
-		}
-		logger.Info.Println("Created config.yml")
-	}
+// Current is the config with the last reload applied, only the fields in reload.go ever change
+func (l *Live) Current() *Settings {
+	return l.current.Load().(*Settings)
 }
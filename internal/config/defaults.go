@@ -0,0 +1,146 @@
+package config
+
+import "time"
+
+// defaults is the bottom layer, the config file, env vars and flags go on top of it
+// nothing secret is in here so running without a config file never ends up with a known password
+func defaults() *Settings {
+	return &Settings{
+		Guild: guild{
+			MaxInvites:   10,
+			MaxMsgLength: 2048,
+			MaxWebhooks:  10,
+			MaxCommands:  100,
+			Timeout:      20 * time.Second,
+			StorageQuota: 1024 * 1024 * 1024 * 5, // 5gb
+
+			InteractionTimeout: 15 * time.Minute,
+		},
+		User: user{
+			MaxGuildsPerUser:  100,
+			MaxFriendsPerUser: 200,
+			MaxBotsPerUser:    10,
+			TokenExpireTime:   60 * time.Hour * 24,
+			WSPerUser:         5,
+			StorageQuota:      1024 * 1024 * 1024, // 1gb
+		},
+		Captcha: captcha{
+			Enabled:         false, //clients have to know how to solve challenges before this is turned on
+			Provider:        "pow",
+			Difficulty:      18,
+			MaxDifficulty:   24,
+			StrikesPerLevel: 5,
+			StrikeDecay:     10 * time.Minute,
+			Expire:          5 * time.Minute,
+		},
+		EventHooks: eventHooks{
+			MaxPerGuild:  5,
+			MaxAttempts:  8,
+			BaseBackoff:  10 * time.Second,
+			MaxBackoff:   time.Hour,
+			Timeout:      10 * time.Second,
+			PollInterval: 5 * time.Second,
+			BatchSize:    50,
+		},
+		Storage: storage{
+			Backend: "local",
+			Local: localStorage{
+				Path: "uploads",
+			},
+			S3: s3Storage{
+				Region:    "us-east-1",
+				PathStyle: true,
+			},
+		},
+		Scanner: scanner{
+			Backend: "none",
+			Network: "unix",
+			Address: "/var/run/clamav/clamd.ctl",
+			Timeout: 30 * time.Second,
+			Policy:  "allow",
+		},
+		Transcode: transcode{
+			Enabled:       false,
+			FFmpegPath:    "ffmpeg",
+			PollInterval:  10 * time.Second,
+			BatchSize:     2,
+			Timeout:       30 * time.Minute,
+			MaxAttempts:   3,
+			SegmentLength: 6 * time.Second,
+		},
+		RateLimit: rateLimit{
+			Backend: "memory",
+			Default: rateLimitRule{Limit: 50, Window: 10 * time.Second},
+			Routes: map[string]rateLimitRule{
+				"POST /api/users/auth":                 {Limit: 5, Window: time.Minute},
+				"POST /api/users/":                     {Limit: 3, Window: 10 * time.Minute},
+				"POST /api/guilds/:guildId/msgs":       {Limit: 10, Window: 10 * time.Second},
+				"POST /api/guilds/:guildId/invites":    {Limit: 5, Window: time.Minute},
+				"POST /api/webhooks/:webhookId/:token": {Limit: 10, Window: 10 * time.Second},
+			},
+			BotMultiplier: 4,
+		},
+		Tracing: tracing{
+			Enabled:     false,
+			Endpoint:    "http://localhost:4318",
+			ServiceName: "backendserver",
+			SampleRatio: 1,
+			BatchSize:   512,
+			QueueSize:   4096,
+			Interval:    5 * time.Second,
+		},
+		Logging: logging{
+			Level:   "info",
+			Format:  "json",
+			Dir:     "logs",
+			MaxSize: 100 * 1024 * 1024, // 100mb
+			MaxAge:  30,
+		},
+		Gateway: gateway{
+			MaxFrameSize: 4096,
+			Compression:  true,
+			DrainJitter:  10 * time.Second,
+
+			ReadinessInterval: 5 * time.Second,
+			OpRateLimits: map[string]rateLimitRule{
+				"heartbeat": {Limit: 5, Window: 20 * time.Second},
+				"identify":  {Limit: 1, Window: time.Minute},
+			},
+		},
+		Server: server{
+			Host: "0.0.0.0",
+			Port: "8080",
+			Timeout: timeout{
+				Server: 15 * time.Second,
+				Write:  15 * time.Second,
+				Read:   15 * time.Second,
+				Idle:   15 * time.Second,
+				Drain:  30 * time.Second,
+			},
+			BufferSize: bufferSize{
+				Read:  4096,
+				Write: 4096,
+			},
+			SnowflakeNodeID:    1,
+			TempFileAlive:      24 * time.Hour,
+			ImageProfileSize:   4096,
+			MaxFileSize:        1024 * 1024 * 15, // 15mb
+			MaxBodyRequestSize: 1024 * 1024 * 5,  // 5mb
+			MaxChunkSize:       1024 * 1024 * 4,  // 4mb
+			UploadSessionAlive: 24 * time.Hour,
+			FileURLSecret:      "", //set one to sign download links
+			FileURLExpire:      time.Hour,
+			MetricsToken:       "", //set one to turn on /metrics and /api/status
+			DatabaseConfig: database{
+				Host:         "localhost",
+				Port:         5432,
+				User:         "postgres",
+				Password:     "", //comes from the file or BACKEND_SERVER_DATABASECONFIG_PASSWORD(_FILE)
+				DBName:       "chatapp",
+				SSLMode:      "disable",
+				MaxOpenConns: 50,
+				MaxIdleConns: 25,
+			},
+		},
+	}
+}
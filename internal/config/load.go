@@ -0,0 +1,276 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asianchinaboi/backendserver/internal/logger"
+	"gopkg.in/yaml.v3"
+)
+
+//layers from bottom to top: defaults, config file, env vars, flags
+//env vars are the yaml path in caps joined with _ e.g. BACKEND_SERVER_DATABASECONFIG_PASSWORD
+//adding _FILE to any of them reads the value from a file instead so secrets can be mounted
+
+const (
+	EnvPrefix   = "BACKEND_"
+	envFile     = "_FILE"
+	defaultPath = "config.yml"
+)
+
+// keys older config files can still have, theyre dropped with a warning instead of failing the load
+var deprecated = map[string]string{
+	"user.coolDownLength": "rateLimit.default.window",
+	"user.coolDownTokens": "rateLimit.default.limit",
+}
+
+type setFlags []string
+
+func (s *setFlags) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *setFlags) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// Sources is where the config is read from, keep it around so a reload reads the same places
+type Sources struct {
+	Path string   //empty uses BACKEND_CONFIG, then config.yml which doesnt have to exist
+	Sets []string //key=value from -set, applied last
+}
+
+// Flags adds -config and -set to fs, the sources are filled in once fs is parsed
+func Flags(fs *flag.FlagSet) *Sources {
+	src := &Sources{}
+	fs.StringVar(&src.Path, "config", "", "path to the config file (default config.yml, or "+EnvPrefix+"CONFIG)")
+	fs.Var((*setFlags)(&src.Sets), "set", "override a config value e.g. -set server.port=9000, can be repeated")
+	return src
+}
+
+// Load builds the config from every layer and validates it, nothing global is changed until Use
+func Load(src Sources) (*Settings, error) {
+	path, required := src.Path, true
+	if path == "" {
+		path = os.Getenv(EnvPrefix + "CONFIG")
+	}
+	if path == "" {
+		path, required = defaultPath, false
+	}
+	conf := defaults()
+	if err := loadFile(conf, path, required); err != nil {
+		return nil, err
+	}
+	if err := loadEnv(reflect.ValueOf(conf).Elem(), EnvPrefix); err != nil {
+		return nil, err
+	}
+	for _, set := range src.Sets {
+		key, value, ok := cut(set, "=")
+		if !ok {
+			return nil, fmt.Errorf("-set %q: expected key=value", set)
+		}
+		if err := setPath(conf, key, value); err != nil {
+			return nil, fmt.Errorf("-set %s: %v", key, err)
+		}
+	}
+	if err := conf.validate(); err != nil {
+		return nil, err
+	}
+	return conf, nil
+}
+
+// loadFile decodes over the defaults so the file only needs what it changes
+func loadFile(conf *Settings, path string, required bool) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) && !required {
+		logger.Warn.Printf("no %s found, using defaults and %s env vars\n", path, EnvPrefix)
+		return nil
+	} else if err != nil {
+		return err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	if len(doc.Content) == 0 { //empty file
+		return nil
+	}
+	if lines := deprecatedLines(&doc, ""); len(lines) > 0 {
+		data = blankLines(data, lines)
+	}
+	d := yaml.NewDecoder(bytes.NewReader(data))
+	d.KnownFields(true) //typos should fail instead of silently using the default
+	if err := d.Decode(conf); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
+
+// deprecatedLines warns about every key in deprecated the file has and returns the lines they take up
+func deprecatedLines(node *yaml.Node, prefix string) map[int]bool {
+	lines := make(map[int]bool)
+	if node.Kind == yaml.DocumentNode {
+		for _, child := range node.Content {
+			for line := range deprecatedLines(child, prefix) {
+				lines[line] = true
+			}
+		}
+		return lines
+	}
+	if node.Kind != yaml.MappingNode {
+		return lines
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		path := key.Value
+		if prefix != "" {
+			path = prefix + "." + key.Value
+		}
+		if replacement, ok := deprecated[path]; ok {
+			logger.Default.Warn("config key is no longer used", "key", path, "use", replacement, "line", key.Line)
+			for line := key.Line; line <= lastLine(value); line++ {
+				lines[line] = true
+			}
+			continue
+		}
+		for line := range deprecatedLines(value, path) {
+			lines[line] = true
+		}
+	}
+	return lines
+}
+
+// lastLine is the last line a node starts something on, good enough for the scalars in deprecated
+func lastLine(node *yaml.Node) int {
+	last := node.Line
+	for _, child := range node.Content {
+		if line := lastLine(child); line > last {
+			last = line
+		}
+	}
+	return last
+}
+
+// blankLines empties the given 1 based lines instead of removing them so decode errors keep the line numbers of the file
+func blankLines(data []byte, lines map[int]bool) []byte {
+	split := bytes.Split(data, []byte("\n"))
+	for i := range split {
+		if lines[i+1] {
+			split[i] = nil
+		}
+	}
+	return bytes.Join(split, []byte("\n"))
+}
+
+// loadEnv walks the struct and sets every field that has an env var, maps are left to the file
+func loadEnv(v reflect.Value, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := v.Field(i)
+		name := prefix + strings.ToUpper(yamlName(t.Field(i)))
+		if field.Kind() == reflect.Struct && field.Type() != reflect.TypeOf(time.Duration(0)) {
+			if err := loadEnv(field, name+"_"); err != nil {
+				return err
+			}
+			continue
+		}
+		if field.Kind() == reflect.Map {
+			continue
+		}
+		value, ok := os.LookupEnv(name)
+		if path, fileOk := os.LookupEnv(name + envFile); fileOk {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("%s: %v", name+envFile, err)
+			}
+			value, ok = strings.TrimRight(string(data), "\r\n"), true
+		}
+		if !ok {
+			continue
+		}
+		if err := setValue(field, value); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// setPath sets a field from a dotted yaml path like server.timeout.read
+func setPath(conf *Settings, path, value string) error {
+	v := reflect.ValueOf(conf).Elem()
+	for _, part := range strings.Split(path, ".") {
+		if v.Kind() != reflect.Struct {
+			return fmt.Errorf("not a config key")
+		}
+		found := false
+		for i := 0; i < v.NumField(); i++ {
+			if strings.EqualFold(yamlName(v.Type().Field(i)), part) {
+				v, found = v.Field(i), true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("not a config key")
+		}
+	}
+	return setValue(v, value)
+}
+
+func setValue(v reflect.Value, value string) error {
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("cant be set from a string, use the config file")
+	}
+	return nil
+}
+
+func yamlName(field reflect.StructField) string {
+	name, _, _ := cut(field.Tag.Get("yaml"), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// cut is strings.Cut which needs go 1.18
+func cut(s, sep string) (string, string, bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// baseline is the config.yml the server wrote on first start before the config was layered
+const baseline = `guild:
+    maxInvites: 10
+    maxMsgLength: 2048
+    timeout: 20s
+user:
+    maxGuildsPerUser: 100
+    maxFriendsPerUser: 200
+    coolDownLength: 10s
+    coolDownTokens: 25
+    tokenExpireTime: 1440h0m0s
+    wsPerUser: 5
+server:
+    host: 0.0.0.0
+    port: "8080"
+    timeout:
+        server: 15s
+        write: 15s
+        read: 15s
+        idle: 15s
+    bufferSize:
+        read: 4096
+        write: 4096
+    snowflakeNodeID: 1
+    tempFileAlive: 24h0m0s
+    imageProfileSize: 4096
+    maxFileSize: 15728640
+    maxBodyRequestSize: 5242880
+    databaseConfig:
+        host: localhost
+        port: 5432
+        user: postgres
+        password: "1"
+        dbName: chatapp
+        sslMode: disable
+        maxOpenConns: 50
+        maxIdleConns: 25
+`
+
+func writeConfig(t *testing.T, data string) Sources {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return Sources{Path: path}
+}
+
+func TestLoadBaselineFile(t *testing.T) {
+	conf, err := Load(writeConfig(t, baseline))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Guild.MaxInvites != 10 || conf.User.TokenExpireTime != 1440*time.Hour || conf.Server.DatabaseConfig.DBName != "chatapp" {
+		t.Errorf("values from the file werent used: %+v %+v", conf.Guild, conf.User)
+	}
+	if conf.Server.Timeout.Server != 15*time.Second || conf.Server.BufferSize.Read != 4096 {
+		t.Errorf("nested values from the file werent used: %+v", conf.Server)
+	}
+}
+
+func TestLoadUnknownKey(t *testing.T) {
+	//the cooldown keys before it are dropped but the error should still have the line in the file
+	_, err := Load(writeConfig(t, strings.Replace(baseline, "wsPerUser", "wsPerUsr", 1)))
+	if err == nil || !strings.Contains(err.Error(), "line 11") || !strings.Contains(err.Error(), "wsPerUsr") {
+		t.Errorf("got %v, want the typo and its line", err)
+	}
+	if _, err := Load(writeConfig(t, baseline+"bogus: 1\n")); err == nil || !strings.Contains(err.Error(), "bogus") {
+		t.Errorf("got %v for an unknown top level key", err)
+	}
+}
+
+func TestLoadEmptyFile(t *testing.T) {
+	if _, err := Load(writeConfig(t, "")); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,21 @@
+package config
+
+//only fields that are read on every use can be reloaded, everything else needs a restart
+//e.g. the rate limit backend or the db pool are set up once at startup
+
+// Reload reads every layer again and applies the safe fields to Current
+// the new config has to pass validation, a bad file leaves the old values in place
+func (l *Live) Reload(src Sources) error {
+	fresh, err := Load(src)
+	if err != nil {
+		return err
+	}
+	next := *l.Current()
+	next.RateLimit.Default = fresh.RateLimit.Default
+	next.RateLimit.Routes = fresh.RateLimit.Routes
+	next.RateLimit.BotMultiplier = fresh.RateLimit.BotMultiplier
+	next.Guild.MaxMsgLength = fresh.Guild.MaxMsgLength
+	next.Guild.MaxInvites = fresh.Guild.MaxInvites
+	l.current.Store(&next)
+	return nil
+}
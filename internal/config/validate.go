@@ -0,0 +1,179 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asianchinaboi/backendserver/internal/logger"
+)
+
+// validationError lists every bad field at once so fixing the config isnt a guessing game
+type validationError []string
+
+func (e validationError) Error() string {
+	return "invalid config:\n  " + strings.Join(e, "\n  ")
+}
+
+type validator struct {
+	problems validationError
+}
+
+func (v *validator) check(ok bool, key string, format string, args ...interface{}) {
+	if !ok {
+		v.problems = append(v.problems, key+": "+fmt.Sprintf(format, args...))
+	}
+}
+
+func (v *validator) atLeast(key string, value int64, min int64) {
+	v.check(value >= min, key, "must be at least %d, got %d", min, value)
+}
+
+func (v *validator) between(key string, value int64, min int64, max int64) {
+	v.check(value >= min && value <= max, key, "must be between %d and %d, got %d", min, max, value)
+}
+
+func (v *validator) positive(key string, d time.Duration) {
+	v.check(d > 0, key, "must be a positive duration e.g. 10s, got %v", d)
+}
+
+func (v *validator) oneOf(key string, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.check(false, key, "must be one of %s, got %q", strings.Join(allowed, ", "), value)
+}
+
+func (v *validator) rule(key string, rule rateLimitRule) {
+	v.atLeast(key+".limit", int64(rule.Limit), 1)
+	v.positive(key+".window", rule.Window)
+}
+
+func (c *Settings) validate() error {
+	v := &validator{}
+
+	v.atLeast("guild.maxInvites", int64(c.Guild.MaxInvites), 1)
+	v.atLeast("guild.maxMsgLength", int64(c.Guild.MaxMsgLength), 1)
+	v.atLeast("guild.maxWebhooks", int64(c.Guild.MaxWebhooks), 0)
+	v.atLeast("guild.maxCommands", int64(c.Guild.MaxCommands), 0)
+	v.positive("guild.timeout", c.Guild.Timeout)
+	v.atLeast("guild.storageQuota", c.Guild.StorageQuota, 0)
+	v.positive("guild.interactionTimeout", c.Guild.InteractionTimeout)
+
+	v.atLeast("user.maxBotsPerUser", int64(c.User.MaxBotsPerUser), 0)
+	v.positive("user.tokenExpireTime", c.User.TokenExpireTime)
+	v.atLeast("user.wsPerUser", int64(c.User.WSPerUser), 1)
+	v.atLeast("user.storageQuota", c.User.StorageQuota, 0)
+
+	if c.Captcha.Enabled {
+		v.check(c.Captcha.Provider != "", "captcha.provider", "must be set when captchas are enabled")
+		v.between("captcha.maxDifficulty", int64(c.Captcha.MaxDifficulty), 1, 64)
+		v.between("captcha.difficulty", int64(c.Captcha.Difficulty), 0, int64(c.Captcha.MaxDifficulty))
+		v.atLeast("captcha.strikesPerLevel", int64(c.Captcha.StrikesPerLevel), 1)
+		v.positive("captcha.strikeDecay", c.Captcha.StrikeDecay)
+		v.positive("captcha.expire", c.Captcha.Expire)
+	}
+
+	v.atLeast("eventHooks.maxPerGuild", int64(c.EventHooks.MaxPerGuild), 0)
+	v.atLeast("eventHooks.maxAttempts", int64(c.EventHooks.MaxAttempts), 1)
+	v.positive("eventHooks.baseBackoff", c.EventHooks.BaseBackoff)
+	v.check(c.EventHooks.MaxBackoff >= c.EventHooks.BaseBackoff, "eventHooks.maxBackoff", "must not be less than baseBackoff (%v), got %v", c.EventHooks.BaseBackoff, c.EventHooks.MaxBackoff)
+	v.positive("eventHooks.timeout", c.EventHooks.Timeout)
+	v.positive("eventHooks.pollInterval", c.EventHooks.PollInterval)
+	v.atLeast("eventHooks.batchSize", int64(c.EventHooks.BatchSize), 1)
+
+	v.oneOf("storage.backend", c.Storage.Backend, "local", "s3")
+	if c.Storage.Backend == "local" {
+		v.check(c.Storage.Local.Path != "", "storage.local.path", "must be set for the local backend")
+	}
+	if c.Storage.Backend == "s3" {
+		v.check(strings.HasPrefix(c.Storage.S3.Endpoint, "http://") || strings.HasPrefix(c.Storage.S3.Endpoint, "https://"), "storage.s3.endpoint", "must start with http:// or https://, got %q", c.Storage.S3.Endpoint)
+		v.check(c.Storage.S3.Bucket != "", "storage.s3.bucket", "must be set for the s3 backend")
+	}
+
+	v.oneOf("scanner.backend", c.Scanner.Backend, "none", "clamd", "fake")
+	if c.Scanner.Backend == "clamd" {
+		v.oneOf("scanner.network", c.Scanner.Network, "unix", "tcp")
+		v.check(c.Scanner.Address != "", "scanner.address", "must be set for clamd")
+	}
+	v.positive("scanner.timeout", c.Scanner.Timeout)
+	v.oneOf("scanner.policy", c.Scanner.Policy, "allow", "quarantine", "reject")
+
+	if c.Transcode.Enabled {
+		v.check(c.Transcode.FFmpegPath != "", "transcode.ffmpegPath", "must be set when transcoding is enabled")
+		v.positive("transcode.pollInterval", c.Transcode.PollInterval)
+		v.atLeast("transcode.batchSize", int64(c.Transcode.BatchSize), 1)
+		v.positive("transcode.timeout", c.Transcode.Timeout)
+		v.atLeast("transcode.maxAttempts", int64(c.Transcode.MaxAttempts), 1)
+		v.positive("transcode.segmentLength", c.Transcode.SegmentLength)
+	}
+
+	v.oneOf("rateLimit.backend", c.RateLimit.Backend, "memory", "postgres")
+	v.rule("rateLimit.default", c.RateLimit.Default)
+	for route, rule := range c.RateLimit.Routes {
+		v.rule("rateLimit.routes["+route+"]", rule)
+	}
+	v.atLeast("rateLimit.botMultiplier", int64(c.RateLimit.BotMultiplier), 1)
+
+	v.atLeast("gateway.maxFrameSize", c.Gateway.MaxFrameSize, 512)
+	for op, rule := range c.Gateway.OpRateLimits {
+		v.rule("gateway.opRateLimits["+op+"]", rule)
+	}
+	v.check(c.Gateway.DrainJitter >= 0, "gateway.drainJitter", "must not be negative, got %v", c.Gateway.DrainJitter)
+	v.check(c.Gateway.ReadinessInterval >= 0, "gateway.readinessInterval", "must not be negative, got %v", c.Gateway.ReadinessInterval)
+
+	if c.Tracing.Enabled {
+		v.check(strings.HasPrefix(c.Tracing.Endpoint, "http://") || strings.HasPrefix(c.Tracing.Endpoint, "https://"), "tracing.endpoint", "must start with http:// or https://, got %q", c.Tracing.Endpoint)
+		v.check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sampleRatio", "must be between 0 and 1, got %v", c.Tracing.SampleRatio)
+		v.atLeast("tracing.batchSize", int64(c.Tracing.BatchSize), 1)
+		v.atLeast("tracing.queueSize", int64(c.Tracing.QueueSize), int64(c.Tracing.BatchSize))
+		v.positive("tracing.interval", c.Tracing.Interval)
+	}
+
+	_, ok := logger.ParseLevel(c.Logging.Level)
+	v.check(ok, "logging.level", "must be one of debug, info, warn, error, got %q", c.Logging.Level)
+	v.oneOf("logging.format", c.Logging.Format, "json", "text")
+	v.atLeast("logging.maxSize", c.Logging.MaxSize, 0)
+	v.atLeast("logging.maxAge", int64(c.Logging.MaxAge), 0)
+
+	port, err := strconv.Atoi(c.Server.Port)
+	v.check(err == nil && port > 0 && port < 65536, "server.port", "must be a port between 1 and 65535, got %q", c.Server.Port)
+	v.positive("server.timeout.server", c.Server.Timeout.Server)
+	v.positive("server.timeout.write", c.Server.Timeout.Write)
+	v.positive("server.timeout.read", c.Server.Timeout.Read)
+	v.positive("server.timeout.idle", c.Server.Timeout.Idle)
+	v.check(c.Server.Timeout.Drain >= 0, "server.timeout.drain", "must not be negative, got %v", c.Server.Timeout.Drain)
+	v.atLeast("server.bufferSize.read", int64(c.Server.BufferSize.Read), 1)
+	v.atLeast("server.bufferSize.write", int64(c.Server.BufferSize.Write), 1)
+	v.between("server.snowflakeNodeID", c.Server.SnowflakeNodeID, 0, 1023) //10 bits in a snowflake
+	v.positive("server.tempFileAlive", c.Server.TempFileAlive)
+	v.atLeast("server.imageProfileSize", int64(c.Server.ImageProfileSize), 1)
+	v.atLeast("server.maxFileSize", int64(c.Server.MaxFileSize), 1)
+	v.atLeast("server.maxBodyRequestSize", int64(c.Server.MaxBodyRequestSize), 1)
+	v.between("server.maxChunkSize", int64(c.Server.MaxChunkSize), 1, int64(c.Server.MaxBodyRequestSize))
+	v.positive("server.uploadSessionAlive", c.Server.UploadSessionAlive)
+	if c.Server.FileURLSecret != "" {
+		v.atLeast("server.fileUrlSecret length", int64(len(c.Server.FileURLSecret)), 32)
+		v.positive("server.fileUrlExpire", c.Server.FileURLExpire)
+	}
+	if c.Server.MetricsToken != "" {
+		v.atLeast("server.metricsToken length", int64(len(c.Server.MetricsToken)), 16)
+	}
+
+	db := c.Server.DatabaseConfig
+	v.check(db.Host != "", "server.databaseConfig.host", "must be set")
+	v.between("server.databaseConfig.port", int64(db.Port), 1, 65535)
+	v.check(db.User != "", "server.databaseConfig.user", "must be set")
+	v.check(db.DBName != "", "server.databaseConfig.dbName", "must be set")
+	v.oneOf("server.databaseConfig.sslMode", db.SSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full")
+	v.atLeast("server.databaseConfig.maxOpenConns", int64(db.MaxOpenConns), 1)
+	v.between("server.databaseConfig.maxIdleConns", int64(db.MaxIdleConns), 0, int64(db.MaxOpenConns))
+
+	if len(v.problems) > 0 {
+		return v.problems
+	}
+	return nil
+}
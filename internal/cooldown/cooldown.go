@@ -1,81 +1,138 @@
 package cooldown
 
 import (
+	"context"
+	"database/sql"
 	"sync"
 	"time"
 
 	"github.com/asianchinaboi/backendserver/internal/config"
+	"github.com/asianchinaboi/backendserver/internal/errors"
 )
 
-//TODO: test this
+//token buckets, every key gets its own bucket per route rule
+//buckets refill continuously so nothing has to run in the background, theyre just topped up when used
 
-//sorta works ig?
-//idk
+// Rule is how many requests a bucket holds and how long an empty bucket takes to fill back up
+type Rule struct {
+	Limit  int
+	Window time.Duration
+}
+
+// Result is what the headers are built from
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Time     //when the bucket is full again
+	RetryAfter time.Duration //only set when not allowed
+}
 
-type Limiter struct {
-	ip       string
-	maxCount int //max amount of requests
-	count    int
-	ch       chan struct{}
-	ticker   *time.Ticker
-	deadline *time.Timer
+// Limiter hands out tokens, the postgres one lets every instance of the server share the same buckets
+type Limiter interface {
+	Take(ctx context.Context, key string, rule Rule) (Result, error)
 }
 
-var Manager *manager
+var defaultRule = Rule{Limit: 25, Window: 10 * time.Second} //used if the config doesnt have one
 
-type manager struct {
-	sync.RWMutex
-	limiters map[string]*Limiter
+const sweepInterval = time.Minute
+
+// Strikes counts how many times an ip has hit the cooldown, captchas get harder with more of them
+// theyre kept per app like captchas are
+type Strikes struct {
+	sync.Mutex
+	strikes   map[string]*strike
+	decay     time.Duration //strikes older than this are forgotten
+	lastSweep time.Time
 }
 
-func (l *Limiter) run() { //6 am code
-	defer func() {
-		Manager.removeLimiter(l.ip)
-	}()
-	for {
-		select {
-		case <-l.ch:
-			l.count--
-		case <-l.ticker.C:
-			l.count = l.maxCount
-		case <-l.deadline.C:
-			return
-		}
+type strike struct {
+	count int
+	last  time.Time
+}
+
+// RuleFor returns the bucket name and rule in settings for a route like "POST /api/users/auth"
+// routes without their own rule share the default bucket
+func RuleFor(settings *config.Settings, route string) (string, Rule) {
+	conf := settings.RateLimit
+	if rule, ok := conf.Routes[route]; ok && rule.Limit > 0 && rule.Window > 0 {
+		return route, Rule{Limit: rule.Limit, Window: rule.Window}
+	}
+	if conf.Default.Limit > 0 && conf.Default.Window > 0 {
+		return "default", Rule{Limit: conf.Default.Limit, Window: conf.Default.Window}
 	}
+	return "default", defaultRule
 }
 
-func (m *manager) AddCount(ip string) bool { //idk if this works (returns true if can else false)
-	m.RWMutex.Lock()
-	defer m.RWMutex.Unlock()
-	limiter, ok := m.limiters[ip]
-	if !ok {
-		limiter = &Limiter{
-			ip:       ip,
-			maxCount: config.Config.User.CoolDownTokens,
-			count:    config.Config.User.CoolDownTokens,
-			ch:       make(chan struct{}),
-			ticker:   time.NewTicker(config.Config.User.CoolDownLength),
-			deadline: time.NewTimer(60 * time.Second),
-		}
-		m.limiters[ip] = limiter
-		go limiter.run()
+// rate is tokens per second
+func (r Rule) rate() float64 {
+	return float64(r.Limit) / r.Window.Seconds()
+}
+
+// newResult works out the headers from how many tokens are left after taking one
+func newResult(rule Rule, tokens float64, allowed bool, now time.Time) Result {
+	result := Result{Allowed: allowed, Limit: rule.Limit, Remaining: int(tokens)}
+	if !allowed {
+		result.RetryAfter = time.Duration((1 - tokens) / rule.rate() * float64(time.Second))
 	}
-	limiter.deadline.Reset(60 * time.Second)
-	if limiter.count <= 0 {
-		return false
+	result.Reset = now.Add(time.Duration((float64(rule.Limit) - tokens) / rule.rate() * float64(time.Second)))
+	return result
+}
+
+func NewStrikes(decay time.Duration) *Strikes {
+	return &Strikes{strikes: make(map[string]*strike), decay: decay}
+}
+
+// AddStrike records the ip being rejected, captchas get harder the more strikes there are
+func (k *Strikes) AddStrike(ip string) {
+	k.Lock()
+	defer k.Unlock()
+	now := time.Now()
+	if now.Sub(k.lastSweep) > sweepInterval {
+		k.sweep(now)
+	}
+	s, ok := k.strikes[ip]
+	if !ok || now.Sub(s.last) > k.decay {
+		s = &strike{}
+		k.strikes[ip] = s
+	}
+	s.count++
+	s.last = now
+}
+
+// lock must be held by caller
+func (k *Strikes) sweep(now time.Time) {
+	k.lastSweep = now
+	for ip, s := range k.strikes { //clean up old strikes so the map doesnt grow forever
+		if now.Sub(s.last) > k.decay {
+			delete(k.strikes, ip)
+		}
 	}
-	limiter.ch <- struct{}{}
-	return true
 }
 
-func (m *manager) removeLimiter(ip string) {
-	m.RWMutex.Lock()
-	defer m.RWMutex.Unlock()
-	delete(m.limiters, ip)
+// Count returns how many times the ip has been rejected recently
+func (k *Strikes) Count(ip string) int {
+	k.Lock()
+	defer k.Unlock()
+	s, ok := k.strikes[ip]
+	if !ok {
+		return 0
+	}
+	if time.Since(s.last) > k.decay {
+		delete(k.strikes, ip)
+		return 0
+	}
+	return s.count
 }
 
-func init() {
-	Manager = &manager{
-		limiters: make(map[string]*Limiter),
+// New creates the limiter with the given name, conn is only used by the postgres one
+func New(name string, conn *sql.DB) (Limiter, error) {
+	switch name {
+	case "", "memory":
+		return NewMemory(), nil
+	case "postgres":
+		return NewPostgres(conn), nil
+	default:
+		return nil, errors.ErrRateLimitBackendNotExist
 	}
 }
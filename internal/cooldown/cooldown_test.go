@@ -0,0 +1,159 @@
+package cooldown_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/asianchinaboi/backendserver/internal/config"
+	"github.com/asianchinaboi/backendserver/internal/cooldown"
+	"github.com/asianchinaboi/backendserver/internal/db"
+)
+
+// long enough that nothing refills while the test runs
+var slow = cooldown.Rule{Limit: 10, Window: time.Hour}
+
+// key gives every test its own bucket so reruns against the same db dont see old rows
+func key(t *testing.T) string {
+	return fmt.Sprintf("%s-%d", t.Name(), time.Now().UnixNano())
+}
+
+// take fails the test on errors
+func take(t *testing.T, l cooldown.Limiter, key string, rule cooldown.Rule) cooldown.Result {
+	t.Helper()
+	result, err := l.Take(context.Background(), key, rule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+// testShared runs against limiters that all use the same buckets, like instances behind a load balancer
+func testShared(t *testing.T, limiters []cooldown.Limiter) {
+	t.Run("limit", func(t *testing.T) {
+		k := key(t)
+		for i := 0; i < slow.Limit; i++ {
+			result := take(t, limiters[i%len(limiters)], k, slow)
+			if !result.Allowed {
+				t.Fatalf("request %d was limited", i)
+			}
+			if result.Remaining != slow.Limit-i-1 {
+				t.Errorf("request %d: %d remaining, want %d", i, result.Remaining, slow.Limit-i-1)
+			}
+		}
+		for _, l := range limiters { //every instance sees the bucket is empty
+			result := take(t, l, k, slow)
+			if result.Allowed {
+				t.Fatal("allowed past the limit")
+			}
+			if result.RetryAfter <= 0 {
+				t.Errorf("retry after is %v when limited", result.RetryAfter)
+			}
+		}
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		k := key(t)
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		allowed := 0
+		for _, l := range limiters {
+			for i := 0; i < slow.Limit; i++ {
+				wg.Add(1)
+				go func(l cooldown.Limiter) {
+					defer wg.Done()
+					result, err := l.Take(context.Background(), k, slow)
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					if result.Allowed {
+						mu.Lock()
+						allowed++
+						mu.Unlock()
+					}
+				}(l)
+			}
+		}
+		wg.Wait()
+		if allowed != slow.Limit {
+			t.Errorf("%d requests allowed across %d instances, want %d", allowed, len(limiters), slow.Limit)
+		}
+	})
+
+	t.Run("rule change", func(t *testing.T) {
+		k := key(t)
+		for i := 0; i <= slow.Limit; i++ {
+			take(t, limiters[0], k, slow)
+		}
+		bigger := cooldown.Rule{Limit: 20, Window: time.Hour}
+		result := take(t, limiters[len(limiters)-1], k, bigger)
+		if !result.Allowed || result.Limit != bigger.Limit || result.Remaining != bigger.Limit-1 {
+			t.Errorf("got %+v after raising the limit, want a fresh bucket of %d", result, bigger.Limit)
+		}
+		longer := cooldown.Rule{Limit: 20, Window: 2 * time.Hour}
+		result = take(t, limiters[0], k, longer)
+		if !result.Allowed || result.Remaining != longer.Limit-1 {
+			t.Errorf("got %+v after changing the window, want a fresh bucket", result)
+		}
+	})
+
+	t.Run("refill", func(t *testing.T) {
+		k := key(t)
+		fast := cooldown.Rule{Limit: 2, Window: 200 * time.Millisecond}
+		for i := 0; i < fast.Limit; i++ {
+			take(t, limiters[0], k, fast)
+		}
+		if take(t, limiters[0], k, fast).Allowed {
+			t.Fatal("allowed past the limit")
+		}
+		time.Sleep(fast.Window)
+		if !take(t, limiters[len(limiters)-1], k, fast).Allowed {
+			t.Error("bucket didnt refill")
+		}
+	})
+
+	t.Run("keys", func(t *testing.T) {
+		a, b := key(t)+"a", key(t)+"b"
+		for i := 0; i < slow.Limit; i++ {
+			take(t, limiters[0], a, slow)
+		}
+		if !take(t, limiters[0], b, slow).Allowed {
+			t.Error("one key emptied another")
+		}
+	})
+}
+
+func TestMemory(t *testing.T) {
+	m := cooldown.NewMemory() //memory buckets are per process so its one store shared by every caller
+	testShared(t, []cooldown.Limiter{m, m, m})
+}
+
+func TestPostgres(t *testing.T) {
+	if os.Getenv(config.EnvPrefix+"SERVER_DATABASECONFIG_HOST") == "" {
+		t.Skip("no test database, set " + config.EnvPrefix + "SERVER_DATABASECONFIG_HOST")
+	}
+	conf, err := config.Load(config.Sources{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	//separate pools so each limiter has its own connections like separate instances would
+	limiters := make([]cooldown.Limiter, 3)
+	for i := range limiters {
+		conn, err := db.Open(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		limiters[i] = cooldown.NewPostgres(conn)
+		if i == 0 {
+			if err := db.Migrate(conn); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	testShared(t, limiters)
+}
@@ -0,0 +1,59 @@
+package cooldown
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	rule   Rule
+}
+
+// Memory keeps the buckets in this process, limits reset on restart and arent shared between instances
+type Memory struct {
+	sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{buckets: make(map[string]*bucket)}
+}
+
+func (m *Memory) Take(ctx context.Context, key string, rule Rule) (Result, error) {
+	m.Lock()
+	defer m.Unlock()
+	now := time.Now()
+	if now.Sub(m.lastSweep) > sweepInterval {
+		m.sweep(now)
+	}
+
+	b, ok := m.buckets[key]
+	if !ok || b.rule != rule { //rule changes start a fresh bucket
+		b = &bucket{tokens: float64(rule.Limit), last: now, rule: rule}
+		m.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(rule.Limit), b.tokens+now.Sub(b.last).Seconds()*rule.rate())
+	b.last = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return newResult(rule, b.tokens, allowed, now), nil
+}
+
+// lock must be held by caller
+// full buckets are the same as missing ones so they can go
+func (m *Memory) sweep(now time.Time) {
+	m.lastSweep = now
+	for key, b := range m.buckets {
+		if now.Sub(b.last) >= b.rule.Window {
+			delete(m.buckets, key)
+		}
+	}
+}
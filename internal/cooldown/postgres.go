@@ -0,0 +1,46 @@
+package cooldown
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// Postgres keeps the buckets in the unlogged rate_limits table so every instance shares them
+// unlogged since losing them in a crash doesnt matter and its a lot faster to write
+type Postgres struct {
+	conn *sql.DB
+}
+
+func NewPostgres(conn *sql.DB) *Postgres {
+	return &Postgres{conn: conn}
+}
+
+// refill is LEAST(limit, tokens + seconds since last update * rate)
+// rows left by a different rule start full like a new bucket, same as the memory limiter
+const refill = `CASE WHEN r.rule_limit = $2::float8 AND r.rule_window = $3::float8 
+			THEN LEAST($2::float8, r.tokens + EXTRACT(EPOCH FROM now() - r.updated) * $4::float8) 
+			ELSE $2::float8 END`
+
+// on conflict cant use FROM so the refill is repeated
+var takeQuery = strings.ReplaceAll(`INSERT INTO rate_limits AS r (key, tokens, allowed, updated, expires, rule_limit, rule_window) 
+	VALUES ($1, $2::float8 - 1, true, now(), now() + $3::float8 * interval '1 second', $2::float8, $3::float8) 
+	ON CONFLICT (key) DO UPDATE SET 
+		tokens = CASE WHEN {refill} >= 1 THEN {refill} - 1 ELSE {refill} END, 
+		allowed = {refill} >= 1, 
+		updated = now(), 
+		expires = now() + $3::float8 * interval '1 second', 
+		rule_limit = $2::float8, 
+		rule_window = $3::float8 
+	RETURNING r.tokens, r.allowed`, "{refill}", refill)
+
+// Take refills and takes from the bucket in one statement so instances racing on the same key cant both get the last token
+func (p *Postgres) Take(ctx context.Context, key string, rule Rule) (Result, error) {
+	var tokens float64
+	var allowed bool
+	if err := p.conn.QueryRowContext(ctx, takeQuery, key, rule.Limit, rule.Window.Seconds(), rule.rate()).Scan(&tokens, &allowed); err != nil {
+		return Result{}, err
+	}
+	return newResult(rule, tokens, allowed, time.Now()), nil
+}
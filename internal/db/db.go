@@ -3,30 +3,32 @@ package db
 import (
 	"database/sql"
 	"fmt"
-
-	_ "github.com/lib/pq"
+	"strings"
 
 	"github.com/asianchinaboi/backendserver/internal/config"
-	"github.com/asianchinaboi/backendserver/internal/logger"
+	"github.com/lib/pq"
 )
 
-var (
-	Db *sql.DB
-)
+// dsnQuoter quotes values so passwords from secret files can have spaces and quotes in them
+var dsnQuoter = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
 
-func init() {
-	var err error
-	loginInfo := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		config.Config.Server.DatabaseConfig.Host,
-		config.Config.Server.DatabaseConfig.Port,
-		config.Config.Server.DatabaseConfig.User,
-		config.Config.Server.DatabaseConfig.Password,
-		config.Config.Server.DatabaseConfig.DBName,
-		config.Config.Server.DatabaseConfig.SSLMode)
-	Db, err = sql.Open("postgres", loginInfo)
+// Open creates the pool from the config, it doesnt connect until the first query
+// the app hands it to everything that needs it
+func Open(settings *config.Settings) (*sql.DB, error) {
+	conf := settings.Server.DatabaseConfig
+	loginInfo := fmt.Sprintf("host='%s' port=%d user='%s' password='%s' dbname='%s' sslmode='%s'",
+		dsnQuoter.Replace(conf.Host),
+		conf.Port,
+		dsnQuoter.Replace(conf.User),
+		dsnQuoter.Replace(conf.Password),
+		dsnQuoter.Replace(conf.DBName),
+		dsnQuoter.Replace(conf.SSLMode))
+	connector, err := pq.NewConnector(loginInfo)
 	if err != nil {
-		logger.Fatal.Println(err)
+		return nil, err
 	}
-	Db.SetMaxOpenConns(config.Config.Server.DatabaseConfig.MaxOpenConns)
-	Db.SetMaxIdleConns(config.Config.Server.DatabaseConfig.MaxOpenConns)
+	conn := sql.OpenDB(tracedConnector{connector})
+	conn.SetMaxOpenConns(conf.MaxOpenConns)
+	conn.SetMaxIdleConns(conf.MaxIdleConns)
+	return conn, nil
 }
@@ -0,0 +1,126 @@
+package db
+
+import "database/sql"
+
+// tables and columns added on top of the original schema
+// these get run every time the server starts so they all have to be safe to run again
+var migrations = []string{
+	`CREATE TABLE IF NOT EXISTS bots (
+		user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+		owner_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		token TEXT NOT NULL UNIQUE,
+		created TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+	)`,
+	`CREATE INDEX IF NOT EXISTS bots_owner_id_idx ON bots (owner_id)`,
+	`CREATE TABLE IF NOT EXISTS webhooks (
+		user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+		guild_id BIGINT NOT NULL REFERENCES guilds(id) ON DELETE CASCADE,
+		creator_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+		name TEXT NOT NULL,
+		token TEXT NOT NULL,
+		created TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+	)`,
+	`CREATE INDEX IF NOT EXISTS webhooks_guild_id_idx ON webhooks (guild_id)`,
+	`ALTER TABLE msgs ADD COLUMN IF NOT EXISTS webhook_name TEXT`,
+	`CREATE TABLE IF NOT EXISTS event_subscriptions (
+		id BIGINT PRIMARY KEY,
+		guild_id BIGINT NOT NULL REFERENCES guilds(id) ON DELETE CASCADE,
+		creator_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+		url TEXT NOT NULL,
+		secret TEXT NOT NULL,
+		events TEXT[] NOT NULL,
+		created TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+	)`,
+	`CREATE INDEX IF NOT EXISTS event_subscriptions_guild_id_idx ON event_subscriptions (guild_id)`,
+	`CREATE TABLE IF NOT EXISTS event_deliveries (
+		id BIGINT PRIMARY KEY,
+		subscription_id BIGINT NOT NULL REFERENCES event_subscriptions(id) ON DELETE CASCADE,
+		guild_id BIGINT NOT NULL,
+		event TEXT NOT NULL,
+		payload TEXT NOT NULL,
+		attempts INT NOT NULL DEFAULT 0,
+		next_attempt TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+		last_status INT,
+		last_error TEXT,
+		dead BOOLEAN NOT NULL DEFAULT false,
+		created TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+	)`,
+	`CREATE INDEX IF NOT EXISTS event_deliveries_pending_idx ON event_deliveries (next_attempt) WHERE dead = false`,
+	`CREATE TABLE IF NOT EXISTS commands (
+		id BIGINT PRIMARY KEY,
+		guild_id BIGINT NOT NULL REFERENCES guilds(id) ON DELETE CASCADE,
+		bot_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		name TEXT NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		options TEXT NOT NULL DEFAULT '[]',
+		created TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+		UNIQUE (guild_id, name)
+	)`,
+	`ALTER TABLE files ADD COLUMN IF NOT EXISTS uploader_id BIGINT REFERENCES users(id) ON DELETE SET NULL`,
+	`CREATE TABLE IF NOT EXISTS uploads (
+		id BIGINT PRIMARY KEY,
+		user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		filename TEXT NOT NULL,
+		filesize BIGINT NOT NULL,
+		received BIGINT NOT NULL DEFAULT 0,
+		created TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+		updated TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+	)`,
+	`CREATE TABLE IF NOT EXISTS upload_chunks (
+		upload_id BIGINT NOT NULL REFERENCES uploads(id) ON DELETE CASCADE,
+		chunk_offset BIGINT NOT NULL,
+		chunk_id BIGINT NOT NULL,
+		PRIMARY KEY (upload_id, chunk_offset)
+	)`,
+	`CREATE TABLE IF NOT EXISTS blobs (
+		hash TEXT PRIMARY KEY,
+		size BIGINT NOT NULL,
+		stored_size BIGINT NOT NULL DEFAULT 0,
+		created TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+	)`,
+	`ALTER TABLE files ADD COLUMN IF NOT EXISTS hash TEXT REFERENCES blobs(hash)`,
+	`CREATE INDEX IF NOT EXISTS files_hash_idx ON files (hash)`,
+	`ALTER TABLE files ADD COLUMN IF NOT EXISTS width INT`,
+	`ALTER TABLE files ADD COLUMN IF NOT EXISTS height INT`,
+	`ALTER TABLE files ADD COLUMN IF NOT EXISTS blurhash TEXT`,
+	`ALTER TABLE files ADD COLUMN IF NOT EXISTS duration DOUBLE PRECISION`,
+	`ALTER TABLE files ADD COLUMN IF NOT EXISTS quarantined BOOLEAN NOT NULL DEFAULT false`,
+	`ALTER TABLE files ADD COLUMN IF NOT EXISTS quarantine_reason TEXT`,
+	`CREATE INDEX IF NOT EXISTS files_quarantined_idx ON files (created) WHERE quarantined = true`,
+	`ALTER TABLE guilds ADD COLUMN IF NOT EXISTS scan_policy TEXT NOT NULL DEFAULT 'allow'`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS storage_quota BIGINT`, //null uses the config
+	`ALTER TABLE guilds ADD COLUMN IF NOT EXISTS storage_quota BIGINT`,
+	`CREATE INDEX IF NOT EXISTS files_uploader_id_idx ON files (uploader_id)`,
+	`CREATE INDEX IF NOT EXISTS files_msg_id_idx ON files (msg_id)`,
+	`ALTER TABLE files ADD COLUMN IF NOT EXISTS msg_guild_id BIGINT`, //guild an attachment was sent in, set even when the msg isnt saved
+	`CREATE TABLE IF NOT EXISTS transcodes (
+		hash TEXT PRIMARY KEY REFERENCES blobs(hash) ON DELETE CASCADE,
+		content_type TEXT NOT NULL,
+		status TEXT NOT NULL DEFAULT 'pending',
+		attempts INT NOT NULL DEFAULT 0,
+		next_attempt TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+		last_error TEXT,
+		created TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+		updated TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+	)`,
+	`CREATE INDEX IF NOT EXISTS transcodes_pending_idx ON transcodes (next_attempt) WHERE status IN ('pending', 'processing')`,
+	`CREATE UNLOGGED TABLE IF NOT EXISTS rate_limits (
+		key TEXT PRIMARY KEY,
+		tokens DOUBLE PRECISION NOT NULL,
+		allowed BOOLEAN NOT NULL,
+		updated TIMESTAMP WITH TIME ZONE NOT NULL,
+		expires TIMESTAMP WITH TIME ZONE NOT NULL,
+		rule_limit INT NOT NULL,
+		rule_window DOUBLE PRECISION NOT NULL
+	)`, //expires is when the bucket is full again, after that the row is the same as no row. a different rule than the one the bucket was filled with starts a fresh bucket
+	`CREATE INDEX IF NOT EXISTS rate_limits_expires_idx ON rate_limits (expires)`,
+}
+
+func Migrate(conn *sql.DB) error {
+	for _, query := range migrations {
+		if _, err := conn.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+
+	"github.com/asianchinaboi/backendserver/internal/tracing"
+	"github.com/lib/pq"
+)
+
+//wraps lib/pq so every query gets a span, queries without a context still get one but it starts a new trace
+
+type tracedConnector struct {
+	*pq.Connector
+}
+
+func (c tracedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	traced, ok := conn.(pqConn)
+	if !ok { //shouldnt happen unless lib/pq changes
+		return conn, nil
+	}
+	return &tracedConn{traced}, nil
+}
+
+// everything lib/pq connections implement, the wrapper has to implement the same or database/sql falls back to slower paths
+type pqConn interface {
+	driver.Conn
+	driver.ConnBeginTx
+	driver.ConnPrepareContext
+	driver.QueryerContext
+	driver.ExecerContext
+	driver.Pinger
+	driver.SessionResetter
+	driver.Validator
+}
+
+type tracedConn struct {
+	pqConn
+}
+
+func startQuery(ctx context.Context, query string) (context.Context, *tracing.Span) {
+	name := "sql"
+	if fields := strings.Fields(query); len(fields) > 0 {
+		name = "sql " + strings.ToUpper(fields[0]) //SELECT, INSERT...
+	}
+	return tracing.Start(ctx, name, tracing.KindClient, "db.system", "postgresql", "db.statement", query)
+}
+
+func (c *tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	ctx, span := startQuery(ctx, query)
+	defer span.End()
+	rows, err := c.pqConn.QueryContext(ctx, query, args)
+	span.RecordError(err)
+	return rows, err
+}
+
+func (c *tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	ctx, span := startQuery(ctx, query)
+	defer span.End()
+	result, err := c.pqConn.ExecContext(ctx, query, args)
+	span.RecordError(err)
+	return result, err
+}
+
+func (c *tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	ctx, span := tracing.Start(ctx, "sql BEGIN", tracing.KindClient, "db.system", "postgresql")
+	defer span.End()
+	tx, err := c.pqConn.BeginTx(ctx, opts)
+	span.RecordError(err)
+	return tx, err
+}
@@ -11,6 +11,8 @@ type Body struct {
 	Error  string  `json:"error"`
 	Status ErrCode `json:"status"`
 	Index  int     `json:"index,omitempty"`
+
+	RequestId string `json:"requestId,omitempty"` //matches the requestId in the logs
 }
 
 var (
@@ -95,16 +97,20 @@ var (
 
 	//COOLDOWN
 
-	ErrCooldownActive = errors.New("cooldown: cooldown is active")
+	ErrCooldownActive           = errors.New("cooldown: cooldown is active")
+	ErrRateLimitBackendNotExist = errors.New("cooldown: rate limit backend doesn't exist") //internal error
 
 	//IP
 	ErrIpBanned = errors.New("ip: banned")
 
 	//FILES
-	ErrFileNotFound = errors.New("file: not found")
-	ErrFileInvalid  = errors.New("file: invalid")
-	ErrFileNoBytes  = errors.New("file: no bytes")
-	ErrFileTooLarge = errors.New("file: too large")
+	ErrFileNotFound         = errors.New("file: not found")
+	ErrFileInvalid          = errors.New("file: invalid")
+	ErrFileNoBytes          = errors.New("file: no bytes")
+	ErrFileTooLarge         = errors.New("file: too large")
+	ErrFileInvalidSeek      = errors.New("file: invalid seek") //internal error
+	ErrFileBlobRemoved      = errors.New("file: blob removed") //internal error
+	ErrFileSignatureInvalid = errors.New("file: invalid or expired signature")
 
 	//ROUTES
 	ErrRouteParamInvalid = errors.New("route: invalid param")
@@ -117,14 +123,91 @@ var (
 
 	//CONTENT TYPE
 	ErrNotSupportedContentType = errors.New("content type: not supported")
+
+	//CAPTCHA
+	ErrCaptchaRequired         = errors.New("captcha: required")
+	ErrCaptchaInvalid          = errors.New("captcha: invalid solution")
+	ErrCaptchaExpired          = errors.New("captcha: expired or does not exist")
+	ErrCaptchaProviderNotExist = errors.New("captcha: provider does not exist") //internal error
+
+	//BOT
+	ErrBotNotExist     = errors.New("bot: doesn't exist")
+	ErrBotLimitReached = errors.New("bot: limit reached")
+	ErrBotNotAllowed   = errors.New("bot: bots can't do this")
+	ErrBotOnly         = errors.New("bot: only bots can do this")
+
+	//WEBHOOK
+	ErrWebhookNotExist     = errors.New("webhook: doesn't exist")
+	ErrWebhookLimitReached = errors.New("webhook: limit reached")
+
+	//EVENT SUBSCRIPTION
+	ErrSubscriptionNotExist     = errors.New("subscription: doesn't exist")
+	ErrSubscriptionLimitReached = errors.New("subscription: limit reached")
+	ErrSubscriptionInvalidUrl   = errors.New("subscription: invalid url")
+	ErrSubscriptionInvalidEvent = errors.New("subscription: invalid event")
+	ErrDeliveryNotExist         = errors.New("subscription: delivery doesn't exist")
+
+	//COMMAND
+	ErrCommandNotExist      = errors.New("command: doesn't exist")
+	ErrCommandAlreadyExists = errors.New("command: name already used by another bot")
+	ErrCommandInvalid       = errors.New("command: invalid name or options")
+	ErrCommandLimitReached  = errors.New("command: limit reached")
+	ErrCommandBadArguments  = errors.New("command: invalid arguments")
+
+	//STORAGE
+	ErrStorageObjectNotExist  = errors.New("storage: object doesn't exist")
+	ErrStorageBackendNotExist = errors.New("storage: backend doesn't exist") //internal error
+	ErrStorageInvalidKey      = errors.New("storage: invalid key")           //internal error
+	ErrStorageInvalidConfig   = errors.New("storage: invalid config")        //internal error
+
+	//UPLOAD
+	ErrUploadNotExist       = errors.New("upload: doesn't exist")
+	ErrUploadOffsetMismatch = errors.New("upload: offset doesn't match received bytes")
+	ErrUploadChunkTooLarge  = errors.New("upload: chunk too large")
+	ErrUploadIncomplete     = errors.New("upload: not all bytes received")
+
+	//SERVER
+	ErrServerDraining = errors.New("server: shutting down, connect to another instance")
+
+	//GATEWAY
+	ErrGatewayInvalidFrame  = errors.New("gateway: invalid frame")
+	ErrGatewayOptionInvalid = errors.New("gateway: invalid encoding or compress option")
+
+	//MSGPACK
+	ErrMsgpackUnsupported = errors.New("msgpack: unsupported type")
+	ErrMsgpackShort       = errors.New("msgpack: unexpected end of data")
+	ErrMsgpackMapKey      = errors.New("msgpack: map keys must be strings")
+	ErrMsgpackTrailing    = errors.New("msgpack: data after value")
+
+	//SCANNER
+	ErrScannerUnavailable     = errors.New("scanner: unavailable, try again later")
+	ErrScannerBackendNotExist = errors.New("scanner: backend doesn't exist") //internal error
+	ErrScannerBadReply        = errors.New("scanner: bad reply")             //internal error
+	ErrScanPolicyInvalid      = errors.New("scanner: invalid policy")
+
+	//QUOTA
+	ErrQuotaUserExceeded  = errors.New("quota: user storage quota exceeded")
+	ErrQuotaGuildExceeded = errors.New("quota: guild storage quota exceeded")
+	ErrQuotaInvalid       = errors.New("quota: invalid quota")
+
+	//TRANSCODE
+	ErrTranscodeNotReady = errors.New("transcode: not ready")
+
+	//INTERACTION
+	ErrInteractionNotExist       = errors.New("interaction: doesn't exist or expired")
+	ErrInteractionEphemeralFiles = errors.New("interaction: ephemeral responses can't have attachments")
 )
 
 func SendErrorResponse(c *gin.Context, err error, errorCode ErrCode) {
-	if err := logger.Error.Output(2, err.Error()); err != nil {
-		logger.Error.Println("Failed to log error")
+	httpCode := getHTTPStatusCode(errorCode)
+	level := logger.LevelWarn //the client did something wrong, nothing to fix here
+	if httpCode >= 500 {
+		level = logger.LevelError
 	}
-	c.JSON(getHTTPStatusCode(errorCode), Body{
-		Error:  err.Error(),
-		Status: errorCode,
+	logger.Ctx(c).Output(2, level, err.Error(), "status", errorCode, "httpStatus", httpCode)
+	c.JSON(httpCode, Body{
+		Error:     err.Error(),
+		Status:    errorCode,
+		RequestId: c.GetString(logger.RequestIdKey),
 	})
 }
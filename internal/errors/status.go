@@ -84,6 +84,50 @@ const (
 	StatusSessionTooManySessions //not used
 
 	StatusNotAuthorised
+
+	StatusCaptchaRequired
+	StatusCaptchaInvalid
+
+	StatusBotNotExist
+	StatusBotLimitReached
+	StatusBotNotAllowed
+
+	StatusWebhookNotExist
+	StatusWebhookLimitReached
+
+	StatusSubscriptionNotExist
+	StatusSubscriptionLimitReached
+	StatusSubscriptionInvalidUrl
+	StatusSubscriptionInvalidEvent
+	StatusDeliveryNotExist
+
+	StatusBotOnly
+
+	StatusCommandNotExist
+	StatusCommandAlreadyExists
+	StatusCommandInvalid
+	StatusCommandLimitReached
+	StatusCommandBadArguments
+
+	StatusInteractionNotExist
+	StatusInteractionEphemeralFiles
+
+	StatusUploadNotExist
+	StatusUploadOffsetMismatch
+	StatusUploadChunkTooLarge
+	StatusUploadIncomplete
+
+	StatusScannerUnavailable
+	StatusScanPolicyInvalid
+
+	StatusQuotaExceeded
+	StatusQuotaInvalid
+
+	StatusFileSignatureInvalid
+
+	StatusTranscodeNotReady
+
+	StatusServerDraining
 )
 
 func getHTTPStatusCode(errorCode ErrCode) int {
@@ -212,6 +256,68 @@ func getHTTPStatusCode(errorCode ErrCode) int {
 		return http.StatusForbidden
 	case StatusNotAuthorised:
 		return http.StatusForbidden
+	case StatusCaptchaRequired:
+		return http.StatusPreconditionRequired
+	case StatusCaptchaInvalid:
+		return http.StatusForbidden
+	case StatusBotNotExist:
+		return http.StatusNotFound
+	case StatusBotLimitReached:
+		return http.StatusForbidden
+	case StatusBotNotAllowed:
+		return http.StatusForbidden
+	case StatusWebhookNotExist:
+		return http.StatusNotFound
+	case StatusWebhookLimitReached:
+		return http.StatusForbidden
+	case StatusSubscriptionNotExist:
+		return http.StatusNotFound
+	case StatusSubscriptionLimitReached:
+		return http.StatusForbidden
+	case StatusSubscriptionInvalidUrl:
+		return http.StatusUnprocessableEntity
+	case StatusSubscriptionInvalidEvent:
+		return http.StatusUnprocessableEntity
+	case StatusDeliveryNotExist:
+		return http.StatusNotFound
+	case StatusBotOnly:
+		return http.StatusForbidden
+	case StatusCommandNotExist:
+		return http.StatusNotFound
+	case StatusCommandAlreadyExists:
+		return http.StatusConflict
+	case StatusCommandInvalid:
+		return http.StatusUnprocessableEntity
+	case StatusCommandLimitReached:
+		return http.StatusForbidden
+	case StatusCommandBadArguments:
+		return http.StatusUnprocessableEntity
+	case StatusInteractionNotExist:
+		return http.StatusNotFound
+	case StatusInteractionEphemeralFiles:
+		return http.StatusBadRequest
+	case StatusUploadNotExist:
+		return http.StatusNotFound
+	case StatusUploadOffsetMismatch:
+		return http.StatusConflict
+	case StatusUploadChunkTooLarge:
+		return http.StatusRequestEntityTooLarge
+	case StatusUploadIncomplete:
+		return http.StatusConflict
+	case StatusScannerUnavailable:
+		return http.StatusServiceUnavailable
+	case StatusScanPolicyInvalid:
+		return http.StatusUnprocessableEntity
+	case StatusQuotaExceeded:
+		return http.StatusRequestEntityTooLarge
+	case StatusQuotaInvalid:
+		return http.StatusUnprocessableEntity
+	case StatusFileSignatureInvalid:
+		return http.StatusForbidden
+	case StatusTranscodeNotReady:
+		return http.StatusConflict
+	case StatusServerDraining:
+		return http.StatusServiceUnavailable
 	default:
 		logger.Warn.Printf("Unknown error code: %v\n", errorCode)
 		return http.StatusInternalServerError
@@ -0,0 +1,10 @@
+package events
+
+import "time"
+
+type Bot struct {
+	UserInfo User      `json:"userInfo"`
+	OwnerId  int64     `json:"ownerId,string"`
+	Token    string    `json:"token,omitempty"` //only sent when created or reset
+	Created  time.Time `json:"created"`
+}
@@ -0,0 +1,134 @@
+package events
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	CommandOptionString  = "string"
+	CommandOptionInteger = "integer"
+	CommandOptionBoolean = "boolean"
+	CommandOptionUser    = "user"
+)
+
+type Command struct {
+	CommandId   int64           `json:"id,string"`
+	GuildId     int64           `json:"guildId,string"`
+	BotId       int64           `json:"botId,string"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Options     []CommandOption `json:"options"`
+	Created     time.Time       `json:"created"`
+}
+
+type CommandOption struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Type        string `json:"type"`
+	Required    bool   `json:"required"`
+}
+
+type Interaction struct {
+	InteractionId int64               `json:"id,string"`
+	CommandId     int64               `json:"commandId,string"`
+	GuildId       int64               `json:"guildId,string"`
+	Name          string              `json:"name"`
+	Options       []InteractionOption `json:"options"`
+	User          User                `json:"user"` //whoever used the command
+	Expires       time.Time           `json:"expires"`
+}
+
+type InteractionOption struct {
+	Name  string      `json:"name"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"` //user values are the user id as a string
+}
+
+var commandNameExp = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+var commandInvokeExp = regexp.MustCompile(`^/([a-z0-9_-]{1,32})(?:\s+([\s\S]*))?$`)
+var userArgExp = regexp.MustCompile(`^(?:\<\@(\d+)\>|(\d+))$`)
+
+func ValidateCommand(command Command) bool {
+	if !commandNameExp.MatchString(command.Name) || len(command.Description) > 100 || len(command.Options) > 25 {
+		return false
+	}
+	optional := false
+	names := make(map[string]bool)
+	for _, option := range command.Options {
+		if !commandNameExp.MatchString(option.Name) || names[option.Name] || len(option.Description) > 100 {
+			return false
+		}
+		switch option.Type {
+		case CommandOptionString, CommandOptionInteger, CommandOptionBoolean, CommandOptionUser:
+		default:
+			return false
+		}
+		if option.Required && optional { //required options have to come first since args are positional
+			return false
+		}
+		optional = !option.Required
+		names[option.Name] = true
+	}
+	return true
+}
+
+// ParseCommandInvocation splits "/name args" into the name and whatever comes after
+func ParseCommandInvocation(content string) (string, string, bool) {
+	match := commandInvokeExp.FindStringSubmatch(strings.TrimSpace(content))
+	if match == nil {
+		return "", "", false
+	}
+	return match[1], strings.TrimSpace(match[2]), true
+}
+
+// ParseCommandArgs matches space separated args to the options in order
+// the last string option gets the rest of the line so it can have spaces in it
+func ParseCommandArgs(options []CommandOption, args string) ([]InteractionOption, bool) {
+	fields := strings.Fields(args)
+	parsed := []InteractionOption{}
+	for i, option := range options {
+		if len(fields) == 0 {
+			if option.Required {
+				return nil, false
+			}
+			break
+		}
+		raw := fields[0]
+		fields = fields[1:]
+		if i == len(options)-1 && option.Type == CommandOptionString && len(fields) > 0 {
+			raw = raw + " " + strings.Join(fields, " ")
+			fields = nil
+		}
+		var value interface{}
+		switch option.Type {
+		case CommandOptionString:
+			value = raw
+		case CommandOptionInteger:
+			number, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				return nil, false
+			}
+			value = number
+		case CommandOptionBoolean:
+			boolean, err := strconv.ParseBool(raw)
+			if err != nil {
+				return nil, false
+			}
+			value = boolean
+		case CommandOptionUser:
+			match := userArgExp.FindStringSubmatch(raw)
+			if match == nil {
+				return nil, false
+			}
+			value = match[1] + match[2]
+		}
+		parsed = append(parsed, InteractionOption{Name: option.Name, Type: option.Type, Value: value})
+	}
+	if len(fields) > 0 { //too many args
+		return nil, false
+	}
+	return parsed, true
+}
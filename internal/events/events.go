@@ -44,4 +44,8 @@ const (
 	LOG_OUT = "LOG_OUT"
 
 	USER_INFO_UPDATE = "USER_INFO_UPDATE"
+
+	INTERACTION_CREATE = "INTERACTION_CREATE"
+
+	ATTACHMENT_UPDATE = "ATTACHMENT_UPDATE"
 )
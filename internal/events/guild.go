@@ -16,6 +16,8 @@ type Guild struct {
 	ImageId  int64      `json:"imageId,omitempty,string"`
 	Unread   *UnreadMsg `json:"unread,omitempty"`
 	SaveChat *bool      `json:"saveChat,omitempty"`
+
+	ScanPolicy string `json:"scanPolicy,omitempty"` //what happens to uploads when the scanner is down
 }
 
 type Invite struct {
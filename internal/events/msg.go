@@ -23,14 +23,32 @@ type Msg struct { //id and request id not omitted for checking purposes
 	Author           User          `json:"author,omitempty"` // author id aka user id
 	Created          time.Time     `json:"created,omitempty"`
 	Modified         time.Time     `json:"modified,omitempty"`
-	MsgSaved         bool          `json:"msgSaved,omitempty"` //shows if the message is saved or not
+	MsgSaved         bool          `json:"msgSaved,omitempty"`             //shows if the message is saved or not
+	WebhookId        int64         `json:"webhookId,omitempty,string"`     //set if the message was sent by a webhook
+	InteractionId    int64         `json:"interactionId,omitempty,string"` //set if the message is a bot replying to a command
+	Ephemeral        bool          `json:"ephemeral,omitempty"`            //only sent to whoever used the command, never saved
 }
 
 type Attachment struct {
-	Id int64 `json:"id,string"`
-	//ContentType string `json:"contentType"` //file type
-	Filename string `json:"filename"`
-	Type     string `json:"type"`
+	Id          int64   `json:"id,string"`
+	ContentType string  `json:"contentType"` //file type
+	Filename    string  `json:"filename"`
+	Type        string  `json:"type"`                  //same as contentType, kept for older clients
+	Size        int64   `json:"size"`                  //bytes after metadata was stripped
+	Width       int     `json:"width,omitempty"`       //only set for images
+	Height      int     `json:"height,omitempty"`      //only set for images
+	Blurhash    string  `json:"blurhash,omitempty"`    //placeholder to show while the image loads
+	Duration    float64 `json:"duration,omitempty"`    //seconds, only set for audio and video
+	Quarantined bool    `json:"quarantined,omitempty"` //flagged by the scanner, cant be downloaded
+	Transcode   string  `json:"transcode,omitempty"`   //pending, processing, ready or failed for video and audio
+}
+
+// AttachmentUpdate is sent when a video or audio attachment has been transcoded
+type AttachmentUpdate struct {
+	Id        int64  `json:"id,string"`
+	MsgId     int64  `json:"msgId,omitempty,string"`
+	GuildId   int64  `json:"guildId,string"`
+	Transcode string `json:"transcode"`
 }
 
 var MentionExp = regexp.MustCompile(`\<\@(\d+)\>`)
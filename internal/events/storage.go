@@ -0,0 +1,55 @@
+package events
+
+import "time"
+
+// StorageReport shows how much space deduplication is saving
+// only files stored by hash are counted, older files are stored once per row anyway
+type StorageReport struct {
+	Files       int64 `json:"files"`       //files rows that point at a blob
+	Blobs       int64 `json:"blobs"`       //distinct contents actually stored
+	LogicalSize int64 `json:"logicalSize"` //bytes if every file was stored separately
+	UniqueSize  int64 `json:"uniqueSize"`  //bytes of the distinct contents before compression
+	StoredSize  int64 `json:"storedSize"`  //bytes in storage after compression
+	SavedSize   int64 `json:"savedSize"`   //bytes saved by deduplication
+}
+
+// QuarantinedFile is a file hidden by the scanner waiting for an admin to look at it
+type QuarantinedFile struct {
+	FileId     int64     `json:"id,string"`
+	EntityType string    `json:"entityType"` //msg, user or guild
+	Filename   string    `json:"filename"`
+	Type       string    `json:"type"`
+	Size       int64     `json:"size"`
+	UploaderId int64     `json:"uploaderId,omitempty,string"`
+	MsgId      int64     `json:"msgId,omitempty,string"`
+	GuildId    int64     `json:"guildId,omitempty,string"` //where the msg was sent or whose icon it is
+	Reason     string    `json:"reason"`                   //signature found or unscanned
+	Created    time.Time `json:"created"`
+}
+
+// StorageUsage is how much a user or guild is storing against its quota
+type StorageUsage struct {
+	Used  int64 `json:"used"` //bytes
+	Files int64 `json:"files"`
+	Quota int64 `json:"quota"` //bytes, 0 means unlimited
+}
+
+// StorageConsumer is a user or guild in a list of who is storing the most
+type StorageConsumer struct {
+	Id    int64  `json:"id,string"`
+	Name  string `json:"name"`
+	Used  int64  `json:"used"`
+	Files int64  `json:"files"`
+}
+
+// GuildStorage is the storage summary of a guild
+type GuildStorage struct {
+	StorageUsage
+	TopUploaders []StorageConsumer `json:"topUploaders"`
+}
+
+// SignedURL is a download link that works without the authorization header until it expires
+type SignedURL struct {
+	Url     string    `json:"url"`
+	Expires time.Time `json:"expires"`
+}
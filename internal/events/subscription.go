@@ -0,0 +1,77 @@
+package events
+
+import (
+	"net"
+	"net/url"
+	"time"
+)
+
+// events that get sent to guild broadcasts and can be delivered to an outside url
+var SubscribableEvents = map[string]bool{
+	GUILD_DELETE:         true,
+	GUILD_UPDATE:         true,
+	INVITE_CREATE:        true,
+	INVITE_DELETE:        true,
+	MESSAGE_CREATE:       true,
+	MESSAGE_DELETE:       true,
+	MESSAGE_UPDATE:       true,
+	MESSAGES_USER_CLEAR:  true,
+	MESSAGES_GUILD_CLEAR: true,
+	MEMBER_ADD:           true,
+	MEMBER_REMOVE:        true,
+	MEMBER_BAN_ADD:       true,
+	MEMBER_BAN_REMOVE:    true,
+	MEMBER_ADMIN_ADD:     true,
+	MEMBER_ADMIN_REMOVE:  true,
+	ATTACHMENT_UPDATE:    true,
+}
+
+type Subscription struct {
+	SubscriptionId int64     `json:"id,string"`
+	GuildId        int64     `json:"guildId,string"`
+	CreatorId      int64     `json:"creatorId,omitempty,string"`
+	Url            string    `json:"url"`
+	Secret         string    `json:"secret,omitempty"` //only sent when created
+	Events         []string  `json:"events"`
+	Created        time.Time `json:"created"`
+}
+
+type Delivery struct {
+	DeliveryId     int64     `json:"id,string"`
+	SubscriptionId int64     `json:"subscriptionId,string"`
+	GuildId        int64     `json:"guildId,string"`
+	Event          string    `json:"event"`
+	Url            string    `json:"url"`
+	Attempts       int       `json:"attempts"`
+	LastStatus     int       `json:"lastStatus,omitempty"`
+	LastError      string    `json:"lastError,omitempty"`
+	Created        time.Time `json:"created"`
+}
+
+// ValidateSubscriptionUrl resolves the host so subscriptions cant point at the server or the network its on
+// the outbox checks the address again when connecting since dns can change after this
+func ValidateSubscriptionUrl(rawUrl string) bool {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return false
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return false
+	}
+	ips, err := net.LookupIP(u.Hostname())
+	if err != nil || len(ips) == 0 {
+		return false
+	}
+	for _, ip := range ips {
+		if !PublicAddress(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// PublicAddress is false for loopback, private, link local, unspecified and multicast addresses
+func PublicAddress(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified())
+}
@@ -0,0 +1,28 @@
+package events
+
+import "testing"
+
+func TestValidateSubscriptionUrl(t *testing.T) {
+	for rawUrl, want := range map[string]bool{
+		"https://93.184.216.34/hook":    true,
+		"http://93.184.216.34:8080":     true,
+		"ftp://93.184.216.34":           false,
+		"https://":                      false,
+		"http://127.0.0.1/hook":         false,
+		"http://localhost/hook":         false,
+		"http://10.1.2.3":               false,
+		"http://192.168.0.10":           false,
+		"http://172.16.0.1":             false,
+		"http://169.254.169.254/latest": false, //cloud metadata
+		"http://0.0.0.0":                false,
+		"http://[::1]:8080":             false,
+		"http://[fd00::1]":              false,
+		"http://[fe80::1]":              false,
+		"http://[::ffff:127.0.0.1]":     false,
+		"http://224.0.0.1":              false,
+	} {
+		if got := ValidateSubscriptionUrl(rawUrl); got != want {
+			t.Errorf("ValidateSubscriptionUrl(%q) = %v, want %v", rawUrl, got, want)
+		}
+	}
+}
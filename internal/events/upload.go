@@ -0,0 +1,12 @@
+package events
+
+import "time"
+
+type Upload struct {
+	UploadId     int64     `json:"id,string"`
+	Filename     string    `json:"filename"`
+	Size         int64     `json:"size"`
+	Received     int64     `json:"received"` //next chunk has to start here
+	MaxChunkSize int       `json:"maxChunkSize"`
+	Expires      time.Time `json:"expires"` //pushed back every chunk
+}
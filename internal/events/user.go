@@ -31,8 +31,8 @@ type Member struct { //may use for nicks later
 * 0x02 moderator
 * 0x04 tester
 * 0x08 first user
-* 0x10
-* 0x20
+* 0x10 bot
+* 0x20 webhook
 * 0x40
 * 0x80
  */
@@ -42,6 +42,8 @@ const (
 	FLmoderator
 	FLtester
 	FLfirstUser
+	FLbot
+	FLwebhook
 )
 
 /*
@@ -91,6 +93,17 @@ func ValidateUserInput(body User) (errors.ErrCode, error) {
 	return 0, nil
 }
 
+func ValidateBotInput(body User) (errors.ErrCode, error) {
+	usernameValid, err := validateUsername(body.Name)
+	if err != nil {
+		return errors.StatusInternalError, err
+	}
+	if !usernameValid {
+		return errors.StatusInvalidUsername, errors.ErrInvalidUsername
+	}
+	return 0, nil
+}
+
 func validateUsername(name string) (bool, error) {
 	return regexp.MatchString("^[a-zA-Z0-9_]{3,32}$", name)
 }
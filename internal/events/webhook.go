@@ -0,0 +1,21 @@
+package events
+
+import (
+	"time"
+)
+
+type Webhook struct {
+	WebhookId int64     `json:"id,string"` //also the user id the messages are sent as
+	GuildId   int64     `json:"guildId,string"`
+	Name      string    `json:"name"`
+	ImageId   int64     `json:"imageId,string"`
+	CreatorId int64     `json:"creatorId,omitempty,string"`
+	Token     string    `json:"token,omitempty"`
+	Url       string    `json:"url,omitempty"`
+	Created   time.Time `json:"created"`
+}
+
+// ValidateWebhookName uses the username rules since messages show the webhook like a user
+func ValidateWebhookName(name string) (bool, error) {
+	return validateUsername(name)
+}
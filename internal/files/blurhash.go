@@ -0,0 +1,111 @@
+package files
+
+import (
+	"image"
+	"math"
+	"strings"
+)
+
+//blurhash encoder, see https://github.com/woltapp/blurhash/blob/master/Algorithm.md
+//clients draw the hash as a blurry placeholder while the real image loads
+
+const base83Chars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+// Blurhash encodes the image with xComponents by yComponents colours, both have to be between 1 and 9
+func Blurhash(img *image.RGBA, xComponents int, yComponents int) string {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	//the colours at every pixel only need converting to linear once
+	linear := make([][3]float64, width*height)
+	for y := 0; y < height; y++ {
+		i := img.PixOffset(bounds.Min.X, bounds.Min.Y+y)
+		for x := 0; x < width; x++ {
+			linear[y*width+x] = [3]float64{sRGBToLinear(img.Pix[i]), sRGBToLinear(img.Pix[i+1]), sRGBToLinear(img.Pix[i+2])}
+			i += 4
+		}
+	}
+
+	factors := make([][3]float64, 0, xComponents*yComponents)
+	for j := 0; j < yComponents; j++ {
+		for i := 0; i < xComponents; i++ {
+			normalisation := 2.0
+			if i == 0 && j == 0 {
+				normalisation = 1
+			}
+			var factor [3]float64
+			for y := 0; y < height; y++ {
+				basisY := math.Cos(math.Pi * float64(j) * float64(y) / float64(height))
+				for x := 0; x < width; x++ {
+					basis := math.Cos(math.Pi*float64(i)*float64(x)/float64(width)) * basisY
+					pixel := linear[y*width+x]
+					factor[0] += basis * pixel[0]
+					factor[1] += basis * pixel[1]
+					factor[2] += basis * pixel[2]
+				}
+			}
+			scale := normalisation / float64(width*height)
+			factor[0] *= scale
+			factor[1] *= scale
+			factor[2] *= scale
+			factors = append(factors, factor)
+		}
+	}
+
+	var hash strings.Builder
+	encode83(&hash, (xComponents-1)+(yComponents-1)*9, 1)
+
+	maximumValue := 1.0
+	if len(factors) > 1 {
+		actualMaximum := 0.0
+		for _, factor := range factors[1:] {
+			for _, value := range factor {
+				actualMaximum = math.Max(actualMaximum, math.Abs(value))
+			}
+		}
+		quantisedMaximum := int(math.Max(0, math.Min(82, math.Floor(actualMaximum*166-0.5))))
+		maximumValue = float64(quantisedMaximum+1) / 166
+		encode83(&hash, quantisedMaximum, 1)
+	} else {
+		encode83(&hash, 0, 1)
+	}
+
+	dc := factors[0]
+	encode83(&hash, linearToSRGB(dc[0])<<16+linearToSRGB(dc[1])<<8+linearToSRGB(dc[2]), 4)
+	for _, factor := range factors[1:] {
+		encode83(&hash, quantiseAC(factor[0], maximumValue)*19*19+quantiseAC(factor[1], maximumValue)*19+quantiseAC(factor[2], maximumValue), 2)
+	}
+	return hash.String()
+}
+
+func encode83(hash *strings.Builder, value int, length int) {
+	for i := 1; i <= length; i++ {
+		digit := value
+		for j := 0; j < length-i; j++ {
+			digit /= 83
+		}
+		hash.WriteByte(base83Chars[digit%83])
+	}
+}
+
+func quantiseAC(value float64, maximumValue float64) int {
+	v := value / maximumValue
+	signPow := math.Copysign(math.Sqrt(math.Abs(v)), v)
+	return int(math.Max(0, math.Min(18, math.Floor(signPow*9+9.5))))
+}
+
+func sRGBToLinear(value uint8) float64 {
+	v := float64(value) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(value float64) int {
+	v := math.Max(0, math.Min(1, value))
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
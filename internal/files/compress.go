@@ -1,16 +1,96 @@
 package files
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/asianchinaboi/backendserver/internal/storage"
+	"github.com/asianchinaboi/backendserver/internal/tracing"
 	"github.com/pierrec/lz4/v4"
 )
 
+//files are stored as lz4 frames so they can be compressed and decompressed as a stream
+//older files were stored as a single lz4 block, those are still readable but need the whole thing in memory
+
+var frameMagic = []byte{0x04, 0x22, 0x4d, 0x18}
+
 func Compress(fileBytes []byte, filesize int) ([]byte, error) { //wrapper for lz4 compression
-	compressor := lz4.CompressorHC{Level: lz4.Level2} //TODO: might move lvl to config later on
-	size := lz4.CompressBlockBound(filesize)
-	compressedBuffer := make([]byte, size)
-	compressedSize, err := compressor.CompressBlock(fileBytes, compressedBuffer)
+	var buffer bytes.Buffer
+	buffer.Grow(lz4.CompressBlockBound(filesize))
+	if _, err := CompressStream(&buffer, bytes.NewReader(fileBytes)); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// CompressStream compresses src into dst and returns how many uncompressed bytes were read
+func CompressStream(dst io.Writer, src io.Reader) (int64, error) {
+	zw := lz4.NewWriter(dst)
+	if err := zw.Apply(lz4.CompressionLevelOption(lz4.Level2)); err != nil { //TODO: might move lvl to config later on
+		return 0, err
+	}
+	written, err := io.Copy(zw, src)
+	if err != nil {
+		return written, err
+	}
+	return written, zw.Close()
+}
+
+// Save compresses src straight into storage without holding the whole file in memory
+func Save(ctx context.Context, store storage.Backend, key string, src io.Reader) (int64, error) {
+	ctx, span := tracing.Start(ctx, "files.compress", tracing.KindInternal, "storage.key", key)
+	defer span.End()
+	pr, pw := io.Pipe()
+	var written int64
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var err error
+		written, err = CompressStream(pw, src)
+		pw.CloseWithError(err)
+	}()
+	err := store.Put(ctx, key, pr, -1)
+	pr.CloseWithError(err) //stops the compressor if put gave up early
+	<-done
+	span.SetAttributes("files.size", written)
+	span.RecordError(err)
+	return written, err
+}
+
+// Decompress returns a reader of the original file
+// filesize is only needed for files stored before frames were used
+func Decompress(src io.Reader, filesize int64) (io.Reader, error) {
+	br := bufio.NewReader(src)
+	if magic, err := br.Peek(len(frameMagic)); err == nil && bytes.Equal(magic, frameMagic) {
+		return lz4.NewReader(br), nil
+	}
+	compressed, err := io.ReadAll(br)
+	if err != nil {
+		return nil, err
+	}
+	uncompressedBuffer := make([]byte, filesize)
+	n, err := lz4.UncompressBlock(compressed, uncompressedBuffer)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(uncompressedBuffer[:n]), nil
+}
+
+// Open gets the file from storage and decompresses it
+func Open(ctx context.Context, store storage.Backend, key string, filesize int64) (io.ReadCloser, error) {
+	object, err := store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	r, err := Decompress(object, filesize)
 	if err != nil {
+		object.Close()
 		return nil, err
 	}
-	return compressedBuffer[:compressedSize], nil
+	return struct {
+		io.Reader
+		io.Closer
+	}{r, object}, nil
 }
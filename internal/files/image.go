@@ -0,0 +1,203 @@
+package files
+
+import (
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	"github.com/asianchinaboi/backendserver/internal/errors"
+)
+
+//only the formats the standard library can decode are handled
+//anything else is still stored, it just gets no thumbnails or metadata
+
+// ThumbnailSizes are the sizes that can be asked for with ?size=
+var ThumbnailSizes = []int{64, 128, 256, 512}
+
+const maxImagePixels = 50000000 //decoding anything bigger takes too much memory
+
+var imageFormats = map[string]string{
+	".jpg":  "jpeg",
+	".jpeg": "jpeg",
+	".png":  "png",
+	".gif":  "gif",
+}
+
+var imageMIMETypes = map[string]string{
+	"image/jpeg": "jpeg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+}
+
+// ImageFormat returns the format of a detected content type or false if it isnt a supported image
+func ImageFormat(contentType string) (string, bool) {
+	format, ok := imageMIMETypes[contentType]
+	return format, ok
+}
+
+// ThumbnailContentType returns what a thumbnail of the content type gets encoded as
+func ThumbnailContentType(contentType string) string {
+	if contentType == "image/jpeg" {
+		return contentType
+	}
+	return "image/png" //a single frame gif would look worse than a png
+}
+
+// DecodeImage decodes a jpeg, png or gif from the start of r and returns which one it was
+func DecodeImage(r io.ReadSeeker) (image.Image, string, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, "", err
+	}
+	imageConfig, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return nil, "", err
+	}
+	if imageConfig.Width*imageConfig.Height > maxImagePixels {
+		return nil, "", errors.ErrFileTooLarge
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, "", err
+	}
+	return image.Decode(r)
+}
+
+// EncodeImage writes the image in format, gifs lose their animation
+func EncodeImage(w io.Writer, img image.Image, format string) error {
+	switch format {
+	case "jpeg":
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: 90})
+	case "gif":
+		return gif.Encode(w, img, nil)
+	default:
+		return png.Encode(w, img)
+	}
+}
+
+// CropSquare cuts the biggest square it can out of the middle of the image
+func CropSquare(img image.Image) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width == height {
+		return img
+	}
+	var square image.Rectangle
+	if width > height {
+		x := bounds.Min.X + (width-height)/2
+		square = image.Rect(x, bounds.Min.Y, x+height, bounds.Max.Y)
+	} else {
+		y := bounds.Min.Y + (height-width)/2
+		square = image.Rect(bounds.Min.X, y, bounds.Max.X, y+width)
+	}
+	if sub, ok := img.(interface {
+		SubImage(r image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(square)
+	}
+	cropped := image.NewRGBA(image.Rect(0, 0, square.Dx(), square.Dy()))
+	draw.Draw(cropped, cropped.Bounds(), img, square.Min, draw.Src)
+	return cropped
+}
+
+// Fit returns the size the image should be scaled to so it fits in a size x size box
+// images are never scaled up
+func Fit(bounds image.Rectangle, size int) (int, int) {
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= size && height <= size {
+		return width, height
+	}
+	if width >= height {
+		height = height * size / width
+		width = size
+	} else {
+		width = width * size / height
+		height = size
+	}
+	if width < 1 {
+		width = 1
+	}
+	if height < 1 {
+		height = 1
+	}
+	return width, height
+}
+
+// Resize scales the image by averaging every source pixel that lands on a destination pixel
+// good enough for shrinking, which is all it gets used for
+func Resize(img image.Image, width, height int) *image.RGBA {
+	bounds := img.Bounds()
+	src, ok := img.(*image.RGBA)
+	if !ok || bounds.Min != (image.Point{}) {
+		src = image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+		draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+	}
+	srcWidth, srcHeight := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	if width == srcWidth && height == srcHeight {
+		copy(dst.Pix, src.Pix)
+		return dst
+	}
+	for y := 0; y < height; y++ {
+		y0 := y * srcHeight / height
+		y1 := (y + 1) * srcHeight / height
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0 := x * srcWidth / width
+			x1 := (x + 1) * srcWidth / width
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			var r, g, b, a, count int
+			for sy := y0; sy < y1; sy++ {
+				i := src.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += int(src.Pix[i])
+					g += int(src.Pix[i+1])
+					b += int(src.Pix[i+2])
+					a += int(src.Pix[i+3])
+					i += 4
+					count++
+				}
+			}
+			j := dst.PixOffset(x, y)
+			dst.Pix[j] = uint8(r / count)
+			dst.Pix[j+1] = uint8(g / count)
+			dst.Pix[j+2] = uint8(b / count)
+			dst.Pix[j+3] = uint8(a / count)
+		}
+	}
+	return dst
+}
+
+// Thumbnail decodes the image and writes a version that fits in a size x size box
+// it gets encoded as ThumbnailContentType of the original
+func Thumbnail(w io.Writer, r io.ReadSeeker, size int) error {
+	img, format, err := DecodeImage(r)
+	if err != nil {
+		return err
+	}
+	width, height := Fit(img.Bounds(), size)
+	if format == "gif" {
+		format = "png"
+	}
+	return EncodeImage(w, Resize(img, width, height), format)
+}
+
+// ImageInfo returns the size and blurhash of an image
+// errors.ErrFileInvalid if it couldnt be decoded
+func ImageInfo(r io.ReadSeeker) (width int, height int, hash string, err error) {
+	img, _, err := DecodeImage(r)
+	if err == errors.ErrFileTooLarge {
+		return 0, 0, "", err
+	} else if err != nil {
+		return 0, 0, "", errors.ErrFileInvalid
+	}
+	bounds := img.Bounds()
+	//the blurhash only keeps a few colours so a tiny copy gives the same result much faster
+	smallWidth, smallHeight := Fit(bounds, 32)
+	return bounds.Dx(), bounds.Dy(), Blurhash(Resize(img, smallWidth, smallHeight), 4, 3), nil
+}
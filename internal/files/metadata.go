@@ -0,0 +1,161 @@
+package files
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// Metadata is what gets recorded about an uploaded file
+type Metadata struct {
+	Size     int64
+	Width    int     //images only
+	Height   int     //images only
+	Blurhash string  //images only
+	Duration float64 //seconds, mp4 and wav only
+}
+
+// Inspect strips metadata from images and describes the file
+// what it returns is what should be stored instead of src, Size is the size of that
+// images that cant be decoded return errors.ErrFileInvalid, or errors.ErrFileTooLarge if there are too many pixels
+func Inspect(src io.ReadSeeker, size int64, contentType string) (io.ReadSeeker, Metadata, error) {
+	meta := Metadata{Size: size}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return nil, meta, err
+	}
+	if format, ok := ImageFormat(contentType); ok {
+		data, err := io.ReadAll(src) //images are never bigger than MaxFileSize
+		if err != nil {
+			return nil, meta, err
+		}
+		strippedData, err := StripImage(data, format)
+		if err != nil {
+			return nil, meta, err
+		}
+		stripped := bytes.NewReader(strippedData)
+		meta.Size = stripped.Size()
+		if meta.Width, meta.Height, meta.Blurhash, err = ImageInfo(stripped); err != nil {
+			return nil, meta, err
+		}
+		return stripped, meta, nil
+	}
+
+	var duration float64
+	var err error
+	switch contentType {
+	case "video/mp4":
+		duration, err = mp4Duration(src)
+	case "audio/wave":
+		duration, err = wavDuration(src)
+	}
+	if err == nil {
+		meta.Duration = duration
+	}
+	return src, meta, nil
+}
+
+// finds the movie header box, it has the length of the whole video
+func mp4Duration(r io.ReadSeeker) (float64, error) {
+	return findMvhd(r, -1)
+}
+
+// looks through the boxes in r up to end (-1 for the end of the file)
+func findMvhd(r io.ReadSeeker, end int64) (float64, error) {
+	header := make([]byte, 16)
+	for {
+		pos, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, err
+		}
+		if end >= 0 && pos+8 > end {
+			return 0, io.EOF
+		}
+		if _, err := io.ReadFull(r, header[:8]); err != nil {
+			return 0, err
+		}
+		boxSize := int64(binary.BigEndian.Uint32(header[:4]))
+		boxType := string(header[4:8])
+		headerSize := int64(8)
+		if boxSize == 1 { //64 bit size
+			if _, err := io.ReadFull(r, header[8:16]); err != nil {
+				return 0, err
+			}
+			boxSize = int64(binary.BigEndian.Uint64(header[8:16]))
+			headerSize = 16
+		}
+		if boxSize != 0 && boxSize < headerSize {
+			return 0, io.ErrUnexpectedEOF
+		}
+		switch boxType {
+		case "moov":
+			boxEnd := int64(-1)
+			if boxSize != 0 {
+				boxEnd = pos + boxSize
+			}
+			return findMvhd(r, boxEnd)
+		case "mvhd":
+			body := make([]byte, 32)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return 0, err
+			}
+			var timescale uint32
+			var duration uint64
+			if body[0] == 1 {
+				timescale = binary.BigEndian.Uint32(body[20:24])
+				duration = binary.BigEndian.Uint64(body[24:32])
+			} else {
+				timescale = binary.BigEndian.Uint32(body[12:16])
+				duration = uint64(binary.BigEndian.Uint32(body[16:20]))
+			}
+			if timescale == 0 {
+				return 0, io.ErrUnexpectedEOF
+			}
+			return float64(duration) / float64(timescale), nil
+		}
+		if boxSize == 0 { //runs to the end of the file
+			return 0, io.EOF
+		}
+		if _, err := r.Seek(pos+boxSize, io.SeekStart); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// wav files say how many bytes a second of audio takes and how many bytes of audio there are
+func wavDuration(r io.ReadSeeker) (float64, error) {
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, err
+	}
+	if string(header[:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return 0, io.ErrUnexpectedEOF
+	}
+	var byteRate uint32
+	chunk := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			return 0, err
+		}
+		chunkSize := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+		switch string(chunk[:4]) {
+		case "fmt ":
+			if chunkSize < 12 {
+				return 0, io.ErrUnexpectedEOF
+			}
+			format := make([]byte, 12)
+			if _, err := io.ReadFull(r, format); err != nil {
+				return 0, err
+			}
+			byteRate = binary.LittleEndian.Uint32(format[8:12])
+			chunkSize -= 12
+		case "data":
+			if byteRate == 0 {
+				return 0, io.ErrUnexpectedEOF
+			}
+			return float64(chunkSize) / float64(byteRate), nil
+		}
+		if _, err := r.Seek(chunkSize+chunkSize%2, io.SeekCurrent); err != nil { //chunks are padded to an even size
+			return 0, err
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package files
+
+import (
+	"io"
+
+	"github.com/asianchinaboi/backendserver/internal/errors"
+)
+
+//compressed files cant be seeked so this fakes it for http.ServeContent (range requests)
+//seeking forward skips over the decompressed bytes, seeking backwards opens the file again
+//ServeContent only seeks to the end to get the size, which is known already so that costs nothing
+
+type Seeker struct {
+	open    func() (io.ReadCloser, error)
+	size    int64
+	pos     int64 //where the next read should start
+	readPos int64 //where the open reader is at
+	rc      io.ReadCloser
+}
+
+func NewSeeker(size int64, open func() (io.ReadCloser, error)) *Seeker {
+	return &Seeker{open: open, size: size}
+}
+
+func (s *Seeker) Read(p []byte) (int, error) {
+	if s.pos >= s.size {
+		return 0, io.EOF
+	}
+	if s.rc == nil || s.pos < s.readPos {
+		if err := s.Close(); err != nil {
+			return 0, err
+		}
+		rc, err := s.open()
+		if err != nil {
+			return 0, err
+		}
+		s.rc = rc
+		s.readPos = 0
+	}
+	if s.pos > s.readPos {
+		skipped, err := io.CopyN(io.Discard, s.rc, s.pos-s.readPos)
+		s.readPos += skipped
+		if err != nil {
+			return 0, err
+		}
+	}
+	n, err := s.rc.Read(p)
+	s.pos += int64(n)
+	s.readPos += int64(n)
+	return n, err
+}
+
+func (s *Seeker) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = s.pos + offset
+	case io.SeekEnd:
+		pos = s.size + offset
+	default:
+		return 0, errors.ErrFileInvalidSeek
+	}
+	if pos < 0 {
+		return 0, errors.ErrFileInvalidSeek
+	}
+	s.pos = pos
+	return pos, nil
+}
+
+func (s *Seeker) Close() error {
+	if s.rc == nil {
+		return nil
+	}
+	err := s.rc.Close()
+	s.rc = nil
+	return err
+}
@@ -0,0 +1,47 @@
+package files
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/asianchinaboi/backendserver/internal/config"
+)
+
+//signed links let a file be downloaded without the authorization header, mainly for img tags
+//anyone with the link can use it until it expires so keep the expiry short
+
+// SignedURLsEnabled is false when theres no secret in the config
+func SignedURLsEnabled(conf *config.Settings) bool {
+	return conf.Server.FileURLSecret != ""
+}
+
+// Sign returns the signature for a download link that stops working at expires (unix seconds)
+func Sign(conf *config.Settings, entityType string, fileId int64, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(conf.Server.FileURLSecret))
+	fmt.Fprintf(mac, "%s:%d:%d", entityType, fileId, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignedURL returns a download link for the file and when it expires
+func SignedURL(conf *config.Settings, entityType string, fileId int64) (string, time.Time) {
+	expires := time.Now().Add(conf.Server.FileURLExpire).Truncate(time.Second)
+	signature := Sign(conf, entityType, fileId, expires.Unix())
+	return fmt.Sprintf("/api/files/%s/%d?expires=%d&signature=%s", entityType, fileId, expires.Unix(), signature), expires
+}
+
+// VerifySignature checks the expires and signature query params of a download link
+func VerifySignature(conf *config.Settings, entityType string, fileId int64, expires string, signature string) bool {
+	if !SignedURLsEnabled(conf) {
+		return false
+	}
+	intExpires, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > intExpires {
+		return false
+	}
+	expected := Sign(conf, entityType, fileId, intExpires)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
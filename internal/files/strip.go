@@ -0,0 +1,277 @@
+package files
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+
+	"github.com/asianchinaboi/backendserver/internal/errors"
+)
+
+//cameras and phones put the location, device and time in the file itself
+//all of that is removed before anything is stored, the pixels are left alone where possible
+
+var pngSignature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+
+// chunks that can hold exif, xmp or free text
+var pngMetadataChunks = map[string]bool{
+	"eXIf": true,
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"tIME": true,
+}
+
+// StripImage removes exif, xmp and text metadata from a jpeg, png or gif
+// jpegs that rely on the exif orientation get rotated and encoded again so they still show the right way up
+// anything it cant parse is decoded and encoded again since that leaves nothing but the pixels,
+// errors.ErrFileInvalid if it cant be decoded either
+func StripImage(data []byte, format string) ([]byte, error) {
+	switch format {
+	case "jpeg":
+		stripped, orientation, ok := stripJPEG(data)
+		if !ok {
+			return reencode(data, format)
+		}
+		if orientation > 1 && orientation <= 8 {
+			if rotated, ok := applyOrientation(stripped, orientation); ok {
+				return rotated, nil
+			}
+			return nil, errors.ErrFileInvalid
+		}
+		return stripped, nil
+	case "png":
+		if stripped, ok := stripPNG(data); ok {
+			return stripped, nil
+		}
+	case "gif":
+		if stripped, ok := stripGIF(data); ok {
+			return stripped, nil
+		}
+	}
+	return reencode(data, format)
+}
+
+// reencode keeps only the pixels, gifs lose their animation
+func reencode(data []byte, format string) ([]byte, error) {
+	img, _, err := DecodeImage(bytes.NewReader(data))
+	if err == errors.ErrFileTooLarge {
+		return nil, err
+	} else if err != nil {
+		return nil, errors.ErrFileInvalid
+	}
+	var buffer bytes.Buffer
+	if err := EncodeImage(&buffer, img, format); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func stripJPEG(data []byte) ([]byte, int, bool) {
+	if len(data) < 2 || data[0] != 0xff || data[1] != 0xd8 {
+		return nil, 0, false
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xff, 0xd8)
+	orientation := 1
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xff {
+			return nil, 0, false
+		}
+		marker := data[i+1]
+		if marker == 0xff { //fill byte
+			i++
+			continue
+		}
+		if marker == 0xd9 || marker == 0xda { //end of image or start of scan, the rest is image data
+			return append(out, data[i:]...), orientation, true
+		}
+		if (marker >= 0xd0 && marker <= 0xd7) || marker == 0x01 { //no length
+			out = append(out, data[i:i+2]...)
+			i += 2
+			continue
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		end := i + 2 + length
+		if length < 2 || end > len(data) {
+			return nil, 0, false
+		}
+		segment := data[i+4 : end]
+		switch {
+		case marker == 0xe1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")):
+			orientation = exifOrientation(segment[6:])
+		case marker == 0xe1: //xmp
+		case marker == 0xed: //photoshop iptc
+		case marker == 0xfe: //comment
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	return nil, 0, false
+}
+
+// reads the orientation tag out of the first ifd, 1 if it isnt there
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	offset := int(order.Uint32(tiff[4:8]))
+	if offset+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[offset : offset+2]))
+	for n := 0; n < entries; n++ {
+		entry := offset + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 {
+			return int(order.Uint16(tiff[entry+8 : entry+10]))
+		}
+	}
+	return 1
+}
+
+func stripPNG(data []byte) ([]byte, bool) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, false
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, pngSignature...)
+	i := len(pngSignature)
+	for i+12 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[i : i+4]))
+		end := i + 12 + length
+		if length < 0 || end > len(data) {
+			return nil, false
+		}
+		if !pngMetadataChunks[string(data[i+4:i+8])] {
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	return out, true
+}
+
+// keeps the frames and the extensions needed to animate them, comments and application data like xmp are dropped
+func stripGIF(data []byte) ([]byte, bool) {
+	if len(data) < 13 || (string(data[:6]) != "GIF87a" && string(data[:6]) != "GIF89a") {
+		return nil, false
+	}
+	i := 13
+	if data[10]&0x80 != 0 { //global colour table
+		i += 3 << (data[10]&0x07 + 1)
+	}
+	if i > len(data) {
+		return nil, false
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:i]...)
+	for i < len(data) {
+		switch data[i] {
+		case 0x3b: //trailer
+			return append(out, 0x3b), true
+		case 0x21: //extension
+			if i+2 > len(data) {
+				return nil, false
+			}
+			end, ok := skipSubBlocks(data, i+2)
+			if !ok {
+				return nil, false
+			}
+			label := data[i+1]
+			keep := label == 0xf9 || label == 0x01 //graphic control and plain text are part of the picture
+			if label == 0xff {                     //application, only the ones that make it loop
+				keep = i+14 <= len(data) && data[i+2] == 11 && (string(data[i+3:i+14]) == "NETSCAPE2.0" || string(data[i+3:i+14]) == "ANIMEXTS1.0")
+			}
+			if keep {
+				out = append(out, data[i:end]...)
+			}
+			i = end
+		case 0x2c: //image descriptor
+			if i+10 > len(data) {
+				return nil, false
+			}
+			start := i
+			flags := data[i+9]
+			i += 10
+			if flags&0x80 != 0 { //local colour table
+				i += 3 << (flags&0x07 + 1)
+			}
+			i++ //lzw minimum code size
+			end, ok := skipSubBlocks(data, i)
+			if !ok {
+				return nil, false
+			}
+			out = append(out, data[start:end]...)
+			i = end
+		default:
+			return nil, false
+		}
+	}
+	return nil, false //no trailer
+}
+
+// skipSubBlocks returns where the sub blocks starting at i end, just after the empty one
+func skipSubBlocks(data []byte, i int) (int, bool) {
+	for i < len(data) {
+		size := int(data[i])
+		i += 1 + size
+		if size == 0 {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func applyOrientation(data []byte, orientation int) ([]byte, bool) {
+	img, _, err := DecodeImage(bytes.NewReader(data))
+	if err != nil {
+		return nil, false
+	}
+	src := Resize(img, img.Bounds().Dx(), img.Bounds().Dy())
+	width, height := src.Bounds().Dx(), src.Bounds().Dy()
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 { //the ones that turn it sideways
+		dstWidth, dstHeight = height, width
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = width-1-x, y
+			case 3:
+				dx, dy = width-1-x, height-1-y
+			case 4:
+				dx, dy = x, height-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = height-1-y, x
+			case 7:
+				dx, dy = height-1-y, width-1-x
+			case 8:
+				dx, dy = y, width-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):dst.PixOffset(dx, dy)+4], src.Pix[src.PixOffset(x, y):src.PixOffset(x, y)+4])
+		}
+	}
+	var buffer bytes.Buffer
+	if err := EncodeImage(&buffer, dst, "jpeg"); err != nil {
+		return nil, false
+	}
+	return buffer.Bytes(), true
+}
@@ -0,0 +1,148 @@
+package files
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/asianchinaboi/backendserver/internal/errors"
+)
+
+func testImage() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for x := 0; x < 4; x++ {
+		img.Set(x, 0, color.RGBA{R: 255, A: 255})
+		img.Set(x, 1, color.RGBA{B: 255, A: 255})
+	}
+	return img
+}
+
+// exifSegment is an app1 segment with a gps looking tag and the orientation
+func exifSegment(orientation uint16) []byte {
+	tiff := []byte{'M', 'M', 0, 42, 0, 0, 0, 8, 0, 1, 0x01, 0x12, 0, 3, 0, 0, 0, 1, byte(orientation >> 8), byte(orientation), 0, 0, 0, 0, 0, 0}
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	payload = append(payload, []byte("GPS 51.5N 0.1W")...)
+	length := len(payload) + 2
+	return append([]byte{0xff, 0xe1, byte(length >> 8), byte(length)}, payload...)
+}
+
+func TestStripJPEG(t *testing.T) {
+	var buffer bytes.Buffer
+	if err := jpeg.Encode(&buffer, testImage(), nil); err != nil {
+		t.Fatal(err)
+	}
+	plain := buffer.Bytes()
+	withExif := append(append(append([]byte{}, plain[:2]...), exifSegment(1)...), plain[2:]...)
+
+	stripped, err := StripImage(withExif, "jpeg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(stripped, []byte("GPS")) || !bytes.Equal(stripped, plain) {
+		t.Fatal("exif wasnt removed or the image changed")
+	}
+
+	//sideways photos get turned the right way up
+	rotated, err := StripImage(append(append(append([]byte{}, plain[:2]...), exifSegment(6)...), plain[2:]...), "jpeg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, _, err := image.Decode(bytes.NewReader(rotated))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(rotated, []byte("GPS")) || img.Bounds().Dx() != 2 || img.Bounds().Dy() != 4 {
+		t.Fatalf("got a %v image", img.Bounds())
+	}
+}
+
+func TestStripPNG(t *testing.T) {
+	var buffer bytes.Buffer
+	if err := png.Encode(&buffer, testImage()); err != nil {
+		t.Fatal(err)
+	}
+	data := buffer.Bytes()
+	//a text chunk after the header, the crc isnt checked by the stripper
+	text := append([]byte{0, 0, 0, 3}, []byte("tEXtGPS")...)
+	text = append(text, 0, 0, 0, 0)
+	withText := append(append(append([]byte{}, data[:33]...), text...), data[33:]...)
+
+	stripped, err := StripImage(withText, "png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(stripped, data) {
+		t.Fatal("text chunk wasnt removed")
+	}
+}
+
+func TestStripGIF(t *testing.T) {
+	frames := &gif.GIF{LoopCount: 0}
+	for i := 0; i < 2; i++ {
+		frame := image.NewPaletted(image.Rect(0, 0, 4, 2), color.Palette{color.Black, color.White})
+		frame.SetColorIndex(i, 0, 1)
+		frames.Image = append(frames.Image, frame)
+		frames.Delay = append(frames.Delay, 10)
+	}
+	var buffer bytes.Buffer
+	if err := gif.EncodeAll(&buffer, frames); err != nil {
+		t.Fatal(err)
+	}
+	data := buffer.Bytes()
+	//a comment before the first frame
+	start := 13
+	if data[10]&0x80 != 0 {
+		start += 3 << (data[10]&0x07 + 1)
+	}
+	comment := append([]byte{0x21, 0xfe, 3}, []byte("GPS")...)
+	comment = append(comment, 0)
+	withComment := append(append(append([]byte{}, data[:start]...), comment...), data[start:]...)
+
+	stripped, err := StripImage(withComment, "gif")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(stripped, []byte("GPS")) {
+		t.Fatal("comment wasnt removed")
+	}
+	decoded, err := gif.DecodeAll(bytes.NewReader(stripped))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Image) != 2 {
+		t.Fatalf("got %d frames, want 2", len(decoded.Image))
+	}
+}
+
+func TestStripImageFailsClosed(t *testing.T) {
+	var buffer bytes.Buffer
+	if err := png.Encode(&buffer, testImage()); err != nil {
+		t.Fatal(err)
+	}
+	//a png that the chunk parser cant follow gets encoded again instead of stored as it is
+	broken := append(append([]byte{}, buffer.Bytes()...), 0, 0, 0xff, 0xff, 'e', 'X', 'I', 'f', 'G', 'P', 'S')
+	stripped, err := StripImage(broken, "png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(stripped, []byte("GPS")) {
+		t.Fatal("original bytes were kept")
+	}
+
+	for _, format := range []string{"jpeg", "png", "gif"} {
+		if _, err := StripImage([]byte("not an image GPS"), format); err != errors.ErrFileInvalid {
+			t.Errorf("%s: got %v, want %v", format, err, errors.ErrFileInvalid)
+		}
+	}
+}
+
+func TestInspectInvalidImage(t *testing.T) {
+	data := []byte("\xff\xd8\xff\xe0 not really a jpeg")
+	if _, _, err := Inspect(bytes.NewReader(data), int64(len(data)), "image/jpeg"); err != errors.ErrFileInvalid {
+		t.Fatalf("got %v, want %v", err, errors.ErrFileInvalid)
+	}
+}
@@ -2,45 +2,46 @@ package files
 
 import (
 	"bytes"
-	"image"
-	"image/gif"
-	"image/jpeg"
-	"image/png"
 
-	"github.com/asianchinaboi/backendserver/internal/config"
 	"github.com/asianchinaboi/backendserver/internal/logger"
 )
 
-func ValidateImage(fileBytes []byte, fileType string) bool {
-	var imageFile image.Image
-	var err error
+// ValidateImage checks the profile image and returns it ready to store
+// exif is stripped, images that arent square get center cropped and ones bigger than maxSize get scaled down
+// otherwise the bytes are kept as they are so animated gifs stay animated
+func ValidateImage(fileBytes []byte, fileType string, maxSize int) ([]byte, bool) {
 	logger.Debug.Println(fileType)
-	r := bytes.NewReader(fileBytes)
-	switch fileType {
-	case ".jpg":
-		fallthrough
-	case ".jpeg":
-		if imageFile, err = jpeg.Decode(r); err != nil {
-			return false
-		}
-	case ".png":
-		if imageFile, err = png.Decode(r); err != nil {
-			return false
-		}
-	case ".gif":
-		if imageFile, err = gif.Decode(r); err != nil {
-			return false
-		}
-	default:
-		return false
+	format, ok := imageFormats[fileType]
+	if !ok {
+		return nil, false
+	}
+	fileBytes, err := StripImage(fileBytes, format)
+	if err != nil {
+		return nil, false
+	}
+	imageFile, decodedFormat, err := DecodeImage(bytes.NewReader(fileBytes))
+	if err != nil || decodedFormat != format {
+		return nil, false
 	}
 	bounds := imageFile.Bounds()
 	width := bounds.Dx()
 	height := bounds.Dy()
-	if width > config.Config.Server.ImageProfileSize || height > config.Config.Server.ImageProfileSize {
-		logger.Debug.Println("bad 1")
-		return false
+	if width == height && width <= maxSize {
+		return fileBytes, true
+	}
+
+	side := width
+	if height < side {
+		side = height
+	}
+	if side > maxSize {
+		side = maxSize
+	}
+	resized := Resize(CropSquare(imageFile), side, side)
+	var buffer bytes.Buffer
+	if err := EncodeImage(&buffer, resized, format); err != nil {
+		logger.Warn.Println(err)
+		return nil, false
 	}
-	logger.Debug.Println(width == height)
-	return width == height
+	return buffer.Bytes(), true
 }
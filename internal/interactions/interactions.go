@@ -0,0 +1,70 @@
+package interactions
+
+import (
+	"sync"
+	"time"
+
+	"github.com/asianchinaboi/backendserver/internal/events"
+)
+
+//interactions only live in memory until the bot replies or the deadline passes
+//nothing needs them after that so they arent saved
+//this means they only work with a single instance, a reply that lands on another instance wont find the interaction
+//if the server ever runs behind a load balancer these need to go in postgres like the outbox
+
+type Pending struct {
+	events.Interaction
+	BotId     int64
+	InvokerId int64
+}
+
+// Manager keeps the interactions waiting on a reply, the app creates one
+type Manager struct {
+	sync.Mutex
+	pending map[int64]*Pending
+	timeout time.Duration //how long bots get to reply
+}
+
+func New(timeout time.Duration) *Manager {
+	return &Manager{pending: make(map[int64]*Pending), timeout: timeout}
+}
+
+func (m *Manager) Add(interaction events.Interaction, botId int64) *Pending {
+	interaction.Expires = time.Now().Add(m.timeout).UTC()
+	pending := &Pending{
+		Interaction: interaction,
+		BotId:       botId,
+		InvokerId:   interaction.User.UserId,
+	}
+	m.Lock()
+	defer m.Unlock()
+	m.removeExpired()
+	m.pending[interaction.InteractionId] = pending
+	return pending
+}
+
+// Get returns the interaction if it hasnt expired and belongs to the bot
+// bots can reply more than once until it expires
+func (m *Manager) Get(interactionId int64, botId int64) (*Pending, bool) {
+	m.Lock()
+	defer m.Unlock()
+	pending, ok := m.pending[interactionId]
+	if !ok || pending.BotId != botId {
+		return nil, false
+	}
+	if time.Now().After(pending.Expires) {
+		delete(m.pending, interactionId)
+		return nil, false
+	}
+	return pending, true
+}
+
+// lock must be held by caller
+func (m *Manager) removeExpired() {
+	now := time.Now()
+	for id, pending := range m.pending {
+		if now.After(pending.Expires) {
+			delete(m.pending, id)
+		}
+	}
+}
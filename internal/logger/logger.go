@@ -1,48 +1,184 @@
 package logger
 
 import (
-	"io"
+	"errors"
 	"log"
-	"os"
-	"path/filepath"
-	"time"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 )
 
+// the old loggers are kept so nothing has to change, every line they print is turned into a record
+// new code should use Default or Ctx with key value pairs e.g. logger.Default.Info("blob removed", "hash", hash)
 var (
-	Warn  *log.Logger
-	Info  *log.Logger
-	Error *log.Logger
-	Fatal *log.Logger
-	Debug *log.Logger
+	Warn  = log.New(lineWriter{LevelWarn, true}, "", log.Lshortfile)
+	Info  = log.New(lineWriter{LevelInfo, true}, "", log.Lshortfile)
+	Error = log.New(lineWriter{LevelError, true}, "", log.Lshortfile)
+	Fatal = log.New(lineWriter{LevelFatal, true}, "", log.Lshortfile)
+	Debug = log.New(lineWriter{LevelDebug, true}, "", log.Lshortfile)
 )
 
-//replace with Zap library later
-
-func init() {
-	//get current date for file
-	t := time.Now()
-	name := t.Format("2006-01-02") + ".log"
-	dirPath := filepath.Join(".", "logs")
-	err := os.MkdirAll(dirPath, os.ModePerm)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	logFilePath := filepath.Join(dirPath, name)
-	file, err := os.OpenFile(logFilePath, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
-	if err != nil {
-		log.Fatal(err)
-	}
-	//print to file and console
-	output := io.MultiWriter(os.Stdout, file)
-	gin.DefaultWriter = output
-	errOutput := io.MultiWriter(os.Stderr, file)
-
-	Info = log.New(output, "INFO:\t", log.Ldate|log.Ltime|log.Lshortfile)
-	Warn = log.New(errOutput, "WARN:\t", log.Ldate|log.Ltime|log.Lshortfile)
-	Error = log.New(errOutput, "ERROR:\t", log.Ldate|log.Ltime|log.Lshortfile)
-	Fatal = log.New(errOutput, "FATAL:\t", log.Ldate|log.Ltime|log.Lshortfile)
-	Debug = log.New(output, "DEBUG:\t", log.Ldate|log.Ltime|log.Lshortfile)
+var Default = &Logger{}
+
+// RequestIdKey is where the request id middleware stores the id in the gin context
+const RequestIdKey = "requestId"
+
+const loggerKey = "logger" //where the request logger from SetCtx is kept
+
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelFatal //always logged
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	}
+	return "FATAL"
+}
+
+func ParseLevel(level string) (Level, bool) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return LevelDebug, true
+	case "info", "":
+		return LevelInfo, true
+	case "warn":
+		return LevelWarn, true
+	case "error":
+		return LevelError, true
+	}
+	return 0, false
+}
+
+// Logger adds the same key value pairs to everything it logs
+type Logger struct {
+	attrs []interface{}
+}
+
+// With returns a logger that adds args (key, value, key, value...) to every record
+func (l *Logger) With(args ...interface{}) *Logger {
+	attrs := make([]interface{}, 0, len(l.attrs)+len(args))
+	attrs = append(attrs, l.attrs...)
+	return &Logger{attrs: append(attrs, args...)}
+}
+
+func (l *Logger) Debug(msg string, args ...interface{}) {
+	l.log(LevelDebug, msg, args)
+}
+
+func (l *Logger) Info(msg string, args ...interface{}) {
+	l.log(LevelInfo, msg, args)
+}
+
+func (l *Logger) Warn(msg string, args ...interface{}) {
+	l.log(LevelWarn, msg, args)
+}
+
+func (l *Logger) Error(msg string, args ...interface{}) {
+	l.log(LevelError, msg, args)
+}
+
+func (l *Logger) log(level Level, msg string, args []interface{}) {
+	l.Output(3, level, msg, args...)
+}
+
+// Output is like log.Logger.Output, calldepth picks which caller is used as the source
+func (l *Logger) Output(calldepth int, level Level, msg string, args ...interface{}) {
+	if !out.enabled(level) {
+		return
+	}
+	source := ""
+	if _, file, line, ok := runtime.Caller(calldepth); ok {
+		source = file[strings.LastIndex(file, "/")+1:] + ":" + strconv.Itoa(line)
+	}
+	attrs := make([]interface{}, 0, len(l.attrs)+len(args))
+	attrs = append(attrs, l.attrs...)
+	out.write(level, source, msg, append(attrs, args...))
+}
+
+// Ctx returns the logger for a request, it tags everything with the request id
+func Ctx(c *gin.Context) *Logger {
+	if l, ok := c.Get(loggerKey); ok {
+		return l.(*Logger)
+	}
+	if id := c.GetString(RequestIdKey); id != "" {
+		return Default.With(RequestIdKey, id)
+	}
+	return Default
+}
+
+// SetCtx makes l the logger Ctx returns for the request
+func SetCtx(c *gin.Context, l *Logger) {
+	c.Set(loggerKey, l)
+}
+
+// lineWriter turns lines from a log.Logger into records
+type lineWriter struct {
+	level  Level
+	source bool //lines start with file.go:12: from log.Lshortfile
+}
+
+func (w lineWriter) Write(p []byte) (int, error) {
+	if !out.enabled(w.level) {
+		return len(p), nil
+	}
+	line := strings.TrimRight(string(p), "\n")
+	source := ""
+	if w.source {
+		if i := strings.Index(line, ": "); i >= 0 {
+			source, line = line[:i], line[i+2:]
+		}
+	}
+	out.write(w.level, source, line, nil)
+	return len(p), nil
+}
+
+var setupMutex sync.Mutex
+
+var ( //cant use the errors package since it logs
+	errInvalidLevel  = errors.New("logger: invalid level")
+	errInvalidFormat = errors.New("logger: invalid format")
+)
+
+// Options come from the config, logger cant import it since the config logs while loading
+type Options struct {
+	Level   string
+	Format  string //json or text
+	Dir     string //empty only logs to the console
+	MaxSize int64  //bytes before the file is rotated, 0 only rotates daily
+	MaxAge  int    //days old log files are kept for, 0 keeps them forever
+}
+
+// Setup switches to the level, format and files in opts, until then everything info and up goes to the console as json
+// gin is pointed at the logger here too so importing this package doesnt change anything by itself
+func Setup(opts Options) error {
+	level, ok := ParseLevel(opts.Level)
+	if !ok {
+		return errInvalidLevel
+	}
+	if opts.Format != "" && opts.Format != "json" && opts.Format != "text" {
+		return errInvalidFormat
+	}
+	setupMutex.Lock()
+	defer setupMutex.Unlock()
+	out.configure(level, opts.Format != "text", opts.Dir, opts.MaxSize, opts.MaxAge)
+	gin.DefaultWriter = lineWriter{level: LevelInfo}
+	gin.DefaultErrorWriter = lineWriter{level: LevelError}
+	return nil
 }
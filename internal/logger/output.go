@@ -0,0 +1,145 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var out = newOutput(LevelInfo, true, "", 0, 0) //console only until Setup
+
+// output formats records and writes them to the console and the log file
+type output struct {
+	sync.Mutex
+	level Level
+	json  bool
+	file  *rotatingFile //nil if only logging to the console
+}
+
+func newOutput(level Level, json bool, dir string, maxSize int64, maxAge int) *output {
+	o := &output{}
+	o.configure(level, json, dir, maxSize, maxAge)
+	return o
+}
+
+func (o *output) configure(level Level, json bool, dir string, maxSize int64, maxAge int) {
+	o.Lock()
+	defer o.Unlock()
+	o.level = level
+	o.json = json
+	if o.file != nil {
+		o.file.Close()
+		o.file = nil
+	}
+	if dir != "" {
+		o.file = &rotatingFile{dir: dir, maxSize: maxSize, maxAge: maxAge}
+	}
+}
+
+func (o *output) enabled(level Level) bool {
+	o.Lock()
+	defer o.Unlock()
+	return level >= o.level
+}
+
+func (o *output) write(level Level, source string, msg string, attrs []interface{}) {
+	var buf bytes.Buffer
+	o.Lock()
+	defer o.Unlock()
+	if o.json {
+		formatJSON(&buf, level, source, msg, attrs)
+	} else {
+		formatText(&buf, level, source, msg, attrs)
+	}
+
+	var console io.Writer = os.Stdout
+	if level >= LevelWarn {
+		console = os.Stderr
+	}
+	console.Write(buf.Bytes())
+	if o.file != nil {
+		if _, err := o.file.Write(buf.Bytes()); err != nil {
+			fmt.Fprintf(os.Stderr, "unable to write to log file: %v\n", err)
+		}
+	}
+}
+
+// pairs calls fn for each key value pair, a key without a value gets logged as !BADKEY like slog does
+func pairs(attrs []interface{}, fn func(key string, value interface{})) {
+	for i := 0; i < len(attrs); i++ {
+		key, ok := attrs[i].(string)
+		if !ok || i+1 == len(attrs) {
+			fn("!BADKEY", attrs[i])
+			continue
+		}
+		fn(key, attrs[i+1])
+		i++
+	}
+}
+
+// simplify turns values that dont encode well into strings
+func simplify(value interface{}) interface{} {
+	switch v := value.(type) {
+	case error:
+		return v.Error()
+	case time.Duration:
+		return v.String()
+	case time.Time:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	}
+	return value
+}
+
+func formatJSON(buf *bytes.Buffer, level Level, source string, msg string, attrs []interface{}) {
+	write := func(key string, value interface{}) {
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		encodedKey, _ := json.Marshal(key)
+		buf.Write(encodedKey)
+		buf.WriteByte(':')
+		encoded, err := json.Marshal(simplify(value))
+		if err != nil {
+			encoded, _ = json.Marshal(fmt.Sprint(value))
+		}
+		buf.Write(encoded)
+	}
+	buf.WriteByte('{')
+	write("time", time.Now())
+	write("level", level.String())
+	write("msg", msg)
+	if source != "" {
+		write("source", source)
+	}
+	pairs(attrs, write)
+	buf.WriteString("}\n")
+}
+
+func formatText(buf *bytes.Buffer, level Level, source string, msg string, attrs []interface{}) {
+	write := func(key string, value interface{}) {
+		if buf.Len() > 0 {
+			buf.WriteByte(' ')
+		}
+		str := fmt.Sprint(simplify(value))
+		if str == "" || strings.ContainsAny(str, " =\"\t\n") {
+			str = strconv.Quote(str)
+		}
+		buf.WriteString(key + "=" + str)
+	}
+	write("time", time.Now().Format(time.RFC3339Nano))
+	write("level", level.String())
+	if source != "" {
+		write("source", source)
+	}
+	write("msg", msg)
+	pairs(attrs, write)
+	buf.WriteByte('\n')
+}
@@ -0,0 +1,97 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// rotatingFile starts a new file every day and whenever the current one would go over maxSize
+// the newest file is always <date>.log, full ones are moved to <date>.1.log, <date>.2.log...
+type rotatingFile struct {
+	dir     string
+	maxSize int64
+	maxAge  int
+	file    *os.File
+	day     string
+	size    int64
+}
+
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	day := time.Now().Format("2006-01-02")
+	if f.file == nil || day != f.day || (f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize) {
+		if err := f.rotate(day, int64(len(p))); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *rotatingFile) rotate(day string, next int64) error {
+	f.Close()
+	if err := os.MkdirAll(f.dir, os.ModePerm); err != nil {
+		return err
+	}
+	name := filepath.Join(f.dir, day+".log")
+	if info, err := os.Stat(name); err == nil && f.maxSize > 0 && info.Size() > 0 && info.Size()+next > f.maxSize {
+		for i := 1; ; i++ {
+			full := filepath.Join(f.dir, fmt.Sprintf("%s.%d.log", day, i))
+			if _, err := os.Stat(full); os.IsNotExist(err) {
+				if err := os.Rename(name, full); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+
+	file, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	if day != f.day {
+		f.removeOld()
+	}
+	f.file = file
+	f.day = day
+	f.size = info.Size()
+	return nil
+}
+
+// removeOld deletes log files older than maxAge days
+func (f *rotatingFile) removeOld() {
+	if f.maxAge <= 0 {
+		return
+	}
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return
+	}
+	cutoff := time.Now().AddDate(0, 0, -f.maxAge)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".log") {
+			continue
+		}
+		if info, err := entry.Info(); err == nil && info.ModTime().Before(cutoff) {
+			os.Remove(filepath.Join(f.dir, entry.Name()))
+		}
+	}
+}
+
+func (f *rotatingFile) Close() error {
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
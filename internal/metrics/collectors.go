@@ -0,0 +1,28 @@
+package metrics
+
+//everything exported on /metrics, gauges that can be read at any time (pools, db stats) are set when scraped
+
+var (
+	HTTPRequests        = NewCounter("http_requests_total", "HTTP requests by route and status.", "method", "route", "status")
+	HTTPRequestDuration = NewHistogram("http_request_duration_seconds", "HTTP request latency by route and status.", nil, "method", "route", "status")
+	RateLimitRejections = NewCounter("rate_limit_rejections_total", "Requests and gateway frames rejected by a rate limit.", "route")
+
+	WebsocketConnections = NewGauge("websocket_connections", "Open websocket connections.")
+	WebsocketUsers       = NewGauge("websocket_users", "Users with at least one identified connection.")
+	GuildPools           = NewGauge("websocket_guild_pools", "Guild pools with connections in them.")
+	BroadcastQueue       = NewGauge("websocket_broadcast_queue", "Broadcasts waiting to be picked up by a guild pool or connection.")
+	WebsocketCloses      = NewCounter("websocket_closes_total", "Connections closed by the server by close code.", "code")
+
+	DBOpenConnections  = NewGauge("db_open_connections", "Open database connections.")
+	DBInUse            = NewGauge("db_in_use_connections", "Database connections in use.")
+	DBIdle             = NewGauge("db_idle_connections", "Idle database connections.")
+	DBWaitCount        = NewCounter("db_wait_count_total", "Times a query waited for a free connection.")
+	DBWaitDuration     = NewCounter("db_wait_duration_seconds_total", "Time spent waiting for a free connection.")
+	DBMaxIdleClosed    = NewCounter("db_max_idle_closed_total", "Connections closed because of the max idle limit.")
+	DBMaxLifetimeClose = NewCounter("db_max_lifetime_closed_total", "Connections closed because of the max lifetime.")
+
+	SchedulerJobs        = NewCounter("scheduler_job_runs_total", "Scheduled job runs by result.", "job", "result")
+	SchedulerJobDuration = NewHistogram("scheduler_job_duration_seconds", "How long scheduled jobs take.", []float64{.1, 1, 10, 60, 300, 1800}, "job")
+
+	UploadBytes = NewCounter("upload_bytes_total", "Bytes of uploaded files, new blobs are stored and duplicates reuse an existing one.", "blob")
+)
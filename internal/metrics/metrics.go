@@ -0,0 +1,214 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//just enough of the prometheus text format to be scraped
+//every metric registers itself when created and is written in the order they were created
+
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+)
+
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10} //seconds
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+var registry struct {
+	sync.Mutex
+	metrics []*metric
+}
+
+type metric struct {
+	sync.Mutex
+	name    string
+	help    string
+	kind    string
+	labels  []string
+	buckets []float64 //histograms only
+	series  map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	value       float64  //counters and gauges
+	counts      []uint64 //histograms, one per bucket (not cumulative)
+	sum         float64
+	count       uint64
+}
+
+type Counter struct{ m *metric }
+type Gauge struct{ m *metric }
+type Histogram struct{ m *metric }
+
+func register(name string, help string, kind string, labels []string, buckets []float64) *metric {
+	m := &metric{name: name, help: help, kind: kind, labels: labels, buckets: buckets, series: make(map[string]*series)}
+	if len(labels) == 0 { //shows up as 0 instead of missing until its first used
+		m.get(nil)
+	}
+	registry.Lock()
+	registry.metrics = append(registry.metrics, m)
+	registry.Unlock()
+	return m
+}
+
+func NewCounter(name string, help string, labels ...string) *Counter {
+	return &Counter{register(name, help, typeCounter, labels, nil)}
+}
+
+func NewGauge(name string, help string, labels ...string) *Gauge {
+	return &Gauge{register(name, help, typeGauge, labels, nil)}
+}
+
+// NewHistogram uses DefaultBuckets if buckets is nil
+func NewHistogram(name string, help string, buckets []float64, labels ...string) *Histogram {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	return &Histogram{register(name, help, typeHistogram, labels, buckets)}
+}
+
+// get returns the series for the label values, lock must be held by caller
+// missing label values are left empty and extra ones are ignored
+func (m *metric) get(labelValues []string) *series {
+	values := make([]string, len(m.labels))
+	copy(values, labelValues)
+	key := strings.Join(values, "\xff")
+	s, ok := m.series[key]
+	if !ok {
+		s = &series{labelValues: values}
+		if m.kind == typeHistogram {
+			s.counts = make([]uint64, len(m.buckets))
+		}
+		m.series[key] = s
+	}
+	return s
+}
+
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 { //counters only go up
+		return
+	}
+	c.m.Lock()
+	c.m.get(labelValues).value += v
+	c.m.Unlock()
+}
+
+// Set is for counters kept somewhere else like sql.DBStats, they get copied in when scraped
+func (c *Counter) Set(v float64, labelValues ...string) {
+	c.m.Lock()
+	c.m.get(labelValues).value = v
+	c.m.Unlock()
+}
+
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.m.Lock()
+	g.m.get(labelValues).value = v
+	g.m.Unlock()
+}
+
+func (g *Gauge) Add(v float64, labelValues ...string) {
+	g.m.Lock()
+	g.m.get(labelValues).value += v
+	g.m.Unlock()
+}
+
+func (g *Gauge) Inc(labelValues ...string) {
+	g.Add(1, labelValues...)
+}
+
+func (g *Gauge) Dec(labelValues ...string) {
+	g.Add(-1, labelValues...)
+}
+
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.m.Lock()
+	defer h.m.Unlock()
+	s := h.m.get(labelValues)
+	if i := sort.SearchFloat64s(h.m.buckets, v); i < len(h.m.buckets) { //first bucket with an upper bound >= v
+		s.counts[i]++
+	}
+	s.sum += v
+	s.count++
+}
+
+// Write writes every metric in the prometheus text format
+func Write(w io.Writer) error {
+	registry.Lock()
+	metrics := make([]*metric, len(registry.metrics))
+	copy(metrics, registry.metrics)
+	registry.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, m := range metrics {
+		m.write(bw)
+	}
+	return bw.Flush()
+}
+
+func (m *metric) write(w *bufio.Writer) {
+	m.Lock()
+	defer m.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n", m.name, m.help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", m.name, m.kind)
+
+	keys := make([]string, 0, len(m.series))
+	for key := range m.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys) //stable output makes it easier to read
+	for _, key := range keys {
+		s := m.series[key]
+		if m.kind != typeHistogram {
+			fmt.Fprintf(w, "%s%s %s\n", m.name, m.labelString(s.labelValues, ""), formatFloat(s.value))
+			continue
+		}
+		var cumulative uint64
+		for i, bound := range m.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", m.name, m.labelString(s.labelValues, formatFloat(bound)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", m.name, m.labelString(s.labelValues, "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", m.name, m.labelString(s.labelValues, ""), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", m.name, m.labelString(s.labelValues, ""), s.count)
+	}
+}
+
+// labelString formats {label="value",...}, le is added for histogram buckets
+func (m *metric) labelString(values []string, le string) string {
+	var pairs []string
+	for i, label := range m.labels {
+		pairs = append(pairs, label+`="`+labelEscaper.Replace(values[i])+`"`)
+	}
+	if le != "" {
+		pairs = append(pairs, `le="`+le+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,189 @@
+package msgpack
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/asianchinaboi/backendserver/internal/errors"
+)
+
+// roundTrip checks v comes back from msgpack as the same json encoding/json would give
+func roundTrip(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	data, err := Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ToJSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameJSON(t, got, want) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	return data
+}
+
+// sameJSON compares decoded values since map keys come back sorted, numbers are kept exact
+func sameJSON(t *testing.T, a []byte, b []byte) bool {
+	t.Helper()
+	decode := func(data []byte) interface{} {
+		d := json.NewDecoder(bytes.NewReader(data))
+		d.UseNumber()
+		var v interface{}
+		if err := d.Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	return reflect.DeepEqual(decode(a), decode(b))
+}
+
+func TestRoundTripNumbers(t *testing.T) {
+	//every size boundary for each int format
+	for _, n := range []int64{0, 1, 127, 128, 255, 256, -1, -32, -33, -128, -129, math.MaxInt16, math.MaxInt16 + 1, math.MinInt16, math.MinInt16 - 1,
+		math.MaxInt32, math.MaxInt32 + 1, math.MinInt32, math.MinInt32 - 1, math.MaxInt64, math.MinInt64} {
+		roundTrip(t, n)
+	}
+	roundTrip(t, uint64(math.MaxUint64))
+	for _, f := range []float64{0.5, -1.25, 3.141592653589793, 1e300, -1e-300} {
+		roundTrip(t, f)
+	}
+}
+
+func TestRoundTripStrings(t *testing.T) {
+	for _, n := range []int{0, 1, 31, 32, 255, 256, math.MaxUint16, math.MaxUint16 + 1} {
+		roundTrip(t, strings.Repeat("a", n))
+	}
+	roundTrip(t, "unicode ✓ and \"quotes\" and \\ and \n")
+}
+
+func TestRoundTripCollections(t *testing.T) {
+	for _, n := range []int{0, 15, 16, math.MaxUint16 + 1} {
+		array := make([]int, n)
+		m := make(map[string]int, n)
+		for i := range array {
+			array[i] = i
+			m[strings.Repeat("k", i%40)+strconv.Itoa(i)] = i //some keys longer than a fixstr
+		}
+		roundTrip(t, array)
+		roundTrip(t, m)
+	}
+	roundTrip(t, []interface{}{nil, true, false, "", []interface{}{}, map[string]interface{}{}})
+}
+
+func TestRoundTripFrame(t *testing.T) {
+	//json tags decide the keys, snowflakes tagged string and big ints must not lose precision
+	type attachment struct {
+		Id   int64  `json:"id,string"`
+		Size int64  `json:"size"`
+		Name string `json:"filename"`
+	}
+	type msg struct {
+		MsgId       int64        `json:"id,string"`
+		Content     string       `json:"content"`
+		Author      *string      `json:"author,omitempty"`
+		Attachments []attachment `json:"attachments"`
+		Big         int64        `json:"big"`
+	}
+	data := roundTrip(t, struct {
+		Op    int    `json:"op"`
+		Data  msg    `json:"data"`
+		Event string `json:"event"`
+	}{0, msg{MsgId: 1500000000000000001, Content: "hi", Attachments: []attachment{{1500000000000000002, 1 << 40, "a.png"}}, Big: 1<<62 + 1}, "MESSAGE_CREATE"})
+	if bytes.Contains(data, []byte("author")) {
+		t.Error("omitempty field was encoded")
+	}
+}
+
+// exact bytes from the msgpack spec so other implementations can read what we send
+func TestMarshalBytes(t *testing.T) {
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{nil, "c0"},
+		{true, "c3"},
+		{false, "c2"},
+		{5, "05"},
+		{-5, "fb"},
+		{200, "d1 00c8"}, //positive ints are encoded signed
+		{-200, "d1 ff38"},
+		{uint64(math.MaxUint64), "cf ffffffffffffffff"},
+		{1.5, "cb 3ff8000000000000"},
+		{"abc", "a3 616263"},
+		{[]int{1, 2}, "92 01 02"},
+		{map[string]int{"b": 2, "a": 1}, "82 a161 01 a162 02"}, //keys sorted
+	}
+	for _, test := range tests {
+		data, err := Marshal(test.v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, _ := hex.DecodeString(strings.ReplaceAll(test.want, " ", ""))
+		if !bytes.Equal(data, want) {
+			t.Errorf("%#v: got % x, want % x", test.v, data, want)
+		}
+	}
+}
+
+// formats we never send but clients might, like unsigned ints, float32 and bin
+func TestToJSONOtherFormats(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{"cc ff", "255"},
+		{"cd 0100", "256"},
+		{"ce 00010000", "65536"},
+		{"ca 3fc00000", "1.5"},
+		{"c4 02 6869", `"hi"`},
+		{"d9 02 6869", `"hi"`},
+		{"dc 0001 c3", "[true]"},
+		{"de 0001 a161 c0", `{"a":null}`},
+		{"d0 80", "-128"},
+		{"d3 8000000000000000", "-9223372036854775808"},
+	}
+	for _, test := range tests {
+		data, _ := hex.DecodeString(strings.ReplaceAll(test.data, " ", ""))
+		got, err := ToJSON(data)
+		if err != nil {
+			t.Errorf("%s: %v", test.data, err)
+			continue
+		}
+		if string(got) != test.want {
+			t.Errorf("%s: got %s, want %s", test.data, got, test.want)
+		}
+	}
+}
+
+func TestToJSONErrors(t *testing.T) {
+	tests := []struct {
+		data string
+		err  error
+	}{
+		{"", errors.ErrMsgpackShort},
+		{"a3 6162", errors.ErrMsgpackShort},
+		{"dd ffffffff", errors.ErrMsgpackShort}, //huge length shouldnt allocate
+		{"92 01", errors.ErrMsgpackShort},
+		{"01 02", errors.ErrMsgpackTrailing},
+		{"81 01 02", errors.ErrMsgpackMapKey},
+		{"d4 01 00", errors.ErrMsgpackUnsupported}, //ext
+	}
+	for _, test := range tests {
+		data, _ := hex.DecodeString(strings.ReplaceAll(test.data, " ", ""))
+		if _, err := ToJSON(data); err != test.err {
+			t.Errorf("%q: got %v, want %v", test.data, err, test.err)
+		}
+	}
+}
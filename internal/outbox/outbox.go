@@ -0,0 +1,51 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/asianchinaboi/backendserver/internal/events"
+	"github.com/asianchinaboi/backendserver/internal/uid"
+)
+
+//guild events get written to event_deliveries for every subscription that wants them
+//the worker picks them up from there so nothing is lost if the server restarts or the endpoint is down
+
+// Enqueue stores the event for every subscription in the guild listening to it
+// tx has to be the one making the change so the event is only kept if the change is committed
+func Enqueue(ctx context.Context, tx *sql.Tx, guildId int64, event string, data interface{}) error {
+	if !events.SubscribableEvents[event] {
+		return nil
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT id FROM event_subscriptions WHERE guild_id = $1 AND $2 = ANY(events)", guildId, event)
+	if err != nil {
+		return err
+	}
+	subscriptionIds := []int64{}
+	for rows.Next() {
+		var subscriptionId int64
+		if err := rows.Scan(&subscriptionId); err != nil {
+			rows.Close()
+			return err
+		}
+		subscriptionIds = append(subscriptionIds, subscriptionId)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, subscriptionId := range subscriptionIds { //has to be after the rows are closed, a transaction only runs one query at a time
+		if _, err := tx.ExecContext(ctx, "INSERT INTO event_deliveries (id, subscription_id, guild_id, event, payload) VALUES ($1, $2, $3, $4, $5)",
+			uid.Snowflake.Generate().Int64(), subscriptionId, guildId, event, string(payload)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
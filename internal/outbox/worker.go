@@ -0,0 +1,246 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/asianchinaboi/backendserver/internal/config"
+	"github.com/asianchinaboi/backendserver/internal/events"
+	"github.com/asianchinaboi/backendserver/internal/logger"
+)
+
+const (
+	SignatureHeader = "X-Signature-256"
+	TimestampHeader = "X-Signature-Timestamp"
+	EventHeader     = "X-Event"
+	DeliveryHeader  = "X-Delivery-Id"
+)
+
+type delivery struct {
+	id       int64
+	guildId  int64
+	event    string
+	payload  string
+	attempts int
+	created  time.Time
+	url      string
+	secret   string
+}
+
+// what the endpoint receives
+type deliveryBody struct {
+	DeliveryId int64           `json:"id,string"`
+	GuildId    int64           `json:"guildId,string"`
+	Event      string          `json:"event"`
+	Data       json.RawMessage `json:"data"`
+	Created    time.Time       `json:"created"`
+}
+
+// what happens to a delivery after an attempt
+type result int
+
+const (
+	delivered result = iota
+	retry
+	dead
+)
+
+var errBlockedAddress = errors.New("subscription url resolves to an address that isnt allowed")
+
+// allowAddress is checked on every connection, after dns has been resolved so a host cant switch to an internal address after being validated
+var allowAddress = events.PublicAddress
+
+var dialer = &net.Dialer{
+	Timeout: 30 * time.Second,
+	Control: func(network string, address string, _ syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		if ip := net.ParseIP(host); ip == nil || !allowAddress(ip) {
+			return errBlockedAddress
+		}
+		return nil
+	},
+}
+
+// no proxy from the environment since the proxy would be the one connecting
+var client = &http.Client{Transport: &http.Transport{
+	DialContext:         dialer.DialContext,
+	MaxIdleConns:        100,
+	IdleConnTimeout:     90 * time.Second,
+	TLSHandshakeTimeout: 10 * time.Second,
+}}
+
+// worker delivers the events in conn with the settings in conf
+type worker struct {
+	db   *sql.DB
+	conf *config.Settings
+}
+
+// Start runs the delivery worker on conn until stop is called
+// stop waits for the batch being delivered until ctx is done, call it before closing conn
+func Start(conn *sql.DB, conf *config.Settings) (stop func(ctx context.Context)) {
+	w := &worker{db: conn, conf: conf}
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(conf.EventHooks.PollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-quit:
+				return
+			case <-ticker.C:
+				w.deliverBatch()
+			}
+		}
+	}()
+	return func(ctx context.Context) {
+		close(quit)
+		select {
+		case <-done:
+		case <-ctx.Done():
+			logger.Default.Warn("event deliveries still running, stopping anyway")
+		}
+	}
+}
+
+func (w *worker) deliverBatch() {
+	//claiming pushes next_attempt forward so another worker or the next tick wont pick the same rows
+	//if the server dies halfway through the rows just get retried after the lease runs out
+	lease := w.conf.EventHooks.Timeout * 2
+	rows, err := w.db.Query(`UPDATE event_deliveries d SET next_attempt = now() + $2 * interval '1 second', attempts = d.attempts + 1 
+	FROM event_subscriptions s 
+	WHERE s.id = d.subscription_id AND d.id IN (
+		SELECT id FROM event_deliveries WHERE dead = false AND next_attempt <= now() ORDER BY next_attempt LIMIT $1 FOR UPDATE SKIP LOCKED
+	) RETURNING d.id, d.guild_id, d.event, d.payload, d.attempts, d.created, s.url, s.secret`, w.conf.EventHooks.BatchSize, lease.Seconds())
+	if err != nil {
+		logger.Default.Error("unable to claim deliveries", "error", err)
+		return
+	}
+	deliveries := []delivery{}
+	for rows.Next() {
+		var d delivery
+		if err := rows.Scan(&d.id, &d.guildId, &d.event, &d.payload, &d.attempts, &d.created, &d.url, &d.secret); err != nil {
+			logger.Default.Error("unable to read delivery", "error", err)
+			continue
+		}
+		deliveries = append(deliveries, d)
+	}
+	rows.Close()
+
+	var wg sync.WaitGroup
+	for _, d := range deliveries {
+		wg.Add(1)
+		go func(d delivery) {
+			defer wg.Done()
+			status, err := w.send(d)
+			w.finish(d, status, err)
+		}(d)
+	}
+	wg.Wait()
+}
+
+func (w *worker) send(d delivery) (int, error) {
+	body, err := json.Marshal(deliveryBody{
+		DeliveryId: d.id,
+		GuildId:    d.guildId,
+		Event:      d.event,
+		Data:       json.RawMessage(d.payload),
+		Created:    d.created,
+	})
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	ctx, cancel := context.WithTimeout(context.Background(), w.conf.EventHooks.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, d.event)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(d.id, 10))
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, "sha256="+Sign(d.secret, timestamp, body))
+
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(res.Body, 4096)) //lets the connection get reused
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("endpoint responded with %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
+
+// Sign returns the hex hmac of timestamp.body
+// the timestamp is signed too so old deliveries cant be replayed
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// outcome decides if a delivery is done, tried again later or given up on
+func (w *worker) outcome(d delivery, sendErr error) result {
+	if sendErr == nil {
+		return delivered
+	}
+	if d.attempts >= w.conf.EventHooks.MaxAttempts {
+		return dead
+	}
+	return retry
+}
+
+func (w *worker) finish(d delivery, status int, sendErr error) {
+	var err error
+	switch w.outcome(d, sendErr) {
+	case delivered:
+		_, err = w.db.Exec("DELETE FROM event_deliveries WHERE id = $1", d.id)
+	case dead:
+		logger.Default.Warn("delivery failed, giving up", "id", d.id, "url", d.url, "attempt", d.attempts, "status", status, "error", sendErr)
+		_, err = w.db.Exec("UPDATE event_deliveries SET dead = true, last_status = $2, last_error = $3 WHERE id = $1", d.id, status, sendErr.Error())
+	case retry:
+		logger.Default.Warn("delivery failed", "id", d.id, "url", d.url, "attempt", d.attempts, "status", status, "error", sendErr)
+		_, err = w.db.Exec("UPDATE event_deliveries SET next_attempt = now() + $2 * interval '1 second', last_status = $3, last_error = $4 WHERE id = $1",
+			d.id, w.backoff(d.attempts).Seconds(), status, sendErr.Error())
+	}
+	if err != nil {
+		logger.Default.Error("unable to save delivery result", "id", d.id, "error", err)
+	}
+}
+
+func (w *worker) backoff(attempts int) time.Duration {
+	wait := w.conf.EventHooks.BaseBackoff
+	for i := 1; i < attempts; i++ {
+		wait *= 2
+		if wait >= w.conf.EventHooks.MaxBackoff {
+			return w.conf.EventHooks.MaxBackoff
+		}
+	}
+	return wait
+}
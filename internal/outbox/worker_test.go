@@ -0,0 +1,177 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/asianchinaboi/backendserver/internal/config"
+	"github.com/asianchinaboi/backendserver/internal/db"
+)
+
+// setup returns a worker without a db, only the ones that dont touch it can be used
+func setup(t *testing.T) *worker {
+	t.Helper()
+	conf, err := config.Load(config.Sources{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.EventHooks.MaxAttempts = 3
+	conf.EventHooks.BaseBackoff = time.Second
+	conf.EventHooks.MaxBackoff = 4 * time.Second
+	conf.EventHooks.Timeout = 5 * time.Second
+
+	//httptest only listens on loopback
+	allowAddress = func(ip net.IP) bool { return true }
+	t.Cleanup(func() { allowAddress = defaultAllowAddress })
+	return &worker{conf: conf}
+}
+
+var defaultAllowAddress = allowAddress
+
+// endpoint fails the first failures requests then accepts, checking the signature on every one
+func endpoint(t *testing.T, secret string, failures int32) (*httptest.Server, *int32) {
+	var requests int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		if got, want := r.Header.Get(SignatureHeader), "sha256="+Sign(secret, r.Header.Get(TimestampHeader), body); got != want {
+			t.Errorf("signature %q, want %q", got, want)
+		}
+		if r.Header.Get(EventHeader) != "MESSAGE_CREATE" {
+			t.Errorf("event header %q", r.Header.Get(EventHeader))
+		}
+		if n <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(s.Close)
+	return s, &requests
+}
+
+// attempt does what the worker does for one claimed delivery without the db
+func attempt(w *worker, d *delivery) (int, result) {
+	d.attempts++
+	status, err := w.send(*d)
+	return status, w.outcome(*d, err)
+}
+
+func TestDeliveryRetriesUntilDelivered(t *testing.T) {
+	w := setup(t)
+	s, requests := endpoint(t, "secret", 2)
+	d := &delivery{id: 1, guildId: 2, event: "MESSAGE_CREATE", payload: `{"content":"hi"}`, created: time.Now(), url: s.URL, secret: "secret"}
+
+	for i := 1; i <= 2; i++ {
+		status, res := attempt(w, d)
+		if status != http.StatusServiceUnavailable || res != retry {
+			t.Fatalf("attempt %d: got status %d result %d, want a retry", i, status, res)
+		}
+	}
+	status, res := attempt(w, d)
+	if status != http.StatusNoContent || res != delivered {
+		t.Fatalf("attempt 3: got status %d result %d, want delivered", status, res)
+	}
+	if n := atomic.LoadInt32(requests); n != 3 {
+		t.Fatalf("endpoint got %d requests, want 3", n)
+	}
+}
+
+func TestDeliveryDeadAfterMaxAttempts(t *testing.T) {
+	w := setup(t)
+	s, requests := endpoint(t, "secret", 100)
+	d := &delivery{id: 1, guildId: 2, event: "MESSAGE_CREATE", payload: `{}`, created: time.Now(), url: s.URL, secret: "secret"}
+
+	results := []result{}
+	for i := 0; i < w.conf.EventHooks.MaxAttempts; i++ {
+		_, res := attempt(w, d)
+		results = append(results, res)
+	}
+	want := []result{retry, retry, dead}
+	for i := range want {
+		if results[i] != want[i] {
+			t.Fatalf("results %v, want %v", results, want)
+		}
+	}
+	if n := atomic.LoadInt32(requests); n != 3 {
+		t.Fatalf("endpoint got %d requests, want 3", n)
+	}
+}
+
+func TestDeliveryUnreachableRetries(t *testing.T) {
+	w := setup(t)
+	s, _ := endpoint(t, "secret", 0)
+	url := s.URL
+	s.Close()
+	d := &delivery{id: 1, event: "MESSAGE_CREATE", payload: `{}`, url: url, secret: "secret"}
+	if status, res := attempt(w, d); status != 0 || res != retry {
+		t.Fatalf("got status %d result %d, want a retry with no status", status, res)
+	}
+}
+
+func TestDialerBlocksInternalAddresses(t *testing.T) {
+	w := setup(t)
+	allowAddress = defaultAllowAddress
+	s, requests := endpoint(t, "secret", 0)
+	d := delivery{id: 1, event: "MESSAGE_CREATE", payload: `{}`, url: s.URL, secret: "secret"}
+	if _, err := w.send(d); !errors.Is(err, errBlockedAddress) {
+		t.Fatalf("got %v, want %v", err, errBlockedAddress)
+	}
+	if n := atomic.LoadInt32(requests); n != 0 {
+		t.Fatalf("endpoint got %d requests, want none", n)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	w := setup(t)
+	for attempts, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 10: 4 * time.Second} {
+		if got := w.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+func TestSign(t *testing.T) {
+	a := Sign("secret", "1", []byte("body"))
+	if a != Sign("secret", "1", []byte("body")) {
+		t.Fatal("signature isnt stable")
+	}
+	if a == Sign("secret", "2", []byte("body")) {
+		t.Fatal("timestamp isnt signed")
+	}
+	if a == Sign("other", "1", []byte("body")) {
+		t.Fatal("secret isnt used")
+	}
+}
+
+func TestStop(t *testing.T) {
+	conf := setup(t).conf
+	conf.EventHooks.PollInterval = time.Millisecond
+	//nothing listens on the db port so every poll fails straight away
+	conf.Server.DatabaseConfig.Host = "127.0.0.1"
+	conf.Server.DatabaseConfig.Port = 1
+	conn, err := db.Open(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	stop := Start(conn, conf)
+	time.Sleep(20 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stop(ctx) //only returns before the timeout once the worker goroutine has returned
+	if ctx.Err() != nil {
+		t.Fatal("worker didnt stop")
+	}
+}
@@ -0,0 +1,88 @@
+package quota
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/asianchinaboi/backendserver/internal/config"
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/asianchinaboi/backendserver/internal/events"
+)
+
+// querier is either the database or a transaction, transactions see their own files before theyre committed
+type querier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// User works out how much the user has uploaded, every file they uploaded counts even in dms
+func User(ctx context.Context, q querier, conf *config.Settings, userId int64) (events.StorageUsage, error) {
+	var usage events.StorageUsage
+	var override sql.NullInt64
+	if err := q.QueryRowContext(ctx, `SELECT COALESCE(SUM(f.filesize), 0), COUNT(f.id), u.storage_quota 
+	FROM users u LEFT JOIN files f ON f.uploader_id = u.id 
+	WHERE u.id = $1 GROUP BY u.id`, userId).Scan(&usage.Used, &usage.Files, &override); err != nil && err != sql.ErrNoRows {
+		return usage, err
+	} else if err == sql.ErrNoRows {
+		return usage, errors.ErrUserNotFound
+	}
+	usage.Quota = conf.User.StorageQuota
+	if override.Valid {
+		usage.Quota = override.Int64
+	}
+	return usage, nil
+}
+
+// Guild works out how much is stored in the guilds messages
+// files from guilds with chat saving off are temporary so they dont count
+func Guild(ctx context.Context, q querier, conf *config.Settings, guildId int64) (events.StorageUsage, error) {
+	var usage events.StorageUsage
+	var override sql.NullInt64
+	if err := q.QueryRowContext(ctx, `SELECT COALESCE(SUM(f.filesize), 0), COUNT(f.id), g.storage_quota 
+	FROM guilds g LEFT JOIN msgs m ON m.guild_id = g.id LEFT JOIN files f ON f.msg_id = m.id 
+	WHERE g.id = $1 GROUP BY g.id`, guildId).Scan(&usage.Used, &usage.Files, &override); err != nil && err != sql.ErrNoRows {
+		return usage, err
+	} else if err == sql.ErrNoRows {
+		return usage, errors.ErrGuildNotExist
+	}
+	usage.Quota = conf.Guild.StorageQuota
+	if override.Valid {
+		usage.Quota = override.Int64
+	}
+	return usage, nil
+}
+
+// Exceeds reports if adding more bytes would go over the quota, a quota of 0 is unlimited
+func Exceeds(usage events.StorageUsage, adding int64) bool {
+	return usage.Quota > 0 && usage.Used+adding > usage.Quota
+}
+
+// Check makes sure the uploader and guild are still within their quotas
+// call it after the files are written so theyre counted, guildId can be 0 for files not in a guild yet
+func Check(ctx context.Context, tx *sql.Tx, conf *config.Settings, userId int64, guildId int64) error {
+	//locks the rows so two uploads at once cant both squeeze under the quota
+	//no key update so msgs and files can still reference them
+	if _, err := tx.ExecContext(ctx, "SELECT 1 FROM users WHERE id = $1 FOR NO KEY UPDATE", userId); err != nil {
+		return err
+	}
+	usage, err := User(ctx, tx, conf, userId)
+	if err != nil {
+		return err
+	}
+	if Exceeds(usage, 0) {
+		return errors.ErrQuotaUserExceeded
+	}
+	if guildId == 0 {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, "SELECT 1 FROM guilds WHERE id = $1 FOR NO KEY UPDATE", guildId); err != nil {
+		return err
+	}
+	usage, err = Guild(ctx, tx, conf, guildId)
+	if err != nil {
+		return err
+	}
+	if Exceeds(usage, 0) {
+		return errors.ErrQuotaGuildExceeded
+	}
+	return nil
+}
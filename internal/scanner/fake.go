@@ -0,0 +1,43 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sort"
+)
+
+// standard antivirus test file, harmless but every scanner flags it
+const Eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// Fake flags anything containing one of its signatures, used for tests
+type Fake struct {
+	Signatures map[string]string //content to look for and the name to report
+	Err        error             //returned instead of scanning to act like the scanner is down
+}
+
+func NewFake() *Fake {
+	return &Fake{Signatures: map[string]string{Eicar: "Eicar-Test-Signature"}}
+}
+
+func (s *Fake) Scan(ctx context.Context, r io.Reader) (Result, error) {
+	if s.Err != nil {
+		return Result{}, s.Err
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Result{}, err
+	}
+	//sorted so the same file always reports the same signature
+	contents := make([]string, 0, len(s.Signatures))
+	for content := range s.Signatures {
+		contents = append(contents, content)
+	}
+	sort.Strings(contents)
+	for _, content := range contents {
+		if bytes.Contains(data, []byte(content)) {
+			return Result{Infected: true, Signature: s.Signatures[content]}, nil
+		}
+	}
+	return Result{}, nil
+}
@@ -0,0 +1,106 @@
+package scanner
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/asianchinaboi/backendserver/internal/config"
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/asianchinaboi/backendserver/internal/logger"
+)
+
+// what to do with a file when the scanner cant be reached
+const (
+	PolicyAllow      = "allow"      //store it like normal
+	PolicyQuarantine = "quarantine" //store it but hide it until an admin releases it
+	PolicyReject     = "reject"     //refuse the upload
+)
+
+// reason stored for files that were quarantined without being scanned
+const ReasonUnscanned = "unscanned"
+
+// Result is what a scanner found in a file
+type Result struct {
+	Infected  bool
+	Signature string //name of what was found
+}
+
+type Scanner interface {
+	Scan(ctx context.Context, r io.Reader) (Result, error)
+}
+
+// Verdict is what should happen to a file once its been scanned
+type Verdict struct {
+	Quarantined bool
+	Reason      string //signature found or unscanned
+}
+
+func ValidPolicy(policy string) bool {
+	return policy == PolicyAllow || policy == PolicyQuarantine || policy == PolicyReject
+}
+
+// Check scans the file with s and applies the policy if the scanner is down, s is nil when scanning is turned off
+// the reader is rewound afterwards so it can be stored
+func Check(ctx context.Context, s Scanner, r io.ReadSeeker, policy string) (Verdict, error) {
+	if s == nil {
+		return Verdict{}, nil
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return Verdict{}, err
+	}
+	start := time.Now()
+	result, err := s.Scan(ctx, r)
+	if _, seekErr := r.Seek(0, io.SeekStart); seekErr != nil {
+		return Verdict{}, seekErr
+	}
+	if err != nil {
+		logger.Default.Warn("scanner unavailable", "after", time.Since(start), "error", err, "policy", policy)
+		switch policy {
+		case PolicyQuarantine:
+			return Verdict{Quarantined: true, Reason: ReasonUnscanned}, nil
+		case PolicyReject:
+			return Verdict{}, errors.ErrScannerUnavailable
+		default:
+			return Verdict{}, nil
+		}
+	}
+	if result.Infected {
+		logger.Default.Info("quarantining file", "signature", result.Signature)
+		return Verdict{Quarantined: true, Reason: result.Signature}, nil
+	}
+	return Verdict{}, nil
+}
+
+// New creates the scanner picked in conf, nil when scanning is turned off
+func New(conf *config.Settings) (Scanner, error) {
+	switch conf.Scanner.Backend {
+	case "", "none":
+		return nil, nil
+	case "clamd":
+		return WithTimeout(NewClamd(conf.Scanner.Network, conf.Scanner.Address), conf.Scanner.Timeout), nil
+	case "fake":
+		return WithTimeout(NewFake(), conf.Scanner.Timeout), nil
+	default:
+		return nil, errors.ErrScannerBackendNotExist
+	}
+}
+
+type timeoutScanner struct {
+	Scanner
+	timeout time.Duration
+}
+
+// WithTimeout gives up on scans that take longer than timeout, 0 waits as long as the context does
+func WithTimeout(s Scanner, timeout time.Duration) Scanner {
+	if timeout <= 0 {
+		return s
+	}
+	return timeoutScanner{Scanner: s, timeout: timeout}
+}
+
+func (s timeoutScanner) Scan(ctx context.Context, r io.Reader) (Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	return s.Scanner.Scan(ctx, r)
+}
@@ -0,0 +1,100 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asianchinaboi/backendserver/internal/errors"
+)
+
+func TestCheckPolicyWhenUnavailable(t *testing.T) {
+	down := &Fake{Err: fmt.Errorf("connection refused")}
+	tests := []struct {
+		policy  string
+		verdict Verdict
+		err     error
+	}{
+		{PolicyAllow, Verdict{}, nil},
+		{PolicyQuarantine, Verdict{Quarantined: true, Reason: ReasonUnscanned}, nil},
+		{PolicyReject, Verdict{}, errors.ErrScannerUnavailable},
+	}
+	for _, test := range tests {
+		t.Run(test.policy, func(t *testing.T) {
+			verdict, err := Check(context.Background(), down, strings.NewReader("hello"), test.policy)
+			if err != test.err {
+				t.Fatalf("got error %v, want %v", err, test.err)
+			}
+			if verdict != test.verdict {
+				t.Errorf("got %+v, want %+v", verdict, test.verdict)
+			}
+		})
+	}
+}
+
+func TestCheckScanned(t *testing.T) {
+	//the policy only matters when the scanner is down
+	for _, policy := range []string{PolicyAllow, PolicyQuarantine, PolicyReject} {
+		t.Run(policy, func(t *testing.T) {
+			verdict, err := Check(context.Background(), NewFake(), strings.NewReader("prefix "+Eicar), policy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := (Verdict{Quarantined: true, Reason: "Eicar-Test-Signature"}); verdict != want {
+				t.Errorf("infected: got %+v, want %+v", verdict, want)
+			}
+			verdict, err = Check(context.Background(), NewFake(), strings.NewReader("clean file"), policy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if verdict != (Verdict{}) {
+				t.Errorf("clean: got %+v, want nothing", verdict)
+			}
+		})
+	}
+}
+
+func TestCheckRewinds(t *testing.T) {
+	r := bytes.NewReader([]byte("some file"))
+	r.Seek(4, io.SeekStart) //partly read already
+	if _, err := Check(context.Background(), NewFake(), r, PolicyReject); err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "some file" {
+		t.Errorf("got %q after checking, want the whole file", data)
+	}
+}
+
+func TestCheckDisabled(t *testing.T) {
+	verdict, err := Check(context.Background(), nil, strings.NewReader(Eicar), PolicyReject)
+	if err != nil || verdict != (Verdict{}) {
+		t.Errorf("got %+v, %v with scanning turned off", verdict, err)
+	}
+}
+
+// blocking never answers until the context is done, like clamd hanging
+type blocking struct{}
+
+func (blocking) Scan(ctx context.Context, r io.Reader) (Result, error) {
+	<-ctx.Done()
+	return Result{}, ctx.Err()
+}
+
+func TestCheckTimeout(t *testing.T) {
+	start := time.Now()
+	_, err := Check(context.Background(), WithTimeout(blocking{}, 50*time.Millisecond), strings.NewReader("hello"), PolicyReject)
+	if err != errors.ErrScannerUnavailable {
+		t.Fatalf("got %v, want %v", err, errors.ErrScannerUnavailable)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("took %v, the scanner timeout wasnt used", elapsed)
+	}
+}
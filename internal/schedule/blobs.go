@@ -0,0 +1,58 @@
+package schedule
+
+import (
+	"context"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/asianchinaboi/backendserver/internal/blobs"
+	"github.com/asianchinaboi/backendserver/internal/logger"
+)
+
+// blobs normally go when their last files row is deleted
+// this catches rows removed by cascades and blobs left behind by failed uploads
+func (r *runner) deleteBlobs() error {
+	logger.Default.Info("deleting unreferenced blobs")
+	rows, err := r.db.Query("SELECT hash FROM blobs b WHERE NOT EXISTS (SELECT 1 FROM files f WHERE f.hash = b.hash)")
+	if err != nil {
+		return err
+	}
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			logger.Default.Error("unable to read blob", "error", err)
+			continue
+		}
+		hashes = append(hashes, hash)
+	}
+	rows.Close()
+
+	ctx := context.Background()
+	for _, hash := range hashes {
+		if err := blobs.Release(ctx, r.db, r.store, hash); err != nil {
+			logger.Default.Warn("unable to remove blob", "hash", hash, "error", err)
+		}
+	}
+
+	//stored but the transaction that would have added the row never committed, or the row went and deleting the content failed
+	objects, err := r.store.List(ctx, "blobs/")
+	if err != nil {
+		return err
+	}
+	for _, object := range objects {
+		if time.Since(object.Modified) < time.Hour { //could still be getting stored
+			continue
+		}
+		hash := strings.TrimSuffix(path.Base(object.Key), ".lz4")
+		if !blobs.ValidHash(hash) { //not something we stored, leave it alone
+			logger.Default.Warn("skipping unknown object in blobs", "key", object.Key)
+			continue
+		}
+		if err := blobs.RemoveOrphan(ctx, r.db, r.store, hash); err != nil {
+			logger.Default.Warn("unable to remove blob", "hash", hash, "error", err)
+		}
+	}
+	return nil
+}
@@ -1,32 +1,32 @@
 package schedule
 
 import (
-	"fmt"
-	"os"
+	"context"
+	"database/sql"
 	"time"
 
-	"github.com/asianchinaboi/backendserver/internal/config"
-	"github.com/asianchinaboi/backendserver/internal/db"
+	"github.com/asianchinaboi/backendserver/internal/blobs"
 	"github.com/asianchinaboi/backendserver/internal/logger"
 )
 
-func deleteTempFile() {
-	logger.Info.Println("Deleting temp files")
-	fileRows, err := db.Db.Query("DELETE FROM files WHERE temp = true AND created < $1 RETURNING id, entity_type", time.Now().Add(-config.Config.Server.TempFileAlive))
+func (r *runner) deleteTempFile() error {
+	logger.Default.Info("deleting temp files")
+	fileRows, err := r.db.Query("DELETE FROM files WHERE temp = true AND created < $1 RETURNING id, entity_type, hash", time.Now().Add(-r.conf.Server.TempFileAlive))
 	if err != nil {
-		logger.Error.Println(err)
-		return
+		return err
 	}
 	defer fileRows.Close()
 	for fileRows.Next() {
 		var fileId int64
 		var entityType string
-		if err := fileRows.Scan(&fileId, &entityType); err != nil {
-			logger.Error.Println(err)
+		var hash sql.NullString
+		if err := fileRows.Scan(&fileId, &entityType, &hash); err != nil {
+			logger.Default.Error("unable to read temp file", "error", err)
 			continue
 		}
-		if err := os.Remove(fmt.Sprintf("uploads/%s/%d.lz4", entityType, fileId)); err != nil {
-			logger.Warn.Printf("unable to remove file: %v\n", err)
+		if err := blobs.Remove(context.Background(), r.db, r.store, entityType, fileId, hash); err != nil {
+			logger.Default.Warn("unable to remove file", "id", fileId, "error", err)
 		}
 	}
+	return nil
 }
@@ -0,0 +1,7 @@
+package schedule
+
+// removes rate limit buckets that have filled back up since theyre the same as not having one
+func (r *runner) deleteRateLimits() error {
+	_, err := r.db.Exec("DELETE FROM rate_limits WHERE expires < now()")
+	return err
+}
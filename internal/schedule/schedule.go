@@ -1,14 +1,81 @@
 package schedule
 
 import (
+	"context"
+	"database/sql"
 	"time"
 
+	"github.com/asianchinaboi/backendserver/internal/config"
+	"github.com/asianchinaboi/backendserver/internal/logger"
+	"github.com/asianchinaboi/backendserver/internal/metrics"
+	"github.com/asianchinaboi/backendserver/internal/storage"
+	"github.com/asianchinaboi/backendserver/internal/wsclient"
 	"github.com/go-co-op/gocron"
 )
 
-func Start() {
+// Scheduler runs the jobs for one app
+type Scheduler struct {
+	s *gocron.Scheduler
+}
+
+// runner has what the jobs need to clean up after the routes
+type runner struct {
+	db    *sql.DB
+	store storage.Backend
+	conf  *config.Settings
+	pools *wsclient.ClientPools //transcode jobs tell guilds when theyre done
+}
+
+func Start(conn *sql.DB, store storage.Backend, conf *config.Settings, pools *wsclient.ClientPools) *Scheduler {
+	r := &runner{db: conn, store: store, conf: conf, pools: pools}
 	s := gocron.NewScheduler(time.UTC)
-	s.Every(1).Day().At("00:00").Do(deleteTempFile)
-	s.Every(1).Day().At("00:00").Do(deleteTokens)
+	s.Every(1).Day().At("00:00").Do(job("deleteTempFile", r.deleteTempFile))
+	s.Every(1).Day().At("00:00").Do(job("deleteTokens", r.deleteTokens))
+	s.Every(1).Day().At("00:00").Do(job("deleteWebhookUsers", r.deleteWebhookUsers))
+	s.Every(1).Hour().Do(job("deleteUploads", r.deleteUploads))
+	s.Every(1).Day().At("00:00").Do(job("deleteBlobs", r.deleteBlobs))
+	if conf.RateLimit.Backend == "postgres" {
+		s.Every(1).Minute().Do(job("deleteRateLimits", r.deleteRateLimits))
+	}
+	if conf.Transcode.Enabled {
+		s.Every(conf.Transcode.PollInterval).SingletonMode().Do(job("transcodeVideos", r.transcodeVideos)) //singleton so a long job doesnt get started twice
+	}
 	s.StartAsync()
+	return &Scheduler{s: s}
+}
+
+// Running is used by the readiness check
+func (s *Scheduler) Running() bool {
+	return s != nil && s.s.IsRunning()
+}
+
+// Stop stops new jobs from starting and waits for running ones until ctx is done
+func (s *Scheduler) Stop(ctx context.Context) {
+	if s == nil {
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		s.s.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		logger.Default.Warn("scheduled jobs still running, stopping anyway")
+	}
+}
+
+// job logs the error of a job and records how it went for the metrics
+func job(name string, run func() error) func() {
+	return func() {
+		start := time.Now()
+		result := "success"
+		if err := run(); err != nil {
+			logger.Default.Error("scheduled job failed", "job", name, "error", err)
+			result = "failure"
+		}
+		metrics.SchedulerJobs.Inc(name, result)
+		metrics.SchedulerJobDuration.Observe(time.Since(start).Seconds(), name)
+	}
 }
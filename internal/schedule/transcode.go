@@ -0,0 +1,243 @@
+package schedule
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/asianchinaboi/backendserver/internal/events"
+	"github.com/asianchinaboi/backendserver/internal/files"
+	"github.com/asianchinaboi/backendserver/internal/logger"
+	"github.com/asianchinaboi/backendserver/internal/outbox"
+	"github.com/asianchinaboi/backendserver/internal/storage"
+	"github.com/asianchinaboi/backendserver/internal/transcode"
+	"github.com/asianchinaboi/backendserver/internal/wsclient"
+)
+
+type transcodeJob struct {
+	hash        string
+	contentType string
+	attempts    int
+}
+
+// picks up transcode jobs, claimed rows are pushed past the timeout
+// so if the server dies halfway through they get picked up again after a restart
+func (r *runner) transcodeVideos() error {
+	lease := r.conf.Transcode.Timeout + time.Minute
+	ctx := context.Background()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //rollback changes if failed
+	rows, err := tx.QueryContext(ctx, `UPDATE transcodes SET status = 'processing', attempts = attempts + 1, next_attempt = now() + $2 * interval '1 second', updated = now() 
+	WHERE hash IN (
+		SELECT hash FROM transcodes WHERE status IN ('pending', 'processing') AND next_attempt <= now() ORDER BY next_attempt LIMIT $1 FOR UPDATE SKIP LOCKED
+	) RETURNING hash, content_type, attempts`, r.conf.Transcode.BatchSize, lease.Seconds())
+	if err != nil {
+		return err
+	}
+	jobs := []transcodeJob{}
+	for rows.Next() {
+		var job transcodeJob
+		if err := rows.Scan(&job.hash, &job.contentType, &job.attempts); err != nil {
+			logger.Default.Error("unable to read transcode job", "error", err)
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	rows.Close()
+
+	updates := []events.AttachmentUpdate{}
+	for _, job := range jobs {
+		jobUpdates, err := queueTranscodeUpdates(ctx, tx, job.hash, transcode.StatusProcessing)
+		if err != nil {
+			return err
+		}
+		updates = append(updates, jobUpdates...)
+	}
+	if err := tx.Commit(); err != nil { //commits the transaction
+		return err
+	}
+	r.notifyTranscode(updates)
+
+	//one at a time since ffmpeg already uses every core
+	for _, job := range jobs {
+		err := r.runTranscode(job)
+		r.finishTranscode(job, err)
+	}
+	return nil
+}
+
+func (r *runner) runTranscode(job transcodeJob) error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.conf.Transcode.Timeout)
+	defer cancel()
+
+	var size int64
+	if err := r.db.QueryRowContext(ctx, "SELECT size FROM blobs WHERE hash = $1", job.hash).Scan(&size); err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "transcode-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	//ffmpeg needs to seek around so the source gets written out first
+	source, err := os.Create(filepath.Join(dir, "source"))
+	if err != nil {
+		return err
+	}
+	content, err := files.Open(ctx, r.store, storage.BlobKey(job.hash), size)
+	if err != nil {
+		source.Close()
+		return err
+	}
+	_, err = io.Copy(source, content)
+	content.Close()
+	source.Close()
+	if err != nil {
+		return err
+	}
+
+	output := filepath.Join(dir, "output")
+	if err := os.Mkdir(output, 0700); err != nil {
+		return err
+	}
+	if err := transcode.Run(ctx, r.conf, source.Name(), output, transcode.IsVideo(job.contentType)); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(output)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !transcode.ValidName(entry.Name()) {
+			continue
+		}
+		if err := r.putTranscoded(ctx, job.hash, filepath.Join(output, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *runner) putTranscoded(ctx context.Context, hash string, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	//stored as they are, video is already compressed
+	return r.store.Put(ctx, transcode.Key(hash, filepath.Base(path)), file, info.Size())
+}
+
+func (r *runner) finishTranscode(job transcodeJob, transcodeErr error) {
+	ctx := context.Background()
+	if transcodeErr == nil {
+		if found, err := r.setTranscodeStatus(ctx, job.hash, transcode.StatusReady, nil); err != nil {
+			logger.Default.Error("unable to save transcode status", "hash", job.hash, "error", err)
+		} else if !found { //blob was released while transcoding
+			if err := storage.DeletePrefix(ctx, r.store, transcode.Prefix(job.hash)); err != nil {
+				logger.Default.Warn("unable to remove transcode", "hash", job.hash, "error", err)
+			}
+		}
+		return
+	}
+
+	logger.Default.Warn("transcode failed", "hash", job.hash, "attempt", job.attempts, "error", transcodeErr)
+	if err := storage.DeletePrefix(ctx, r.store, transcode.Prefix(job.hash)); err != nil {
+		logger.Default.Warn("unable to remove transcode", "hash", job.hash, "error", err)
+	}
+	if job.attempts >= r.conf.Transcode.MaxAttempts {
+		if _, err := r.setTranscodeStatus(ctx, job.hash, transcode.StatusFailed, transcodeErr.Error()); err != nil {
+			logger.Default.Error("unable to save transcode status", "hash", job.hash, "error", err)
+		}
+		return
+	}
+	//tries again later, waiting longer each time
+	wait := time.Duration(job.attempts) * time.Minute
+	if _, err := r.db.Exec("UPDATE transcodes SET status = 'pending', next_attempt = now() + $2 * interval '1 second', last_error = $3, updated = now() WHERE hash = $1",
+		job.hash, wait.Seconds(), transcodeErr.Error()); err != nil {
+		logger.Default.Error("unable to retry transcode", "hash", job.hash, "error", err)
+	}
+}
+
+// setTranscodeStatus saves a finished status along with the updates for subscriptions, false if the blob was released
+func (r *runner) setTranscodeStatus(ctx context.Context, hash string, status string, lastError interface{}) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback() //rollback changes if failed
+
+	result, err := tx.ExecContext(ctx, "UPDATE transcodes SET status = $2, last_error = $3, updated = now() WHERE hash = $1", hash, status, lastError)
+	if err != nil {
+		return false, err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return false, err
+	} else if affected == 0 {
+		return false, nil
+	}
+	updates, err := queueTranscodeUpdates(ctx, tx, hash, status)
+	if err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil { //commits the transaction
+		return false, err
+	}
+	r.notifyTranscode(updates)
+	return true, nil
+}
+
+// queueTranscodeUpdates finds every attachment using the blob and queues the update for subscriptions in tx
+// the same updates go to the guilds once tx is committed
+func queueTranscodeUpdates(ctx context.Context, tx *sql.Tx, hash string, status string) ([]events.AttachmentUpdate, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT f.id, COALESCE(f.msg_id, 0), COALESCE(m.guild_id, f.msg_guild_id) 
+	FROM files f LEFT JOIN msgs m ON m.id = f.msg_id 
+	WHERE f.hash = $1 AND f.entity_type = 'msg' AND f.quarantined = false AND COALESCE(m.guild_id, f.msg_guild_id) IS NOT NULL`, hash)
+	if err != nil {
+		return nil, err
+	}
+	updates := []events.AttachmentUpdate{}
+	for rows.Next() {
+		update := events.AttachmentUpdate{Transcode: status}
+		if err := rows.Scan(&update.Id, &update.MsgId, &update.GuildId); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		updates = append(updates, update)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, update := range updates {
+		if err := outbox.Enqueue(ctx, tx, update.GuildId, events.ATTACHMENT_UPDATE, update); err != nil {
+			return nil, err
+		}
+	}
+	return updates, nil
+}
+
+// tells every guild the blob was sent in how the transcode is going
+func (r *runner) notifyTranscode(updates []events.AttachmentUpdate) {
+	for _, update := range updates {
+		r.pools.BroadcastGuild(update.GuildId, wsclient.DataFrame{
+			Op:    wsclient.TYPE_DISPATCH,
+			Data:  update,
+			Event: events.ATTACHMENT_UPDATE,
+		})
+	}
+}
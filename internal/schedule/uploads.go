@@ -0,0 +1,46 @@
+package schedule
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asianchinaboi/backendserver/internal/logger"
+)
+
+// removes chunked uploads that stopped receiving chunks and any chunks left without an upload
+func (r *runner) deleteUploads() error {
+	if _, err := r.db.Exec("DELETE FROM uploads WHERE updated < $1", time.Now().Add(-r.conf.Server.UploadSessionAlive)); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	objects, err := r.store.List(ctx, "chunks/")
+	if err != nil {
+		return err
+	}
+	checked := make(map[int64]bool) //upload id to whether the upload still exists
+	for _, object := range objects {
+		parts := strings.SplitN(strings.TrimPrefix(object.Key, "chunks/"), "/", 2)
+		uploadId, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			logger.Default.Warn("skipping unknown chunk", "key", object.Key)
+			continue
+		}
+		exists, ok := checked[uploadId]
+		if !ok {
+			if err := r.db.QueryRow("SELECT EXISTS (SELECT 1 FROM uploads WHERE id = $1)", uploadId).Scan(&exists); err != nil {
+				return err
+			}
+			checked[uploadId] = exists
+		}
+		if exists {
+			continue
+		}
+		if err := r.store.Delete(ctx, object.Key); err != nil {
+			logger.Default.Warn("unable to remove chunk", "key", object.Key, "error", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package schedule
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/asianchinaboi/backendserver/internal/blobs"
+	"github.com/asianchinaboi/backendserver/internal/events"
+	"github.com/asianchinaboi/backendserver/internal/logger"
+)
+
+// webhook users are kept after the webhook is deleted so their messages still have an author
+// once all of their messages are gone they can be removed too
+func (r *runner) deleteWebhookUsers() error {
+	ctx := context.Background()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	orphaned := `SELECT u.id FROM users u WHERE u.flags & $1 != 0 
+	AND NOT EXISTS (SELECT 1 FROM webhooks w WHERE w.user_id = u.id) 
+	AND NOT EXISTS (SELECT 1 FROM msgs m WHERE m.user_id = u.id)`
+
+	fileRows, err := tx.QueryContext(ctx, "DELETE FROM files WHERE entity_type = 'user' AND user_id IN ("+orphaned+") RETURNING id, hash", events.FLwebhook)
+	if err != nil {
+		return err
+	}
+	var fileIds []int64
+	var hashes []sql.NullString
+	for fileRows.Next() {
+		var fileId int64
+		var hash sql.NullString
+		if err := fileRows.Scan(&fileId, &hash); err != nil {
+			logger.Default.Error("unable to read webhook file", "error", err)
+			continue
+		}
+		fileIds = append(fileIds, fileId)
+		hashes = append(hashes, hash)
+	}
+	fileRows.Close()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM users WHERE id IN ("+orphaned+")", events.FLwebhook); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	for i, fileId := range fileIds {
+		if err := blobs.Remove(context.Background(), r.db, r.store, "user", fileId, hashes[i]); err != nil {
+			logger.Default.Warn("unable to remove file", "id", fileId, "error", err)
+		}
+	}
+	return nil
+}
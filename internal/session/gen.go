@@ -5,25 +5,29 @@ import (
 	"database/sql"
 	"encoding/hex"
 	"math/rand"
+	"strings"
+	"sync"
 	"time"
 
-	"github.com/asianchinaboi/backendserver/internal/config"
-	"github.com/asianchinaboi/backendserver/internal/db"
 	"github.com/asianchinaboi/backendserver/internal/errors"
-	"github.com/asianchinaboi/backendserver/internal/logger"
 )
 
 const (
 	tokenLength = 32
+	BotPrefix   = "Bot "
 )
 
 var (
 	characters = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ1234567890")
+	random     = struct { //rand.Rand isnt safe to share between goroutines
+		sync.Mutex
+		*rand.Rand
+	}{Rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
 )
 
-func CheckToken(token string) (*Session, error) {
+func CheckToken(conn *sql.DB, token string) (*Session, error) {
 	var user Session
-	err := db.Db.QueryRow("SELECT user_id, token_expires FROM tokens WHERE token=$1", token).Scan(&user.Id, &user.Expires)
+	err := conn.QueryRow("SELECT user_id, token_expires FROM tokens WHERE token=$1", token).Scan(&user.Id, &user.Expires)
 	if err != nil && err == sql.ErrNoRows {
 		return nil, errors.ErrInvalidToken
 	} else if err != nil {
@@ -31,7 +35,7 @@ func CheckToken(token string) (*Session, error) {
 	}
 
 	if time.Now().Unix() > user.Expires {
-		db.Db.Exec("DELETE FROM tokens WHERE token=$1", token)
+		conn.Exec("DELETE FROM tokens WHERE token=$1", token)
 		return nil, errors.ErrExpiredToken
 	}
 
@@ -41,7 +45,7 @@ func CheckToken(token string) (*Session, error) {
 	//e.g if admin is enabled and the user had multiple roles
 	//then admin is enabled for the user even if other roles dont mention admin
 
-	rows, err := db.Db.Query(`SELECT DISTINCT r.permission_id FROM userroles u INNER JOIN rolepermissions r ON u.role_id = r.role_id WHERE user_id = $1 `, user.Id)
+	rows, err := conn.Query(`SELECT DISTINCT r.permission_id FROM userroles u INNER JOIN rolepermissions r ON u.role_id = r.role_id WHERE user_id = $1 `, user.Id)
 	if err != nil {
 		return nil, err
 	}
@@ -60,25 +64,55 @@ func CheckToken(token string) (*Session, error) {
 	return &user, nil
 }
 
-func GenToken(id int64) (Session, error) {
+// CheckAuthorization accepts either a user token or "Bot <token>"
+func CheckAuthorization(conn *sql.DB, header string) (*Session, error) {
+	if strings.HasPrefix(header, BotPrefix) {
+		return CheckBotToken(conn, strings.TrimPrefix(header, BotPrefix))
+	}
+	return CheckToken(conn, header)
+}
+
+func CheckBotToken(conn *sql.DB, token string) (*Session, error) {
+	bot := Session{Bot: true, Perms: &Permissions{}}
+	err := conn.QueryRow("SELECT user_id FROM bots WHERE token=$1", token).Scan(&bot.Id)
+	if err != nil && err == sql.ErrNoRows {
+		return nil, errors.ErrInvalidToken
+	} else if err != nil {
+		return nil, err
+	}
+	return &bot, nil
+}
+
+func GenBotToken() (string, error) {
+	return generateSecureToken(tokenLength)
+}
+
+func GenWebhookToken() (string, error) {
+	return generateSecureToken(tokenLength)
+}
+
+func GenSubscriptionSecret() (string, error) {
+	return generateSecureToken(tokenLength)
+}
+
+func GenToken(conn *sql.DB, expireTime time.Duration, id int64) (Session, error) {
 	var authData Session
 	//delete token if expired
-	_, err := db.Db.Exec("DELETE FROM tokens WHERE user_id=$1 AND token_expires < $2", id, time.Now().Unix())
+	_, err := conn.Exec("DELETE FROM tokens WHERE user_id=$1 AND token_expires < $2", id, time.Now().Unix())
 	if err != nil {
 		return Session{}, err
 	}
-	err = db.Db.QueryRow("SELECT token, token_expires FROM tokens WHERE user_id=$1", id).Scan(&authData.Token, &authData.Expires)
+	err = conn.QueryRow("SELECT token, token_expires FROM tokens WHERE user_id=$1", id).Scan(&authData.Token, &authData.Expires)
 	if err != nil && err != sql.ErrNoRows {
 		return authData, err
 	} else if err == sql.ErrNoRows {
 		authToken, err := generateSecureToken(tokenLength)
-		logger.Debug.Println(authToken)
 		if err != nil {
 			return Session{}, err
 		}
-		authExpires := time.Now().Add(config.Config.User.TokenExpireTime).Unix()
+		authExpires := time.Now().Add(expireTime).Unix()
 		authData = Session{Id: id, Expires: authExpires, Token: authToken}
-		_, err = db.Db.Exec("INSERT INTO tokens (user_id, token, token_expires) VALUES ($1, $2, $3)", id, authToken, authExpires)
+		_, err = conn.Exec("INSERT INTO tokens (user_id, token, token_expires) VALUES ($1, $2, $3)", id, authToken, authExpires)
 		if err != nil {
 			return Session{}, err
 		}
@@ -99,12 +133,10 @@ func generateSecureToken(l int) (string, error) { //also copied from stackoverfl
 
 func GenerateRandString(l int) string { //copied from stackoverflow (insecure and shit dont use for token)
 	b := make([]rune, l)
+	random.Lock()
+	defer random.Unlock()
 	for i := range b {
-		b[i] = characters[rand.Intn(len(characters))]
+		b[i] = characters[random.Intn(len(characters))]
 	}
 	return string(b)
 }
-
-func init() {
-	rand.Seed(time.Now().UnixNano())
-}
@@ -5,6 +5,7 @@ type Session struct {
 	Id      int64        `json:"-"`
 	Token   string       `json:"token,omitempty"`
 	Perms   *Permissions `json:"perms,omitempty"`
+	Bot     bool         `json:"bot,omitempty"` //bot tokens never expire and have no perms
 }
 
 type Permissions struct { //admin stuff
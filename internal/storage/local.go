@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/asianchinaboi/backendserver/internal/errors"
+)
+
+const tempPrefix = ".tmp-"
+
+type local struct {
+	root string
+}
+
+func NewLocal(root string) (Backend, error) {
+	if err := os.MkdirAll(root, os.ModePerm); err != nil {
+		return nil, err
+	}
+	return &local{root: root}, nil
+}
+
+func (l *local) path(key string) (string, error) {
+	if key == "" || strings.Contains(key, "..") {
+		return "", errors.ErrStorageInvalidKey
+	}
+	return filepath.Join(l.root, filepath.FromSlash(key)), nil
+}
+
+// written to a temp file first so a half written file never shows up under the key
+func (l *local) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	tempFile, err := os.CreateTemp(dir, tempPrefix+"*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempFile.Name()) //does nothing once renamed
+	if _, err := io.Copy(tempFile, r); err != nil {
+		tempFile.Close()
+		return err
+	}
+	if err := tempFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tempFile.Name(), path)
+}
+
+func (l *local) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, errors.ErrStorageObjectNotExist
+	}
+	return file, err
+}
+
+func (l *local) Delete(ctx context.Context, key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if os.IsNotExist(err) {
+		return errors.ErrStorageObjectNotExist
+	}
+	return err
+}
+
+func (l *local) Stat(ctx context.Context, key string) (Object, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return Object{}, err
+	}
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return Object{}, errors.ErrStorageObjectNotExist
+	} else if err != nil {
+		return Object{}, err
+	}
+	return Object{Key: key, Size: info.Size(), Modified: info.ModTime()}, nil
+}
+
+func (l *local) List(ctx context.Context, prefix string) ([]Object, error) {
+	objects := []Object{}
+	//only walk the folder the prefix points into
+	start := filepath.Join(l.root, filepath.FromSlash(prefix[:strings.LastIndex(prefix, "/")+1]))
+	err := filepath.WalkDir(start, func(path string, d fs.DirEntry, err error) error {
+		if os.IsNotExist(err) && path == start {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), tempPrefix) {
+			return nil
+		}
+		rel, err := filepath.Rel(l.root, path)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, Object{Key: key, Size: info.Size(), Modified: info.ModTime()})
+		return nil
+	})
+	return objects, err
+}
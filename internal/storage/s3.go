@@ -0,0 +1,351 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asianchinaboi/backendserver/internal/errors"
+)
+
+//talks to anything that speaks the s3 api (aws, minio, garage...)
+//requests are signed with signature v4, payloads are not hashed since the body is streamed
+
+const (
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+	amzDateFormat   = "20060102T150405Z"
+	partSize        = 8 << 20 //every part but the last has to be at least 5mb
+)
+
+type s3 struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	pathStyle bool //minio and most self hosted ones need path style
+	client    *http.Client
+}
+
+type listBucketResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+}
+
+type initiateMultipartUploadResult struct {
+	UploadId string `xml:"UploadId"`
+}
+
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type completeMultipartUpload struct {
+	XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+	Parts   []completedPart `xml:"Part"`
+}
+
+func NewS3(endpoint string, region string, bucket string, accessKey string, secretKey string, pathStyle bool) (Backend, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" || bucket == "" {
+		return nil, errors.ErrStorageInvalidConfig
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &s3{
+		endpoint:  u,
+		region:    region,
+		bucket:    bucket,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		pathStyle: pathStyle,
+		client:    &http.Client{},
+	}, nil
+}
+
+func (s *s3) url(key string, query url.Values) *url.URL {
+	u := *s.endpoint
+	path := strings.TrimSuffix(u.Path, "/")
+	if s.pathStyle {
+		path += "/" + s.bucket
+	} else {
+		u.Host = s.bucket + "." + u.Host
+	}
+	path += "/" + key
+	u.Path = path
+	u.RawPath = uriEncode(path, false)
+	u.RawQuery = canonicalQuery(query)
+	return &u
+}
+
+func (s *s3) do(ctx context.Context, method string, key string, query url.Values, body io.Reader, size int64) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, s.url(key, query).String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	s.sign(req, time.Now().UTC())
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode == http.StatusNotFound {
+		res.Body.Close()
+		return nil, errors.ErrStorageObjectNotExist
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		res.Body.Close()
+		return nil, fmt.Errorf("storage: s3 responded with %s: %s", res.Status, message)
+	}
+	return res, nil
+}
+
+// Put needs the length up front, when its unknown anything bigger than a part goes up as a multipart upload
+// so only one part is ever held in memory
+func (s *s3) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	if size >= 0 {
+		return s.put(ctx, key, r, size)
+	}
+	buffer := make([]byte, partSize)
+	n, err := io.ReadFull(r, buffer)
+	if err == io.EOF || err == io.ErrUnexpectedEOF { //fits in one request
+		return s.put(ctx, key, bytes.NewReader(buffer[:n]), int64(n))
+	} else if err != nil {
+		return err
+	}
+	return s.putMultipart(ctx, key, buffer, r)
+}
+
+func (s *s3) put(ctx context.Context, key string, r io.Reader, size int64) error {
+	res, err := s.do(ctx, http.MethodPut, key, nil, r, size)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+// putMultipart uploads buffer, which is already full, then the rest of r a part at a time
+func (s *s3) putMultipart(ctx context.Context, key string, buffer []byte, r io.Reader) error {
+	res, err := s.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil, 0)
+	if err != nil {
+		return err
+	}
+	var initiated initiateMultipartUploadResult
+	err = xml.NewDecoder(res.Body).Decode(&initiated)
+	res.Body.Close()
+	if err != nil {
+		return err
+	}
+	uploadQuery := url.Values{"uploadId": {initiated.UploadId}}
+
+	complete, err := s.uploadParts(ctx, key, initiated.UploadId, buffer, r)
+	if err != nil {
+		//parts of an unfinished upload are kept (and billed) until its aborted
+		if res, abortErr := s.do(context.Background(), http.MethodDelete, key, uploadQuery, nil, 0); abortErr == nil {
+			res.Body.Close()
+		}
+		return err
+	}
+	body, err := xml.Marshal(complete)
+	if err != nil {
+		return err
+	}
+	res, err = s.do(ctx, http.MethodPost, key, uploadQuery, bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+func (s *s3) uploadParts(ctx context.Context, key string, uploadId string, buffer []byte, r io.Reader) (completeMultipartUpload, error) {
+	complete := completeMultipartUpload{}
+	n := len(buffer)
+	for number := 1; n > 0; number++ {
+		query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {uploadId}}
+		res, err := s.do(ctx, http.MethodPut, key, query, bytes.NewReader(buffer[:n]), int64(n))
+		if err != nil {
+			return complete, err
+		}
+		res.Body.Close()
+		complete.Parts = append(complete.Parts, completedPart{PartNumber: number, ETag: res.Header.Get("ETag")})
+
+		n, err = io.ReadFull(r, buffer)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return complete, err
+		}
+	}
+	return complete, nil
+}
+
+func (s *s3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	res, err := s.do(ctx, http.MethodGet, key, nil, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	return res.Body, nil
+}
+
+func (s *s3) Delete(ctx context.Context, key string) error {
+	//s3 doesnt care if the key existed so check first to match the local backend
+	if _, err := s.Stat(ctx, key); err != nil {
+		return err
+	}
+	res, err := s.do(ctx, http.MethodDelete, key, nil, nil, 0)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+func (s *s3) Stat(ctx context.Context, key string) (Object, error) {
+	res, err := s.do(ctx, http.MethodHead, key, nil, nil, 0)
+	if err != nil {
+		return Object{}, err
+	}
+	res.Body.Close()
+	object := Object{Key: key, Size: res.ContentLength}
+	if modified, err := http.ParseTime(res.Header.Get("Last-Modified")); err == nil {
+		object.Modified = modified
+	}
+	return object, nil
+}
+
+func (s *s3) List(ctx context.Context, prefix string) ([]Object, error) {
+	objects := []Object{}
+	token := ""
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", prefix)
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		res, err := s.do(ctx, http.MethodGet, "", query, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		var result listBucketResult
+		err = xml.NewDecoder(res.Body).Decode(&result)
+		res.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, content := range result.Contents {
+			objects = append(objects, Object{Key: content.Key, Size: content.Size, Modified: content.LastModified})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// signs host, range and every x-amz header
+func (s *s3) sign(req *http.Request, now time.Time) {
+	amzDate := now.Format(amzDateFormat)
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if req.Header.Get("X-Amz-Content-Sha256") == "" {
+		req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-amz-") || lower == "range" || lower == "content-md5" {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		uriEncode(req.URL.Path, false),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		req.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := hmacSha256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSha256(key, s.region)
+	key = hmacSha256(key, "s3")
+	key = hmacSha256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSha256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", s.accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := []string{}
+	for _, key := range keys {
+		values := query[key]
+		sort.Strings(values)
+		for _, value := range values {
+			pairs = append(pairs, uriEncode(key, true)+"="+uriEncode(value, true))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// aws flavoured percent encoding, slashes are left alone in paths
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			b.WriteByte(c)
+		} else {
+			b.WriteString(fmt.Sprintf("%%%02X", c))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/asianchinaboi/backendserver/internal/config"
+	"github.com/asianchinaboi/backendserver/internal/errors"
+)
+
+//everything uploaded goes through here instead of touching the uploads folder directly
+//keys look like <entity>/<id>.lz4 so the same key works on every backend
+
+type Object struct {
+	Key      string
+	Size     int64
+	Modified time.Time
+}
+
+// Backend stores blobs by key
+// Get, Delete and Stat return errors.ErrStorageObjectNotExist if the key isnt there
+type Backend interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	Stat(ctx context.Context, key string) (Object, error)
+	List(ctx context.Context, prefix string) ([]Object, error)
+}
+
+// Key returns the key of an uploaded file
+func Key(entityType string, id int64) string {
+	return fmt.Sprintf("%s/%d.lz4", entityType, id)
+}
+
+// BlobKey returns the key of deduplicated content by its sha256
+// the first two characters are split off so a single folder doesnt end up with every file
+func BlobKey(hash string) string {
+	return fmt.Sprintf("blobs/%s/%s.lz4", hash[:2], hash)
+}
+
+// ChunkKey returns the key of one chunk of an unfinished upload
+// chunkId keeps two requests racing for the same offset from overwriting each other
+func ChunkKey(uploadId int64, offset int64, chunkId int64) string {
+	return fmt.Sprintf("%s%020d-%d", ChunkPrefix(uploadId), offset, chunkId)
+}
+
+func ChunkPrefix(uploadId int64) string {
+	return fmt.Sprintf("chunks/%d/", uploadId)
+}
+
+// DeletePrefix removes everything under the prefix
+func DeletePrefix(ctx context.Context, store Backend, prefix string) error {
+	objects, err := store.List(ctx, prefix)
+	if err != nil {
+		return err
+	}
+	for _, object := range objects {
+		if err := store.Delete(ctx, object.Key); err != nil && err != errors.ErrStorageObjectNotExist {
+			return err
+		}
+	}
+	return nil
+}
+
+// New creates the backend with the given name using the settings in conf
+func New(conf *config.Settings, name string) (Backend, error) {
+	switch name {
+	case "", "local":
+		return NewLocal(conf.Storage.Local.Path)
+	case "s3":
+		s3 := conf.Storage.S3
+		return NewS3(s3.Endpoint, s3.Region, s3.Bucket, s3.AccessKey, s3.SecretKey, s3.PathStyle)
+	default:
+		return nil, errors.ErrStorageBackendNotExist
+	}
+}
+
+// Open is New with tracing added, the app assigns the result to Store
+func Open(conf *config.Settings, name string) (Backend, error) {
+	backend, err := New(conf, name)
+	if err != nil {
+		return nil, err
+	}
+	return traced{Backend: backend, name: name}, nil
+}
@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"context"
+	"io"
+
+	"github.com/asianchinaboi/backendserver/internal/tracing"
+)
+
+// traced adds spans to writes, reads are left alone since downloads are streamed long after the handler started them
+type traced struct {
+	Backend
+	name string
+}
+
+func (t traced) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	ctx, span := tracing.Start(ctx, "storage.put", tracing.KindClient, "storage.backend", t.name, "storage.key", key)
+	defer span.End()
+	err := t.Backend.Put(ctx, key, r, size)
+	span.RecordError(err)
+	return err
+}
+
+func (t traced) Delete(ctx context.Context, key string) error {
+	ctx, span := tracing.Start(ctx, "storage.delete", tracing.KindClient, "storage.backend", t.name, "storage.key", key)
+	defer span.End()
+	err := t.Backend.Delete(ctx, key)
+	span.RecordError(err)
+	return err
+}
@@ -0,0 +1,230 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/asianchinaboi/backendserver/internal/logger"
+)
+
+//spans are batched and sent to <endpoint>/v1/traces in the otlp json format
+//if the collector cant keep up spans are dropped instead of slowing requests down
+
+var exporter = &batcher{}
+
+type batcher struct {
+	sync.Mutex
+	spans   []*Span
+	started bool
+	flush   chan struct{}
+	dropped int
+}
+
+func (b *batcher) add(span *Span) {
+	b.Lock()
+	defer b.Unlock()
+	if !b.started {
+		b.started = true
+		b.flush = make(chan struct{}, 1)
+		go b.run()
+	}
+	conf := loaded().Tracing
+	if conf.QueueSize > 0 && len(b.spans) >= conf.QueueSize {
+		b.dropped++
+		return
+	}
+	b.spans = append(b.spans, span)
+	if len(b.spans) >= conf.BatchSize {
+		select {
+		case b.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (b *batcher) run() {
+	client := &http.Client{Timeout: 10 * time.Second}
+	for {
+		interval := loaded().Tracing.Interval
+		if interval <= 0 {
+			interval = 5 * time.Second
+		}
+		select {
+		case <-time.After(interval):
+		case <-b.flush:
+		}
+		b.Flush(client)
+	}
+}
+
+// Flush sends every queued span
+func (b *batcher) Flush(client *http.Client) {
+	b.Lock()
+	spans := b.spans
+	b.spans = nil
+	if b.dropped > 0 {
+		logger.Default.Warn("dropped spans, the collector isnt keeping up", "spans", b.dropped)
+		b.dropped = 0
+	}
+	b.Unlock()
+
+	conf := loaded().Tracing
+	for len(spans) > 0 {
+		n := len(spans)
+		if conf.BatchSize > 0 && n > conf.BatchSize {
+			n = conf.BatchSize
+		}
+		if err := send(client, conf.Endpoint, spans[:n]); err != nil {
+			logger.Default.Warn("unable to export spans", "error", err)
+		}
+		spans = spans[n:]
+	}
+}
+
+// Shutdown sends whatever is left, called when the server stops
+func Shutdown(ctx context.Context) {
+	if !Enabled() {
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		exporter.Flush(&http.Client{Timeout: 10 * time.Second})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
+func send(client *http.Client, endpoint string, spans []*Span) error {
+	body, err := json.Marshal(encode(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, endpoint+"/v1/traces", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range loaded().Tracing.Headers { //usually auth for hosted collectors
+		req.Header.Set(key, value)
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		return fmt.Errorf("collector returned %v", res.Status)
+	}
+	return nil
+}
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceId           string          `json:"traceId"`
+	SpanId            string          `json:"spanId"`
+	ParentSpanId      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+func encode(spans []*Span) otlpRequest {
+	encoded := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		span.Lock()
+		s := otlpSpan{
+			TraceId:           hex.EncodeToString(span.traceId[:]),
+			SpanId:            hex.EncodeToString(span.spanId[:]),
+			Name:              span.name,
+			Kind:              span.kind,
+			StartTimeUnixNano: strconv.FormatInt(span.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.end.UnixNano(), 10),
+			Attributes:        attributes(span.attributes),
+			Status:            otlpStatus{Code: span.status, Message: span.message},
+		}
+		if span.parentId != (SpanId{}) {
+			s.ParentSpanId = hex.EncodeToString(span.parentId[:])
+		}
+		span.Unlock()
+		encoded = append(encoded, s)
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: attributes(map[string]interface{}{"service.name": loaded().Tracing.ServiceName})},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "github.com/asianchinaboi/backendserver"}, Spans: encoded}},
+	}}}
+}
+
+func attributes(values map[string]interface{}) []otlpAttribute {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	encoded := make([]otlpAttribute, 0, len(keys))
+	for _, key := range keys {
+		var value map[string]interface{}
+		switch v := values[key].(type) {
+		case string:
+			value = map[string]interface{}{"stringValue": v}
+		case bool:
+			value = map[string]interface{}{"boolValue": v}
+		case int:
+			value = map[string]interface{}{"intValue": strconv.Itoa(v)}
+		case int64:
+			value = map[string]interface{}{"intValue": strconv.FormatInt(v, 10)}
+		case float64:
+			value = map[string]interface{}{"doubleValue": v}
+		case error:
+			value = map[string]interface{}{"stringValue": v.Error()}
+		default:
+			value = map[string]interface{}{"stringValue": fmt.Sprint(v)}
+		}
+		encoded = append(encoded, otlpAttribute{Key: key, Value: value})
+	}
+	return encoded
+}
@@ -0,0 +1,232 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/asianchinaboi/backendserver/internal/config"
+)
+
+// receiver is a fake otlp collector that keeps every span it gets
+// spans are decoded without the exporters types so the field names are checked against the spec
+type receiver struct {
+	sync.Mutex
+	spans    []map[string]interface{}
+	resource []interface{}
+	status   int
+	err      []string
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.Lock()
+	defer r.Unlock()
+	if req.Method != http.MethodPost || req.URL.Path != "/v1/traces" {
+		r.err = append(r.err, fmt.Sprintf("got %s %s, want POST /v1/traces", req.Method, req.URL.Path))
+	}
+	if req.Header.Get("Content-Type") != "application/json" {
+		r.err = append(r.err, "content type is "+req.Header.Get("Content-Type"))
+	}
+	if req.Header.Get("Authorization") != "Bearer collector-key" {
+		r.err = append(r.err, "configured headers werent sent")
+	}
+	var body struct {
+		ResourceSpans []struct {
+			Resource struct {
+				Attributes []interface{} `json:"attributes"`
+			} `json:"resource"`
+			ScopeSpans []struct {
+				Spans []map[string]interface{} `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		r.err = append(r.err, err.Error())
+	}
+	for _, resource := range body.ResourceSpans {
+		r.resource = resource.Resource.Attributes
+		for _, scope := range resource.ScopeSpans {
+			r.spans = append(r.spans, scope.Spans...)
+		}
+	}
+	if r.status != 0 {
+		w.WriteHeader(r.status)
+	}
+}
+
+// collected waits for n spans since the batcher might send some on its own first
+func (r *receiver) collected(t *testing.T, n int) map[string]map[string]interface{} {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		Shutdown(context.Background())
+		r.Lock()
+		if len(r.spans) >= n || time.Now().After(deadline) {
+			defer r.Unlock()
+			for _, err := range r.err {
+				t.Error(err)
+			}
+			byName := map[string]map[string]interface{}{}
+			for _, span := range r.spans {
+				byName[span["name"].(string)] = span
+			}
+			if len(r.spans) != n {
+				t.Fatalf("collector got %d spans, want %d", len(r.spans), n)
+			}
+			return byName
+		}
+		r.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+var collector = &receiver{}
+
+// the config is set once since the batcher keeps reading it from its own goroutine
+func TestMain(m *testing.M) {
+	server := httptest.NewServer(collector)
+	conf, err := config.Load(config.Sources{})
+	if err != nil {
+		panic(err)
+	}
+	conf.Tracing.Enabled = true
+	conf.Tracing.Endpoint = server.URL
+	conf.Tracing.Headers = map[string]string{"Authorization": "Bearer collector-key"}
+	conf.Tracing.ServiceName = "backendserver-test"
+	conf.Tracing.Interval = time.Hour //only sent when the test flushes
+	Setup(conf)
+	code := m.Run()
+	server.Close()
+	os.Exit(code)
+}
+
+// setup empties the collector, sampleRatio is only read when spans start so its safe to change here
+func setup(t *testing.T, sampleRatio float64) *receiver {
+	t.Helper()
+	Shutdown(context.Background()) //nothing left over from the last test
+	collector.Lock()
+	collector.spans, collector.resource, collector.status, collector.err = nil, nil, 0, nil
+	collector.Unlock()
+	loaded().Tracing.SampleRatio = sampleRatio
+	return collector
+}
+
+// attribute finds a key in otlp attributes and returns its value object
+func attribute(attributes interface{}, key string) map[string]interface{} {
+	list, _ := attributes.([]interface{})
+	for _, a := range list {
+		a := a.(map[string]interface{})
+		if a["key"] == key {
+			return a["value"].(map[string]interface{})
+		}
+	}
+	return nil
+}
+
+func TestExport(t *testing.T) {
+	r := setup(t, 1)
+	ctx, parent := Start(context.Background(), "GET /api/test", KindServer, "http.status_code", 200, "http.route", "/api/test")
+	_, child := Start(ctx, "db.query", KindClient, "db.rows", int64(3), "cached", true, "ratio", 0.5)
+	child.RecordError(fmt.Errorf("connection reset"))
+	child.End()
+	parent.End()
+	parent.End() //ending twice doesnt send it twice
+
+	spans := r.collected(t, 2)
+	if value := attribute(r.resource, "service.name"); value == nil || value["stringValue"] != "backendserver-test" {
+		t.Errorf("service.name is %v", value)
+	}
+	server, client := spans["GET /api/test"], spans["db.query"]
+	if server == nil || client == nil {
+		t.Fatalf("missing spans, got %v", spans)
+	}
+	if len(server["traceId"].(string)) != 32 || len(server["spanId"].(string)) != 16 {
+		t.Errorf("ids arent hex encoded: %v %v", server["traceId"], server["spanId"])
+	}
+	if client["traceId"] != server["traceId"] || client["parentSpanId"] != server["spanId"] {
+		t.Error("child isnt part of the parents trace")
+	}
+	if _, ok := server["parentSpanId"]; ok {
+		t.Error("root span has a parent")
+	}
+	if server["kind"] != float64(KindServer) || client["kind"] != float64(KindClient) {
+		t.Errorf("kinds are %v and %v", server["kind"], client["kind"])
+	}
+
+	//times and int64 values are strings in otlp json
+	start, err := strconv.ParseInt(client["startTimeUnixNano"].(string), 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	end, err := strconv.ParseInt(client["endTimeUnixNano"].(string), 10, 64)
+	if err != nil || end < start {
+		t.Errorf("span ends at %v, starts at %v", client["endTimeUnixNano"], start)
+	}
+	if value := attribute(server["attributes"], "http.status_code"); value == nil || value["intValue"] != "200" {
+		t.Errorf("http.status_code is %v", value)
+	}
+	if value := attribute(client["attributes"], "db.rows"); value == nil || value["intValue"] != "3" {
+		t.Errorf("db.rows is %v", value)
+	}
+	if value := attribute(client["attributes"], "cached"); value == nil || value["boolValue"] != true {
+		t.Errorf("cached is %v", value)
+	}
+	if value := attribute(client["attributes"], "ratio"); value == nil || value["doubleValue"] != 0.5 {
+		t.Errorf("ratio is %v", value)
+	}
+
+	status := client["status"].(map[string]interface{})
+	if status["code"] != float64(statusError) || status["message"] != "connection reset" {
+		t.Errorf("error status is %v", status)
+	}
+	if status := server["status"].(map[string]interface{}); len(status) != 0 {
+		t.Errorf("unset status is %v", status)
+	}
+}
+
+func TestExportContinuesRemoteTrace(t *testing.T) {
+	r := setup(t, 1)
+	traceparent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	ctx, span := Start(Extract(context.Background(), traceparent), "POST /api/guilds/:guildId/msgs", KindServer)
+	if got := Traceparent(ctx); got[:36] != traceparent[:36] || got[52:] != "-01" {
+		t.Errorf("traceparent %s doesnt continue %s", got, traceparent)
+	}
+	span.End()
+	got := r.collected(t, 1)["POST /api/guilds/:guildId/msgs"]
+	if got["traceId"] != "4bf92f3577b34da6a3ce929d0e0e4736" || got["parentSpanId"] != "00f067aa0ba902b7" {
+		t.Errorf("got trace %v parent %v", got["traceId"], got["parentSpanId"])
+	}
+}
+
+func TestExportUnsampled(t *testing.T) {
+	r := setup(t, 0)
+	ctx, parent := Start(context.Background(), "dropped", KindServer)
+	_, child := Start(ctx, "dropped child", KindInternal)
+	child.End()
+	parent.End()
+	//a sampled remote parent is still followed
+	_, remote := Start(Extract(context.Background(), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"), "kept", KindServer)
+	remote.End()
+	if spans := r.collected(t, 1); spans["kept"] == nil {
+		t.Errorf("got %v, want only the span with a sampled parent", spans)
+	}
+}
+
+func TestExportCollectorError(t *testing.T) {
+	r := setup(t, 1)
+	r.Lock()
+	r.status = http.StatusServiceUnavailable
+	r.Unlock()
+	_, span := Start(context.Background(), "failed", KindInternal)
+	span.End()
+	if err := send(http.DefaultClient, loaded().Tracing.Endpoint, []*Span{span}); err == nil {
+		t.Error("collector errors arent returned")
+	}
+}
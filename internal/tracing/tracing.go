@@ -0,0 +1,212 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/asianchinaboi/backendserver/internal/config"
+)
+
+//a small opentelemetry compatible tracer, spans are exported to a collector as otlp over http (json)
+//everything is a no op when tracing is turned off so its safe to call from anywhere
+
+const (
+	KindInternal = 1
+	KindServer   = 2
+	KindClient   = 3
+	KindProducer = 4
+	KindConsumer = 5
+)
+
+const (
+	statusOk    = 1
+	statusError = 2
+)
+
+type TraceId [16]byte
+type SpanId [8]byte
+
+type Span struct {
+	sync.Mutex
+	traceId    TraceId
+	spanId     SpanId
+	parentId   SpanId
+	name       string
+	kind       int
+	start      time.Time
+	end        time.Time
+	attributes map[string]interface{}
+	status     int
+	message    string //status message
+	sampled    bool
+	ended      bool
+}
+
+type spanKey struct{}
+
+// remote is a span from another process, only its ids are known
+type remote struct {
+	traceId TraceId
+	spanId  SpanId
+	sampled bool
+}
+
+type remoteKey struct{}
+
+var settings atomic.Value //*config.Settings
+
+// Setup sets where spans go from conf, the tracer is shared by the whole process like the logger
+func Setup(conf *config.Settings) {
+	settings.Store(conf)
+}
+
+// loaded is the config from Setup, nil before its called
+func loaded() *config.Settings {
+	conf, _ := settings.Load().(*config.Settings)
+	return conf
+}
+
+func Enabled() bool {
+	conf := loaded()
+	return conf != nil && conf.Tracing.Enabled
+}
+
+// Start starts a span as a child of whatever span is in ctx, the returned context has the new span
+// attributes are key value pairs like the logger
+func Start(ctx context.Context, name string, kind int, attributes ...interface{}) (context.Context, *Span) {
+	if !Enabled() {
+		return ctx, nil
+	}
+	span := &Span{name: name, kind: kind, start: time.Now(), attributes: make(map[string]interface{})}
+	if parent := FromContext(ctx); parent != nil {
+		span.traceId = parent.traceId
+		span.parentId = parent.spanId
+		span.sampled = parent.sampled
+	} else if r, ok := ctx.Value(remoteKey{}).(remote); ok {
+		span.traceId = r.traceId
+		span.parentId = r.spanId
+		span.sampled = r.sampled
+	} else {
+		rand.Read(span.traceId[:])
+		span.sampled = sample(span.traceId)
+	}
+	rand.Read(span.spanId[:])
+	span.SetAttributes(attributes...)
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// sample keeps the same share of traces on every instance since its decided from the trace id
+func sample(traceId TraceId) bool {
+	ratio := loaded().Tracing.SampleRatio
+	if ratio >= 1 {
+		return true
+	}
+	return float64(binary.BigEndian.Uint64(traceId[8:])) < ratio*math.MaxUint64
+}
+
+func FromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// Detach keeps the span from ctx but not its cancellation, for work that has to finish even if the client goes away
+func Detach(ctx context.Context) context.Context {
+	if span := FromContext(ctx); span != nil {
+		return context.WithValue(context.Background(), spanKey{}, span)
+	}
+	if r, ok := ctx.Value(remoteKey{}).(remote); ok {
+		return context.WithValue(context.Background(), remoteKey{}, r)
+	}
+	return context.Background()
+}
+
+func (s *Span) SetAttributes(attributes ...interface{}) {
+	if s == nil {
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	for i := 0; i+1 < len(attributes); i += 2 {
+		if key, ok := attributes[i].(string); ok {
+			s.attributes[key] = attributes[i+1]
+		}
+	}
+}
+
+// RecordError marks the span as failed, nil errors are ignored so it can be called with any err
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.SetError(err.Error())
+}
+
+func (s *Span) SetError(message string) {
+	if s == nil {
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.status = statusError
+	s.message = message
+}
+
+// End finishes the span and queues it to be exported
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.Lock()
+	if s.ended {
+		s.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.Unlock()
+	if s.sampled {
+		exporter.add(s)
+	}
+}
+
+// Traceparent is the w3c header for the span in ctx, empty if theres no span
+func Traceparent(ctx context.Context) string {
+	span := FromContext(ctx)
+	if span == nil {
+		return ""
+	}
+	flags := "00"
+	if span.sampled {
+		flags = "01"
+	}
+	return "00-" + hex.EncodeToString(span.traceId[:]) + "-" + hex.EncodeToString(span.spanId[:]) + "-" + flags
+}
+
+// Extract returns ctx with the span from a traceparent header as the parent of the next span
+// invalid headers are ignored and a new trace is started instead
+func Extract(ctx context.Context, traceparent string) context.Context {
+	parts := strings.Split(traceparent, "-")
+	if len(parts) != 4 || parts[0] != "00" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return ctx
+	}
+	var r remote
+	if _, err := hex.Decode(r.traceId[:], []byte(parts[1])); err != nil || r.traceId == (TraceId{}) {
+		return ctx
+	}
+	if _, err := hex.Decode(r.spanId[:], []byte(parts[2])); err != nil || r.spanId == (SpanId{}) {
+		return ctx
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return ctx
+	}
+	r.sampled = flags[0]&1 == 1
+	return context.WithValue(ctx, remoteKey{}, r)
+}
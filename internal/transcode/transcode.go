@@ -0,0 +1,113 @@
+package transcode
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/asianchinaboi/backendserver/internal/config"
+)
+
+//video and audio attachments get turned into hls so they can be streamed instead of downloaded whole
+//jobs are kept in the transcodes table by content hash so the same file is only transcoded once
+//and the output lives next to the thumbnails, removed when the blob is released
+
+const (
+	StatusPending    = "pending"
+	StatusProcessing = "processing"
+	StatusReady      = "ready"
+	StatusFailed     = "failed"
+)
+
+const (
+	PlaylistName = "index.m3u8"
+	PosterName   = "poster.jpg"
+)
+
+const maxErrorOutput = 4096 //how much of ffmpegs output gets kept when it fails
+
+var segmentExp = regexp.MustCompile(`^seg[0-9]{5}\.ts$`)
+
+// Wanted reports if files of the content type get transcoded
+func Wanted(conf *config.Settings, contentType string) bool {
+	return conf.Transcode.Enabled && (IsVideo(contentType) || strings.HasPrefix(contentType, "audio/"))
+}
+
+func IsVideo(contentType string) bool {
+	return strings.HasPrefix(contentType, "video/")
+}
+
+// Enqueue adds a job for the blob if there isnt one yet and returns its status
+// call it in the same transaction as the files row, returns an empty status if the type isnt transcoded
+func Enqueue(ctx context.Context, tx *sql.Tx, conf *config.Settings, hash string, contentType string) (string, error) {
+	if !Wanted(conf, contentType) {
+		return "", nil
+	}
+	var status string
+	//do update so the existing row gets returned when the blob was already queued
+	if err := tx.QueryRowContext(ctx, `INSERT INTO transcodes (hash, content_type) VALUES ($1, $2) 
+	ON CONFLICT (hash) DO UPDATE SET hash = EXCLUDED.hash RETURNING status`, hash, contentType).Scan(&status); err != nil {
+		return "", err
+	}
+	return status, nil
+}
+
+// Prefix returns where the output for a blob is kept
+func Prefix(hash string) string {
+	return fmt.Sprintf("hls/%s/", hash)
+}
+
+func Key(hash string, name string) string {
+	return Prefix(hash) + name
+}
+
+// ValidName checks the name is something the transcoder writes so it can be used in a key
+func ValidName(name string) bool {
+	return name == PlaylistName || name == PosterName || segmentExp.MatchString(name)
+}
+
+// Run transcodes source into dir, writing the playlist, the segments and a poster for videos
+func Run(ctx context.Context, conf *config.Settings, source string, dir string, video bool) error {
+	args := []string{"-hide_banner", "-loglevel", "error", "-y", "-i", source}
+	if video {
+		//widths have to be even for h264
+		args = append(args, "-map", "0:v:0", "-map", "0:a:0?", "-c:v", "libx264", "-preset", "veryfast", "-crf", "23", "-pix_fmt", "yuv420p",
+			"-vf", `scale=trunc(min(1920\,iw)/2)*2:-2`)
+	} else {
+		args = append(args, "-map", "0:a:0", "-vn")
+	}
+	segmentLength := strconv.Itoa(int(conf.Transcode.SegmentLength.Seconds()))
+	args = append(args, "-c:a", "aac", "-b:a", "128k",
+		"-f", "hls", "-hls_time", segmentLength, "-hls_playlist_type", "vod",
+		"-hls_segment_filename", filepath.Join(dir, "seg%05d.ts"), filepath.Join(dir, PlaylistName))
+	if err := ffmpeg(ctx, conf.Transcode.FFmpegPath, args); err != nil {
+		return err
+	}
+	if !video {
+		return nil
+	}
+	//thumbnail filter picks a frame that isnt just black or a fade
+	return ffmpeg(ctx, conf.Transcode.FFmpegPath, []string{"-hide_banner", "-loglevel", "error", "-y", "-i", source,
+		"-map", "0:v:0", "-vf", `thumbnail,scale=min(1280\,iw):-2`, "-frames:v", "1", "-q:v", "3", filepath.Join(dir, PosterName)})
+}
+
+func ffmpeg(ctx context.Context, path string, args []string) error {
+	cmd := exec.CommandContext(ctx, path, args...)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		out := output.Bytes()
+		if len(out) > maxErrorOutput {
+			out = out[len(out)-maxErrorOutput:]
+		}
+		return fmt.Errorf("ffmpeg: %v: %s", err, bytes.TrimSpace(out))
+	}
+	return nil
+}
@@ -1,8 +1,6 @@
 package uid
 
 import (
-	"github.com/asianchinaboi/backendserver/internal/config"
-	"github.com/asianchinaboi/backendserver/internal/logger"
 	"github.com/bwmarrin/snowflake"
 )
 
@@ -10,10 +8,12 @@ var (
 	Snowflake *snowflake.Node
 )
 
-func init() {
-	var err error
-	Snowflake, err = snowflake.NewNode(config.Config.Server.SnowflakeNodeID)
+// Setup creates the node ids are generated from, every instance needs its own node id
+func Setup(nodeId int64) error {
+	node, err := snowflake.NewNode(nodeId)
 	if err != nil {
-		logger.Error.Fatalln(err)
+		return err
 	}
+	Snowflake = node
+	return nil
 }
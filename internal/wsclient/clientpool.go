@@ -4,9 +4,10 @@ import (
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
 	"github.com/asianchinaboi/backendserver/internal/logger"
+	"github.com/asianchinaboi/backendserver/internal/metrics"
 )
 
-func (p *pools) AddUserToClientPool(id int64, uid string, broadcast brcastEvents) {
+func (p *ClientPools) AddUserToClientPool(id int64, uid string, broadcast brcastEvents) {
 	p.clientsMutex.Lock() //prevents datarace
 	defer p.clientsMutex.Unlock()
 	if _, ok := p.clients[id]; !ok {
@@ -16,7 +17,7 @@ func (p *pools) AddUserToClientPool(id int64, uid string, broadcast brcastEvents
 	p.clients[id][uid] = broadcast
 }
 
-func (p *pools) removeUserUIDFromClientPool(id int64, uid string) {
+func (p *ClientPools) removeUserUIDFromClientPool(id int64, uid string) {
 	p.clientsMutex.Lock()
 	defer p.clientsMutex.Unlock()
 	if _, ok := p.clients[id]; !ok {
@@ -31,7 +32,7 @@ func (p *pools) removeUserUIDFromClientPool(id int64, uid string) {
 	//logger.Debug.Printf("apple pie %v \n", p.clients)
 }
 
-func (p *pools) DisconnectUserFromClientPool(id int64) {
+func (p *ClientPools) DisconnectUserFromClientPool(id int64) {
 	p.clientsMutex.Lock()
 	defer p.clientsMutex.Unlock()
 	clientList, ok := p.clients[id] //problem if two websockets of same user exist only of those two will be sent
@@ -47,7 +48,7 @@ func (p *pools) DisconnectUserFromClientPool(id int64) {
 	} //will automatically delete itself from defer funciton in wsclient through RemoveUserUIDFromClientPool
 }
 
-func (p *pools) BroadcastClient(id int64, data DataFrame) error {
+func (p *ClientPools) BroadcastClient(id int64, data DataFrame) error {
 	p.clientsMutex.RLock()
 	defer p.clientsMutex.RUnlock()
 	clientList, ok := p.clients[id] //problem if two websockets of same user exist only of those two will be sent
@@ -55,26 +56,30 @@ func (p *pools) BroadcastClient(id int64, data DataFrame) error {
 		return errors.ErrUserClientNotExist
 	}
 	for _, client := range clientList {
+		metrics.BroadcastQueue.Inc()
 		client <- data
+		metrics.BroadcastQueue.Dec()
 	}
 	return nil
 }
 
-func (p *pools) BroadcastClientUIDMap(clients map[string]brcastEvents, data DataFrame) {
+func (p *ClientPools) BroadcastClientUIDMap(clients map[string]brcastEvents, data DataFrame) {
 	p.clientsMutex.RLock()
 	defer p.clientsMutex.RUnlock()
 	for _, ch := range clients {
+		metrics.BroadcastQueue.Inc()
 		ch <- data //closed channel fatal error FIX NOW
+		metrics.BroadcastQueue.Dec()
 	}
 }
 
-func (p *pools) GetLengthClients() int {
+func (p *ClientPools) GetLengthClients() int {
 	p.clientsMutex.RLock()
 	defer p.clientsMutex.RUnlock()
 	return len(p.clients)
 }
 
-func (p *pools) GetLengthForClient(id int64) int {
+func (p *ClientPools) GetLengthForClient(id int64) int {
 	p.clientsMutex.RLock()
 	defer p.clientsMutex.RUnlock()
 	return len(p.clients[id])
@@ -0,0 +1,79 @@
+package wsclient
+
+import (
+	"context"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+//every connection is tracked here, not just identified ones, so they can all be told to leave when draining
+
+func (p *ClientPools) Draining() bool {
+	return atomic.LoadInt32(&p.draining) == 1
+}
+
+func (p *ClientPools) ActiveConnections() int {
+	p.connMutex.Lock()
+	defer p.connMutex.Unlock()
+	return len(p.connections)
+}
+
+func (c *wsClient) track() {
+	c.pools.connMutex.Lock()
+	c.pools.connections[c] = struct{}{}
+	c.pools.connMutex.Unlock()
+	if c.pools.Draining() { //connected while Drain was going through the list
+		go c.reconnect(0)
+	}
+}
+
+func (c *wsClient) untrack() {
+	c.pools.connMutex.Lock()
+	delete(c.pools.connections, c)
+	c.pools.connMutex.Unlock()
+}
+
+// Drain stops new connections and tells every client to reconnect, which sends them to another instance
+// readyz fails straight away but the reconnects wait for wait (one readiness interval) so the load balancer
+// has stopped sending clients here by the time they reconnect, theyre spread over jitter after that
+// returns once every connection is gone or ctx is done
+func (p *ClientPools) Drain(ctx context.Context, wait time.Duration, jitter time.Duration) {
+	atomic.StoreInt32(&p.draining, 1)
+	select {
+	case <-time.After(wait):
+	case <-ctx.Done():
+		return
+	}
+	p.connMutex.Lock()
+	for c := range p.connections {
+		var delay time.Duration
+		if jitter > 0 {
+			delay = time.Duration(rand.Int63n(int64(jitter)))
+		}
+		go c.reconnect(delay)
+	}
+	p.connMutex.Unlock()
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for p.ActiveConnections() > 0 {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *wsClient) reconnect(delay time.Duration) {
+	select {
+	case <-time.After(delay):
+	case <-c.quitctx.Done():
+		return
+	}
+	c.reply(DataFrame{Op: TYPE_RECONNECT})
+	c.closeWith(websocket.CloseServiceRestart, "reconnect")
+}
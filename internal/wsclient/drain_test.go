@@ -0,0 +1,70 @@
+package wsclient
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/asianchinaboi/backendserver/internal/logger"
+)
+
+// newDrainClient is enough of a client for reconnect, replies are read by the test instead of writePipe
+func newDrainClient(pools *ClientPools) *wsClient {
+	c := &wsClient{pools: pools, replies: make(brcastEvents)}
+	c.quitctx, c.quit = context.WithCancel(context.Background())
+	return c
+}
+
+func TestDrainWaitsForReadiness(t *testing.T) {
+	pools := NewPools(nil, logger.Default)
+	c := newDrainClient(pools)
+	c.track()
+
+	wait := 300 * time.Millisecond
+	start := time.Now()
+	done := make(chan struct{})
+	go func() {
+		pools.Drain(context.Background(), wait, 0)
+		close(done)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	if !pools.Draining() {
+		t.Fatal("readyz should fail as soon as draining starts")
+	}
+	select {
+	case frame := <-c.replies:
+		if frame.Op != TYPE_RECONNECT {
+			t.Fatalf("got op %d, want reconnect", frame.Op)
+		}
+		if elapsed := time.Since(start); elapsed < wait {
+			t.Errorf("reconnect sent after %v, before the load balancer saw readyz fail", elapsed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("reconnect never sent")
+	}
+
+	<-c.quitctx.Done() //closed after the reconnect, the pipes would untrack it here
+	c.untrack()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("drain didnt return once every connection was gone")
+	}
+}
+
+func TestDrainStopsWaitingWhenCancelled(t *testing.T) {
+	pools := NewPools(nil, logger.Default)
+	c := newDrainClient(pools)
+	c.track()
+	t.Cleanup(c.untrack)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	pools.Drain(ctx, time.Hour, 0)
+	select {
+	case <-c.replies:
+		t.Error("reconnect sent after the drain timed out")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,88 @@
+package wsclient
+
+import (
+	"encoding/json"
+
+	"github.com/asianchinaboi/backendserver/internal/errors"
+	"github.com/asianchinaboi/backendserver/internal/msgpack"
+	"github.com/gorilla/websocket"
+)
+
+//chosen with the encoding and compress query params on /api/ws
+
+const (
+	ENCODING_JSON        = "json"
+	ENCODING_MSGPACK     = "msgpack"
+	COMPRESS_ZLIB_STREAM = "zlib-stream" //one zlib context for the whole connection, each frame is sync flushed
+)
+
+func ValidEncoding(encoding string) bool {
+	return encoding == ENCODING_JSON || encoding == ENCODING_MSGPACK
+}
+
+func ValidCompress(compress string) bool {
+	return compress == "" || compress == COMPRESS_ZLIB_STREAM
+}
+
+// write encodes and compresses a frame
+// only writePipe (and hello before it starts) should call this since the zlib context cant be shared between goroutines
+func (c *wsClient) write(frame DataFrame) error {
+	messageType, data, err := c.encode(frame)
+	if err != nil {
+		return err
+	}
+	return c.ws.WriteMessage(messageType, data)
+}
+
+// encode returns the websocket message type and what to send, data is only valid until the next call when compressing
+func (c *wsClient) encode(frame DataFrame) (int, []byte, error) {
+	messageType := websocket.TextMessage
+	var data []byte
+	var err error
+	if c.encoding == ENCODING_MSGPACK {
+		messageType = websocket.BinaryMessage
+		data, err = msgpack.Marshal(frame)
+	} else {
+		data, err = json.Marshal(frame)
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	if c.compressor != nil { //sync flushing means every frame ends in 00 00 ff ff so clients know when to inflate
+		messageType = websocket.BinaryMessage
+		c.compressed.Reset()
+		if _, err := c.compressor.Write(data); err != nil {
+			return 0, nil, err
+		}
+		if err := c.compressor.Flush(); err != nil {
+			return 0, nil, err
+		}
+		data = c.compressed.Bytes()
+	}
+	return messageType, data, nil
+}
+
+// decode reads a frame from the client, msgpack clients send binary frames and everyone else sends text
+// clients never compress what they send
+func (c *wsClient) decode(messageType int, message []byte, v interface{}) error {
+	if c.encoding == ENCODING_MSGPACK {
+		if messageType != websocket.BinaryMessage {
+			return errors.ErrGatewayInvalidFrame
+		}
+		var err error
+		if message, err = msgpack.ToJSON(message); err != nil { //validated the same way as json after
+			return err
+		}
+	} else if messageType != websocket.TextMessage {
+		return errors.ErrGatewayInvalidFrame
+	}
+	return decodeStrict(message, v)
+}
+
+// reply queues a frame for writePipe, used by readPipe so only one goroutine writes
+func (c *wsClient) reply(frame DataFrame) {
+	select {
+	case c.replies <- frame:
+	case <-c.quitctx.Done():
+	}
+}
@@ -0,0 +1,133 @@
+package wsclient
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/asianchinaboi/backendserver/internal/events"
+	"github.com/asianchinaboi/backendserver/internal/msgpack"
+)
+
+// frames are message creates in one guild, close to what most of a busy connection receives
+func frames(n int) []DataFrame {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	saved := true
+	list := make([]DataFrame, n)
+	for i := range list {
+		msg := events.Msg{
+			MsgId:     1500000000000000000 + int64(i),
+			GuildId:   1400000000000000000,
+			RequestId: fmt.Sprintf("request-%d", i),
+			Content:   fmt.Sprintf("message number %d, nothing much to say", i),
+			Author:    events.User{UserId: 1300000000000000000 + int64(i%5), Name: fmt.Sprintf("user_%d", i%5)},
+			Created:   created.Add(time.Duration(i) * time.Second),
+			MsgSaved:  saved,
+		}
+		if i%4 == 0 {
+			msg.Attachments = &[]events.Attachment{{
+				Id:          1600000000000000000 + int64(i),
+				ContentType: "image/png",
+				Type:        "image/png",
+				Filename:    "screenshot.png",
+				Size:        123456,
+				Width:       1920,
+				Height:      1080,
+				Blurhash:    "LEHV6nWB2yk8pyo0adR*.7kCMdnj",
+			}}
+		}
+		list[i] = DataFrame{Op: TYPE_DISPATCH, Data: msg, Event: events.MESSAGE_CREATE}
+	}
+	return list
+}
+
+func newTestClient(encoding string, compress string) *wsClient {
+	c := &wsClient{encoding: encoding}
+	if compress == COMPRESS_ZLIB_STREAM {
+		c.compressor = zlib.NewWriter(&c.compressed)
+	}
+	return c
+}
+
+// BenchmarkEncode reports bytes/op as the average size of a frame on the wire for each option
+// zlib-stream keeps one context per connection so later frames compress against earlier ones
+func BenchmarkEncode(b *testing.B) {
+	for _, bench := range []struct {
+		encoding string
+		compress string
+	}{
+		{ENCODING_JSON, ""},
+		{ENCODING_MSGPACK, ""},
+		{ENCODING_JSON, COMPRESS_ZLIB_STREAM},
+		{ENCODING_MSGPACK, COMPRESS_ZLIB_STREAM},
+	} {
+		name := bench.encoding
+		if bench.compress != "" {
+			name += "+" + bench.compress
+		}
+		b.Run(name, func(b *testing.B) {
+			c := newTestClient(bench.encoding, bench.compress)
+			list := frames(b.N) //no repeats, zlib would shrink a repeated frame to almost nothing
+			total := 0
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, data, err := c.encode(list[i])
+				if err != nil {
+					b.Fatal(err)
+				}
+				total += len(data)
+			}
+			b.ReportMetric(float64(total)/float64(b.N), "bytes/op")
+		})
+	}
+}
+
+func TestEncodeZlibStream(t *testing.T) {
+	//clients keep one inflater for the connection and feed it every frame
+	c := newTestClient(ENCODING_MSGPACK, COMPRESS_ZLIB_STREAM)
+	var stream bytes.Buffer
+	list := frames(10)
+	sizes := make([]int, len(list))
+	for i, frame := range list {
+		_, data, err := c.encode(frame)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasSuffix(data, []byte{0, 0, 0xff, 0xff}) {
+			t.Fatalf("frame %d doesnt end in a sync flush", i)
+		}
+		stream.Write(data)
+		expected, err := msgpack.Marshal(frame)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sizes[i] = len(expected)
+	}
+	r, err := zlib.NewReader(&stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, frame := range list {
+		data := make([]byte, sizes[i])
+		if _, err := io.ReadFull(r, data); err != nil {
+			t.Fatalf("frame %d: %v", i, err)
+		}
+		got, err := msgpack.ToJSON(data)
+		if err != nil {
+			t.Fatalf("frame %d: %v", i, err)
+		}
+		want, _ := json.Marshal(frame)
+		var gotValue, wantValue interface{} //key order differs so compare what they decode to
+		json.Unmarshal(got, &gotValue)
+		json.Unmarshal(want, &wantValue)
+		if !reflect.DeepEqual(gotValue, wantValue) {
+			t.Errorf("frame %d: got %s, want %s", i, got, want)
+		}
+	}
+}
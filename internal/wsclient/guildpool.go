@@ -4,9 +4,10 @@ import (
 	"context"
 	"time"
 
-	"github.com/asianchinaboi/backendserver/internal/config"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/logger"
+	"github.com/asianchinaboi/backendserver/internal/metrics"
+	"github.com/asianchinaboi/backendserver/internal/tracing"
 )
 
 type addClientData struct {
@@ -20,6 +21,7 @@ type (
 )
 
 type guildPool struct {
+	pools         *ClientPools
 	guildId       int64
 	clients       map[string]brcastEvents //channel of clients
 	Remove        removeClient            //channel to broadcast which client to remove
@@ -61,7 +63,7 @@ func (p *guildPool) run() {
 			}
 		} //welp it actually works christ
 		//Dont remove this code or else everything will break
-		Pools.RemoveFromGuildPool(p.guildId)
+		p.pools.RemoveFromGuildPool(p.guildId)
 		//I think this is the only thing thats needed to prevent a deadlock since lockpool will release the lock
 		close(p.Broadcast)
 		close(p.Remove)
@@ -74,7 +76,9 @@ func (p *guildPool) run() {
 		case data := <-p.Add:
 			p.clients[data.UniqueId] = data.Ch
 		case data := <-p.Broadcast:
-			Pools.BroadcastClientUIDMap(p.clients, data) // (BIG BAD BUG) problem this gets called before pool removal thus call on closed channel occurs
+			_, span := tracing.Start(tracing.Extract(context.Background(), data.Trace), "pool.fanout", tracing.KindConsumer, "guild.id", p.guildId, "clients", len(p.clients))
+			p.pools.BroadcastClientUIDMap(p.clients, data) // (BIG BAD BUG) problem this gets called before pool removal thus call on closed channel occurs
+			span.End()
 		case <-p.quitCtx.Done():
 			return
 		case <-p.deadline.C: //check if pool is empty every interval
@@ -86,7 +90,15 @@ func (p *guildPool) run() {
 	}
 }
 
-func (p *pools) BroadcastGuild(guildId int64, data DataFrame) error {
+func (p *ClientPools) BroadcastGuild(guildId int64, data DataFrame) error {
+	return p.BroadcastGuildContext(context.Background(), guildId, data)
+}
+
+// BroadcastGuildContext is BroadcastGuild as part of a trace, the traceparent is sent with the event
+func (p *ClientPools) BroadcastGuildContext(ctx context.Context, guildId int64, data DataFrame) error {
+	ctx, span := tracing.Start(ctx, "pool.broadcast", tracing.KindProducer, "guild.id", guildId, "event", data.Event)
+	defer span.End()
+	data.Trace = tracing.Traceparent(ctx)
 	p.guildsMutex.RLock() //prevents datarace
 	defer p.guildsMutex.RUnlock()
 	guildPool, ok := p.guilds[guildId]
@@ -96,19 +108,22 @@ func (p *pools) BroadcastGuild(guildId int64, data DataFrame) error {
 	if guildPool.Disconnecting {
 		return nil
 	}
+	metrics.BroadcastQueue.Inc()
 	guildPool.Broadcast <- data //stuck here
+	metrics.BroadcastQueue.Dec()
 	return nil
 }
 
-func (p *pools) AddUserToGuildPool(guildId int64, userId int64) {
+func (p *ClientPools) AddUserToGuildPool(guildId int64, userId int64) {
 	p.guildsMutex.Lock()
 	defer p.guildsMutex.Unlock()
 	p.clientsMutex.RLock()
 	defer p.clientsMutex.RUnlock()
 	if _, ok := p.guilds[guildId]; !ok {
 		quitCtx, quit := context.WithCancel(context.Background())
-		deadline := time.NewTicker(config.Config.Guild.Timeout)
+		deadline := time.NewTicker(p.conf.Guild.Timeout)
 		pool := &guildPool{
+			pools:         p,
 			guildId:       guildId,
 			clients:       make(map[string]brcastEvents),
 			Remove:        make(removeClient),
@@ -134,7 +149,7 @@ func (p *pools) AddUserToGuildPool(guildId int64, userId int64) {
 	}
 }
 
-func (p *pools) AddUIDToGuildPool(guildId int64, uid string, broadcast brcastEvents) {
+func (p *ClientPools) AddUIDToGuildPool(guildId int64, uid string, broadcast brcastEvents) {
 	p.guildsMutex.Lock()
 	defer p.guildsMutex.Unlock()
 	p.clientsMutex.RLock()
@@ -146,8 +161,9 @@ func (p *pools) AddUIDToGuildPool(guildId int64, uid string, broadcast brcastEve
 	}
 	if !ok {
 		quitCtx, quit := context.WithCancel(context.Background())
-		deadline := time.NewTicker(config.Config.Guild.Timeout)
+		deadline := time.NewTicker(p.conf.Guild.Timeout)
 		newPool := &guildPool{
+			pools:         p,
 			guildId:       guildId,
 			clients:       make(map[string]brcastEvents),
 			Remove:        make(removeClient),
@@ -166,7 +182,7 @@ func (p *pools) AddUIDToGuildPool(guildId int64, uid string, broadcast brcastEve
 	}
 }
 
-func (p *pools) RemoveUIDFromGuildPool(guildId int64, uid string) {
+func (p *ClientPools) RemoveUIDFromGuildPool(guildId int64, uid string) {
 	p.guildsMutex.RLock()
 	defer p.guildsMutex.RUnlock()
 	p.clientsMutex.RLock()
@@ -179,7 +195,7 @@ func (p *pools) RemoveUIDFromGuildPool(guildId int64, uid string) {
 	p.guilds[guildId].Remove <- uid //RACE CONDITION This may be the cause of the websockets being stopped
 }
 
-func (p *pools) RemoveUserFromGuildPool(guildId int64, userId int64) { //most likely occurs here (WEBSOCKET BUG)
+func (p *ClientPools) RemoveUserFromGuildPool(guildId int64, userId int64) { //most likely occurs here (WEBSOCKET BUG)
 	p.guildsMutex.RLock()
 	defer p.guildsMutex.RUnlock()
 	p.clientsMutex.Lock()
@@ -196,13 +212,13 @@ func (p *pools) RemoveUserFromGuildPool(guildId int64, userId int64) { //most li
 	} //removes all instances of the client alias from the pool to avoid exploits
 }
 
-func (p *pools) RemoveFromGuildPool(guildId int64) {
+func (p *ClientPools) RemoveFromGuildPool(guildId int64) {
 	p.guildsMutex.Lock()
 	defer p.guildsMutex.Unlock()
 	delete(p.guilds, guildId)
 }
 
-func (p *pools) GetLengthGuilds() int {
+func (p *ClientPools) GetLengthGuilds() int {
 	p.guildsMutex.RLock()
 	defer p.guildsMutex.RUnlock()
 	return len(p.guilds)
@@ -1,9 +1,19 @@
 package wsclient
 
+import "encoding/json"
+
 type DataFrame struct {
 	Op    int         `json:"op"`   //opcode (shows what datatype)
 	Data  interface{} `json:"data"` //contains data
 	Event string      `json:"event"`
+	Trace string      `json:"trace,omitempty"` //traceparent of whatever sent it so clients can tell how long events took to arrive
+}
+
+// receivedFrame is what clients send, data is decoded once the op is known
+type receivedFrame struct {
+	Op    *int            `json:"op"`
+	Data  json.RawMessage `json:"data"`
+	Event string          `json:"event"`
 }
 
 type helloFrame struct {
@@ -3,26 +3,37 @@ package wsclient
 import (
 	"sync"
 
+	"github.com/asianchinaboi/backendserver/internal/config"
 	"github.com/asianchinaboi/backendserver/internal/events"
+	"github.com/asianchinaboi/backendserver/internal/logger"
 )
 
-type pools struct {
+// ClientPools has every connection by user and every guild pool, the app creates one
+// connections get the config and logger from here
+type ClientPools struct {
 	guildsMutex  sync.RWMutex
 	clientsMutex sync.RWMutex
 	guilds       map[int64]*guildPool
 	clients      map[int64]map[string]brcastEvents
-}
+	conf         *config.Settings
+	log          *logger.Logger
 
-var Pools *pools
+	draining    int32
+	connMutex   sync.Mutex
+	connections map[*wsClient]struct{} //every connection, identified or not, see drain.go
+}
 
-func NewPools() *pools {
-	return &pools{
-		guilds:  make(map[int64]*guildPool),
-		clients: make(map[int64]map[string]brcastEvents),
+func NewPools(conf *config.Settings, log *logger.Logger) *ClientPools {
+	return &ClientPools{
+		guilds:      make(map[int64]*guildPool),
+		clients:     make(map[int64]map[string]brcastEvents),
+		conf:        conf,
+		log:         log,
+		connections: make(map[*wsClient]struct{}),
 	}
 }
 
-func (p *pools) RemoveAll() {
+func (p *ClientPools) RemoveAll() {
 	p.guildsMutex.Lock()
 	defer p.guildsMutex.Unlock()
 	p.clientsMutex.Lock()
@@ -41,7 +52,3 @@ func (p *pools) RemoveAll() {
 	p.guilds = make(map[int64]*guildPool)
 	p.clients = make(map[int64]map[string]brcastEvents)
 }
-
-func init() {
-	Pools = NewPools()
-}
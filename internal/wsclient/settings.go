@@ -14,7 +14,25 @@ const (
 	TYPE_IDENTIFY
 	TYPE_HELLO
 	TYPE_READY
+	TYPE_RECONNECT          //server is going away, reconnect (the load balancer will pick another instance)
 	TYPE_CLOSE        = 0x8 //(used when client has done something invalid causing ws to close)
 	TYPE_HEARTBEAT    = 0x9
 	TYPE_HEARTBEATACK = 0xa
 )
+
+// close codes sent when the client gets kicked so it knows what it did wrong
+const (
+	CLOSE_UNKNOWN_OP            = 4001
+	CLOSE_DECODE_ERROR          = 4002 //invalid json or a payload that doesnt match the op
+	CLOSE_NOT_AUTHENTICATED     = 4003 //didnt identify in time
+	CLOSE_AUTH_FAILED           = 4004
+	CLOSE_ALREADY_AUTHENTICATED = 4005
+	CLOSE_TOKEN_EXPIRED         = 4006
+	CLOSE_TOO_MANY_SESSIONS     = 4007
+	CLOSE_RATE_LIMITED          = 4008
+)
+
+var opNames = map[int]string{ //used for the rate limits in the config
+	TYPE_IDENTIFY:  "identify",
+	TYPE_HEARTBEAT: "heartbeat",
+}
@@ -1,61 +1,84 @@
 package wsclient
 
 import (
+	"bytes"
+	"compress/zlib"
 	"context"
+	"database/sql"
+	"strconv"
+	"sync"
 	"time"
 
-	"github.com/asianchinaboi/backendserver/internal/db"
+	"github.com/asianchinaboi/backendserver/internal/cooldown"
 	"github.com/asianchinaboi/backendserver/internal/logger"
+	"github.com/asianchinaboi/backendserver/internal/metrics"
+	"github.com/asianchinaboi/backendserver/internal/uid"
 	"github.com/gorilla/websocket"
 )
 
 type brcastEvents chan DataFrame
 type wsClient struct {
 	ws       *websocket.Conn
+	db       *sql.DB
+	pools    *ClientPools
 	id       int64
 	uniqueId string //since some guys might be using multiple connections on one account
+	connId   string //set as soon as it connects unlike uniqueId, only used for logging
+	log      *logger.Logger
 	//	guilds         []int  //not used might remove later
 	broadcast      brcastEvents
+	replies        brcastEvents //frames from readPipe, seperate from broadcast since that gets closed
+	encoding       string
+	compressor     *zlib.Writer //nil if not compressing, writes into compressed
+	compressed     bytes.Buffer
 	quitctx        context.Context //chan bool //also temporary maybe use contexts later
 	quit           context.CancelFunc
 	deadline       context.Context
 	deadlineCancel context.CancelFunc
+	limiter        *cooldown.Memory //per connection so its fine to keep in memory
+	closeMutex     sync.Mutex
+	closeCode      int //sent in the close frame, 0 for a normal closure
+	closeReason    string
 	//	keepAlive bool //temporary try find solution
 }
 
 func (c *wsClient) Run() {
+	c.track()
+	defer c.untrack() //runs last so draining waits for the cleanup below
 	defer func() {
 		if err := c.ws.Close(); err != nil {
-			logger.Warn.Println("an error occured when leaving websocket: ", err)
+			c.log.Warn("an error occured when leaving websocket", "error", err)
 			return
 		}
 
-		logger.Info.Println("Websocket of " + c.ws.LocalAddr().String() + " has been closed")
+		c.log.Info("websocket closed")
 		//leaves the guild pools
-		rows, err := db.Db.Query("SELECT guild_id FROM userguilds WHERE user_id = $1 AND banned = false", c.id)
+		rows, err := c.db.Query("SELECT guild_id FROM userguilds WHERE user_id = $1 AND banned = false", c.id)
 		if err != nil {
-			logger.Error.Printf("an error occured when getting guilds of user %v\n", err)
+			c.log.Error("an error occured when getting guilds of user", "error", err)
 			return
 		}
 		for rows.Next() { //should be using guilds array instead lol
 			var guildId int64
 			err = rows.Scan(&guildId)
 			if err != nil {
-				logger.Error.Printf("an error occured when getting guilds of user %v\n", err)
+				c.log.Error("an error occured when getting guilds of user", "error", err)
 				return
 			}
 
-			Pools.RemoveUserFromGuildPool(guildId, c.id)
+			c.pools.RemoveUserFromGuildPool(guildId, c.id)
 		}
 
 		//moved line here to stop close channel errors
-		Pools.removeUserUIDFromClientPool(c.id, c.uniqueId) // dont move this line above where guilds is deleted
+		c.pools.removeUserUIDFromClientPool(c.id, c.uniqueId) // dont move this line above where guilds is deleted
 		//order is extremely important
 
 		close(c.broadcast) //close of nil channel error occurs here sometimes
 		c.deadlineCancel()
 	}()
-	logger.Info.Println("Websocket active of " + c.ws.LocalAddr().String())
+	c.log.Info("websocket active")
+	metrics.WebsocketConnections.Inc()
+	defer metrics.WebsocketConnections.Dec()
 
 	c.hello()
 	go c.tokenDeadline()
@@ -66,7 +89,7 @@ func (c *wsClient) Run() {
 func (c *wsClient) tokenDeadline() {
 	<-c.deadline.Done() //crash sometimes happens here (invalid memory address or nil pointer dereference)
 	if c.deadline.Err() != context.Canceled {
-		c.quit()
+		c.closeWith(CLOSE_NOT_AUTHENTICATED, "identify timed out")
 		return
 	}
 }
@@ -75,13 +98,25 @@ func (c *wsClient) tokenExpireDeadline(expireTime int64) { //i have no idea if t
 	timeLeft := time.Until(time.Now().Add(time.Duration(expireTime) * time.Second))
 	select {
 	case <-time.After(timeLeft):
-		c.quit()
+		c.closeWith(CLOSE_TOKEN_EXPIRED, "token expired")
 	case <-c.quitctx.Done():
 		return
 	}
 
 }
 
+// closeWith quits with a close code, only the first code is kept if theres more than one
+func (c *wsClient) closeWith(code int, reason string) {
+	c.closeMutex.Lock()
+	if c.closeCode == 0 {
+		c.closeCode = code
+		c.closeReason = reason
+		metrics.WebsocketCloses.Inc(strconv.Itoa(code))
+	}
+	c.closeMutex.Unlock()
+	c.quit()
+}
+
 func (c *wsClient) hello() {
 	body := DataFrame{
 		Op: TYPE_HELLO,
@@ -90,9 +125,9 @@ func (c *wsClient) hello() {
 		},
 		Event: "",
 	}
-	err := c.ws.WriteJSON(body)
+	err := c.write(body) //writePipe hasnt started yet
 	if err != nil {
-		logger.Error.Println(err)
+		c.log.Error("unable to send hello", "error", err)
 		c.quit()
 		return
 	}
@@ -101,16 +136,26 @@ func (c *wsClient) hello() {
 	c.deadlineCancel = cancelFunc
 }
 
-func NewWsClient(ws *websocket.Conn) (*wsClient, error) {
+func NewWsClient(ws *websocket.Conn, conn *sql.DB, pools *ClientPools, encoding string, compress string) (*wsClient, error) {
 	ctx := context.Background()
 	quit, quitFunc := context.WithCancel(ctx)
 	instanceuser := wsClient{
 		ws:        ws,
+		db:        conn,
+		pools:     pools,
 		id:        0,  //user id will be received when user sends identify payload
 		uniqueId:  "", //uniqueId,
 		broadcast: make(brcastEvents),
+		replies:   make(brcastEvents),
+		encoding:  encoding,
 		quitctx:   quit,
 		quit:      quitFunc,
+		limiter:   cooldown.NewMemory(),
+	}
+	instanceuser.connId = uid.Snowflake.Generate().String()
+	instanceuser.log = pools.log.With("connId", instanceuser.connId, "ip", ws.RemoteAddr().String())
+	if compress == COMPRESS_ZLIB_STREAM {
+		instanceuser.compressor = zlib.NewWriter(&instanceuser.compressed)
 	}
 	return &instanceuser, nil
 }
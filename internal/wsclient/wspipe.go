@@ -1,36 +1,62 @@
 package wsclient
 
 import (
+	"bytes"
 	"encoding/json"
+	"io"
 	"time"
 
-	"github.com/asianchinaboi/backendserver/internal/config"
-	"github.com/asianchinaboi/backendserver/internal/db"
+	"github.com/asianchinaboi/backendserver/internal/cooldown"
 	"github.com/asianchinaboi/backendserver/internal/errors"
 	"github.com/asianchinaboi/backendserver/internal/events"
-	"github.com/asianchinaboi/backendserver/internal/logger"
+	"github.com/asianchinaboi/backendserver/internal/metrics"
 	"github.com/asianchinaboi/backendserver/internal/session"
 	"github.com/gorilla/websocket"
 )
 
 func (c *wsClient) readPipe() {
-	c.ws.SetReadDeadline(time.Now().Add(pingDelay)) //note to self put that thing in seconds otherwise its goddamn miliseconds which is hard to debug
-	for {                                           //need to check for quit
-		_, message, err := c.ws.ReadMessage()
+	c.ws.SetReadLimit(c.pools.conf.Gateway.MaxFrameSize) //gorilla closes with 1009 if a frame is bigger
+	c.ws.SetReadDeadline(time.Now().Add(pingDelay))      //note to self put that thing in seconds otherwise its goddamn miliseconds which is hard to debug
+	for {                                                //need to check for quit
+		messageType, message, err := c.ws.ReadMessage()
 		if err != nil { //should usually return io error which is fine since it means the websocket has timeouted
-			logger.Error.Println(err) //or if the websocket has closed which is a 1000 (normal)
-			c.quit()                  //if recieve websocket closed error then you gotta do what you gotta do
-			logger.Info.Printf("Disconnecting websocket: %v\n", c.ws.RemoteAddr().String())
+			c.log.Info("websocket read failed", "error", err) //or if the websocket has closed which is a 1000 (normal)
+			c.quit()                                          //if recieve websocket closed error then you gotta do what you gotta do
+			c.log.Info("disconnecting websocket")
 			return
 		}
-		var received DataFrame
-		err = json.Unmarshal(message, &received)
+		var received receivedFrame
+		if err = c.decode(messageType, message, &received); err == nil && received.Op == nil {
+			err = errors.ErrGatewayInvalidFrame
+		}
 		if err != nil {
-			logger.Warn.Printf("an error occured during unmarshalling with websocket: %v: %v", c.ws.LocalAddr().String(), err.Error())
-			continue
+			c.log.Warn("an error occured during unmarshalling with websocket", "error", err)
+			c.closeWith(CLOSE_DECODE_ERROR, "invalid frame")
+			return
 		}
 		c.readData(received)
+		if c.quitctx.Err() != nil { //got kicked while handling it
+			return
+		}
+	}
+}
+
+// decodeStrict is json.Unmarshal but unknown fields and anything after the value are errors
+func decodeStrict(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return errors.ErrGatewayInvalidFrame
 	}
+	return nil
+}
+
+// hasData is true when the frame has something other than null in data
+func hasData(data json.RawMessage) bool {
+	return len(data) > 0 && string(data) != "null"
 }
 
 func (c *wsClient) writePipe() {
@@ -42,73 +68,100 @@ func (c *wsClient) writePipe() {
 			if !ok { //idk if this is needed or not
 				c.quit() //call cancel but never actually recieve it
 			}
-			c.ws.WriteJSON(data)
+			if err := c.write(data); err != nil {
+				c.log.Warn("error occurred when writing to websocket", "error", err)
+			}
 
 			if data.Event == events.LOG_OUT { //maybe find other solutions later
 				c.quit() // this shouldn't return as c.quitctx.Done() will not be handled
 			}
+		case data := <-c.replies:
+			if err := c.write(data); err != nil {
+				c.log.Warn("error occurred when writing to websocket", "error", err)
+			}
 		case <-c.quitctx.Done(): //<-c.quit:
-			closeMessage := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "bye")
+			code, reason := websocket.CloseNormalClosure, "bye"
+			c.closeMutex.Lock()
+			if c.closeCode != 0 {
+				code, reason = c.closeCode, c.closeReason
+			}
+			c.closeMutex.Unlock()
+			closeMessage := websocket.FormatCloseMessage(code, reason)
 			if err := c.ws.WriteMessage(websocket.CloseMessage, closeMessage); err != nil {
-				logger.Warn.Printf("Error occurred when writing closure message: %v\n", err)
+				c.log.Warn("error occurred when writing closure message", "error", err)
 			}
 			return
 		}
 	}
 }
 
-func (c *wsClient) readData(body DataFrame) {
-	switch body.Op {
+func (c *wsClient) readData(body receivedFrame) {
+	name, ok := opNames[*body.Op]
+	if !ok {
+		c.log.Warn("invalid op", "op", *body.Op)
+		c.closeWith(CLOSE_UNKNOWN_OP, "unknown op")
+		return
+	}
+	if rule, ok := c.pools.conf.Gateway.OpRateLimits[name]; ok && rule.Limit > 0 && rule.Window > 0 {
+		result, _ := c.limiter.Take(c.quitctx, name, cooldown.Rule{Limit: rule.Limit, Window: rule.Window}) //memory never errors
+		if !result.Allowed {
+			metrics.RateLimitRejections.Inc("gateway " + name)
+			c.closeWith(CLOSE_RATE_LIMITED, "too many "+name+" frames")
+			return
+		}
+	}
+
+	switch *body.Op {
 	case TYPE_HEARTBEAT:
+		if hasData(body.Data) {
+			c.closeWith(CLOSE_DECODE_ERROR, "heartbeat has no data")
+			return
+		}
 		c.ws.SetReadDeadline(time.Now().Add(pingDelay))
 		res := DataFrame{
 			Op: TYPE_HEARTBEATACK,
 		}
-		c.ws.WriteJSON(res)
+		c.reply(res)
 	case TYPE_IDENTIFY:
 		if c.id > 0 {
-			c.quit()
-			return
-		}
-		bytes, err := json.Marshal(body.Data) //shit but temporary for now until I find another solution
-		if err != nil {                       //to convert inner into interface and not map[string]interface{}
-			logger.Error.Println(err)
-			c.quit()
+			c.closeWith(CLOSE_ALREADY_AUTHENTICATED, "already identified")
 			return
 		}
 		var data helloResFrame
-		err = json.Unmarshal(bytes, &data)
-		if err != nil {
-			logger.Error.Println(err)
-			c.quit()
+		if err := decodeStrict(body.Data, &data); err != nil || data.Token == "" {
+			c.log.Warn("invalid identify payload", "error", err)
+			c.closeWith(CLOSE_DECODE_ERROR, "invalid identify payload")
 			return
 		}
 		Token := data.Token
-		user, err := session.CheckToken(Token)
+		user, err := session.CheckAuthorization(c.db, Token) //bots identify with "Bot <token>"
 		if err != nil {
-			logger.Error.Println(err)
+			c.log.Warn("identify failed", "error", err)
 			res := DataFrame{
 				Op:    TYPE_DISPATCH,
 				Event: events.LOG_OUT,
 			}
-			c.ws.WriteJSON(res)
-			c.quit()
+			c.reply(res)
+			c.closeWith(CLOSE_AUTH_FAILED, "authentication failed")
 			return
 		}
 
 		c.deadlineCancel()
 		c.id = user.Id
-		if Pools.GetLengthForClient(c.id) >= config.Config.User.WSPerUser {
-			logger.Error.Println(errors.ErrSessionTooManySessions)
-			c.quit()
+		if c.pools.GetLengthForClient(c.id) >= c.pools.conf.User.WSPerUser {
+			c.log.Warn("identify rejected", "error", errors.ErrSessionTooManySessions)
+			c.closeWith(CLOSE_TOO_MANY_SESSIONS, "too many sessions")
 			return
 		}
-		go c.tokenExpireDeadline(user.Expires)
+		if !user.Bot { //bot tokens dont expire
+			go c.tokenExpireDeadline(user.Expires)
+		}
 		c.uniqueId = session.GenerateRandString(32)
+		c.log.Info("identified", "userId", c.id, "uniqueId", c.uniqueId)
 
-		rows, err := db.Db.Query("SELECT guild_id FROM userguilds WHERE user_id=$1 AND (left_dm = false OR left_dm IS NULL)", c.id)
+		rows, err := c.db.Query("SELECT guild_id FROM userguilds WHERE user_id=$1 AND (left_dm = false OR left_dm IS NULL)", c.id)
 		if err != nil {
-			logger.Error.Println(err)
+			c.log.Error("unable to get guilds", "error", err)
 			c.quit()
 			return
 		}
@@ -116,17 +169,15 @@ func (c *wsClient) readData(body DataFrame) {
 		for rows.Next() {
 			var guild int64
 			rows.Scan(&guild)
-			Pools.AddUIDToGuildPool(guild, c.uniqueId, c.broadcast)
+			c.pools.AddUIDToGuildPool(guild, c.uniqueId, c.broadcast)
 		}
 
 		//get all the guilds the user is in
 		rows.Close()
-		Pools.AddUserToClientPool(c.id, c.uniqueId, c.broadcast)
+		c.pools.AddUserToClientPool(c.id, c.uniqueId, c.broadcast)
 		res := DataFrame{
 			Op: TYPE_READY,
 		}
-		c.ws.WriteJSON(res)
-	default:
-		logger.Warn.Printf("Invalid Op: %v\n", body.Op)
+		c.reply(res)
 	}
 }